@@ -19,19 +19,43 @@ func main() {
 	cfg := config.Load()
 	fmt.Println("Configuration loaded successfully!")
 
-	err := database.InitDatabase(cfg)
+	repo, err := database.InitDatabase(cfg)
 	if err != nil {
 		log.Fatal("Database error:", err)
 	}
-	fmt.Println("Database initialized successfully!")
+	defer repo.Close()
+	if err := repo.Migrate(); err != nil {
+		log.Fatal("Database migration error:", err)
+	}
+	fmt.Printf("Database initialized successfully! (backend: %s)\n", cfg.DatabaseBackend)
 
-	docService := services.NewDocumentService()
-	storageService := services.NewStorageService(cfg)
+	docService := services.NewDocumentService(repo, cfg)
+	storageService, err := services.NewStorageService(cfg)
+	if err != nil {
+		log.Fatal("Storage error:", err)
+	}
+	fmt.Printf("Storage backend initialized: %s\n", cfg.StorageBackend)
 	openAPIGeneratorService := services.NewOpenAPIGeneratorService(cfg)
+	retentionService := services.NewRetentionService(docService, storageService, cfg)
+	changelogService := services.NewChangelogService(storageService)
+	lintService, err := services.NewLintService(storageService, cfg)
+	if err != nil {
+		log.Fatal("Lint configuration error:", err)
+	}
+	stopBackground := make(chan struct{})
+	defer close(stopBackground)
+	retentionService.Start(stopBackground)
+	services.NewJanitorService(docService, storageService, cfg).Start(stopBackground)
 
-	uploadHandler := handlers.NewUploadHandler(docService, storageService, cfg)
-	viewerHandler := handlers.NewViewerHandler(docService, storageService, cfg)
-	apiHandler := handlers.NewApiHandler(docService, storageService, openAPIGeneratorService, cfg)
+	specPipeline, err := services.NewSpecPipeline(cfg)
+	if err != nil {
+		log.Fatal("Redaction configuration error:", err)
+	}
+	uploadHandler := handlers.NewUploadHandler(docService, storageService, lintService, cfg)
+	viewerHandler := handlers.NewViewerHandler(docService, storageService, specPipeline, cfg)
+	apiHandler := handlers.NewApiHandler(docService, storageService, openAPIGeneratorService, retentionService, changelogService, lintService, specPipeline, cfg)
+	mockHandler := handlers.NewMockHandler(docService, storageService, specPipeline)
+	proxyHandler := handlers.NewProxyHandler(docService, storageService, specPipeline, cfg)
 
 	router := gin.Default()
 
@@ -135,8 +159,35 @@ func main() {
 
 	router.GET("/api/document/:id/content", apiHandler.GetDocumentContent)
 	router.GET("/api/document/:id/versions", apiHandler.GetDocumentVersions)
+	router.GET("/api/document/:id/diff", apiHandler.GetDocumentDiff)
+	router.GET("/api/document/:id/index", apiHandler.GetDocumentIndex)
+	router.GET("/api/document/:id/changelog", apiHandler.GetDocumentChangelog)
+	router.GET("/api/document/:id/export/:format", apiHandler.ExportDocument)
+	router.PATCH("/api/document/:id/version/:version", apiHandler.UpdateVersion)
+	router.GET("/api/document/:id/version/:version/lint", apiHandler.GetVersionLint)
+	router.GET("/api/document/:id/version/:version/overlays/:name", apiHandler.GetVersionOverlay)
 	if cfg.AllowCustomShareLink {
 		router.POST("/api/document/:id/share", apiHandler.SetShareLink)
+		router.GET("/api/share/:slug/content", apiHandler.GetSharedContent)
+	}
+
+	// Per-document serving policies change what everyone else is served, so
+	// they need ADMIN_TOKEN rather than just the document ID (conditional)
+	if cfg.AdminToken != "" {
+		admin := router.Group("/api/document/:id", handlers.RequireAdminToken(cfg.AdminToken))
+		admin.PUT("/servers", apiHandler.SetServerPolicy)
+		admin.PUT("/redaction", apiHandler.SetRedactionPolicy)
+	}
+
+	// Overlay editing (conditional); overlays rewrite what the viewer and /content serve
+	if cfg.AllowOverlayEdit {
+		router.PUT("/api/document/:id/version/:version/overlays/:name", apiHandler.PutVersionOverlay)
+		router.DELETE("/api/document/:id/version/:version/overlays/:name", apiHandler.DeleteVersionOverlay)
+	}
+
+	// Renewal (conditional); MAX_DOCUMENT_LIFETIME still bounds it
+	if cfg.AllowDocumentRenewal {
+		router.POST("/api/document/:id/expiry", apiHandler.RenewDocument)
 	}
 
 	// Basic health endpoint
@@ -147,12 +198,28 @@ func main() {
 		})
 	})
 
-	// Version deletion endpoint (conditional)
+	// Version deletion endpoints (conditional); a per-document retention
+	// override can make the pruner delete versions too
 	if cfg.AllowVersionDeletion {
 		router.DELETE("/api/document/:id/version/:version", apiHandler.DeleteDocumentVersion)
+		router.PUT("/api/document/:id/retention", apiHandler.SetRetentionPolicy)
 	}
 	if cfg.AllowVersionDownload {
 		router.GET("/api/document/:id/version/:version/download", apiHandler.DownloadDocumentVersion)
+		router.GET("/api/document/:id/version/:version/source", apiHandler.DownloadVersionSource)
+	}
+
+	// Try it out proxy (conditional); documents whose servers are stripped are refused
+	if len(cfg.ProxyAllowedHosts) > 0 {
+		router.Any("/api/document/:id/proxy", proxyHandler.Proxy)
+	}
+
+	// Mock server (conditional)
+	if cfg.MockServerEnabled {
+		router.Any("/mock/:id/*path", mockHandler.Mock)
+		if cfg.AllowCustomShareLink {
+			router.Any("/mock/share/:slug/*path", mockHandler.MockByShare)
+		}
 	}
 
 	fmt.Printf("Server starting on port %s...\n", cfg.Port)
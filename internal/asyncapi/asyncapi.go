@@ -0,0 +1,250 @@
+// Package asyncapi works on AsyncAPI 2.x and 3.x documents in their generic
+// (map) form: parsing, validation, indexing and diffing. Channels, operations
+// and messages of both major versions are indexed the same way, so a document
+// can be compared across a 2.x to 3.x upgrade.
+package asyncapi
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"APIScope/internal/openapi"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a parsed AsyncAPI document.
+type Document struct {
+	Root    map[string]any
+	Version string // value of the asyncapi field, e.g. 3.0.0
+
+	refs *openapi.Spec // resolves local $refs of Root
+}
+
+// Detect reports whether content is a YAML or JSON document with an asyncapi
+// version field.
+func Detect(content []byte) bool {
+	var probe struct {
+		AsyncAPI any `yaml:"asyncapi"`
+	}
+	return yaml.Unmarshal(content, &probe) == nil && probe.AsyncAPI != nil
+}
+
+// Parse reads a YAML or JSON AsyncAPI document.
+func Parse(content []byte) (*Document, error) {
+	var raw any
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("invalid YAML or JSON format: %w", err)
+	}
+	root, ok := openapi.Normalize(raw).(map[string]any)
+	if !ok {
+		return nil, errors.New("document root must be an object")
+	}
+	version, _ := root["asyncapi"].(string)
+	if !strings.HasPrefix(version, "2.") && !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("unsupported asyncapi version %v (expected 2.x or 3.x)", root["asyncapi"])
+	}
+	return &Document{Root: root, Version: version, refs: &openapi.Spec{Root: root}}, nil
+}
+
+// IsV2 reports whether the document is AsyncAPI 2.x.
+func (d *Document) IsV2() bool {
+	return strings.HasPrefix(d.Version, "2.")
+}
+
+// Title returns info.title.
+func (d *Document) Title() string {
+	title, _ := asMap(d.Root["info"])["title"].(string)
+	return title
+}
+
+// Resolve follows local $ref chains of a node.
+func (d *Document) Resolve(node any) any {
+	return d.refs.Resolve(node)
+}
+
+// Channel is one channel of a document.
+type Channel struct {
+	Address string         // the channel address, or its id when it has none
+	ID      string         // key in the channels map
+	Node    map[string]any // the resolved channel object
+}
+
+// Operation is one send or receive operation. AsyncAPI 2.x publish operations
+// are receive operations of the described application, subscribe ones send
+// operations.
+type Operation struct {
+	Action   string         // send or receive
+	Channel  string         // address of the channel
+	ID       string         // operationId (2.x) or key in the operations map (3.x)
+	Messages []string       // names of the messages the operation carries
+	Node     map[string]any // the resolved operation object
+}
+
+// Key identifies the operation, e.g. "send user/signedup".
+func (o Operation) Key() string {
+	return o.Action + " " + o.Channel
+}
+
+// Channels lists the channels of the document by address.
+func (d *Document) Channels() map[string]Channel {
+	out := map[string]Channel{}
+	for id, raw := range asMap(d.Root["channels"]) {
+		node := asMap(d.Resolve(raw))
+		address := id
+		if !d.IsV2() {
+			if a, ok := node["address"].(string); ok && a != "" {
+				address = a
+			}
+		}
+		out[address] = Channel{Address: address, ID: id, Node: node}
+	}
+	return out
+}
+
+// Operations lists the operations of the document by key. Operations sharing a
+// key are told apart by their id, e.g. "send orders (placeOrder)".
+func (d *Document) Operations() map[string]Operation {
+	var ops []Operation
+	channels := d.Channels()
+	if d.IsV2() {
+		for _, address := range openapi.SortedKeys(channels) {
+			ch := channels[address]
+			for keyword, action := range map[string]string{"publish": "receive", "subscribe": "send"} {
+				op := asMap(d.Resolve(ch.Node[keyword]))
+				if op == nil {
+					continue
+				}
+				id, _ := op["operationId"].(string)
+				ops = append(ops, Operation{Action: action, Channel: address, ID: id, Messages: d.v2Messages(op), Node: op})
+			}
+		}
+	} else {
+		byID := map[string]string{}
+		for address, ch := range channels {
+			byID["#/channels/"+escapePointer(ch.ID)] = address
+		}
+		for id, raw := range asMap(d.Root["operations"]) {
+			op := asMap(d.Resolve(raw))
+			action, _ := op["action"].(string)
+			ref, _ := asMap(op["channel"])["$ref"].(string)
+			address := byID[ref]
+			if address == "" {
+				address = ref
+			}
+			var messages []string
+			for _, m := range asList(op["messages"]) {
+				if ref, ok := asMap(m)["$ref"].(string); ok {
+					messages = append(messages, ref[strings.LastIndex(ref, "/")+1:])
+				}
+			}
+			if len(messages) == 0 {
+				// No list means every message of the channel
+				messages = openapi.SortedKeys(asMap(channels[address].Node["messages"]))
+			}
+			sort.Strings(messages)
+			ops = append(ops, Operation{Action: action, Channel: address, ID: id, Messages: messages, Node: op})
+		}
+	}
+
+	counts := map[string]int{}
+	for _, op := range ops {
+		counts[op.Key()]++
+	}
+	out := map[string]Operation{}
+	for _, op := range ops {
+		key := op.Key()
+		if counts[key] > 1 && op.ID != "" {
+			key += " (" + op.ID + ")"
+		}
+		out[key] = op
+	}
+	return out
+}
+
+// v2Messages names the messages of a 2.x operation: the message, or each
+// oneOf alternative, by name, messageId or component name.
+func (d *Document) v2Messages(op map[string]any) []string {
+	message := asMap(op["message"])
+	alternatives := []any{message}
+	if oneOf, ok := d.Resolve(message).(map[string]any)["oneOf"].([]any); ok {
+		alternatives = oneOf
+	}
+	var names []string
+	for _, m := range alternatives {
+		names = append(names, d.messageName(m))
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (d *Document) messageName(m any) string {
+	raw := asMap(m)
+	resolved := asMap(d.Resolve(raw))
+	for _, key := range []string{"messageId", "name"} {
+		if s, ok := resolved[key].(string); ok && s != "" {
+			return s
+		}
+	}
+	if ref, ok := raw["$ref"].(string); ok {
+		return ref[strings.LastIndex(ref, "/")+1:]
+	}
+	return "message"
+}
+
+// Messages lists the messages of the document by name: the reusable ones and
+// those the channels (3.x) or operations (2.x) define.
+func (d *Document) Messages() map[string]map[string]any {
+	out := map[string]map[string]any{}
+	for name, m := range asMap(asMap(d.Root["components"])["messages"]) {
+		out[name] = asMap(d.Resolve(m))
+	}
+	if d.IsV2() {
+		for _, op := range d.Operations() {
+			message := asMap(d.Resolve(op.Node["message"]))
+			alternatives := []any{op.Node["message"]}
+			if oneOf, ok := message["oneOf"].([]any); ok {
+				alternatives = oneOf
+			}
+			for _, m := range alternatives {
+				if name := d.messageName(m); out[name] == nil {
+					out[name] = asMap(d.Resolve(m))
+				}
+			}
+		}
+		return out
+	}
+	for _, ch := range d.Channels() {
+		for name, m := range asMap(ch.Node["messages"]) {
+			if out[name] == nil {
+				out[name] = asMap(d.Resolve(m))
+			}
+		}
+	}
+	return out
+}
+
+// Schemas lists components.schemas.
+func (d *Document) Schemas() map[string]map[string]any {
+	out := map[string]map[string]any{}
+	for name, s := range asMap(asMap(d.Root["components"])["schemas"]) {
+		out[name] = asMap(s)
+	}
+	return out
+}
+
+func asMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func asList(v any) []any {
+	l, _ := v.([]any)
+	return l
+}
+
+func escapePointer(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,270 @@
+package asyncapi
+
+import (
+	"APIScope/internal/openapi"
+)
+
+// Diff is the semantic difference between two AsyncAPI documents.
+type Diff struct {
+	Channels   openapi.ChangeSet `json:"channels"`
+	Operations openapi.ChangeSet `json:"operations"`
+	Messages   openapi.ChangeSet `json:"messages"`
+	Schemas    openapi.ChangeSet `json:"schemas"`
+}
+
+// Sections returns the change sets with their display names in report order.
+func (d *Diff) Sections() []struct {
+	Name string
+	Set  *openapi.ChangeSet
+} {
+	return []struct {
+		Name string
+		Set  *openapi.ChangeSet
+	}{
+		{"Channels", &d.Channels},
+		{"Operations", &d.Operations},
+		{"Messages", &d.Messages},
+		{"Schemas", &d.Schemas},
+	}
+}
+
+// Empty reports whether the documents are semantically identical.
+func (d *Diff) Empty() bool {
+	for _, s := range d.Sections() {
+		if !s.Set.Empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare computes the semantic diff from one document to another. Channels
+// are matched by address and operations by action and channel, so renaming
+// their ids, or moving from 2.x to 3.x, only shows the real changes.
+func Compare(from, to *Document) *Diff {
+	d := &Diff{}
+
+	fromChannels, toChannels := from.Channels(), to.Channels()
+	compare(&d.Channels, channelNodes(from, fromChannels), channelNodes(to, toChannels), func(address string) string {
+		return address
+	})
+	fromOps, toOps := from.Operations(), to.Operations()
+	compare(&d.Operations, operationNodes(fromOps), operationNodes(toOps), func(key string) string {
+		return key
+	})
+	compare(&d.Messages, messageNodes(from), messageNodes(to), func(name string) string {
+		return "message " + name
+	})
+	compare(&d.Schemas, toAnyMap(from.Schemas()), toAnyMap(to.Schemas()), func(name string) string {
+		return "#/components/schemas/" + name
+	})
+
+	// Empty lists instead of null in JSON output
+	for _, s := range d.Sections() {
+		if s.Set.Added == nil {
+			s.Set.Added = []openapi.Change{}
+		}
+		if s.Set.Removed == nil {
+			s.Set.Removed = []openapi.Change{}
+		}
+		if s.Set.Modified == nil {
+			s.Set.Modified = []openapi.Change{}
+		}
+	}
+	return d
+}
+
+// compare fills set with added/removed/modified entries of two named maps.
+func compare(set *openapi.ChangeSet, a, b map[string]any, location func(string) string) {
+	for _, name := range openapi.SortedKeys(b) {
+		if _, ok := a[name]; !ok {
+			set.Added = append(set.Added, openapi.Change{Location: location(name), Name: name})
+		}
+	}
+	for _, name := range openapi.SortedKeys(a) {
+		bv, ok := b[name]
+		if !ok {
+			set.Removed = append(set.Removed, openapi.Change{Location: location(name), Name: name})
+			continue
+		}
+		if fields := openapi.FieldDiff(a[name], bv); len(fields) > 0 {
+			set.Modified = append(set.Modified, openapi.Change{Location: location(name), Name: name, Fields: fields})
+		}
+	}
+}
+
+// channelNodes keeps the descriptive parts of the channels; the operations
+// and messages they hold are compared on their own.
+func channelNodes(d *Document, channels map[string]Channel) map[string]any {
+	out := map[string]any{}
+	for address, ch := range channels {
+		node := map[string]any{}
+		for k, v := range ch.Node {
+			switch k {
+			case "address", "publish", "subscribe", "messages":
+			default:
+				node[k] = v
+			}
+		}
+		if !d.IsV2() {
+			node["messages"] = openapi.SortedKeys(asMap(ch.Node["messages"]))
+		}
+		out[address] = node
+	}
+	return out
+}
+
+// operationNodes reduces operations to the fields that stay comparable across
+// versions: the messages they carry by name, and descriptive fields.
+func operationNodes(ops map[string]Operation) map[string]any {
+	out := map[string]any{}
+	for key, op := range ops {
+		node := map[string]any{}
+		for k, v := range op.Node {
+			switch k {
+			case "action", "channel", "message", "messages":
+			default:
+				node[k] = v
+			}
+		}
+		messages := make([]any, len(op.Messages))
+		for i, m := range op.Messages {
+			messages[i] = m
+		}
+		node["messages"] = messages
+		out[key] = node
+	}
+	return out
+}
+
+// messageNodes returns the messages with their payload and headers resolved
+// one level, so a change inside a referenced schema shows on the message.
+func messageNodes(d *Document) map[string]any {
+	out := map[string]any{}
+	for name, m := range d.Messages() {
+		node := map[string]any{}
+		for k, v := range m {
+			switch k {
+			case "payload", "headers":
+				node[k] = d.Resolve(schemaOf(v))
+			default:
+				node[k] = v
+			}
+		}
+		out[name] = node
+	}
+	return out
+}
+
+// schemaOf unwraps an AsyncAPI 3 multi-format schema to the schema itself.
+func schemaOf(v any) any {
+	m := asMap(v)
+	if _, ok := m["schemaFormat"]; ok {
+		return m["schema"]
+	}
+	return v
+}
+
+func toAnyMap(m map[string]map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// CheckCompatibility classifies the changes from one version to the next as
+// breaking or non-breaking. Messages an application sends are checked like
+// responses (consumers must still understand them) and messages it receives
+// like requests (producers must still be accepted).
+func CheckCompatibility(from, to *Document) *openapi.CompatibilityReport {
+	r := openapi.NewCompatibilityReport()
+
+	fromChannels, toChannels := from.Channels(), to.Channels()
+	for _, address := range openapi.SortedKeys(toChannels) {
+		if _, ok := fromChannels[address]; !ok {
+			r.Add(openapi.SeverityNonBreaking, "channel-added", address, "channel %s was added", address)
+		}
+	}
+	for _, address := range openapi.SortedKeys(fromChannels) {
+		if _, ok := toChannels[address]; !ok {
+			r.Add(openapi.SeverityBreaking, "channel-removed", address, "channel %s was removed", address)
+		}
+	}
+
+	fromOps, toOps := from.Operations(), to.Operations()
+	for _, key := range openapi.SortedKeys(toOps) {
+		if _, ok := fromOps[key]; !ok {
+			r.Add(openapi.SeverityNonBreaking, "operation-added", key, "operation %s was added", key)
+		}
+	}
+	fromMessages, toMessages := from.Messages(), to.Messages()
+	for _, key := range openapi.SortedKeys(fromOps) {
+		a := fromOps[key]
+		b, ok := toOps[key]
+		if !ok {
+			r.Add(openapi.SeverityBreaking, "operation-removed", key, "operation %s was removed", key)
+			continue
+		}
+		if !isTrue(a.Node["deprecated"]) && isTrue(b.Node["deprecated"]) {
+			r.Add(openapi.SeverityNonBreaking, "operation-deprecated", key, "operation %s was deprecated", key)
+		}
+		// Consumers of sent messages must handle new ones; a receiving
+		// application may stop accepting some only at its producers' expense.
+		removed, added := delta(a.Messages, b.Messages)
+		for _, name := range removed {
+			sev := openapi.SeverityNonBreaking
+			if a.Action == "receive" {
+				sev = openapi.SeverityBreaking
+			}
+			r.Add(sev, "message-removed", key+" "+name, "message %s is no longer carried", name)
+		}
+		for _, name := range added {
+			sev := openapi.SeverityNonBreaking
+			if a.Action == "send" {
+				sev = openapi.SeverityBreaking
+			}
+			r.Add(sev, "message-added", key+" "+name, "message %s is now carried", name)
+		}
+		sent := a.Action == "receive" // clients send what the application receives
+		for _, name := range b.Messages {
+			ma, inFrom := fromMessages[name]
+			mb, inTo := toMessages[name]
+			if !inFrom || !inTo {
+				continue
+			}
+			loc := key + " " + name
+			if ca, cb := ma["contentType"], mb["contentType"]; ca != nil && cb != nil && ca != cb {
+				r.Add(openapi.SeverityBreaking, "content-type-changed", loc, "content type changed from %v to %v", ca, cb)
+			}
+			r.CheckSchema(from.refs, to.refs, sent, loc+" payload", schemaOf(ma["payload"]), schemaOf(mb["payload"]))
+			r.CheckSchema(from.refs, to.refs, sent, loc+" headers", schemaOf(ma["headers"]), schemaOf(mb["headers"]))
+		}
+	}
+	return r
+}
+
+// delta returns the names only in a and only in b.
+func delta(a, b []string) (removed, added []string) {
+	inA, inB := map[string]bool{}, map[string]bool{}
+	for _, s := range a {
+		inA[s] = true
+	}
+	for _, s := range b {
+		inB[s] = true
+		if !inA[s] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range a {
+		if !inB[s] {
+			removed = append(removed, s)
+		}
+	}
+	return removed, added
+}
+
+func isTrue(v any) bool {
+	b, _ := v.(bool)
+	return b
+}
@@ -0,0 +1,318 @@
+package asyncapi
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+
+	"APIScope/internal/openapi"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"gopkg.in/yaml.v3"
+)
+
+// Official AsyncAPI Initiative schemas (Apache-2.0), see schemas/README.md.
+//
+//go:embed schemas
+var embeddedSchemas embed.FS
+
+var officialSchemas = newSchemaSet(embeddedSchemas)
+
+// schemaSet compiles the schemas/<version>.json files of fsys on first use.
+type schemaSet struct {
+	fsys     fs.FS
+	mu       sync.Mutex
+	compiled map[string]*jsonschema.Schema
+}
+
+func newSchemaSet(fsys fs.FS) *schemaSet {
+	return &schemaSet{fsys: fsys, compiled: map[string]*jsonschema.Schema{}}
+}
+
+// lookup returns the schema of an asyncapi version, or nil when none is
+// embedded for it.
+func (s *schemaSet) lookup(version string) (*jsonschema.Schema, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sch, ok := s.compiled[version]; ok {
+		return sch, nil
+	}
+	file := "schemas/" + version + ".json"
+	raw, err := fs.ReadFile(s.fsys, file)
+	if !fs.ValidPath(file) || errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	url := "file:///asyncapi/" + file
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(url, doc); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	sch, err := c.Compile(url)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	s.compiled[version] = sch
+	return sch, nil
+}
+
+// Validate checks a YAML or JSON AsyncAPI 2.x or 3.x document and returns
+// every problem found, ordered by position. Versions with an official schema
+// under schemas/ are validated against it; the others get a structural check
+// of the fields the viewer and the diff rely on (info, servers, channels,
+// operations). Either way the references of 3.x operations and every local
+// $ref are checked, which the schemas leave out.
+func Validate(content []byte) []openapi.ValidationError {
+	return validate(content, officialSchemas)
+}
+
+func validate(content []byte, schemas *schemaSet) []openapi.ValidationError {
+	if len(bytes.TrimSpace(content)) == 0 {
+		return []openapi.ValidationError{{Message: "document is empty"}}
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(content, &node); err != nil {
+		return []openapi.ValidationError{openapi.YAMLError(err)}
+	}
+	var raw any
+	if err := node.Decode(&raw); err != nil {
+		return []openapi.ValidationError{openapi.YAMLError(err)}
+	}
+	root, ok := openapi.Normalize(raw).(map[string]any)
+	if !ok {
+		return []openapi.ValidationError{{Message: "document root must be an object"}}
+	}
+
+	v := &validator{doc: &node, refs: &openapi.Spec{Root: root}}
+	version, _ := root["asyncapi"].(string)
+	switch {
+	case strings.HasPrefix(version, "2."), strings.HasPrefix(version, "3."):
+	default:
+		v.add("/asyncapi", "unsupported asyncapi version %v (expected a string like 2.6.0 or 3.0.0)", root["asyncapi"])
+		return v.errors
+	}
+	v2 := strings.HasPrefix(version, "2.")
+
+	schema, err := schemas.lookup(version)
+	if err != nil {
+		return []openapi.ValidationError{{Message: "validator unavailable: " + err.Error()}}
+	}
+	if schema != nil {
+		// Round-trip through JSON so numbers and maps have the types the validator expects
+		encoded, err := json.Marshal(root)
+		if err != nil {
+			return []openapi.ValidationError{{Message: "document cannot be represented as JSON: " + err.Error()}}
+		}
+		instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(encoded))
+		if err != nil {
+			return []openapi.ValidationError{{Message: err.Error()}}
+		}
+		v.errors = openapi.SchemaErrors(schema.Validate(instance), &node)
+	} else {
+		v.structure(root, v2)
+	}
+	if !v2 {
+		v.operationReferences(root)
+	}
+	v.refsIn("", root)
+
+	sort.SliceStable(v.errors, func(i, j int) bool {
+		if v.errors[i].Line != v.errors[j].Line {
+			return v.errors[i].Line < v.errors[j].Line
+		}
+		return v.errors[i].Column < v.errors[j].Column
+	})
+	return v.errors
+}
+
+type validator struct {
+	doc    *yaml.Node
+	refs   *openapi.Spec
+	errors []openapi.ValidationError
+}
+
+func (v *validator) add(pointer, format string, args ...any) {
+	line, col := openapi.Position(v.doc, pointer)
+	v.errors = append(v.errors, openapi.ValidationError{Pointer: pointer, Line: line, Column: col, Message: fmt.Sprintf(format, args...)})
+}
+
+// object checks that the value at pointer is an object and returns it.
+func (v *validator) object(pointer string, value any) (map[string]any, bool) {
+	m, ok := value.(map[string]any)
+	if !ok {
+		v.add(pointer, "must be an object")
+	}
+	return m, ok
+}
+
+// structure checks the fields the viewer and the diff rely on, for versions
+// without an official schema.
+func (v *validator) structure(root map[string]any, v2 bool) {
+	info, ok := root["info"].(map[string]any)
+	if !ok {
+		v.add("", "missing property 'info'")
+	} else {
+		for _, key := range []string{"title", "version"} {
+			if s, _ := info[key].(string); s == "" {
+				v.add("/info", "missing property '%s'", key)
+			}
+		}
+	}
+	v.servers(root["servers"], v2)
+	if v2 {
+		v.channelsV2(root["channels"])
+	} else {
+		v.channelsV3(root["channels"])
+		v.operationsV3(root["operations"])
+	}
+}
+
+func (v *validator) servers(value any, v2 bool) {
+	if value == nil {
+		return
+	}
+	servers, ok := v.object("/servers", value)
+	if !ok {
+		return
+	}
+	location := "host"
+	if v2 {
+		location = "url"
+	}
+	for _, name := range openapi.SortedKeys(servers) {
+		pointer := "/servers/" + escapePointer(name)
+		server, ok := v.object(pointer, v.refs.Resolve(servers[name]))
+		if !ok {
+			continue
+		}
+		for _, key := range []string{location, "protocol"} {
+			if s, _ := server[key].(string); s == "" {
+				v.add(pointer, "missing property '%s'", key)
+			}
+		}
+	}
+}
+
+func (v *validator) channelsV2(value any) {
+	if value == nil {
+		v.add("", "missing property 'channels'")
+		return
+	}
+	channels, ok := v.object("/channels", value)
+	if !ok {
+		return
+	}
+	for _, name := range openapi.SortedKeys(channels) {
+		pointer := "/channels/" + escapePointer(name)
+		channel, ok := v.object(pointer, v.refs.Resolve(channels[name]))
+		if !ok {
+			continue
+		}
+		for _, keyword := range []string{"publish", "subscribe"} {
+			if op, ok := channel[keyword]; ok {
+				v.object(pointer+"/"+keyword, v.refs.Resolve(op))
+			}
+		}
+	}
+}
+
+func (v *validator) channelsV3(value any) {
+	if value == nil {
+		return
+	}
+	channels, ok := v.object("/channels", value)
+	if !ok {
+		return
+	}
+	for _, name := range openapi.SortedKeys(channels) {
+		pointer := "/channels/" + escapePointer(name)
+		channel, ok := v.object(pointer, v.refs.Resolve(channels[name]))
+		if !ok {
+			continue
+		}
+		if address, ok := channel["address"]; ok && address != nil {
+			if _, ok := address.(string); !ok {
+				v.add(pointer+"/address", "must be a string or null")
+			}
+		}
+		if messages, ok := channel["messages"]; ok {
+			v.object(pointer+"/messages", messages)
+		}
+	}
+}
+
+func (v *validator) operationsV3(value any) {
+	if value == nil {
+		return
+	}
+	operations, ok := v.object("/operations", value)
+	if !ok {
+		return
+	}
+	for _, name := range openapi.SortedKeys(operations) {
+		pointer := "/operations/" + escapePointer(name)
+		op, ok := v.object(pointer, v.refs.Resolve(operations[name]))
+		if !ok {
+			continue
+		}
+		if action, _ := op["action"].(string); action != "send" && action != "receive" {
+			v.add(pointer, "property 'action' must be send or receive")
+		}
+	}
+}
+
+// operationReferences checks that every 3.x operation references an entry of
+// #/channels, and that its messages are messages of that channel.
+func (v *validator) operationReferences(root map[string]any) {
+	operations, _ := root["operations"].(map[string]any)
+	for _, name := range openapi.SortedKeys(operations) {
+		pointer := "/operations/" + escapePointer(name)
+		op, ok := v.refs.Resolve(operations[name]).(map[string]any)
+		if !ok {
+			continue
+		}
+		channel, _ := op["channel"].(map[string]any)
+		ref, _ := channel["$ref"].(string)
+		if !strings.HasPrefix(ref, "#/channels/") {
+			v.add(pointer, "property 'channel' must reference an entry of #/channels")
+			continue
+		}
+		for i, m := range asList(op["messages"]) {
+			mref, _ := asMap(m)["$ref"].(string)
+			if !strings.HasPrefix(mref, ref+"/messages/") {
+				v.add(fmt.Sprintf("%s/messages/%d", pointer, i), "must reference a message of channel %s", ref)
+			}
+		}
+	}
+}
+
+// refsIn reports every local $ref below pointer that does not resolve.
+func (v *validator) refsIn(pointer string, value any) {
+	switch n := value.(type) {
+	case map[string]any:
+		if ref, ok := n["$ref"].(string); ok && strings.HasPrefix(ref, "#") {
+			if _, found := v.refs.Lookup(ref); !found {
+				v.add(pointer+"/$ref", "unresolved reference %s", ref)
+			}
+		}
+		for _, key := range openapi.SortedKeys(n) {
+			v.refsIn(pointer+"/"+escapePointer(key), n[key])
+		}
+	case []any:
+		for i, item := range n {
+			v.refsIn(fmt.Sprintf("%s/%d", pointer, i), item)
+		}
+	}
+}
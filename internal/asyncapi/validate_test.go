@@ -0,0 +1,243 @@
+package asyncapi
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"APIScope/internal/openapi"
+)
+
+// testSchema is laid out like the official bundled schemas: draft-07, with
+// the definitions keyed by their $id and referenced by it.
+const testSchema = `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "$id": "http://asyncapi.com/definitions/3.0.0/asyncapi.json",
+  "type": "object",
+  "required": ["asyncapi", "info"],
+  "additionalProperties": false,
+  "patternProperties": {"^x-": {}},
+  "properties": {
+    "asyncapi": {"type": "string", "const": "3.0.0"},
+    "info": {"$ref": "http://asyncapi.com/definitions/3.0.0/info.json"},
+    "channels": {"type": "object"},
+    "operations": {
+      "type": "object",
+      "additionalProperties": {"$ref": "http://asyncapi.com/definitions/3.0.0/operation.json"}
+    },
+    "components": {"type": "object"}
+  },
+  "definitions": {
+    "http://asyncapi.com/definitions/3.0.0/info.json": {
+      "$id": "http://asyncapi.com/definitions/3.0.0/info.json",
+      "type": "object",
+      "required": ["title", "version"],
+      "properties": {"title": {"type": "string"}, "version": {"type": "string"}}
+    },
+    "http://asyncapi.com/definitions/3.0.0/operation.json": {
+      "$id": "http://asyncapi.com/definitions/3.0.0/operation.json",
+      "type": "object",
+      "required": ["action", "channel"],
+      "properties": {"action": {"enum": ["send", "receive"]}, "channel": {"type": "object"}}
+    }
+  }
+}`
+
+func testSchemas() *schemaSet {
+	return newSchemaSet(fstest.MapFS{"schemas/3.0.0.json": {Data: []byte(testSchema)}})
+}
+
+// check compares problems with want, whose Message is matched as a substring.
+func check(t *testing.T, got, want []openapi.ValidationError) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d problems, want %d: %v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.Pointer != w.Pointer || g.Line != w.Line || g.Column != w.Column || !strings.Contains(g.Message, w.Message) {
+			t.Errorf("problem %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+const validV3 = `asyncapi: 3.0.0
+info: {title: Users, version: '1'}
+channels:
+  signup:
+    address: user/signedup
+    messages:
+      created: {payload: {type: object}}
+operations:
+  onSignup:
+    action: receive
+    channel: {$ref: '#/channels/signup'}
+    messages: [{$ref: '#/channels/signup/messages/created'}]
+`
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []openapi.ValidationError
+	}{
+		{name: "valid", doc: validV3},
+		{
+			name: "wrong type, reported at the member's key",
+			doc:  "asyncapi: 3.0.0\ninfo:\n  title: 5\n  version: '1'\n",
+			want: []openapi.ValidationError{{Pointer: "/info/title", Line: 3, Column: 3, Message: "got number, want string"}},
+		},
+		{
+			name: "missing property, reported at its parent",
+			doc:  "asyncapi: 3.0.0\ninfo: {title: Users}\n",
+			want: []openapi.ValidationError{{Pointer: "/info", Line: 2, Column: 1, Message: "missing property 'version'"}},
+		},
+		{
+			name: "unknown root property",
+			doc:  "asyncapi: 3.0.0\ninfo: {title: Users, version: '1'}\nx-team: core\nchanel: {}\n",
+			want: []openapi.ValidationError{{Pointer: "", Line: 1, Column: 1, Message: "chanel"}},
+		},
+		{
+			name: "operation checked by the schema and its references by the validator",
+			doc: `asyncapi: 3.0.0
+info: {title: Users, version: '1'}
+channels:
+  signup: {address: user/signedup}
+operations:
+  onSignup:
+    action: publish
+    channel: {$ref: '#/components/channels/signup'}
+`,
+			want: []openapi.ValidationError{
+				{Pointer: "/operations/onSignup", Line: 6, Column: 3, Message: "property 'channel' must reference an entry of #/channels"},
+				{Pointer: "/operations/onSignup/action", Line: 7, Column: 5, Message: "value must be one of"},
+				{Pointer: "/operations/onSignup/channel/$ref", Line: 8, Column: 15, Message: "unresolved reference #/components/channels/signup"},
+			},
+		},
+		{
+			name: "version without a schema falls back to the structural check",
+			doc:  "asyncapi: 2.6.0\ninfo: {title: Users, version: '1'}\nchanel: {}\n",
+			want: []openapi.ValidationError{{Pointer: "", Line: 1, Column: 1, Message: "missing property 'channels'"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check(t, validate([]byte(tt.doc), testSchemas()), tt.want)
+		})
+	}
+}
+
+func TestValidateStructure(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []openapi.ValidationError
+	}{
+		{name: "valid 3.0", doc: validV3},
+		{
+			name: "valid 2.6",
+			doc: `asyncapi: 2.6.0
+info: {title: Users, version: '1'}
+servers:
+  prod: {url: broker.example.com, protocol: kafka}
+channels:
+  user/signedup:
+    subscribe:
+      message: {$ref: '#/components/messages/Created'}
+components:
+  messages:
+    Created: {payload: {type: object}}
+`,
+		},
+		{name: "empty", doc: " \n", want: []openapi.ValidationError{{Message: "document is empty"}}},
+		{name: "not an object", doc: "- a\n", want: []openapi.ValidationError{{Message: "root must be an object"}}},
+		{
+			name: "unsupported version",
+			doc:  "info: {title: Users, version: '1'}\nasyncapi: 1.2.0\n",
+			want: []openapi.ValidationError{{Pointer: "/asyncapi", Line: 2, Column: 1, Message: "unsupported asyncapi version 1.2.0"}},
+		},
+		{
+			name: "info and server properties",
+			doc:  "asyncapi: 2.6.0\ninfo: {title: Users}\nservers:\n  prod: {url: broker.example.com}\nchannels: {}\n",
+			want: []openapi.ValidationError{
+				{Pointer: "/info", Line: 2, Column: 1, Message: "missing property 'version'"},
+				{Pointer: "/servers/prod", Line: 4, Column: 3, Message: "missing property 'protocol'"},
+			},
+		},
+		{
+			name: "3.0 servers have a host",
+			doc:  "asyncapi: 3.0.0\ninfo: {title: Users, version: '1'}\nservers:\n  prod: {url: broker.example.com, protocol: kafka}\n",
+			want: []openapi.ValidationError{{Pointer: "/servers/prod", Line: 4, Column: 3, Message: "missing property 'host'"}},
+		},
+		{
+			name: "2.x operation is not an object",
+			doc:  "asyncapi: 2.6.0\ninfo: {title: Users, version: '1'}\nchannels:\n  user/signedup:\n    publish: yes\n",
+			want: []openapi.ValidationError{{Pointer: "/channels/user~1signedup/publish", Line: 5, Column: 5, Message: "must be an object"}},
+		},
+		{
+			name: "3.0 channel address and operations",
+			doc: `asyncapi: 3.0.0
+info: {title: Users, version: '1'}
+channels:
+  signup:
+    address: [user, signedup]
+    messages:
+      created: {payload: {type: object}}
+operations:
+  onSignup:
+    action: publish
+    channel: {$ref: '#/channels/signup'}
+    messages: [{$ref: '#/components/messages/created'}]
+`,
+			want: []openapi.ValidationError{
+				{Pointer: "/channels/signup/address", Line: 5, Column: 5, Message: "must be a string or null"},
+				{Pointer: "/operations/onSignup", Line: 9, Column: 3, Message: "property 'action' must be send or receive"},
+				{Pointer: "/operations/onSignup/messages/0", Line: 12, Column: 16, Message: "must reference a message of channel #/channels/signup"},
+				{Pointer: "/operations/onSignup/messages/0/$ref", Line: 12, Column: 17, Message: "unresolved reference #/components/messages/created"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check(t, validate([]byte(tt.doc), newSchemaSet(fstest.MapFS{})), tt.want)
+		})
+	}
+}
+
+func TestSchemaSetLookup(t *testing.T) {
+	schemas := newSchemaSet(fstest.MapFS{
+		"schemas/3.0.0.json": {Data: []byte(testSchema)},
+		"schemas/3.1.0.json": {Data: []byte(`{"type": 5}`)},
+		"3.0.0.json":         {Data: []byte(testSchema)},
+	})
+	first, err := schemas.lookup("3.0.0")
+	if err != nil || first == nil {
+		t.Fatalf("lookup(3.0.0) = %v, %v", first, err)
+	}
+	if again, _ := schemas.lookup("3.0.0"); again != first {
+		t.Error("lookup(3.0.0) compiled the schema again")
+	}
+	for _, version := range []string{"2.6.0", "../3.0.0", "3.0.0/.."} {
+		if sch, err := schemas.lookup(version); sch != nil || err != nil {
+			t.Errorf("lookup(%q) = %v, %v, want no schema", version, sch, err)
+		}
+	}
+	if _, err := schemas.lookup("3.1.0"); err == nil || !strings.Contains(err.Error(), "schemas/3.1.0.json") {
+		t.Errorf("lookup(3.1.0) error = %v, want one naming the file", err)
+	}
+}
+
+// TestEmbeddedSchemas compiles every schema under schemas/.
+func TestEmbeddedSchemas(t *testing.T) {
+	files, err := fs.Glob(embeddedSchemas, "schemas/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		version := strings.TrimSuffix(strings.TrimPrefix(file, "schemas/"), ".json")
+		if sch, err := officialSchemas.lookup(version); sch == nil || err != nil {
+			t.Errorf("%s: %v", file, err)
+		}
+	}
+}
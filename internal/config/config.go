@@ -1,8 +1,10 @@
 package config
 
 import (
+	"APIScope/internal/utils"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -14,10 +16,27 @@ type Config struct {
 	Port                    string
 	DatabasePath            string
 	DatabasePassword        string
+	DatabaseBackend         string
+	EmbeddedDatabasePath    string
 	StoragePath             string
+	StorageBackend          string
+	S3Endpoint              string
+	S3Region                string
+	S3Bucket                string
+	S3AccessKey             string
+	S3SecretKey             string
+	S3UseSSL                bool
+	S3Prefix                string
 	LinkExpiration          time.Duration
+	MaxLinkExpiration       time.Duration
+	AllowNeverExpire        bool
+	AllowDocumentRenewal    bool
+	MaxDocumentLifetime     time.Duration // 0 = unlimited
 	MaxFileSize             int64
 	MaxVersions             int
+	RetentionMaxAgeDays     int
+	RetentionInterval       time.Duration
+	JanitorInterval         time.Duration
 	OpenAPIGeneratorEnabled bool
 	OpenAPIGeneratorServer  string
 	AllowVersionDeletion    bool
@@ -25,7 +44,18 @@ type Config struct {
 	AllowServerEditing      bool
 	AutoAdjustServerOrigin  bool
 	StripServers            bool
+	ConvertSwagger2         bool
+	LintRuleset             string
+	LintRulesetFile         string
+	MockServerEnabled       bool
+	AllowOverlayEdit        bool
+	ProxyAllowedHosts       []string // empty disables the Try it out proxy
+	ProxyTimeout            time.Duration
+	RedactExtension         string
+	RedactTags              []string
+	RedactPaths             []string
 	AllowCustomShareLink    bool
+	AdminToken              string // empty disables the per-document policy routes
 	AllowedOrigins          []string
 	CORSAllowCredentials    bool
 	CORSAllowedMethods      []string
@@ -57,6 +87,7 @@ func Load() *Config {
 	exposeHeadersRaw := getEnv("CORS_EXPOSE_HEADERS", "Content-Length")
 	corsMaxAgeStr := getEnv("CORS_MAX_AGE", "600")
 	corsDebug := getBoolEnv("CORS_DEBUG", false)
+	storagePath := getEnv("STORAGE_PATH", "./storage/documents")
 	corsMaxAge := 600
 	if v, err := strconv.Atoi(corsMaxAgeStr); err == nil && v >= 0 {
 		corsMaxAge = v
@@ -66,10 +97,27 @@ func Load() *Config {
 		Port:                    getEnv("PORT", "8080"),
 		DatabasePath:            getEnv("REDIS_ADDR", "localhost:6379"),
 		DatabasePassword:        getEnv("REDIS_PASSWORD", ""),
-		StoragePath:             getEnv("STORAGE_PATH", "./storage/documents"),
-		LinkExpiration:          time.Hour * 24 * 30, // 30 days
-		MaxFileSize:             50 * 1024 * 1024,    // 50 MB
-		MaxVersions:             20,
+		DatabaseBackend:         getEnv("DATABASE_BACKEND", "redis"),
+		EmbeddedDatabasePath:    getEnv("EMBEDDED_DB_PATH", filepath.Join(storagePath, "apiscope.db")),
+		StoragePath:             storagePath,
+		StorageBackend:          getEnv("STORAGE_BACKEND", "filesystem"),
+		S3Endpoint:              getEnv("S3_ENDPOINT", ""),
+		S3Region:                getEnv("S3_REGION", ""),
+		S3Bucket:                getEnv("S3_BUCKET", ""),
+		S3AccessKey:             getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretKey:             getEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3UseSSL:                getBoolEnv("S3_USE_SSL", true),
+		S3Prefix:                getEnv("S3_PREFIX", ""),
+		LinkExpiration:          getTTLEnv("DOCUMENT_TTL", time.Hour*24*30), // 30 days
+		MaxLinkExpiration:       getTTLEnv("MAX_DOCUMENT_TTL", 0),
+		AllowNeverExpire:        getBoolEnv("ALLOW_NEVER_EXPIRE", false),
+		AllowDocumentRenewal:    getBoolEnv("ALLOW_DOCUMENT_RENEWAL", false),
+		MaxDocumentLifetime:     getTTLEnv("MAX_DOCUMENT_LIFETIME", 0),
+		MaxFileSize:             50 * 1024 * 1024, // 50 MB
+		MaxVersions:             getIntEnv("MAX_VERSIONS", 20),
+		RetentionMaxAgeDays:     getIntEnv("RETENTION_MAX_AGE_DAYS", 0),
+		RetentionInterval:       getDurationEnv("RETENTION_INTERVAL", time.Hour),
+		JanitorInterval:         getDurationEnv("JANITOR_INTERVAL", 6*time.Hour),
 		OpenAPIGeneratorEnabled: openAPIEnabled,
 		OpenAPIGeneratorServer:  openAPIServer,
 		AllowVersionDeletion:    allowVersionDeletion,
@@ -77,7 +125,18 @@ func Load() *Config {
 		AllowServerEditing:      allowServerEditing,
 		AutoAdjustServerOrigin:  autoAdjustServerOrigin,
 		StripServers:            stripServers,
+		ConvertSwagger2:         getBoolEnv("CONVERT_SWAGGER2", false),
+		LintRuleset:             getEnv("LINT_RULESET", "recommended"),
+		LintRulesetFile:         getEnv("LINT_RULESET_FILE", ""),
+		MockServerEnabled:       getBoolEnv("MOCK_SERVER_ENABLED", false),
+		AllowOverlayEdit:        getBoolEnv("ALLOW_OVERLAY_EDIT", false),
+		ProxyAllowedHosts:       getCSVEnv("PROXY_ALLOWED_HOSTS"),
+		ProxyTimeout:            getDurationEnv("PROXY_TIMEOUT", 30*time.Second),
+		RedactExtension:         getEnv("REDACT_EXTENSION", "x-internal"),
+		RedactTags:              getCSVEnv("REDACT_TAGS"),
+		RedactPaths:             getCSVEnv("REDACT_PATHS"),
 		AllowCustomShareLink:    allowCustomShare,
+		AdminToken:              getEnv("ADMIN_TOKEN", ""),
 		AllowedOrigins:          parseCSV(allowedOriginsRaw),
 		CORSAllowCredentials:    corsAllowCreds,
 		CORSAllowedMethods:      parseCSV(allowedMethodsRaw),
@@ -109,6 +168,43 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getIntEnv(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil && parsed >= 0 {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getCSVEnv reads a comma-separated list; unset or empty yields nil.
+func getCSVEnv(key string) []string {
+	if value := os.Getenv(key); value != "" {
+		return parseCSV(value)
+	}
+	return nil
+}
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getTTLEnv reads a TTL like "30d" or "720h"; "never" yields 0 (no expiry).
+func getTTLEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if ttl, _, err := utils.ParseTTL(value); err == nil {
+			return ttl
+		}
+		log.Printf("Warning: invalid %s %q, using default", key, value)
+	}
+	return defaultValue
+}
+
 func getBoolEnv(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if parsed, err := strconv.ParseBool(value); err == nil {
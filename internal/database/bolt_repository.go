@@ -0,0 +1,415 @@
+package database
+
+import (
+	"APIScope/internal/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	berrors "go.etcd.io/bbolt/errors"
+)
+
+var (
+	bucketDocuments = []byte("documents")
+	bucketActive    = []byte("active_documents")
+	bucketVersions  = []byte("versions") // nested bucket per document ID
+	bucketShares    = []byte("shares")
+)
+
+// boltRecord wraps every stored value with its absolute expiry so the embedded
+// store can reproduce Redis key TTLs.
+type boltRecord struct {
+	ExpiresAt time.Time       `json:"expires_at,omitempty"`
+	Value     json.RawMessage `json:"value"`
+}
+
+func (r boltRecord) expired(now time.Time) bool {
+	return !r.ExpiresAt.IsZero() && now.After(r.ExpiresAt)
+}
+
+// BoltRepository is an embedded, single-file Repository for deployments that
+// do not want to run Redis.
+type BoltRepository struct {
+	db    *bolt.DB
+	stop  chan struct{}
+	locks *keyedMutex
+}
+
+// NewBoltRepository opens (or creates) the database file and starts a sweeper
+// that removes expired records, the way Redis evicts expired keys.
+func NewBoltRepository(path string, sweepInterval time.Duration) (*BoltRepository, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open embedded database: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketDocuments, bucketActive, bucketVersions, bucketShares} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	r := &BoltRepository{db: db, stop: make(chan struct{}), locks: newKeyedMutex()}
+	if sweepInterval > 0 {
+		go r.sweepLoop(sweepInterval)
+	}
+	return r, nil
+}
+
+func putRecord(b *bolt.Bucket, key string, value any, expiresAt time.Time) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(boltRecord{ExpiresAt: expiresAt, Value: raw})
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(key), data)
+}
+
+// getRecord decodes a live record into out; expired or missing records yield ErrNotFound.
+func getRecord(b *bolt.Bucket, key string, out any) error {
+	if b == nil {
+		return ErrNotFound
+	}
+	data := b.Get([]byte(key))
+	if data == nil {
+		return ErrNotFound
+	}
+	var rec boltRecord
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return err
+	}
+	if rec.expired(time.Now()) {
+		return ErrNotFound
+	}
+	return json.Unmarshal(rec.Value, out)
+}
+
+func (r *BoltRepository) SaveDocument(doc *models.Document) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return putRecord(tx.Bucket(bucketDocuments), doc.ID, documentRecord(doc), doc.ExpiresAt)
+	})
+}
+
+func (r *BoltRepository) UpdateDocumentExpiry(doc *models.Document) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		if err := putRecord(tx.Bucket(bucketDocuments), doc.ID, documentRecord(doc), doc.ExpiresAt); err != nil {
+			return err
+		}
+		if versions := tx.Bucket(bucketVersions).Bucket([]byte(doc.ID)); versions != nil {
+			if err := setBucketExpiry(versions, doc.ExpiresAt); err != nil {
+				return err
+			}
+		}
+		if doc.ShareSlug != "" {
+			var docID string
+			if err := getRecord(tx.Bucket(bucketShares), doc.ShareSlug, &docID); err == nil && docID == doc.ID {
+				return putRecord(tx.Bucket(bucketShares), doc.ShareSlug, docID, doc.ExpiresAt)
+			}
+		}
+		return nil
+	})
+}
+
+// setBucketExpiry rewrites the expiry of every record in a bucket.
+func setBucketExpiry(b *bolt.Bucket, expiresAt time.Time) error {
+	updated := map[string][]byte{}
+	err := b.ForEach(func(k, v []byte) error {
+		var rec boltRecord
+		if v == nil || json.Unmarshal(v, &rec) != nil {
+			return nil
+		}
+		rec.ExpiresAt = expiresAt
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		updated[string(k)] = data
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for k, data := range updated {
+		if err := b.Put([]byte(k), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *BoltRepository) GetDocument(id string) (*models.Document, error) {
+	var doc models.Document
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return getRecord(tx.Bucket(bucketDocuments), id, &doc)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (r *BoltRepository) AddActiveDocument(id string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketActive).Put([]byte(id), []byte{})
+	})
+}
+
+func (r *BoltRepository) RemoveActiveDocument(id string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketActive).Delete([]byte(id))
+	})
+}
+
+func (r *BoltRepository) ListActiveDocuments() ([]string, error) {
+	var ids []string
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketActive).ForEach(func(k, _ []byte) error {
+			ids = append(ids, string(k))
+			return nil
+		})
+	})
+	return ids, err
+}
+
+func (r *BoltRepository) SaveVersion(version *models.Version, expiresAt time.Time) error {
+	return r.CreateVersion(version, nil, expiresAt)
+}
+
+func (r *BoltRepository) CreateVersion(version *models.Version, demoted []models.Version, expiresAt time.Time) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(bucketVersions).CreateBucketIfNotExists([]byte(version.DocumentID))
+		if err != nil {
+			return err
+		}
+		for i := range demoted {
+			if err := putRecord(b, demoted[i].ID, &demoted[i], expiresAt); err != nil {
+				return err
+			}
+		}
+		return putRecord(b, version.ID, version, expiresAt)
+	})
+}
+
+func (r *BoltRepository) GetVersions(documentID string) ([]models.Version, error) {
+	var versions []models.Version
+	err := r.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketVersions).Bucket([]byte(documentID))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, _ []byte) error {
+			var version models.Version
+			if err := getRecord(b, string(k), &version); err != nil {
+				return nil // skip expired or corrupt entries like the Redis implementation
+			}
+			versions = append(versions, version)
+			return nil
+		})
+	})
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].CreatedAt.Before(versions[j].CreatedAt)
+	})
+	return versions, err
+}
+
+func (r *BoltRepository) DeleteVersion(documentID, versionID string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketVersions).Bucket([]byte(documentID))
+		if b == nil {
+			return nil
+		}
+		return b.Delete([]byte(versionID))
+	})
+}
+
+func (r *BoltRepository) DeleteDocumentVersions(documentID string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		err := tx.Bucket(bucketVersions).DeleteBucket([]byte(documentID))
+		if errors.Is(err, berrors.ErrBucketNotFound) {
+			return nil
+		}
+		return err
+	})
+}
+
+func (r *BoltRepository) ListVersionedDocuments() ([]string, error) {
+	var ids []string
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketVersions).ForEachBucket(func(k []byte) error {
+			ids = append(ids, string(k))
+			return nil
+		})
+	})
+	return ids, err
+}
+
+func (r *BoltRepository) GetShareSlug(slug string) (string, error) {
+	var docID string
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return getRecord(tx.Bucket(bucketShares), slug, &docID)
+	})
+	if err == nil && docID == "" {
+		return "", ErrNotFound
+	}
+	return docID, err
+}
+
+func (r *BoltRepository) ClaimShareSlug(slug, documentID string, expiresAt time.Time) (bool, error) {
+	claimed := false
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketShares)
+		var existing string
+		if err := getRecord(b, slug, &existing); err != ErrNotFound {
+			return err // nil when the slug is taken
+		}
+		claimed = true
+		return putRecord(b, slug, documentID, expiresAt)
+	})
+	return claimed, err
+}
+
+// LockDocument uses an in-process lock; bbolt allows a single process per file.
+func (r *BoltRepository) LockDocument(documentID string) (func(), error) {
+	return r.locks.lock(documentID), nil
+}
+
+func (r *BoltRepository) DeleteShareSlug(slug string) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketShares).Delete([]byte(slug))
+	})
+}
+
+// Migrate is a no-op: versions already live in one bucket per document.
+func (r *BoltRepository) Migrate() error {
+	return nil
+}
+
+func (r *BoltRepository) Close() error {
+	close(r.stop)
+	return r.db.Close()
+}
+
+func (r *BoltRepository) sweepLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if err := r.sweepExpired(); err != nil {
+				log.Printf("embedded database sweep failed: %v", err)
+			}
+		}
+	}
+}
+
+// sweepExpired deletes expired records from every bucket.
+func (r *BoltRepository) sweepExpired() error {
+	now := time.Now()
+	return r.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketDocuments, bucketShares} {
+			if err := sweepBucket(tx.Bucket(name), now); err != nil {
+				return err
+			}
+		}
+		versions := tx.Bucket(bucketVersions)
+		var empty [][]byte
+		err := versions.ForEachBucket(func(k []byte) error {
+			b := versions.Bucket(k)
+			if err := sweepBucket(b, now); err != nil {
+				return err
+			}
+			// Stats do not see this transaction's deletes; a cursor does
+			if first, _ := b.Cursor().First(); first == nil {
+				empty = append(empty, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range empty {
+			if err := versions.DeleteBucket(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func sweepBucket(b *bolt.Bucket, now time.Time) error {
+	var expired [][]byte
+	err := b.ForEach(func(k, v []byte) error {
+		var rec boltRecord
+		if v != nil && json.Unmarshal(v, &rec) == nil && rec.expired(now) {
+			expired = append(expired, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range expired {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// keyedMutex hands out one mutex per key and forgets it once unused.
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	refs int
+}
+
+func newKeyedMutex() *keyedMutex {
+	return &keyedMutex{locks: map[string]*keyedLock{}}
+}
+
+func (k *keyedMutex) lock(key string) func() {
+	k.mu.Lock()
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyedLock{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+		k.mu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
@@ -0,0 +1,278 @@
+package database
+
+import (
+	"APIScope/internal/models"
+	"errors"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestBoltRepository(t *testing.T, sweepInterval time.Duration) *BoltRepository {
+	t.Helper()
+	r, err := NewBoltRepository(filepath.Join(t.TempDir(), "apiscope.db"), sweepInterval)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+// stored reports whether a record is physically present, expired or not.
+func (r *BoltRepository) stored(t *testing.T, bucket []byte, documentID, key string) bool {
+	t.Helper()
+	found := false
+	err := r.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if documentID != "" {
+			b = b.Bucket([]byte(documentID))
+		}
+		found = b != nil && b.Get([]byte(key)) != nil
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return found
+}
+
+func TestBoltRecordExpired(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		expiresAt time.Time
+		want      bool
+	}{
+		{expiresAt: time.Time{}, want: false},
+		{expiresAt: now.Add(time.Second), want: false},
+		{expiresAt: now, want: false},
+		{expiresAt: now.Add(-time.Second), want: true},
+	}
+	for _, tt := range tests {
+		if got := (boltRecord{ExpiresAt: tt.expiresAt}).expired(now); got != tt.want {
+			t.Errorf("expired(%v) at %v = %v, want %v", tt.expiresAt, now, got, tt.want)
+		}
+	}
+}
+
+func TestBoltDocumentExpiry(t *testing.T) {
+	r := newTestBoltRepository(t, 0)
+	now := time.Now()
+	tests := []struct {
+		id        string
+		expiresAt time.Time
+		live      bool
+	}{
+		{id: "never", expiresAt: time.Time{}, live: true},
+		{id: "later", expiresAt: now.Add(time.Hour), live: true},
+		{id: "expired", expiresAt: now.Add(-time.Second), live: false},
+	}
+	for _, tt := range tests {
+		doc := &models.Document{ID: tt.id, Name: tt.id, ExpiresAt: tt.expiresAt, IsActive: true}
+		if err := r.SaveDocument(doc); err != nil {
+			t.Fatal(err)
+		}
+		v := &models.Version{ID: tt.id + "-v1", DocumentID: tt.id, Version: "v1", CreatedAt: now, IsLatest: true}
+		if err := r.CreateVersion(v, nil, tt.expiresAt); err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := r.GetDocument(tt.id)
+		switch {
+		case tt.live && err != nil:
+			t.Errorf("%s: GetDocument: %v", tt.id, err)
+		case tt.live && got.Name != tt.id:
+			t.Errorf("%s: got %+v", tt.id, got)
+		case !tt.live && !errors.Is(err, ErrNotFound):
+			t.Errorf("%s: GetDocument = %+v, %v, want ErrNotFound", tt.id, got, err)
+		}
+		versions, err := r.GetVersions(tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := map[bool]int{true: 1, false: 0}[tt.live]; len(versions) != want {
+			t.Errorf("%s: %d versions, want %d", tt.id, len(versions), want)
+		}
+	}
+}
+
+// TestBoltUpdateDocumentExpiry checks that versions and the share slug follow
+// the document's expiry, like the Redis key TTLs.
+func TestBoltUpdateDocumentExpiry(t *testing.T) {
+	r := newTestBoltRepository(t, 0)
+	now := time.Now()
+	doc := &models.Document{ID: "doc", ShareSlug: "pets", ExpiresAt: now.Add(time.Hour), IsActive: true}
+	if err := r.SaveDocument(doc); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"v1", "v2"} {
+		if err := r.CreateVersion(&models.Version{ID: id, DocumentID: doc.ID, Version: id, CreatedAt: now}, nil, doc.ExpiresAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if claimed, err := r.ClaimShareSlug("pets", doc.ID, doc.ExpiresAt); err != nil || !claimed {
+		t.Fatalf("ClaimShareSlug = %v, %v", claimed, err)
+	}
+	// A slug of another document is left alone
+	if claimed, err := r.ClaimShareSlug("other", "someone-else", now.Add(time.Hour)); err != nil || !claimed {
+		t.Fatalf("ClaimShareSlug = %v, %v", claimed, err)
+	}
+
+	doc.ExpiresAt = time.Time{}
+	if err := r.UpdateDocumentExpiry(doc); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := r.GetDocument(doc.ID); err != nil || !got.ExpiresAt.IsZero() {
+		t.Fatalf("GetDocument = %+v, %v, want it never to expire", got, err)
+	}
+
+	doc.ExpiresAt = now.Add(-time.Second)
+	if err := r.UpdateDocumentExpiry(doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.GetDocument(doc.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetDocument after expiry: %v, want ErrNotFound", err)
+	}
+	if versions, err := r.GetVersions(doc.ID); err != nil || len(versions) != 0 {
+		t.Errorf("GetVersions after expiry = %+v, %v, want none", versions, err)
+	}
+	if id, err := r.GetShareSlug("pets"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetShareSlug after expiry = %q, %v, want ErrNotFound", id, err)
+	}
+	if id, err := r.GetShareSlug("other"); err != nil || id != "someone-else" {
+		t.Errorf("GetShareSlug(other) = %q, %v", id, err)
+	}
+}
+
+func TestBoltShareSlugs(t *testing.T) {
+	r := newTestBoltRepository(t, 0)
+	now := time.Now()
+	tests := []struct {
+		name    string
+		do      func() (bool, error)
+		claimed bool
+		owner   string // owner of the slug afterwards, "" = none
+	}{
+		{
+			name:    "claim a free slug",
+			do:      func() (bool, error) { return r.ClaimShareSlug("pets", "a", now.Add(time.Hour)) },
+			claimed: true,
+			owner:   "a",
+		},
+		{
+			name:  "a live slug cannot be reassigned",
+			do:    func() (bool, error) { return r.ClaimShareSlug("pets", "b", now.Add(time.Hour)) },
+			owner: "a",
+		},
+		{
+			name:  "delete",
+			do:    func() (bool, error) { return false, r.DeleteShareSlug("pets") },
+			owner: "",
+		},
+		{
+			name:    "a deleted slug can be claimed again",
+			do:      func() (bool, error) { return r.ClaimShareSlug("pets", "b", now.Add(-time.Second)) },
+			claimed: true,
+			owner:   "",
+		},
+		{
+			name:    "an expired slug can be claimed again",
+			do:      func() (bool, error) { return r.ClaimShareSlug("pets", "c", time.Time{}) },
+			claimed: true,
+			owner:   "c",
+		},
+		{
+			name:  "deleting a missing slug is not an error",
+			do:    func() (bool, error) { return false, r.DeleteShareSlug("missing") },
+			owner: "c",
+		},
+	}
+	for _, tt := range tests {
+		claimed, err := tt.do()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if claimed != tt.claimed {
+			t.Errorf("%s: claimed = %v, want %v", tt.name, claimed, tt.claimed)
+		}
+		owner, err := r.GetShareSlug("pets")
+		if tt.owner == "" {
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("%s: GetShareSlug = %q, %v, want ErrNotFound", tt.name, owner, err)
+			}
+			continue
+		}
+		if err != nil || owner != tt.owner {
+			t.Errorf("%s: GetShareSlug = %q, %v, want %q", tt.name, owner, err, tt.owner)
+		}
+	}
+}
+
+func TestBoltSweepExpired(t *testing.T) {
+	r := newTestBoltRepository(t, 0)
+	now := time.Now()
+	live, expired := now.Add(time.Hour), now.Add(-time.Second)
+	for id, expiresAt := range map[string]time.Time{"live": live, "expired": expired} {
+		if err := r.SaveDocument(&models.Document{ID: id, ExpiresAt: expiresAt, IsActive: true}); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.CreateVersion(&models.Version{ID: "v1", DocumentID: id, CreatedAt: now}, nil, expiresAt); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.ClaimShareSlug(id, id, expiresAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A version can outlive its siblings, e.g. when written with a later expiry
+	if err := r.CreateVersion(&models.Version{ID: "v2", DocumentID: "live", CreatedAt: now}, nil, expired); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.sweepExpired(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		bucket     []byte
+		documentID string
+		key        string
+		want       bool
+	}{
+		{bucket: bucketDocuments, key: "live", want: true},
+		{bucket: bucketDocuments, key: "expired", want: false},
+		{bucket: bucketShares, key: "live", want: true},
+		{bucket: bucketShares, key: "expired", want: false},
+		{bucket: bucketVersions, documentID: "live", key: "v1", want: true},
+		{bucket: bucketVersions, documentID: "live", key: "v2", want: false},
+		{bucket: bucketVersions, documentID: "expired", key: "v1", want: false},
+	}
+	for _, tt := range tests {
+		if got := r.stored(t, tt.bucket, tt.documentID, tt.key); got != tt.want {
+			t.Errorf("%s %s/%s stored = %v, want %v", tt.bucket, tt.documentID, tt.key, got, tt.want)
+		}
+	}
+	// Version buckets left empty go, so the janitor stops seeing the document
+	ids, err := r.ListVersionedDocuments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(ids)
+	if len(ids) != 1 || ids[0] != "live" {
+		t.Errorf("ListVersionedDocuments = %v, want [live]", ids)
+	}
+}
+
+func TestBoltSweeperRuns(t *testing.T) {
+	r := newTestBoltRepository(t, 10*time.Millisecond)
+	if err := r.SaveDocument(&models.Document{ID: "doc", ExpiresAt: time.Now().Add(20 * time.Millisecond)}); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for r.stored(t, bucketDocuments, "", "doc") {
+		if time.Now().After(deadline) {
+			t.Fatal("the expired document was never swept")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -2,29 +2,19 @@ package database
 
 import (
 	"APIScope/internal/config"
-	"context"
-
-	"github.com/redis/go-redis/v9"
+	"fmt"
+	"strings"
+	"time"
 )
 
-var RedisClient *redis.Client
-var ctx = context.Background()
-
-func InitDatabase(cfg *config.Config) error {
-	RedisClient = redis.NewClient(&redis.Options{
-		Addr:     cfg.DatabasePath,
-		Password: cfg.DatabasePassword,
-		DB:       0,
-	})
-
-	_, err := RedisClient.Ping(ctx).Result()
-	return err
-}
-
-func GetRedisClient() *redis.Client {
-	return RedisClient
-}
-
-func GetContext() context.Context {
-	return ctx
+// InitDatabase opens the persistence backend selected by DATABASE_BACKEND.
+func InitDatabase(cfg *config.Config) (Repository, error) {
+	switch strings.ToLower(cfg.DatabaseBackend) {
+	case "", "redis":
+		return NewRedisRepository(cfg)
+	case "embedded", "bolt":
+		return NewBoltRepository(cfg.EmbeddedDatabasePath, time.Minute)
+	default:
+		return nil, fmt.Errorf("unknown database backend %q", cfg.DatabaseBackend)
+	}
 }
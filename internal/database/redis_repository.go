@@ -0,0 +1,379 @@
+package database
+
+import (
+	"APIScope/internal/config"
+	"APIScope/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisRepository is the default Repository backed by a Redis server.
+type RedisRepository struct {
+	client *redis.Client
+	ctx    context.Context
+}
+
+func NewRedisRepository(cfg *config.Config) (*RedisRepository, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.DatabasePath,
+		Password: cfg.DatabasePassword,
+		DB:       0,
+	})
+
+	ctx := context.Background()
+	if _, err := client.Ping(ctx).Result(); err != nil {
+		return nil, err
+	}
+	return &RedisRepository{client: client, ctx: ctx}, nil
+}
+
+func documentKey(id string) string {
+	return fmt.Sprintf("document:%s", id)
+}
+
+func versionKey(documentID, versionID string) string {
+	return fmt.Sprintf("version:%s:%s", documentID, versionID)
+}
+
+// versionIndexKey holds a sorted set of version IDs scored by CreatedAt.
+func versionIndexKey(documentID string) string {
+	return fmt.Sprintf("versions:%s", documentID)
+}
+
+func versionScore(version *models.Version) float64 {
+	return float64(version.CreatedAt.UnixNano())
+}
+
+func shareKey(slug string) string {
+	return fmt.Sprintf("share:%s", slug)
+}
+
+func (r *RedisRepository) SaveDocument(doc *models.Document) error {
+	docJSON, err := json.Marshal(documentRecord(doc))
+	if err != nil {
+		return err
+	}
+	return r.client.Set(r.ctx, documentKey(doc.ID), docJSON, ttlUntil(doc.ExpiresAt)).Err()
+}
+
+func (r *RedisRepository) UpdateDocumentExpiry(doc *models.Document) error {
+	docJSON, err := json.Marshal(documentRecord(doc))
+	if err != nil {
+		return err
+	}
+	ids, err := r.client.ZRange(r.ctx, versionIndexKey(doc.ID), 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	keys := []string{versionIndexKey(doc.ID)}
+	for _, id := range ids {
+		keys = append(keys, versionKey(doc.ID, id))
+	}
+	if doc.ShareSlug != "" {
+		keys = append(keys, shareKey(doc.ShareSlug))
+	}
+	_, err = r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(r.ctx, documentKey(doc.ID), docJSON, ttlUntil(doc.ExpiresAt))
+		for _, key := range keys {
+			if doc.ExpiresAt.IsZero() {
+				pipe.Persist(r.ctx, key)
+			} else {
+				pipe.ExpireAt(r.ctx, key, doc.ExpiresAt)
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *RedisRepository) GetDocument(id string) (*models.Document, error) {
+	docJSON, err := r.client.Get(r.ctx, documentKey(id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var doc models.Document
+	if err := json.Unmarshal([]byte(docJSON), &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (r *RedisRepository) AddActiveDocument(id string) error {
+	return r.client.SAdd(r.ctx, "active_documents", id).Err()
+}
+
+func (r *RedisRepository) RemoveActiveDocument(id string) error {
+	return r.client.SRem(r.ctx, "active_documents", id).Err()
+}
+
+func (r *RedisRepository) ListActiveDocuments() ([]string, error) {
+	return r.client.SMembers(r.ctx, "active_documents").Result()
+}
+
+func (r *RedisRepository) SaveVersion(version *models.Version, expiresAt time.Time) error {
+	return r.CreateVersion(version, nil, expiresAt)
+}
+
+func (r *RedisRepository) CreateVersion(version *models.Version, demoted []models.Version, expiresAt time.Time) error {
+	versionJSON, err := json.Marshal(version)
+	if err != nil {
+		return err
+	}
+	ttl := ttlUntil(expiresAt)
+	indexKey := versionIndexKey(version.DocumentID)
+	_, err = r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		for i := range demoted {
+			demotedJSON, err := json.Marshal(&demoted[i])
+			if err != nil {
+				return err
+			}
+			pipe.Set(r.ctx, versionKey(demoted[i].DocumentID, demoted[i].ID), demotedJSON, ttl)
+		}
+		pipe.Set(r.ctx, versionKey(version.DocumentID, version.ID), versionJSON, ttl)
+		pipe.ZAdd(r.ctx, indexKey, redis.Z{Score: versionScore(version), Member: version.ID})
+		if expiresAt.IsZero() {
+			pipe.Persist(r.ctx, indexKey)
+		} else {
+			pipe.ExpireAt(r.ctx, indexKey, expiresAt)
+		}
+		return nil
+	})
+	return err
+}
+
+// GetVersions reads the document's version index and fetches every record in a
+// single MGET, oldest first.
+func (r *RedisRepository) GetVersions(documentID string) ([]models.Version, error) {
+	ids, err := r.client.ZRange(r.ctx, versionIndexKey(documentID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = versionKey(documentID, id)
+	}
+	values, err := r.client.MGet(r.ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []models.Version
+	var stale []any
+	for i, value := range values {
+		versionJSON, ok := value.(string)
+		if !ok {
+			stale = append(stale, ids[i])
+			continue
+		}
+
+		var version models.Version
+		if err := json.Unmarshal([]byte(versionJSON), &version); err != nil {
+			continue
+		}
+
+		versions = append(versions, version)
+	}
+	// Drop index entries whose record is gone
+	if len(stale) > 0 {
+		r.client.ZRem(r.ctx, versionIndexKey(documentID), stale...)
+	}
+
+	return versions, nil
+}
+
+func (r *RedisRepository) DeleteVersion(documentID, versionID string) error {
+	_, err := r.client.TxPipelined(r.ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(r.ctx, versionKey(documentID, versionID))
+		pipe.ZRem(r.ctx, versionIndexKey(documentID), versionID)
+		return nil
+	})
+	return err
+}
+
+func (r *RedisRepository) DeleteDocumentVersions(documentID string) error {
+	keys := []string{versionIndexKey(documentID)}
+	iter := r.client.Scan(r.ctx, 0, versionKey(documentID, "*"), 500).Iterator()
+	for iter.Next(r.ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	return r.client.Del(r.ctx, keys...).Err()
+}
+
+func (r *RedisRepository) ListVersionedDocuments() ([]string, error) {
+	seen := map[string]bool{}
+	var ids []string
+	for _, pattern := range []string{"version:*", "versions:*"} {
+		iter := r.client.Scan(r.ctx, 0, pattern, 500).Iterator()
+		for iter.Next(r.ctx) {
+			parts := strings.SplitN(iter.Val(), ":", 3)
+			if len(parts) < 2 || seen[parts[1]] {
+				continue
+			}
+			seen[parts[1]] = true
+			ids = append(ids, parts[1])
+		}
+		if err := iter.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+func (r *RedisRepository) GetShareSlug(slug string) (string, error) {
+	docID, err := r.client.Get(r.ctx, shareKey(slug)).Result()
+	if errors.Is(err, redis.Nil) || (err == nil && docID == "") {
+		return "", ErrNotFound
+	}
+	return docID, err
+}
+
+func (r *RedisRepository) ClaimShareSlug(slug, documentID string, expiresAt time.Time) (bool, error) {
+	return r.client.SetNX(r.ctx, shareKey(slug), documentID, ttlUntil(expiresAt)).Result()
+}
+
+func (r *RedisRepository) DeleteShareSlug(slug string) error {
+	return r.client.Del(r.ctx, shareKey(slug)).Err()
+}
+
+// unlockScript deletes the lock only if it is still held with our token.
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// renewScript extends the lock only if it is still held with our token.
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// LockDocument holds the lock for as long as the caller needs it: lockTTL only
+// bounds how long a crashed holder blocks others, so the lock is renewed every
+// lockTTL/3 until it is released (uploads store their files while locked).
+func (r *RedisRepository) LockDocument(documentID string) (func(), error) {
+	key := fmt.Sprintf("lock:document:%s", documentID)
+	token := uuid.New().String()
+	deadline := time.Now().Add(lockWait)
+	for {
+		ok, err := r.client.SetNX(r.ctx, key, token, lockTTL).Result()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			done := make(chan struct{})
+			go r.renewLock(key, token, done)
+			return func() {
+				close(done)
+				if err := unlockScript.Run(r.ctx, r.client, []string{key}, token).Err(); err != nil {
+					log.Printf("failed to release %s: %v", key, err)
+				}
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, ErrLockTimeout
+		}
+		time.Sleep(25 * time.Millisecond)
+	}
+}
+
+func (r *RedisRepository) renewLock(key, token string, done <-chan struct{}) {
+	ticker := time.NewTicker(lockTTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			held, err := renewScript.Run(r.ctx, r.client, []string{key}, token, lockTTL.Milliseconds()).Int()
+			if err != nil {
+				log.Printf("failed to renew %s: %v", key, err)
+			} else if held == 0 {
+				log.Printf("lost %s before it was released", key)
+				return
+			}
+		}
+	}
+}
+
+const versionIndexMigration = "migrations:version_index"
+
+// Migrate rebuilds the per-document version indexes from existing version:*
+// keys. It runs once per Redis instance and uses SCAN so it never blocks the server.
+func (r *RedisRepository) Migrate() error {
+	done, err := r.client.Exists(r.ctx, versionIndexMigration).Result()
+	if err != nil || done == 1 {
+		return err
+	}
+
+	indexed := 0
+	iter := r.client.Scan(r.ctx, 0, "version:*", 500).Iterator()
+	var batch []string
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		values, err := r.client.MGet(r.ctx, batch...).Result()
+		if err != nil {
+			return err
+		}
+		pipe := r.client.Pipeline()
+		for _, value := range values {
+			versionJSON, ok := value.(string)
+			if !ok {
+				continue
+			}
+			var version models.Version
+			if err := json.Unmarshal([]byte(versionJSON), &version); err != nil || version.DocumentID == "" {
+				continue
+			}
+			pipe.ZAdd(r.ctx, versionIndexKey(version.DocumentID), redis.Z{Score: versionScore(&version), Member: version.ID})
+			indexed++
+		}
+		batch = batch[:0]
+		_, err = pipe.Exec(r.ctx)
+		return err
+	}
+	for iter.Next(r.ctx) {
+		batch = append(batch, iter.Val())
+		if len(batch) == 500 {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	log.Printf("version index migration: indexed %d versions", indexed)
+	return r.client.Set(r.ctx, versionIndexMigration, time.Now().UTC().Format(time.RFC3339), 0).Err()
+}
+
+func (r *RedisRepository) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"APIScope/internal/models"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned when a record does not exist or has expired.
+var ErrNotFound = errors.New("record not found")
+
+// ErrLockTimeout is returned when a document lock could not be acquired in time.
+var ErrLockTimeout = errors.New("document is locked by another operation")
+
+const (
+	// lockTTL bounds how long a crashed holder can keep a document locked.
+	lockTTL = 30 * time.Second
+	// lockWait is how long callers wait for a busy lock before giving up.
+	lockWait = 10 * time.Second
+)
+
+// Repository persists documents, versions and share slugs for DocumentService.
+// Records written with a non-zero expiry disappear once it passes, matching
+// Redis key TTL semantics in every implementation.
+type Repository interface {
+	// SaveDocument stores the document (without its versions) until doc.ExpiresAt.
+	SaveDocument(doc *models.Document) error
+	// UpdateDocumentExpiry stores the document and moves the expiry of its
+	// versions, version index and share slug to doc.ExpiresAt in one transaction.
+	UpdateDocumentExpiry(doc *models.Document) error
+	GetDocument(id string) (*models.Document, error)
+	AddActiveDocument(id string) error
+	RemoveActiveDocument(id string) error
+	ListActiveDocuments() ([]string, error)
+
+	// SaveVersion stores a version until expiresAt, which callers set to the
+	// owning document's expiry so versions never outlive it.
+	SaveVersion(version *models.Version, expiresAt time.Time) error
+	// CreateVersion atomically stores a new version and the demoted copies of
+	// the previously latest versions.
+	CreateVersion(version *models.Version, demoted []models.Version, expiresAt time.Time) error
+	// GetVersions returns the document's versions ordered by CreatedAt, oldest first.
+	GetVersions(documentID string) ([]models.Version, error)
+	DeleteVersion(documentID, versionID string) error
+	// DeleteDocumentVersions removes every version record of a document.
+	DeleteDocumentVersions(documentID string) error
+	// ListVersionedDocuments returns the IDs of all documents that still have
+	// version records, including documents that no longer exist.
+	ListVersionedDocuments() ([]string, error)
+
+	// GetShareSlug returns the document ID a slug points to.
+	GetShareSlug(slug string) (string, error)
+	// ClaimShareSlug maps slug to documentID until expiresAt. It returns false
+	// without changing anything if the slug is already taken.
+	ClaimShareSlug(slug, documentID string, expiresAt time.Time) (bool, error)
+	DeleteShareSlug(slug string) error
+
+	// LockDocument serializes version changes of one document across all
+	// writers. The returned func releases the lock.
+	LockDocument(documentID string) (func(), error)
+
+	// Migrate brings existing data up to the current layout. It is idempotent.
+	Migrate() error
+	Close() error
+}
+
+// ttlUntil converts an absolute expiry into a Redis-style TTL (0 = no expiry).
+func ttlUntil(expiresAt time.Time) time.Duration {
+	if expiresAt.IsZero() {
+		return 0
+	}
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		// Already expired; keep the key just long enough to be unreadable
+		return time.Millisecond
+	}
+	return ttl
+}
+
+// documentRecord strips the versions, which are stored as separate records.
+func documentRecord(doc *models.Document) models.Document {
+	stored := *doc
+	stored.Versions = []models.Version{}
+	return stored
+}
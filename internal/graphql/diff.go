@@ -0,0 +1,441 @@
+package graphql
+
+import (
+	"APIScope/internal/openapi"
+)
+
+// Diff is the semantic difference between two schemas.
+type Diff struct {
+	Operations openapi.ChangeSet `json:"operations"`
+	Types      openapi.ChangeSet `json:"types"`
+	Fields     openapi.ChangeSet `json:"fields"`
+	EnumValues openapi.ChangeSet `json:"enum_values"`
+	Directives openapi.ChangeSet `json:"directives"`
+}
+
+// Sections returns the change sets with their display names in report order.
+func (d *Diff) Sections() []struct {
+	Name string
+	Set  *openapi.ChangeSet
+} {
+	return []struct {
+		Name string
+		Set  *openapi.ChangeSet
+	}{
+		{"Operations", &d.Operations},
+		{"Types", &d.Types},
+		{"Fields", &d.Fields},
+		{"Enum values", &d.EnumValues},
+		{"Directives", &d.Directives},
+	}
+}
+
+// Empty reports whether the schemas are semantically identical.
+func (d *Diff) Empty() bool {
+	for _, s := range d.Sections() {
+		if !s.Set.Empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare computes the semantic diff from one schema to another. Types are
+// matched by name and fields, input fields and enum values by schema
+// coordinate; the order of definitions and extensions is not a change.
+func Compare(from, to *Schema) *Diff {
+	d := &Diff{}
+	compare(&d.Operations, operationNodes(from), operationNodes(to))
+	compare(&d.Types, typeNodes(from), typeNodes(to))
+	compare(&d.Fields, fieldNodes(from), fieldNodes(to))
+	compare(&d.EnumValues, enumValueNodes(from), enumValueNodes(to))
+	compare(&d.Directives, directiveNodes(from), directiveNodes(to))
+
+	// Empty lists instead of null in JSON output
+	for _, s := range d.Sections() {
+		if s.Set.Added == nil {
+			s.Set.Added = []openapi.Change{}
+		}
+		if s.Set.Removed == nil {
+			s.Set.Removed = []openapi.Change{}
+		}
+		if s.Set.Modified == nil {
+			s.Set.Modified = []openapi.Change{}
+		}
+	}
+	return d
+}
+
+// compare fills set with added/removed/modified entries of two named maps.
+func compare(set *openapi.ChangeSet, a, b map[string]any) {
+	for _, name := range openapi.SortedKeys(b) {
+		if _, ok := a[name]; !ok {
+			set.Added = append(set.Added, openapi.Change{Location: name, Name: name})
+		}
+	}
+	for _, name := range openapi.SortedKeys(a) {
+		bv, ok := b[name]
+		if !ok {
+			set.Removed = append(set.Removed, openapi.Change{Location: name, Name: name})
+			continue
+		}
+		if fields := openapi.FieldDiff(a[name], bv); len(fields) > 0 {
+			set.Modified = append(set.Modified, openapi.Change{Location: name, Name: name, Fields: fields})
+		}
+	}
+}
+
+func operationNodes(s *Schema) map[string]any {
+	out := map[string]any{}
+	for op, name := range s.Roots {
+		out[op] = map[string]any{"type": name}
+	}
+	return out
+}
+
+func typeNodes(s *Schema) map[string]any {
+	out := map[string]any{}
+	for _, t := range s.Types() {
+		out[t.Name] = map[string]any{
+			"kind":        t.Kind,
+			"description": t.Description,
+			"interfaces":  anyList(t.Interfaces),
+			"members":     anyList(t.Members),
+		}
+	}
+	return out
+}
+
+func fieldNodes(s *Schema) map[string]any {
+	out := map[string]any{}
+	for _, t := range s.Types() {
+		for _, f := range t.Fields {
+			out[t.Name+"."+f.Name] = map[string]any{
+				"type":        f.Type.String(),
+				"description": f.Description,
+				"deprecated":  f.Deprecated,
+				"arguments":   inputValueNodes(f.Args),
+			}
+		}
+		for _, f := range t.InputFields {
+			out[t.Name+"."+f.Name] = inputValueNode(f)
+		}
+	}
+	return out
+}
+
+func enumValueNodes(s *Schema) map[string]any {
+	out := map[string]any{}
+	for _, t := range s.Types() {
+		for _, v := range t.Values {
+			out[t.Name+"."+v.Name] = map[string]any{"description": v.Description, "deprecated": v.Deprecated}
+		}
+	}
+	return out
+}
+
+func directiveNodes(s *Schema) map[string]any {
+	out := map[string]any{}
+	for _, d := range s.Directives {
+		out["@"+d.Name] = map[string]any{
+			"description": d.Description,
+			"repeatable":  d.Repeatable,
+			"locations":   anyList(d.Locations),
+			"arguments":   inputValueNodes(d.Args),
+		}
+	}
+	return out
+}
+
+func inputValueNodes(values []*InputValue) map[string]any {
+	out := map[string]any{}
+	for _, v := range values {
+		out[v.Name] = inputValueNode(v)
+	}
+	return out
+}
+
+func inputValueNode(v *InputValue) map[string]any {
+	return map[string]any{
+		"type":        v.Type.String(),
+		"default":     v.Default,
+		"description": v.Description,
+		"deprecated":  v.Deprecated,
+	}
+}
+
+// anyList converts a name list for FieldDiff, which compares []any.
+func anyList(list []string) []any {
+	out := make([]any, len(list))
+	for i, s := range list {
+		out[i] = s
+	}
+	return out
+}
+
+// CheckCompatibility classifies the changes from one version to the next as
+// breaking or non-breaking for existing clients: anything that makes a valid
+// query invalid, or lets a server return what a client did not expect, is
+// breaking. Output types may become stricter (a field that now never returns
+// null), input types may become looser (an argument that is now optional).
+func CheckCompatibility(from, to *Schema) *openapi.CompatibilityReport {
+	r := openapi.NewCompatibilityReport()
+
+	for _, op := range operations {
+		a, b := from.Roots[op], to.Roots[op]
+		switch {
+		case a == b:
+		case a == "":
+			r.Add(openapi.SeverityNonBreaking, "operation-type-added", op, "%s type %s was added", op, b)
+		case b == "":
+			r.Add(openapi.SeverityBreaking, "operation-type-removed", op, "%s type %s was removed", op, a)
+		default:
+			r.Add(openapi.SeverityBreaking, "operation-type-changed", op, "%s type changed from %s to %s", op, a, b)
+		}
+	}
+
+	for _, b := range to.Types() {
+		if from.Type(b.Name) == nil {
+			r.Add(openapi.SeverityNonBreaking, "type-added", b.Name, "%s %s was added", kindName(b.Kind), b.Name)
+		}
+	}
+	for _, a := range from.Types() {
+		b := to.Type(a.Name)
+		switch {
+		case b == nil:
+			r.Add(openapi.SeverityBreaking, "type-removed", a.Name, "%s %s was removed", kindName(a.Kind), a.Name)
+			continue
+		case a.Kind != b.Kind:
+			r.Add(openapi.SeverityBreaking, "type-kind-changed", a.Name, "%s changed from %s to %s", a.Name, kindName(a.Kind), kindName(b.Kind))
+			continue
+		}
+		switch a.Kind {
+		case KindObject, KindInterface:
+			removed, added := delta(a.Interfaces, b.Interfaces)
+			for _, name := range removed {
+				r.Add(openapi.SeverityBreaking, "interface-removed", a.Name, "%s no longer implements %s", a.Name, name)
+			}
+			for _, name := range added {
+				r.Add(openapi.SeverityNonBreaking, "interface-added", a.Name, "%s now implements %s", a.Name, name)
+			}
+			checkFields(r, a, b)
+		case KindUnion:
+			removed, added := delta(a.Members, b.Members)
+			for _, name := range removed {
+				r.Add(openapi.SeverityBreaking, "union-member-removed", a.Name, "%s was removed from union %s", name, a.Name)
+			}
+			for _, name := range added {
+				r.Add(openapi.SeverityNonBreaking, "union-member-added", a.Name, "%s was added to union %s", name, a.Name)
+			}
+		case KindEnum:
+			checkValues(r, a, b)
+		case KindInputObject:
+			checkInputValues(r, a.Name, "input-field", "input field", a.InputFields, b.InputFields, func(v *InputValue) string { return a.Name + "." + v.Name })
+		}
+	}
+
+	fromDirectives := byName(from.Directives, func(d *Directive) string { return d.Name })
+	toDirectives := byName(to.Directives, func(d *Directive) string { return d.Name })
+	for _, name := range openapi.SortedKeys(toDirectives) {
+		if _, ok := fromDirectives[name]; !ok {
+			r.Add(openapi.SeverityNonBreaking, "directive-added", "@"+name, "directive @%s was added", name)
+		}
+	}
+	for _, name := range openapi.SortedKeys(fromDirectives) {
+		a, b := fromDirectives[name], toDirectives[name]
+		loc := "@" + name
+		if b == nil {
+			r.Add(openapi.SeverityBreaking, "directive-removed", loc, "directive @%s was removed", name)
+			continue
+		}
+		removed, _ := delta(a.Locations, b.Locations)
+		for _, l := range removed {
+			r.Add(openapi.SeverityBreaking, "directive-location-removed", loc, "@%s can no longer be used on %s", name, l)
+		}
+		if a.Repeatable && !b.Repeatable {
+			r.Add(openapi.SeverityBreaking, "directive-repeatable-removed", loc, "@%s is no longer repeatable", name)
+		}
+		checkInputValues(r, loc, "argument", "argument", a.Args, b.Args, func(v *InputValue) string { return loc + "(" + v.Name + ":)" })
+	}
+	return r
+}
+
+// checkFields compares the fields of an object or interface, matched by name.
+func checkFields(r *openapi.CompatibilityReport, a, b *Type) {
+	for _, g := range b.Fields {
+		if fieldNamed(a, g.Name) == nil {
+			r.Add(openapi.SeverityNonBreaking, "field-added", a.Name+"."+g.Name, "field %s: %s was added", g.Name, g.Type)
+		}
+	}
+	for _, f := range a.Fields {
+		loc := a.Name + "." + f.Name
+		g := fieldNamed(b, f.Name)
+		if g == nil {
+			r.Add(openapi.SeverityBreaking, "field-removed", loc, "field %s was removed", f.Name)
+			continue
+		}
+		if ta, tb := f.Type.String(), g.Type.String(); ta != tb {
+			switch {
+			case safeOutput(f.Type, g.Type):
+				r.Add(openapi.SeverityNonBreaking, "field-type-changed", loc, "type changed from %s to %s, which existing clients still accept", ta, tb)
+			case sameShape(f.Type, g.Type):
+				r.Add(openapi.SeverityBreaking, "field-nullability-loosened", loc, "type changed from %s to %s, so the field may return null where it could not before", ta, tb)
+			default:
+				r.Add(openapi.SeverityBreaking, "field-type-changed", loc, "type changed from %s to %s", ta, tb)
+			}
+		}
+		if !f.Deprecated && g.Deprecated {
+			r.Add(openapi.SeverityNonBreaking, "field-deprecated", loc, "field %s was deprecated", f.Name)
+		}
+		checkInputValues(r, loc, "argument", "argument", f.Args, g.Args, func(v *InputValue) string { return loc + "(" + v.Name + ":)" })
+	}
+}
+
+// checkInputValues compares arguments or input fields, matched by name. rule
+// prefixes the rule names and noun names the values in messages.
+func checkInputValues(r *openapi.CompatibilityReport, owner, rule, noun string, a, b []*InputValue, location func(*InputValue) string) {
+	fromValues := byName(a, func(v *InputValue) string { return v.Name })
+	toValues := byName(b, func(v *InputValue) string { return v.Name })
+	for _, w := range b {
+		if _, ok := fromValues[w.Name]; ok {
+			continue
+		}
+		if w.Required() {
+			r.Add(openapi.SeverityBreaking, rule+"-added", location(w), "required %s %s: %s was added to %s", noun, w.Name, w.Type, owner)
+		} else {
+			r.Add(openapi.SeverityNonBreaking, rule+"-added", location(w), "optional %s %s: %s was added to %s", noun, w.Name, w.Type, owner)
+		}
+	}
+	for _, v := range a {
+		loc := location(v)
+		w, ok := toValues[v.Name]
+		if !ok {
+			r.Add(openapi.SeverityBreaking, rule+"-removed", loc, "%s %s was removed", noun, v.Name)
+			continue
+		}
+		if ta, tb := v.Type.String(), w.Type.String(); ta != tb {
+			switch {
+			case safeInput(v.Type, w.Type):
+				r.Add(openapi.SeverityNonBreaking, rule+"-type-changed", loc, "type changed from %s to %s, which accepts every value it did before", ta, tb)
+			case sameShape(v.Type, w.Type):
+				r.Add(openapi.SeverityBreaking, rule+"-nullability-tightened", loc, "type changed from %s to %s, so null or omitted values are no longer accepted", ta, tb)
+			default:
+				r.Add(openapi.SeverityBreaking, rule+"-type-changed", loc, "type changed from %s to %s", ta, tb)
+			}
+		}
+		switch {
+		case v.Default != "" && w.Default == "" && w.Type.NonNull:
+			r.Add(openapi.SeverityBreaking, rule+"-default-removed", loc, "default %s was removed, so the %s is now required", v.Default, noun)
+		case v.Default != w.Default:
+			r.Add(openapi.SeverityNonBreaking, rule+"-default-changed", loc, "default changed from %s to %s", defaultValue(v.Default), defaultValue(w.Default))
+		}
+		if !v.Deprecated && w.Deprecated {
+			r.Add(openapi.SeverityNonBreaking, rule+"-deprecated", loc, "%s %s was deprecated", noun, v.Name)
+		}
+	}
+}
+
+// checkValues compares the values of an enum, matched by name. Adding one is
+// not breaking, though clients that switch over the values exhaustively may
+// need an update.
+func checkValues(r *openapi.CompatibilityReport, a, b *Type) {
+	fromValues := byName(a.Values, func(v *EnumValue) string { return v.Name })
+	toValues := byName(b.Values, func(v *EnumValue) string { return v.Name })
+	for _, w := range b.Values {
+		if _, ok := fromValues[w.Name]; !ok {
+			r.Add(openapi.SeverityNonBreaking, "enum-value-added", a.Name+"."+w.Name, "value %s was added", w.Name)
+		}
+	}
+	for _, v := range a.Values {
+		loc := a.Name + "." + v.Name
+		w, ok := toValues[v.Name]
+		switch {
+		case !ok:
+			r.Add(openapi.SeverityBreaking, "enum-value-removed", loc, "value %s was removed", v.Name)
+		case !v.Deprecated && w.Deprecated:
+			r.Add(openapi.SeverityNonBreaking, "enum-value-deprecated", loc, "value %s was deprecated", v.Name)
+		}
+	}
+}
+
+// safeOutput reports whether a field's type can change from a to b without
+// clients receiving values they did not expect: wrappers stay, and may only
+// gain non-null.
+func safeOutput(a, b *TypeRef) bool {
+	switch {
+	case a.NonNull:
+		return b.NonNull && safeOutput(a.OfType, b.OfType)
+	case b.NonNull:
+		return safeOutput(a, b.OfType)
+	case a.Name == "":
+		return b.Name == "" && safeOutput(a.OfType, b.OfType)
+	}
+	return a.Name == b.Name
+}
+
+// safeInput reports whether an argument or input field's type can change
+// from a to b while accepting every value it accepted before: wrappers stay,
+// and may only lose non-null.
+func safeInput(a, b *TypeRef) bool {
+	switch {
+	case a.NonNull && b.NonNull:
+		return safeInput(a.OfType, b.OfType)
+	case a.NonNull:
+		return safeInput(a.OfType, b)
+	case b.NonNull:
+		return false
+	case a.Name == "":
+		return b.Name == "" && safeInput(a.OfType, b.OfType)
+	}
+	return a.Name == b.Name
+}
+
+// sameShape reports whether a and b differ only in non-null wrappers, so a
+// change between them is one of nullability.
+func sameShape(a, b *TypeRef) bool {
+	for a.NonNull {
+		a = a.OfType
+	}
+	for b.NonNull {
+		b = b.OfType
+	}
+	if a.Name == "" || b.Name == "" {
+		return a.Name == b.Name && sameShape(a.OfType, b.OfType)
+	}
+	return a.Name == b.Name
+}
+
+func defaultValue(v string) string {
+	if v == "" {
+		return "none"
+	}
+	return v
+}
+
+// delta returns the names only in a and only in b.
+func delta(a, b []string) (removed, added []string) {
+	inA, inB := map[string]bool{}, map[string]bool{}
+	for _, s := range a {
+		inA[s] = true
+	}
+	for _, s := range b {
+		inB[s] = true
+		if !inA[s] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range a {
+		if !inB[s] {
+			removed = append(removed, s)
+		}
+	}
+	return removed, added
+}
+
+func byName[T any](items []T, name func(T) string) map[string]T {
+	out := make(map[string]T, len(items))
+	for _, item := range items {
+		out[name(item)] = item
+	}
+	return out
+}
@@ -0,0 +1,221 @@
+package graphql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"APIScope/internal/openapi"
+)
+
+func parseSchema(t *testing.T, sdl string) *Schema {
+	t.Helper()
+	schema, err := Parse([]byte(sdl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+// report lists the findings of r, one "severity rule location" line each,
+// breaking ones first.
+func report(r *openapi.CompatibilityReport) string {
+	var lines []string
+	for _, list := range [][]openapi.Finding{r.Breaking, r.NonBreaking} {
+		for _, f := range list {
+			lines = append(lines, fmt.Sprintf("%s %s %s", f.Severity, f.Rule, f.Location))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TestNullability checks that the same type change is judged by which way
+// the value flows: outputs may tighten, inputs (arguments and input fields)
+// may loosen.
+func TestNullability(t *testing.T) {
+	tests := []struct {
+		from, to      string
+		output, input string // "severity rule" of the output field and of the argument and input field
+	}{
+		{from: "String", to: "String!", output: "non-breaking field-type-changed", input: "breaking %s-nullability-tightened"},
+		{from: "String!", to: "String", output: "breaking field-nullability-loosened", input: "non-breaking %s-type-changed"},
+		{from: "[String]", to: "[String!]", output: "non-breaking field-type-changed", input: "breaking %s-nullability-tightened"},
+		{from: "[String!]", to: "[String]", output: "breaking field-nullability-loosened", input: "non-breaking %s-type-changed"},
+		{from: "[String]", to: "[String]!", output: "non-breaking field-type-changed", input: "breaking %s-nullability-tightened"},
+		{from: "[String]!", to: "[String!]", output: "breaking field-nullability-loosened", input: "breaking %s-nullability-tightened"},
+		{from: "Int", to: "String", output: "breaking field-type-changed", input: "breaking %s-type-changed"},
+		{from: "String", to: "[String]", output: "breaking field-type-changed", input: "breaking %s-type-changed"},
+	}
+	const sdl = "type Query { pet(name: %[1]s, filter: Filter): %[1]s }\ninput Filter { name: %[1]s }"
+	for _, tt := range tests {
+		r := CheckCompatibility(parseSchema(t, fmt.Sprintf(sdl, tt.from)), parseSchema(t, fmt.Sprintf(sdl, tt.to)))
+		got := map[string]string{}
+		for _, list := range [][]openapi.Finding{r.Breaking, r.NonBreaking} {
+			for _, f := range list {
+				got[f.Location] = fmt.Sprintf("%s %s", f.Severity, f.Rule)
+			}
+		}
+		want := map[string]string{
+			"Query.pet":        tt.output,
+			"Query.pet(name:)": fmt.Sprintf(tt.input, "argument"),
+			"Filter.name":      fmt.Sprintf(tt.input, "input-field"),
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s -> %s: %v, want %v", tt.from, tt.to, got, want)
+		}
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     string // see report
+	}{
+		{
+			name: "unchanged",
+			from: "type Query { pet(id: ID!): Pet }\ntype Pet { name: String }",
+			to:   "type Pet { name: String }\ntype Query { pet(id: ID!): Pet }",
+		},
+		{
+			name: "field added",
+			from: "type Query { pet: Pet }\ntype Pet { name: String }",
+			to:   "type Query { pet: Pet }\ntype Pet { name: String, age: Int }",
+			want: "non-breaking field-added Pet.age",
+		},
+		{
+			name: "field removed",
+			from: "type Query { pet: Pet }\ntype Pet { name: String, age: Int }",
+			to:   "type Query { pet: Pet }\ntype Pet { name: String }",
+			want: "breaking field-removed Pet.age",
+		},
+		{
+			name: "optional argument added",
+			from: "type Query { pets: [String] }",
+			to:   "type Query { pets(first: Int): [String] }",
+			want: "non-breaking argument-added Query.pets(first:)",
+		},
+		{
+			name: "required argument added",
+			from: "type Query { pets: [String] }",
+			to:   "type Query { pets(first: Int!): [String] }",
+			want: "breaking argument-added Query.pets(first:)",
+		},
+		{
+			name: "required argument with a default added",
+			from: "type Query { pets: [String] }",
+			to:   "type Query { pets(first: Int! = 10): [String] }",
+			want: "non-breaking argument-added Query.pets(first:)",
+		},
+		{
+			name: "argument removed",
+			from: "type Query { pet(id: ID, name: String): String }",
+			to:   "type Query { pet(id: ID): String }",
+			want: "breaking argument-removed Query.pet(name:)",
+		},
+		{
+			name: "required input field added",
+			from: "type Query { a: String }\ntype Mutation { add(pet: PetInput): String }\ninput PetInput { name: String }",
+			to:   "type Query { a: String }\ntype Mutation { add(pet: PetInput): String }\ninput PetInput { name: String, age: Int! }",
+			want: "breaking input-field-added PetInput.age",
+		},
+		{
+			name: "enum value added",
+			from: "type Query { kind: Kind }\nenum Kind { DOG }",
+			to:   "type Query { kind: Kind }\nenum Kind { DOG CAT }",
+			want: "non-breaking enum-value-added Kind.CAT",
+		},
+		{
+			name: "enum value removed",
+			from: "type Query { kind: Kind }\nenum Kind { DOG CAT }",
+			to:   "type Query { kind: Kind }\nenum Kind { DOG }",
+			want: "breaking enum-value-removed Kind.CAT",
+		},
+		{
+			name: "union member removed",
+			from: "type Query { pet: Pet }\nunion Pet = Dog | Cat\ntype Dog { a: Int }\ntype Cat { a: Int }",
+			to:   "type Query { pet: Pet }\nunion Pet = Dog\ntype Dog { a: Int }\ntype Cat { a: Int }",
+			want: "breaking union-member-removed Pet",
+		},
+		{
+			name: "type removed",
+			from: "type Query { a: String }\ntype Pet { name: String }",
+			to:   "type Query { a: String }",
+			want: "breaking type-removed Pet",
+		},
+		{
+			name: "type kind changed",
+			from: "type Query { pet: Pet }\ntype Pet { name: String }",
+			to:   "type Query { pet: Pet }\ninterface Pet { name: String }",
+			want: "breaking type-kind-changed Pet",
+		},
+		{
+			name: "mutation type added",
+			from: "type Query { a: String }",
+			to:   "type Query { a: String }\ntype Mutation { b: String }",
+			want: "non-breaking operation-type-added mutation\nnon-breaking type-added Mutation",
+		},
+		{
+			name: "field deprecated",
+			from: "type Query { a: String }",
+			to:   "type Query { a: String @deprecated(reason: \"use b\") }",
+			want: "non-breaking field-deprecated Query.a",
+		},
+		{
+			name: "directive location removed",
+			from: "type Query { a: String }\ndirective @auth on FIELD_DEFINITION | OBJECT",
+			to:   "type Query { a: String }\ndirective @auth on FIELD_DEFINITION",
+			want: "breaking directive-location-removed @auth",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := report(CheckCompatibility(parseSchema(t, tt.from), parseSchema(t, tt.to))); got != tt.want {
+				t.Errorf("findings:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	from := parseSchema(t, "type Query { pet(id: ID!): Pet }\ntype Pet { name: String }\nenum Kind { DOG CAT }")
+
+	// Order of definitions and extensions is not a change
+	reordered := parseSchema(t, "enum Kind { DOG }\nextend enum Kind { CAT }\ntype Pet { name: String }\ntype Query { pet(id: ID!): Pet }")
+	if d := Compare(from, reordered); !d.Empty() {
+		t.Errorf("reordered schema: %+v", d)
+	}
+
+	changed := parseSchema(t, "type Query { pet(id: ID): Pet }\ntype Mutation { ping: String }\ntype Pet { name: String, age: Int }\nenum Kind { DOG }")
+	d := Compare(from, changed)
+	tests := []struct {
+		section                  string
+		set                      *openapi.ChangeSet
+		added, removed, modified string
+	}{
+		{section: "operations", set: &d.Operations, added: "mutation"},
+		{section: "types", set: &d.Types, added: "Mutation"},
+		{section: "fields", set: &d.Fields, added: "Mutation.ping,Pet.age", modified: "Query.pet"},
+		{section: "enum values", set: &d.EnumValues, removed: "Kind.CAT"},
+		{section: "directives", set: &d.Directives},
+	}
+	names := func(changes []openapi.Change) string {
+		var out []string
+		for _, c := range changes {
+			out = append(out, c.Name)
+		}
+		return strings.Join(out, ",")
+	}
+	for _, tt := range tests {
+		if got := names(tt.set.Added); got != tt.added {
+			t.Errorf("%s added = %q, want %q", tt.section, got, tt.added)
+		}
+		if got := names(tt.set.Removed); got != tt.removed {
+			t.Errorf("%s removed = %q, want %q", tt.section, got, tt.removed)
+		}
+		if got := names(tt.set.Modified); got != tt.modified {
+			t.Errorf("%s modified = %q, want %q", tt.section, got, tt.modified)
+		}
+	}
+}
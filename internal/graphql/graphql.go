@@ -0,0 +1,200 @@
+// Package graphql reads GraphQL schema documents (SDL): the types, root
+// operations and directives a GraphQL API exposes. It checks that a schema
+// is complete and consistent, and compares two versions for changes that
+// break existing queries.
+package graphql
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Type kinds, named as in introspection.
+const (
+	KindScalar      = "SCALAR"
+	KindObject      = "OBJECT"
+	KindInterface   = "INTERFACE"
+	KindUnion       = "UNION"
+	KindEnum        = "ENUM"
+	KindInputObject = "INPUT_OBJECT"
+)
+
+// Document is a parsed schema document. Extensions are kept apart until the
+// schema is built, when they are merged into the types they extend.
+type Document struct {
+	Schema     *SchemaDefinition
+	Types      []*Type
+	Directives []*Directive
+	Extensions []*Type // "extend type X ..." and friends; Kind is that of the extended type
+	SchemaExts []*SchemaDefinition
+}
+
+// SchemaDefinition is a "schema { query: Query ... }" block.
+type SchemaDefinition struct {
+	Description string
+	Operations  map[string]string // operation (query, mutation, subscription) to type name
+	Line, Col   int
+}
+
+// Type is a named type definition.
+type Type struct {
+	Kind        string        `json:"kind"`
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Interfaces  []string      `json:"interfaces,omitempty"`   // objects and interfaces
+	Fields      []*Field      `json:"fields,omitempty"`       // objects and interfaces
+	InputFields []*InputValue `json:"input_fields,omitempty"` // input objects
+	Members     []string      `json:"members,omitempty"`      // unions
+	Values      []*EnumValue  `json:"values,omitempty"`       // enums
+	Line        int           `json:"line"`
+	Col         int           `json:"column"`
+}
+
+// Field is a field of an object or interface.
+type Field struct {
+	Name              string        `json:"name"`
+	Description       string        `json:"description,omitempty"`
+	Args              []*InputValue `json:"args,omitempty"`
+	Type              *TypeRef      `json:"type"`
+	Deprecated        bool          `json:"deprecated,omitempty"`
+	DeprecationReason string        `json:"deprecation_reason,omitempty"`
+	Line              int           `json:"line"`
+	Col               int           `json:"column"`
+}
+
+// Arg returns the argument with the given name, or nil.
+func (f *Field) Arg(name string) *InputValue {
+	for _, a := range f.Args {
+		if a.Name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// InputValue is an argument or a field of an input object.
+type InputValue struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Type        *TypeRef `json:"type"`
+	Default     string   `json:"default,omitempty"` // as written, e.g. 10 or {first: 5}
+	Deprecated  bool     `json:"deprecated,omitempty"`
+	Line        int      `json:"line"`
+	Col         int      `json:"column"`
+}
+
+// Required reports whether a value must be given: it is non-null and has no
+// default.
+func (v *InputValue) Required() bool {
+	return v.Type.NonNull && v.Default == ""
+}
+
+// EnumValue is a value of an enum.
+type EnumValue struct {
+	Name              string `json:"name"`
+	Description       string `json:"description,omitempty"`
+	Deprecated        bool   `json:"deprecated,omitempty"`
+	DeprecationReason string `json:"deprecation_reason,omitempty"`
+	Line              int    `json:"line"`
+	Col               int    `json:"column"`
+}
+
+// Directive is a directive definition.
+type Directive struct {
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	Args        []*InputValue `json:"args,omitempty"`
+	Repeatable  bool          `json:"repeatable,omitempty"`
+	Locations   []string      `json:"locations"`
+	Line        int           `json:"line"`
+	Col         int           `json:"column"`
+}
+
+// TypeRef is a type reference: a named type, or a list or non-null wrapper
+// around another reference.
+type TypeRef struct {
+	Name    string   // named type; "" for wrappers
+	OfType  *TypeRef // wrapped reference of a list or non-null type
+	NonNull bool     // a non-null wrapper; otherwise a wrapper is a list
+}
+
+// String renders the reference as written, e.g. [User!]!.
+func (t *TypeRef) String() string {
+	switch {
+	case t.Name != "":
+		return t.Name
+	case t.NonNull:
+		return t.OfType.String() + "!"
+	}
+	return "[" + t.OfType.String() + "]"
+}
+
+// NamedType returns the name of the type inside all wrappers.
+func (t *TypeRef) NamedType() string {
+	for t.Name == "" {
+		t = t.OfType
+	}
+	return t.Name
+}
+
+// Builtin reports whether the named type is a built-in scalar, which the
+// schema references without defining.
+func (t *TypeRef) Builtin() bool {
+	return IsBuiltinScalar(t.NamedType())
+}
+
+func (t *TypeRef) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+// builtinScalars are the scalars every schema has.
+var builtinScalars = map[string]bool{"Int": true, "Float": true, "String": true, "Boolean": true, "ID": true}
+
+// IsBuiltinScalar reports whether name is one of the specified scalars.
+func IsBuiltinScalar(name string) bool {
+	return builtinScalars[name]
+}
+
+var sdlStartRe = regexp.MustCompile(`^(schema|type|interface|union|enum|input|scalar|directive|extend)\b`)
+
+// Detect reports whether content looks like a schema document: its first
+// definition, after comments and a description, is a type system one. An
+// enum is also how a .proto file can start, so a document starting with one
+// must parse.
+func Detect(content []byte) bool {
+	s := string(content)
+	for {
+		s = strings.TrimLeft(s, " \t\r\n,\ufeff")
+		if !strings.HasPrefix(s, "#") {
+			break
+		}
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			return false
+		}
+		s = s[i:]
+	}
+	described := false
+	if strings.HasPrefix(s, `"`) {
+		tokens, err := lex(s)
+		if err != nil || len(tokens) < 2 || tokens[0].kind != tokString {
+			return false
+		}
+		s, described = s[tokens[1].pos:], true
+	}
+	m := sdlStartRe.FindString(s)
+	if m == "" {
+		return false
+	}
+	// "type: ..." is YAML, not a definition
+	rest := strings.TrimLeft(s[len(m):], " \t")
+	if strings.HasPrefix(rest, ":") {
+		return false
+	}
+	if m == "enum" && !described {
+		_, err := ParseDocument(content)
+		return err == nil
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package graphql
+
+// Index lists what a schema declares, for the reference view and the index
+// endpoint. Root operation types are listed under Operations only.
+type Index struct {
+	Description string       `json:"description,omitempty"`
+	Operations  []Operation  `json:"operations"`
+	Objects     []*Type      `json:"objects"`
+	Interfaces  []*Type      `json:"interfaces"`
+	Unions      []*Type      `json:"unions"`
+	Enums       []*Type      `json:"enums"`
+	Inputs      []*Type      `json:"inputs"`
+	Scalars     []*Type      `json:"scalars"`
+	Directives  []*Directive `json:"directives"`
+}
+
+// Operation is a root operation and the object type whose fields are its
+// entry points.
+type Operation struct {
+	Operation string `json:"operation"` // query, mutation or subscription
+	Type      *Type  `json:"type"`
+}
+
+// Index returns the root operations, types and directives of the schema.
+// Built-in scalars are referenced but not listed.
+func (s *Schema) Index() *Index {
+	idx := &Index{
+		Description: s.Description,
+		Operations:  []Operation{},
+		Objects:     []*Type{},
+		Interfaces:  []*Type{},
+		Unions:      []*Type{},
+		Enums:       []*Type{},
+		Inputs:      []*Type{},
+		Scalars:     []*Type{},
+		Directives:  s.Directives,
+	}
+	if idx.Directives == nil {
+		idx.Directives = []*Directive{}
+	}
+	roots := map[string]bool{}
+	for _, op := range operations {
+		if t := s.Root(op); t != nil {
+			idx.Operations = append(idx.Operations, Operation{Operation: op, Type: t})
+			roots[t.Name] = true
+		}
+	}
+	for _, t := range s.Types() {
+		switch t.Kind {
+		case KindObject:
+			if !roots[t.Name] {
+				idx.Objects = append(idx.Objects, t)
+			}
+		case KindInterface:
+			idx.Interfaces = append(idx.Interfaces, t)
+		case KindUnion:
+			idx.Unions = append(idx.Unions, t)
+		case KindEnum:
+			idx.Enums = append(idx.Enums, t)
+		case KindInputObject:
+			idx.Inputs = append(idx.Inputs, t)
+		case KindScalar:
+			idx.Scalars = append(idx.Scalars, t)
+		}
+	}
+	return idx
+}
@@ -0,0 +1,234 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokName
+	tokInt
+	tokFloat
+	tokString // value unquoted; block strings are dedented
+	tokPunct
+)
+
+type token struct {
+	kind      tokenKind
+	text      string
+	block     bool // a """block string"""
+	line, col int
+	pos, end  int // byte offsets of the token in the source
+}
+
+// SyntaxError is a problem found while reading a schema document.
+type SyntaxError struct {
+	Line, Col int
+	Message   string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Col, e.Message)
+}
+
+// lex splits a GraphQL document into tokens. Whitespace, commas and
+// comments are insignificant and dropped.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	line, lineStart := 1, 0
+	i := 0
+	fail := func(at int, format string, args ...any) error {
+		return &SyntaxError{Line: line, Col: at - lineStart + 1, Message: fmt.Sprintf(format, args...)}
+	}
+	emit := func(kind tokenKind, text string, start, end int) {
+		tokens = append(tokens, token{kind: kind, text: text, line: line, col: start - lineStart + 1, pos: start, end: end})
+	}
+
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '\n':
+			i++
+			line, lineStart = line+1, i
+		case c == '\r':
+			i++
+			if i < len(src) && src[i] == '\n' {
+				i++
+			}
+			line, lineStart = line+1, i
+		case c == ' ' || c == '\t' || c == ',':
+			i++
+		case strings.HasPrefix(src[i:], "\ufeff"):
+			i += len("\ufeff")
+		case c == '#':
+			for i < len(src) && src[i] != '\n' && src[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "..."):
+			emit(tokPunct, "...", i, i+3)
+			i += 3
+		case strings.IndexByte("!$&()=:@[]{}|", c) >= 0:
+			emit(tokPunct, string(c), i, i+1)
+			i++
+		case c == '_' || isLetter(c):
+			start := i
+			for i < len(src) && (src[i] == '_' || isLetter(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			emit(tokName, src[start:i], start, i)
+		case c == '-' || isDigit(c):
+			start := i
+			if c == '-' {
+				i++
+			}
+			digits := func() int {
+				n := 0
+				for i < len(src) && isDigit(src[i]) {
+					i++
+					n++
+				}
+				return n
+			}
+			if digits() == 0 {
+				return nil, fail(start, "invalid number")
+			}
+			kind := tokInt
+			if i < len(src) && src[i] == '.' {
+				i++
+				kind = tokFloat
+				if digits() == 0 {
+					return nil, fail(start, "invalid number")
+				}
+			}
+			if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+				i++
+				kind = tokFloat
+				if i < len(src) && (src[i] == '+' || src[i] == '-') {
+					i++
+				}
+				if digits() == 0 {
+					return nil, fail(start, "invalid number")
+				}
+			}
+			if i < len(src) && (src[i] == '_' || src[i] == '.' || isLetter(src[i])) {
+				return nil, fail(start, "invalid number")
+			}
+			emit(kind, src[start:i], start, i)
+		case strings.HasPrefix(src[i:], `"""`):
+			start, startLine, startCol := i, line, i-lineStart+1
+			i += 3
+			var b strings.Builder
+			for {
+				if i >= len(src) {
+					return nil, &SyntaxError{Line: startLine, Col: startCol, Message: "unterminated block string"}
+				}
+				if strings.HasPrefix(src[i:], `\"""`) {
+					b.WriteString(`"""`)
+					i += 4
+					continue
+				}
+				if strings.HasPrefix(src[i:], `"""`) {
+					i += 3
+					break
+				}
+				if src[i] == '\n' {
+					line, lineStart = line+1, i+1
+				}
+				b.WriteByte(src[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokString, text: blockStringValue(b.String()), block: true, line: startLine, col: startCol, pos: start, end: i})
+		case c == '"':
+			start := i
+			i++
+			var b strings.Builder
+			for {
+				if i >= len(src) || src[i] == '\n' || src[i] == '\r' {
+					return nil, fail(start, "unterminated string")
+				}
+				if src[i] == '"' {
+					i++
+					break
+				}
+				if src[i] != '\\' {
+					b.WriteByte(src[i])
+					i++
+					continue
+				}
+				if i+1 >= len(src) {
+					return nil, fail(start, "unterminated string")
+				}
+				switch e := src[i+1]; e {
+				case '"', '\\', '/':
+					b.WriteByte(e)
+				case 'b':
+					b.WriteByte('\b')
+				case 'f':
+					b.WriteByte('\f')
+				case 'n':
+					b.WriteByte('\n')
+				case 'r':
+					b.WriteByte('\r')
+				case 't':
+					b.WriteByte('\t')
+				case 'u':
+					if i+6 > len(src) {
+						return nil, fail(i, "invalid unicode escape")
+					}
+					r, err := strconv.ParseUint(src[i+2:i+6], 16, 32)
+					if err != nil {
+						return nil, fail(i, "invalid unicode escape")
+					}
+					b.WriteRune(rune(r))
+					i += 4
+				default:
+					return nil, fail(i, "invalid escape sequence \\%c", e)
+				}
+				i += 2
+			}
+			emit(tokString, b.String(), start, i)
+		default:
+			r, _ := utf8.DecodeRuneInString(src[i:])
+			return nil, fail(i, "unexpected character %q", r)
+		}
+	}
+	tokens = append(tokens, token{kind: tokEOF, line: line, col: i - lineStart + 1, pos: i, end: i})
+	return tokens, nil
+}
+
+func isLetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// blockStringValue removes the common indentation and the blank first and
+// last lines of a block string, as the GraphQL specification describes.
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	common := -1
+	for _, l := range lines[1:] {
+		indent := len(l) - len(strings.TrimLeft(l, " \t"))
+		if indent < len(l) && (common < 0 || indent < common) {
+			common = indent
+		}
+	}
+	if common > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= common {
+				lines[i] = lines[i][common:]
+			} else {
+				lines[i] = ""
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,392 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// directiveLocations are the places a directive can be declared to apply to.
+var directiveLocations = map[string]bool{
+	"QUERY": true, "MUTATION": true, "SUBSCRIPTION": true, "FIELD": true, "FRAGMENT_DEFINITION": true,
+	"FRAGMENT_SPREAD": true, "INLINE_FRAGMENT": true, "VARIABLE_DEFINITION": true,
+	"SCHEMA": true, "SCALAR": true, "OBJECT": true, "FIELD_DEFINITION": true, "ARGUMENT_DEFINITION": true,
+	"INTERFACE": true, "UNION": true, "ENUM": true, "ENUM_VALUE": true, "INPUT_OBJECT": true,
+	"INPUT_FIELD_DEFINITION": true,
+}
+
+type parser struct {
+	src    string
+	doc    *Document
+	tokens []token
+	pos    int
+}
+
+// ParseDocument parses a schema document. Names are not resolved and
+// extensions are not merged; see Parse for a checked schema.
+func ParseDocument(src []byte) (*Document, error) {
+	tokens, err := lex(string(src))
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: string(src), doc: &Document{}, tokens: tokens}
+	if err := p.parseDocument(); err != nil {
+		return nil, err
+	}
+	return p.doc, nil
+}
+
+// parseError aborts parsing; it is recovered in parseDocument.
+type parseError struct{ err *SyntaxError }
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) failAt(t token, format string, args ...any) {
+	panic(parseError{&SyntaxError{Line: t.line, Col: t.col, Message: fmt.Sprintf(format, args...)}})
+}
+
+func describe(t token) string {
+	switch t.kind {
+	case tokEOF:
+		return "end of file"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return "'" + t.text + "'"
+}
+
+// is reports whether the next token is the punctuation or keyword text.
+func (p *parser) is(text string) bool {
+	t := p.peek()
+	return (t.kind == tokPunct || t.kind == tokName) && t.text == text
+}
+
+func (p *parser) accept(text string) bool {
+	if p.is(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) token {
+	t := p.next()
+	if (t.kind != tokPunct && t.kind != tokName) || t.text != text {
+		p.failAt(t, "expected '%s', found %s", text, describe(t))
+	}
+	return t
+}
+
+func (p *parser) name() token {
+	t := p.next()
+	if t.kind != tokName {
+		p.failAt(t, "expected name, found %s", describe(t))
+	}
+	return t
+}
+
+// description reads the optional string before a definition.
+func (p *parser) description() string {
+	if p.peek().kind == tokString {
+		return p.next().text
+	}
+	return ""
+}
+
+func (p *parser) parseDocument() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pe, ok := r.(parseError)
+			if !ok {
+				panic(r)
+			}
+			err = pe.err
+		}
+	}()
+
+	for p.peek().kind != tokEOF {
+		desc := p.description()
+		t := p.peek()
+		if t.kind != tokName {
+			if t.text == "{" {
+				p.failAt(t, "operations do not belong in a schema document")
+			}
+			p.failAt(t, "expected a definition, found %s", describe(t))
+		}
+		switch t.text {
+		case "schema":
+			p.doc.Schema = p.schemaDefinition(desc, false)
+		case "directive":
+			p.doc.Directives = append(p.doc.Directives, p.directiveDefinition(desc))
+		case "extend":
+			p.next()
+			if desc != "" {
+				p.failAt(t, "extensions cannot have a description")
+			}
+			if p.is("schema") {
+				p.doc.SchemaExts = append(p.doc.SchemaExts, p.schemaDefinition("", true))
+				continue
+			}
+			p.doc.Extensions = append(p.doc.Extensions, p.typeDefinition("", true))
+		case "query", "mutation", "subscription", "fragment":
+			p.failAt(t, "operations and fragments do not belong in a schema document")
+		default:
+			p.doc.Types = append(p.doc.Types, p.typeDefinition(desc, false))
+		}
+	}
+	return nil
+}
+
+func (p *parser) schemaDefinition(desc string, extension bool) *SchemaDefinition {
+	t := p.expect("schema")
+	def := &SchemaDefinition{Description: desc, Operations: map[string]string{}, Line: t.line, Col: t.col}
+	p.directives()
+	if extension && !p.is("{") {
+		return def
+	}
+	p.expect("{")
+	for !p.accept("}") {
+		op := p.name()
+		switch op.text {
+		case "query", "mutation", "subscription":
+		default:
+			p.failAt(op, "expected query, mutation or subscription, found %s", describe(op))
+		}
+		if _, ok := def.Operations[op.text]; ok {
+			p.failAt(op, "the %s type is given twice", op.text)
+		}
+		p.expect(":")
+		def.Operations[op.text] = p.name().text
+	}
+	if len(def.Operations) == 0 {
+		p.failAt(t, "a schema definition needs at least one operation type")
+	}
+	return def
+}
+
+// typeDefinition reads a named type definition or, after "extend", the
+// extension of one, whose parts are all optional.
+func (p *parser) typeDefinition(desc string, extension bool) *Type {
+	kw := p.name()
+	switch kw.text {
+	case "scalar", "type", "interface", "union", "enum", "input":
+	case "directive":
+		p.failAt(kw, "directives cannot be extended")
+	default:
+		if extension {
+			p.failAt(kw, "expected a type or schema to extend, found %s", describe(kw))
+		}
+		p.failAt(kw, "expected a definition, found %s", describe(kw))
+	}
+	name := p.name()
+	t := &Type{Name: name.text, Description: desc, Line: name.line, Col: name.col}
+	switch kw.text {
+	case "scalar":
+		t.Kind = KindScalar
+		p.directives()
+	case "type", "interface":
+		t.Kind = KindObject
+		if kw.text == "interface" {
+			t.Kind = KindInterface
+		}
+		if p.accept("implements") {
+			p.accept("&")
+			t.Interfaces = append(t.Interfaces, p.name().text)
+			// The comma-separated form predates "&"; commas are ignored tokens
+			for p.accept("&") || (p.peek().kind == tokName && !p.isDefinitionStart()) {
+				t.Interfaces = append(t.Interfaces, p.name().text)
+			}
+		}
+		p.directives()
+		if p.accept("{") {
+			for !p.accept("}") {
+				t.Fields = append(t.Fields, p.field())
+			}
+			if len(t.Fields) == 0 {
+				p.failAt(kw, "%s %s has an empty field list", kw.text, t.Name)
+			}
+		}
+	case "union":
+		t.Kind = KindUnion
+		p.directives()
+		if p.accept("=") {
+			p.accept("|")
+			t.Members = append(t.Members, p.name().text)
+			for p.accept("|") {
+				t.Members = append(t.Members, p.name().text)
+			}
+		}
+	case "enum":
+		t.Kind = KindEnum
+		p.directives()
+		if p.accept("{") {
+			for !p.accept("}") {
+				t.Values = append(t.Values, p.enumValue())
+			}
+			if len(t.Values) == 0 {
+				p.failAt(kw, "enum %s has an empty value list", t.Name)
+			}
+		}
+	case "input":
+		t.Kind = KindInputObject
+		p.directives()
+		if p.accept("{") {
+			for !p.accept("}") {
+				t.InputFields = append(t.InputFields, p.inputValue())
+			}
+			if len(t.InputFields) == 0 {
+				p.failAt(kw, "input %s has an empty field list", t.Name)
+			}
+		}
+	}
+	return t
+}
+
+// isDefinitionStart reports whether the next tokens begin a new definition,
+// which ends a comma-separated implements list.
+func (p *parser) isDefinitionStart() bool {
+	switch p.peek().text {
+	case "schema", "scalar", "type", "interface", "union", "enum", "input", "directive", "extend":
+		next := p.tokens[p.pos+1]
+		return next.kind == tokName || next.text == "@" || next.text == "{"
+	}
+	return false
+}
+
+func (p *parser) field() *Field {
+	desc := p.description()
+	name := p.name()
+	f := &Field{Name: name.text, Description: desc, Line: name.line, Col: name.col}
+	if p.accept("(") {
+		for !p.accept(")") {
+			f.Args = append(f.Args, p.inputValue())
+		}
+		if len(f.Args) == 0 {
+			p.failAt(name, "field %s has an empty argument list", f.Name)
+		}
+	}
+	p.expect(":")
+	f.Type = p.typeRef()
+	f.Deprecated, f.DeprecationReason = p.directives()
+	return f
+}
+
+func (p *parser) inputValue() *InputValue {
+	desc := p.description()
+	name := p.name()
+	v := &InputValue{Name: name.text, Description: desc, Line: name.line, Col: name.col}
+	p.expect(":")
+	v.Type = p.typeRef()
+	if p.accept("=") {
+		start := p.peek()
+		p.value()
+		v.Default = p.src[start.pos:p.tokens[p.pos-1].end]
+	}
+	v.Deprecated, _ = p.directives()
+	return v
+}
+
+func (p *parser) enumValue() *EnumValue {
+	desc := p.description()
+	name := p.name()
+	switch name.text {
+	case "true", "false", "null":
+		p.failAt(name, "%s cannot be an enum value", name.text)
+	}
+	v := &EnumValue{Name: name.text, Description: desc, Line: name.line, Col: name.col}
+	v.Deprecated, v.DeprecationReason = p.directives()
+	return v
+}
+
+func (p *parser) directiveDefinition(desc string) *Directive {
+	p.expect("directive")
+	p.expect("@")
+	name := p.name()
+	d := &Directive{Name: name.text, Description: desc, Line: name.line, Col: name.col}
+	if p.accept("(") {
+		for !p.accept(")") {
+			d.Args = append(d.Args, p.inputValue())
+		}
+	}
+	d.Repeatable = p.accept("repeatable")
+	p.expect("on")
+	p.accept("|")
+	for {
+		loc := p.name()
+		if !directiveLocations[loc.text] {
+			p.failAt(loc, "unknown directive location %s", loc.text)
+		}
+		d.Locations = append(d.Locations, loc.text)
+		if !p.accept("|") {
+			break
+		}
+	}
+	return d
+}
+
+// typeRef reads a type reference: Name, [Type] or either followed by !.
+func (p *parser) typeRef() *TypeRef {
+	var t *TypeRef
+	if p.accept("[") {
+		t = &TypeRef{OfType: p.typeRef()}
+		p.expect("]")
+	} else {
+		t = &TypeRef{Name: p.name().text}
+	}
+	if p.accept("!") {
+		t = &TypeRef{OfType: t, NonNull: true}
+	}
+	return t
+}
+
+// directives reads the directives applied to a definition and reports
+// whether @deprecated is one of them, with its reason.
+func (p *parser) directives() (deprecated bool, reason string) {
+	for p.accept("@") {
+		name := p.name()
+		if name.text == "deprecated" {
+			deprecated, reason = true, "No longer supported"
+		}
+		if !p.accept("(") {
+			continue
+		}
+		for !p.accept(")") {
+			arg := p.name()
+			p.expect(":")
+			v := p.peek()
+			p.value()
+			if name.text == "deprecated" && arg.text == "reason" && v.kind == tokString {
+				reason = v.text
+			}
+		}
+	}
+	return deprecated, reason
+}
+
+// value skips a value literal. Schema documents only hold constants, so
+// variables are rejected.
+func (p *parser) value() {
+	t := p.next()
+	switch {
+	case t.kind == tokInt, t.kind == tokFloat, t.kind == tokString, t.kind == tokName:
+	case t.text == "[":
+		for !p.accept("]") {
+			p.value()
+		}
+	case t.text == "{":
+		for !p.accept("}") {
+			p.name()
+			p.expect(":")
+			p.value()
+		}
+	default:
+		p.failAt(t, "expected a value, found %s", describe(t))
+	}
+}
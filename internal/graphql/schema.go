@@ -0,0 +1,431 @@
+package graphql
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"APIScope/internal/openapi"
+)
+
+// Root operation types, in the order a schema lists them.
+var operations = []string{"query", "mutation", "subscription"}
+
+// Schema is a schema document with its extensions merged and every type
+// reference checked.
+type Schema struct {
+	Description string
+	Roots       map[string]string // operation (query, mutation, subscription) to object type name
+	Directives  []*Directive      // directives the document defines, ordered by name
+	types       map[string]*Type
+}
+
+// Type returns the type with the given name, nil for built-in scalars and
+// unknown names.
+func (s *Schema) Type(name string) *Type { return s.types[name] }
+
+// Types returns the types the document defines, ordered by name.
+func (s *Schema) Types() []*Type {
+	out := make([]*Type, 0, len(s.types))
+	for _, name := range openapi.SortedKeys(s.types) {
+		out = append(out, s.types[name])
+	}
+	return out
+}
+
+// Root returns the object type of a root operation, nil when the schema has
+// no such operation.
+func (s *Schema) Root(operation string) *Type {
+	return s.types[s.Roots[operation]]
+}
+
+// Title names the schema: the first line of its description. SDL has no
+// other place for a name.
+func (s *Schema) Title() string {
+	title, _, _ := strings.Cut(s.Description, "\n")
+	return strings.TrimSpace(title)
+}
+
+// Parse reads and checks a schema document. The first problem found is
+// returned as the error; see Validate for all of them.
+func Parse(content []byte) (*Schema, error) {
+	schema, problems := load(content)
+	if len(problems) > 0 {
+		return nil, problems[0]
+	}
+	return schema, nil
+}
+
+// Validate checks a schema document and returns every problem found, ordered
+// by position. The path of a problem is the schema coordinate of what it is
+// about, e.g. User.name or Query.user(id:).
+func Validate(content []byte) []openapi.ValidationError {
+	_, problems := load(content)
+	return problems
+}
+
+type loader struct {
+	schema   *Schema
+	problems []openapi.ValidationError
+}
+
+func (l *loader) fail(coordinate string, line, col int, format string, args ...any) {
+	l.problems = append(l.problems, openapi.ValidationError{Path: coordinate, Line: line, Column: col, Message: fmt.Sprintf(format, args...)})
+}
+
+func load(content []byte) (*Schema, []openapi.ValidationError) {
+	doc, err := ParseDocument(content)
+	if err != nil {
+		if se, ok := err.(*SyntaxError); ok {
+			return nil, []openapi.ValidationError{{Line: se.Line, Column: se.Col, Message: se.Message}}
+		}
+		return nil, []openapi.ValidationError{{Message: err.Error()}}
+	}
+	l := &loader{schema: &Schema{Roots: map[string]string{}, types: map[string]*Type{}}}
+
+	for _, t := range doc.Types {
+		switch existing, ok := l.schema.types[t.Name]; {
+		case strings.HasPrefix(t.Name, "__"):
+			l.fail(t.Name, t.Line, t.Col, "names starting with __ are reserved for introspection")
+		case IsBuiltinScalar(t.Name):
+			l.fail(t.Name, t.Line, t.Col, "%s is a built-in scalar", t.Name)
+		case ok:
+			l.fail(t.Name, t.Line, t.Col, "type %s is already defined (line %d)", t.Name, existing.Line)
+		default:
+			l.schema.types[t.Name] = t
+		}
+	}
+	for _, ext := range doc.Extensions {
+		l.extend(ext)
+	}
+	l.roots(doc)
+
+	directives := map[string]*Directive{}
+	for _, d := range doc.Directives {
+		coordinate := "@" + d.Name
+		if existing, ok := directives[d.Name]; ok {
+			l.fail(coordinate, d.Line, d.Col, "directive @%s is already defined (line %d)", d.Name, existing.Line)
+			continue
+		}
+		directives[d.Name] = d
+		l.checkArgs(coordinate, d.Args)
+	}
+	for _, name := range openapi.SortedKeys(directives) {
+		l.schema.Directives = append(l.schema.Directives, directives[name])
+	}
+
+	for _, t := range l.schema.Types() {
+		switch t.Kind {
+		case KindObject, KindInterface:
+			l.checkFields(t)
+			l.checkInterfaces(t)
+		case KindUnion:
+			l.checkUnion(t)
+		case KindEnum:
+			l.checkEnum(t)
+		case KindInputObject:
+			l.checkInputFields(t)
+		}
+	}
+
+	if len(l.problems) > 0 {
+		return nil, l.sorted()
+	}
+	return l.schema, nil
+}
+
+func (l *loader) sorted() []openapi.ValidationError {
+	sort.SliceStable(l.problems, func(i, j int) bool {
+		a, b := l.problems[i], l.problems[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return l.problems
+}
+
+// extend merges an extension into the type it extends.
+func (l *loader) extend(ext *Type) {
+	t, ok := l.schema.types[ext.Name]
+	switch {
+	case !ok:
+		l.fail(ext.Name, ext.Line, ext.Col, "cannot extend %s, which is not defined", ext.Name)
+		return
+	case t.Kind != ext.Kind:
+		l.fail(ext.Name, ext.Line, ext.Col, "cannot extend %s %s as %s", kindName(t.Kind), t.Name, kindName(ext.Kind))
+		return
+	}
+	t.Interfaces = append(t.Interfaces, ext.Interfaces...)
+	t.Fields = append(t.Fields, ext.Fields...)
+	t.InputFields = append(t.InputFields, ext.InputFields...)
+	t.Members = append(t.Members, ext.Members...)
+	t.Values = append(t.Values, ext.Values...)
+}
+
+// roots sets the root operation types: those of the schema definition, else
+// the types named Query, Mutation and Subscription.
+func (l *loader) roots(doc *Document) {
+	defs := doc.SchemaExts
+	if doc.Schema != nil {
+		defs = append([]*SchemaDefinition{doc.Schema}, defs...)
+		l.schema.Description = doc.Schema.Description
+	}
+	if len(defs) == 0 {
+		for _, op := range operations {
+			name := strings.ToUpper(op[:1]) + op[1:]
+			if t, ok := l.schema.types[name]; ok && t.Kind == KindObject {
+				l.schema.Roots[op] = name
+			}
+		}
+	}
+	for _, def := range defs {
+		for _, op := range operations {
+			name, ok := def.Operations[op]
+			if !ok {
+				continue
+			}
+			if _, ok := l.schema.Roots[op]; ok {
+				l.fail("schema", def.Line, def.Col, "the %s type is given twice", op)
+				continue
+			}
+			if t := l.schema.types[name]; t == nil || t.Kind != KindObject {
+				l.fail("schema", def.Line, def.Col, "%s type %s must be a defined object type", op, name)
+				continue
+			}
+			l.schema.Roots[op] = name
+		}
+	}
+	if _, ok := l.schema.Roots["query"]; !ok {
+		line, col := 1, 1
+		if len(defs) > 0 {
+			line, col = defs[0].Line, defs[0].Col
+		}
+		l.fail("schema", line, col, "the schema has no query type: define type Query or name one in a schema definition")
+	}
+}
+
+// kindOf returns the kind of a named type, built-in scalars included.
+func (l *loader) kindOf(name string) (string, bool) {
+	if IsBuiltinScalar(name) {
+		return KindScalar, true
+	}
+	if t, ok := l.schema.types[name]; ok {
+		return t.Kind, true
+	}
+	return "", false
+}
+
+func (l *loader) checkFields(t *Type) {
+	if len(t.Fields) == 0 {
+		l.fail(t.Name, t.Line, t.Col, "%s %s has no fields", kindName(t.Kind), t.Name)
+	}
+	names := map[string]bool{}
+	for _, f := range t.Fields {
+		coordinate := t.Name + "." + f.Name
+		if names[f.Name] {
+			l.fail(coordinate, f.Line, f.Col, "field %s is declared twice", coordinate)
+		}
+		names[f.Name] = true
+		if strings.HasPrefix(f.Name, "__") {
+			l.fail(coordinate, f.Line, f.Col, "names starting with __ are reserved for introspection")
+		}
+		switch kind, ok := l.kindOf(f.Type.NamedType()); {
+		case !ok:
+			l.fail(coordinate, f.Line, f.Col, "unknown type %s", f.Type.NamedType())
+		case kind == KindInputObject:
+			l.fail(coordinate, f.Line, f.Col, "%s is an input type and cannot be the type of a field", f.Type.NamedType())
+		}
+		l.checkArgs(coordinate, f.Args)
+	}
+}
+
+// checkArgs checks the arguments of a field or directive.
+func (l *loader) checkArgs(owner string, args []*InputValue) {
+	names := map[string]bool{}
+	for _, a := range args {
+		coordinate := owner + "(" + a.Name + ":)"
+		if names[a.Name] {
+			l.fail(coordinate, a.Line, a.Col, "argument %s is declared twice", a.Name)
+		}
+		names[a.Name] = true
+		l.checkInputType(coordinate, a)
+	}
+}
+
+func (l *loader) checkInputFields(t *Type) {
+	if len(t.InputFields) == 0 {
+		l.fail(t.Name, t.Line, t.Col, "input %s has no fields", t.Name)
+	}
+	names := map[string]bool{}
+	for _, f := range t.InputFields {
+		coordinate := t.Name + "." + f.Name
+		if names[f.Name] {
+			l.fail(coordinate, f.Line, f.Col, "field %s is declared twice", coordinate)
+		}
+		names[f.Name] = true
+		l.checkInputType(coordinate, f)
+	}
+}
+
+func (l *loader) checkInputType(coordinate string, v *InputValue) {
+	switch kind, ok := l.kindOf(v.Type.NamedType()); {
+	case !ok:
+		l.fail(coordinate, v.Line, v.Col, "unknown type %s", v.Type.NamedType())
+	case kind != KindScalar && kind != KindEnum && kind != KindInputObject:
+		l.fail(coordinate, v.Line, v.Col, "%s is an output type and cannot be the type of an input value", v.Type.NamedType())
+	}
+	if v.Deprecated && v.Required() {
+		l.fail(coordinate, v.Line, v.Col, "a required input value cannot be deprecated")
+	}
+}
+
+// checkInterfaces checks that a type declares what the interfaces it
+// implements require: their fields, with compatible types and the same
+// arguments, and the interfaces they implement in turn.
+func (l *loader) checkInterfaces(t *Type) {
+	declared := map[string]bool{}
+	for _, name := range t.Interfaces {
+		declared[name] = true
+	}
+	seen := map[string]bool{}
+	for _, name := range t.Interfaces {
+		if seen[name] {
+			l.fail(t.Name, t.Line, t.Col, "%s implements %s twice", t.Name, name)
+			continue
+		}
+		seen[name] = true
+		iface := l.schema.types[name]
+		switch {
+		case name == t.Name:
+			l.fail(t.Name, t.Line, t.Col, "%s cannot implement itself", t.Name)
+			continue
+		case iface == nil:
+			l.fail(t.Name, t.Line, t.Col, "%s implements unknown interface %s", t.Name, name)
+			continue
+		case iface.Kind != KindInterface:
+			l.fail(t.Name, t.Line, t.Col, "%s implements %s, which is not an interface", t.Name, name)
+			continue
+		}
+		for _, inherited := range iface.Interfaces {
+			if !declared[inherited] && inherited != t.Name {
+				l.fail(t.Name, t.Line, t.Col, "%s must also implement %s, which %s implements", t.Name, inherited, name)
+			}
+		}
+		for _, want := range iface.Fields {
+			coordinate := t.Name + "." + want.Name
+			got := fieldNamed(t, want.Name)
+			if got == nil {
+				l.fail(t.Name, t.Line, t.Col, "%s is missing field %s required by %s", t.Name, want.Name, name)
+				continue
+			}
+			if !l.covariant(got.Type, want.Type) {
+				l.fail(coordinate, got.Line, got.Col, "type %s does not match %s of %s.%s", got.Type, want.Type, name, want.Name)
+			}
+			for _, arg := range want.Args {
+				if a := got.Arg(arg.Name); a == nil {
+					l.fail(coordinate, got.Line, got.Col, "argument %s of %s.%s is missing", arg.Name, name, want.Name)
+				} else if a.Type.String() != arg.Type.String() {
+					l.fail(coordinate+"("+a.Name+":)", a.Line, a.Col, "type %s does not match %s of %s.%s(%s:)", a.Type, arg.Type, name, want.Name, arg.Name)
+				}
+			}
+			for _, a := range got.Args {
+				if want.Arg(a.Name) == nil && a.Required() {
+					l.fail(coordinate+"("+a.Name+":)", a.Line, a.Col, "argument %s is required but %s.%s does not declare it", a.Name, name, want.Name)
+				}
+			}
+		}
+	}
+}
+
+// covariant reports whether a field of type sub can implement a field of
+// type super: the same type, or a stricter one.
+func (l *loader) covariant(sub, super *TypeRef) bool {
+	switch {
+	case super.NonNull:
+		return sub.NonNull && l.covariant(sub.OfType, super.OfType)
+	case sub.NonNull:
+		return l.covariant(sub.OfType, super)
+	case super.Name == "":
+		return sub.Name == "" && l.covariant(sub.OfType, super.OfType)
+	case sub.Name == "":
+		return false
+	}
+	return sub.Name == super.Name || l.possibleType(super.Name, sub.Name)
+}
+
+// possibleType reports whether an object or interface named name can be a
+// value of the abstract type.
+func (l *loader) possibleType(abstract, name string) bool {
+	a, t := l.schema.types[abstract], l.schema.types[name]
+	if a == nil || t == nil {
+		return false
+	}
+	switch a.Kind {
+	case KindUnion:
+		return contains(a.Members, name)
+	case KindInterface:
+		return contains(t.Interfaces, abstract)
+	}
+	return false
+}
+
+func (l *loader) checkUnion(t *Type) {
+	if len(t.Members) == 0 {
+		l.fail(t.Name, t.Line, t.Col, "union %s has no member types", t.Name)
+	}
+	seen := map[string]bool{}
+	for _, m := range t.Members {
+		if seen[m] {
+			l.fail(t.Name, t.Line, t.Col, "union %s includes %s twice", t.Name, m)
+		}
+		seen[m] = true
+		if member := l.schema.types[m]; member == nil {
+			l.fail(t.Name, t.Line, t.Col, "union %s includes unknown type %s", t.Name, m)
+		} else if member.Kind != KindObject {
+			l.fail(t.Name, t.Line, t.Col, "union %s includes %s, which is not an object type", t.Name, m)
+		}
+	}
+}
+
+func (l *loader) checkEnum(t *Type) {
+	if len(t.Values) == 0 {
+		l.fail(t.Name, t.Line, t.Col, "enum %s has no values", t.Name)
+	}
+	seen := map[string]bool{}
+	for _, v := range t.Values {
+		if seen[v.Name] {
+			l.fail(t.Name+"."+v.Name, v.Line, v.Col, "enum value %s.%s is declared twice", t.Name, v.Name)
+		}
+		seen[v.Name] = true
+	}
+}
+
+func fieldNamed(t *Type, name string) *Field {
+	for _, f := range t.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// kindName is how a kind is written in SDL.
+func kindName(kind string) string {
+	switch kind {
+	case KindObject:
+		return "type"
+	case KindInputObject:
+		return "input"
+	}
+	return strings.ToLower(kind)
+}
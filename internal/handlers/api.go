@@ -3,12 +3,19 @@ package handlers
 import (
 	"APIScope/internal/config"
 	"APIScope/internal/models"
+	"APIScope/internal/openapi"
 	"APIScope/internal/services"
+	"APIScope/internal/spectype"
 	"APIScope/internal/utils"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
-	"os"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,30 +24,93 @@ type ApiHandler struct {
 	docService              *services.DocumentService
 	storageService          *services.StorageService
 	openAPIGeneratorService *services.OpenAPIGeneratorService
+	retentionService        *services.RetentionService
+	changelogService        *services.ChangelogService
+	lintService             *services.LintService
+	specPipeline            *services.SpecPipeline
 	cfg                     *config.Config
 }
 
-func NewApiHandler(docService *services.DocumentService, storageService *services.StorageService, openAPIGeneratorService *services.OpenAPIGeneratorService, cfg *config.Config) *ApiHandler {
+func NewApiHandler(docService *services.DocumentService, storageService *services.StorageService, openAPIGeneratorService *services.OpenAPIGeneratorService, retentionService *services.RetentionService, changelogService *services.ChangelogService, lintService *services.LintService, specPipeline *services.SpecPipeline, cfg *config.Config) *ApiHandler {
 	return &ApiHandler{
 		docService:              docService,
 		storageService:          storageService,
 		openAPIGeneratorService: openAPIGeneratorService,
+		retentionService:        retentionService,
+		changelogService:        changelogService,
+		lintService:             lintService,
+		specPipeline:            specPipeline,
 		cfg:                     cfg,
 	}
 }
 
+// GetDocumentContent serves the stored spec of a version in the format it was
+// uploaded in, or as JSON/YAML when asked by ?format=json|yaml or Accept.
+// ?format=oas3 serves Swagger 2 versions converted to OpenAPI 3 (combinable,
+// e.g. ?format=oas3,json). ?overlay=public applies overlays of the version.
 func (h *ApiHandler) GetDocumentContent(c *gin.Context) {
-	documentID := c.Param("id")
-	requestedVersion := c.Query("version") // Get version from query parameter
-
 	// Get the document
-	doc, err := h.docService.GetDocumentByID(documentID)
+	doc, err := h.docService.GetDocumentByID(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "Document not found or expired",
 		})
 		return
 	}
+	h.serveContent(c, doc, services.AudienceOwner)
+}
+
+// GetSharedContent serves the content of a shared document like
+// GetDocumentContent, with its internal parts redacted.
+func (h *ApiHandler) GetSharedContent(c *gin.Context) {
+	doc, err := h.docService.GetDocumentByShareSlug(c.Param("slug"))
+	if err != nil || doc == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Share link not found",
+		})
+		return
+	}
+	h.serveContent(c, doc, services.AudiencePublic)
+}
+
+func (h *ApiHandler) serveContent(c *gin.Context, doc *models.Document, audience services.Audience) {
+	requestedVersion := c.Query("version") // Get version from query parameter
+	oas3 := false
+	serialization := ""
+	for _, f := range strings.Split(strings.ToLower(c.Query("format")), ",") {
+		switch f = strings.TrimSpace(f); f {
+		case "":
+		case "oas3":
+			oas3 = true
+		case "yml":
+			serialization = openapi.FormatYAML
+		case openapi.FormatJSON, openapi.FormatYAML:
+			serialization = f
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "format must be json, yaml and/or oas3",
+			})
+			return
+		}
+	}
+	kind := spectype.Of(doc.Type)
+	if !kind.Tree() {
+		// Source formats are served as stored; Accept is not an error for them
+		if serialization != "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "format " + serialization + " does not apply to " + kind.Label() + " documents",
+			})
+			return
+		}
+	} else if serialization == "" {
+		serialization = negotiateFormat(c.GetHeader("Accept"))
+	}
+	if oas3 && kind != spectype.OpenAPI {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "format oas3 only applies to OpenAPI documents",
+		})
+		return
+	}
 
 	var targetVersion *models.Version
 
@@ -76,16 +146,83 @@ func (h *ApiHandler) GetDocumentContent(c *gin.Context) {
 		return
 	}
 
-	content, err := h.storageService.GetFile(targetVersion.FilePath)
+	var content []byte
+	var format string
+	var err error
+	if oas3 {
+		content, err = h.storageService.GetVersionOAS3(targetVersion)
+		format = openapi.DetectFormat(content)
+	} else {
+		content, err = h.storageService.GetVersionFile(targetVersion)
+		format = services.VersionFormat(targetVersion, content)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Error reading file",
 		})
 		return
 	}
+	overlays, err := h.storageService.GetVersionOverlays(targetVersion, overlayNames(c))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrOverlayNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	content, err = h.specPipeline.Prepare(doc, content, requestOrigin(c), audience, overlays...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Error preparing content: " + err.Error(),
+		})
+		return
+	}
 
-	c.Header("Content-Type", "application/yaml")
-	c.String(http.StatusOK, string(content))
+	if serialization != "" && serialization != format {
+		content, err = openapi.Reformat(content, serialization)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Error converting to " + serialization + ": " + err.Error(),
+			})
+			return
+		}
+		format = serialization
+	}
+
+	c.Header("Vary", "Accept")
+	c.Data(http.StatusOK, openapi.FormatMIMEType(format), content)
+}
+
+// overlayNames returns the overlays named by ?overlay=, comma separated or
+// repeated, in the order they are to be applied.
+func overlayNames(c *gin.Context) []string {
+	var names []string
+	for _, v := range c.QueryArray("overlay") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// negotiateFormat picks JSON or YAML from an Accept header, "" when the
+// client accepts either.
+func negotiateFormat(accept string) string {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, _ := strings.Cut(strings.TrimSpace(part), ";")
+		switch strings.ToLower(mediaType) {
+		case "application/json":
+			return openapi.FormatJSON
+		case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+			return openapi.FormatYAML
+		}
+	}
+	return ""
 }
 
 func (h *ApiHandler) GetDocumentVersions(c *gin.Context) {
@@ -101,17 +238,277 @@ func (h *ApiHandler) GetDocumentVersions(c *gin.Context) {
 
 	var versions []gin.H
 	for _, version := range doc.Versions {
-		versions = append(versions, gin.H{
+		entry := gin.H{
 			"id":         version.ID,
 			"version":    version.Version,
 			"created_at": version.CreatedAt,
 			"is_latest":  version.IsLatest,
-		})
+			"pinned":     version.Pinned,
+			"tags":       version.Tags,
+			"converted":  version.ConvertedKey != "",
+			"format":     version.Format,
+		}
+		if version.EntryPoint != "" {
+			entry["entry_point"] = version.EntryPoint
+		}
+		if len(version.Overlays) > 0 {
+			entry["overlays"] = sortedOverlayNames(&version)
+		}
+		versions = append(versions, entry)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"document_id": doc.ID,
+		"type":        spectype.Of(doc.Type).Name(),
 		"versions":    versions,
+		"retention":   h.retentionService.EffectivePolicy(doc),
+		"servers":     h.specPipeline.EffectiveServerPolicy(doc),
+		"redaction":   h.specPipeline.EffectiveRedaction(doc),
+	})
+}
+
+// SetRetentionPolicy overrides the instance retention policy for one document.
+// PUT /api/document/:id/retention  body: {"keep_last":5,"max_age_days":90} or {"inherit":true}
+func (h *ApiHandler) SetRetentionPolicy(c *gin.Context) {
+	documentID := c.Param("id")
+	doc, err := h.docService.GetDocumentByID(documentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+	var payload struct {
+		KeepLast   int  `json:"keep_last"`
+		MaxAgeDays int  `json:"max_age_days"`
+		Inherit    bool `json:"inherit"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body: " + err.Error()})
+		return
+	}
+	var policy *models.RetentionPolicy
+	if !payload.Inherit {
+		policy = &models.RetentionPolicy{KeepLast: payload.KeepLast, MaxAgeDays: payload.MaxAgeDays}
+	}
+	if err := h.docService.SetRetentionPolicy(doc, policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"document_id": doc.ID,
+		"inherited":   policy == nil,
+		"retention":   h.retentionService.EffectivePolicy(doc),
+	})
+}
+
+// SetServerPolicy overrides the instance server policy for one document.
+// PUT /api/document/:id/servers  body: {"mode":"strip"}, {"mode":"rewrite","url":"https://api.example.com"} or {"inherit":true}
+func (h *ApiHandler) SetServerPolicy(c *gin.Context) {
+	documentID := c.Param("id")
+	doc, err := h.docService.GetDocumentByID(documentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+	var payload struct {
+		Mode    string `json:"mode"`
+		URL     string `json:"url"`
+		Inherit bool   `json:"inherit"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body: " + err.Error()})
+		return
+	}
+	var policy *models.ServerPolicy
+	if !payload.Inherit {
+		policy = &models.ServerPolicy{Mode: strings.ToLower(strings.TrimSpace(payload.Mode)), URL: strings.TrimSpace(payload.URL)}
+	}
+	if err := h.docService.SetServerPolicy(doc, policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"document_id": doc.ID,
+		"inherited":   policy == nil,
+		"servers":     h.specPipeline.EffectiveServerPolicy(doc),
+	})
+}
+
+// SetRedactionPolicy overrides the instance redaction rules for one document.
+// PUT /api/document/:id/redaction  body: {"extension":"x-internal","tags":["admin"],"paths":["/internal/**"]} or {"inherit":true}
+func (h *ApiHandler) SetRedactionPolicy(c *gin.Context) {
+	documentID := c.Param("id")
+	doc, err := h.docService.GetDocumentByID(documentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+	var payload struct {
+		models.RedactionPolicy
+		Inherit bool `json:"inherit"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body: " + err.Error()})
+		return
+	}
+	var policy *models.RedactionPolicy
+	if !payload.Inherit {
+		policy = &payload.RedactionPolicy
+		policy.Extension = strings.TrimSpace(policy.Extension)
+	}
+	if err := h.docService.SetRedactionPolicy(doc, policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"document_id": doc.ID,
+		"inherited":   policy == nil,
+		"redaction":   h.specPipeline.EffectiveRedaction(doc),
+	})
+}
+
+// PutVersionOverlay attaches an OpenAPI Overlay 1.0 document (YAML or JSON
+// body) to a version under a name, replacing any overlay of that name.
+// PUT /api/document/:id/version/:version/overlays/:name
+func (h *ApiHandler) PutVersionOverlay(c *gin.Context) {
+	doc, err := h.docService.GetDocumentByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+	version := doc.FindVersion(c.Param("version"))
+	if version == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
+		return
+	}
+	if kind := spectype.Of(doc.Type); !kind.Tree() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "overlays do not apply to " + kind.Label() + " documents"})
+		return
+	}
+	content, err := io.ReadAll(io.LimitReader(c.Request.Body, h.cfg.MaxFileSize+1))
+	if err != nil || int64(len(content)) > h.cfg.MaxFileSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "overlay too large or unreadable"})
+		return
+	}
+	name := c.Param("name")
+	if err := services.CheckOverlayName(name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	overlay, err := openapi.ParseOverlay(content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid overlay: " + err.Error()})
+		return
+	}
+	key, err := h.storageService.SaveOverlay(doc.ID, version.Version, name, content)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error saving overlay: " + err.Error()})
+		return
+	}
+	previous, err := h.docService.SetVersionOverlay(doc.ID, version.Version, name, key)
+	if err != nil {
+		_ = h.storageService.DeleteFile(key)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if previous != "" && previous != key {
+		_ = h.storageService.DeleteFile(previous)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"document_id": doc.ID,
+		"version":     version.Version,
+		"overlay":     name,
+		"title":       overlay.Title,
+		"actions":     len(overlay.Actions),
+	})
+}
+
+// GetVersionOverlay serves an overlay of a version as it was stored.
+// GET /api/document/:id/version/:version/overlays/:name
+func (h *ApiHandler) GetVersionOverlay(c *gin.Context) {
+	doc, err := h.docService.GetDocumentByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+	version := doc.FindVersion(c.Param("version"))
+	if version == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
+		return
+	}
+	key, ok := version.Overlays[c.Param("name")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": services.ErrOverlayNotFound.Error()})
+		return
+	}
+	content, err := h.storageService.GetFile(key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reading file"})
+		return
+	}
+	c.Data(http.StatusOK, openapi.FormatMIMEType(openapi.DetectFormat(content)), content)
+}
+
+// DeleteVersionOverlay detaches an overlay from a version.
+// DELETE /api/document/:id/version/:version/overlays/:name
+func (h *ApiHandler) DeleteVersionOverlay(c *gin.Context) {
+	documentID := c.Param("id")
+	if _, err := h.docService.GetDocumentByID(documentID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+	previous, err := h.docService.SetVersionOverlay(documentID, c.Param("version"), c.Param("name"), "")
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrOverlayNotFound) || err.Error() == "version not found" {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	_ = h.storageService.DeleteFile(previous)
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "overlay deleted"})
+}
+
+// sortedOverlayNames lists the overlays of a version by name.
+func sortedOverlayNames(version *models.Version) []string {
+	names := make([]string, 0, len(version.Overlays))
+	for name := range version.Overlays {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UpdateVersion pins/unpins or tags a version. Pinned and tagged versions are never pruned.
+// PATCH /api/document/:id/version/:version  body: {"pinned":true,"tags":["release-2024.1"]}
+func (h *ApiHandler) UpdateVersion(c *gin.Context) {
+	documentID := c.Param("id")
+	versionStr := c.Param("version")
+	if _, err := h.docService.GetDocumentByID(documentID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+	var payload struct {
+		Pinned *bool    `json:"pinned"`
+		Tags   []string `json:"tags"`
+	}
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid body: " + err.Error()})
+		return
+	}
+	version, err := h.docService.UpdateVersionMetadata(documentID, versionStr, payload.Pinned, payload.Tags)
+	if err != nil {
+		if err.Error() == "version not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"version": version.Version,
+		"pinned":  version.Pinned,
+		"tags":    version.Tags,
 	})
 }
 
@@ -147,6 +544,12 @@ func (h *ApiHandler) GenerateSDK(c *gin.Context) {
 	documentID := c.Param("id")
 	generator := c.Param("generator")
 	requestedVersion := c.Query("version")
+	if doc, err := h.docService.GetDocumentByID(documentID); err == nil && spectype.Of(doc.Type) != spectype.OpenAPI {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "SDKs can only be generated from OpenAPI documents",
+		})
+		return
+	}
 
 	// Get the document content URL
 	baseURL := fmt.Sprintf("%s://%s", func() string {
@@ -156,9 +559,10 @@ func (h *ApiHandler) GenerateSDK(c *gin.Context) {
 		return "http"
 	}(), c.Request.Host)
 
-	contentURL := fmt.Sprintf("%s/api/document/%s/content", baseURL, documentID)
+	// The generator always gets OpenAPI 3, whichever format the version was uploaded in
+	contentURL := fmt.Sprintf("%s/api/document/%s/content?format=oas3", baseURL, documentID)
 	if requestedVersion != "" {
-		contentURL += "?version=" + requestedVersion
+		contentURL += "&version=" + url.QueryEscape(requestedVersion)
 	}
 
 	// Generate SDK
@@ -227,14 +631,53 @@ func (h *ApiHandler) DownloadDocumentVersion(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
 		return
 	}
-	content, err := h.storageService.GetFile(target.FilePath)
+	content, err := h.storageService.GetVersionFile(target)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot read file"})
 		return
 	}
+	content, err = h.specPipeline.Prepare(doc, content, requestOrigin(c), services.AudienceOwner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot prepare file: " + err.Error()})
+		return
+	}
 	// Force download
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-%s.yaml\"", documentID, versionStr))
-	c.Data(http.StatusOK, "application/x-yaml", content)
+	format := services.VersionFormat(target, content)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-%s%s\"", documentID, versionStr, openapi.FormatExtension(format)))
+	c.Data(http.StatusOK, openapi.FormatMIMEType(format), content)
+}
+
+// DownloadVersionSource serves the original files of a multi-file upload as a zip archive.
+func (h *ApiHandler) DownloadVersionSource(c *gin.Context) {
+	documentID := c.Param("id")
+	versionStr := c.Param("version")
+
+	doc, err := h.docService.GetDocumentByID(documentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+	target := doc.FindVersion(versionStr)
+	if target == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
+		return
+	}
+	if target.SourceKey == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "version was uploaded as a single file"})
+		return
+	}
+	archive, err := h.storageService.GetFile(target.SourceKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot read file"})
+		return
+	}
+	archive, err = h.specPipeline.PrepareArchive(doc, archive, requestOrigin(c), services.AudienceOwner)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot prepare archive: " + err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-%s-source.zip\"", documentID, versionStr))
+	c.Data(http.StatusOK, "application/zip", archive)
 }
 
 // DeleteDocumentVersion deletes a single version (by human version string) if allowed.
@@ -254,14 +697,14 @@ func (h *ApiHandler) DeleteDocumentVersion(c *gin.Context) {
 		return
 	}
 
-	var filePath string
-	for _, v := range doc.Versions {
-		if v.Version == version {
-			filePath = v.FilePath
+	var target *models.Version
+	for i := range doc.Versions {
+		if doc.Versions[i].Version == version {
+			target = &doc.Versions[i]
 			break
 		}
 	}
-	if filePath == "" {
+	if target == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
 		return
 	}
@@ -272,9 +715,7 @@ func (h *ApiHandler) DeleteDocumentVersion(c *gin.Context) {
 	}
 
 	// Remove file from storage silently
-	if filePath != "" {
-		_ = os.Remove(filePath)
-	}
+	_ = h.storageService.DeleteVersionFile(target)
 
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "version deleted"})
 }
@@ -316,7 +757,7 @@ func (h *ApiHandler) SetShareLink(c *gin.Context) {
 		slug = utils.GenerateShareSlug()
 	}
 	if err := h.docService.SetShareSlug(doc, slug); err != nil {
-		if err.Error() == "slug already taken" {
+		if err.Error() == "slug already taken" || err.Error() == "share slug already set" {
 			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 			return
 		}
@@ -331,3 +772,333 @@ func (h *ApiHandler) SetShareLink(c *gin.Context) {
 	}(), c.Request.Host, slug)
 	c.JSON(http.StatusCreated, gin.H{"share_slug": slug, "url": fullURL})
 }
+
+// RenewDocument extends or resets a document's lifetime. Versions and the share slug follow.
+// POST /api/document/:id/expiry  body: {"ttl":"30d"} (from now), {"ttl":"never"} or {"extend":"7d"} (added to current expiry)
+func (h *ApiHandler) RenewDocument(c *gin.Context) {
+	documentID := c.Param("id")
+	doc, err := h.docService.GetDocumentByID(documentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+	var payload struct {
+		TTL    string `json:"ttl"`
+		Extend string `json:"extend"`
+	}
+	if c.Request.Body != nil {
+		_ = json.NewDecoder(c.Request.Body).Decode(&payload) // empty body -> renew with default TTL
+	}
+
+	var expiresAt time.Time
+	if payload.Extend != "" {
+		if doc.ExpiresAt.IsZero() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "document never expires"})
+			return
+		}
+		// Extending is the same as renewing from the current expiry
+		expiresAt, err = h.docService.ResolveExpiry(payload.Extend, doc.ExpiresAt)
+		if err == nil && h.cfg.MaxLinkExpiration > 0 && time.Until(expiresAt) > h.cfg.MaxLinkExpiration {
+			err = fmt.Errorf("%w: maximum is %s", services.ErrInvalidTTL, h.cfg.MaxLinkExpiration)
+		}
+	} else {
+		expiresAt, err = h.docService.ResolveExpiry(payload.TTL, time.Now())
+	}
+	if err == nil {
+		err = h.docService.CheckLifetime(doc.CreatedAt, expiresAt)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.docService.RenewDocument(doc, expiresAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"document_id":   doc.ID,
+		"expires_at":    expiresAtJSON(doc.ExpiresAt),
+		"never_expires": doc.ExpiresAt.IsZero(),
+	})
+}
+
+// GetDocumentDiff returns the semantic difference between two versions.
+// GET /api/document/:id/diff?from=v1&to=v2  (defaults: to = latest, from = the version before it)
+func (h *ApiHandler) GetDocumentDiff(c *gin.Context) {
+	documentID := c.Param("id")
+	doc, err := h.docService.GetDocumentByID(documentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+
+	fromStr, toStr := c.Query("from"), c.Query("to")
+	if toStr == "" {
+		if latest := doc.LatestVersion(); latest != nil {
+			toStr = latest.Version
+		}
+	}
+	if fromStr == "" {
+		// Versions are ordered oldest first
+		for i := range doc.Versions {
+			if doc.Versions[i].Version == toStr && i > 0 {
+				fromStr = doc.Versions[i-1].Version
+			}
+		}
+	}
+	if fromStr == "" || toStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to versions are required"})
+		return
+	}
+
+	from, err := h.loadSpec(doc, fromStr)
+	if err != nil {
+		c.JSON(specErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	to, err := h.loadSpec(doc, toStr)
+	if err != nil {
+		c.JSON(specErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	diff, err := from.Compare(to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"document_id": doc.ID,
+		"from":        fromStr,
+		"to":          toStr,
+		"identical":   diff.Empty(),
+		"diff":        diff,
+	})
+}
+
+// GetDocumentIndex lists what a version declares, for kinds shown as a
+// reference (Protobuf: services with their RPCs, messages and enums;
+// GraphQL: root operations, types and directives).
+// GET /api/document/:id/index?version=v1  (default: latest)
+func (h *ApiHandler) GetDocumentIndex(c *gin.Context) {
+	doc, err := h.docService.GetDocumentByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+	versionStr := c.Query("version")
+	if versionStr == "" {
+		if latest := doc.LatestVersion(); latest != nil {
+			versionStr = latest.Version
+		}
+	}
+	spec, err := h.loadSpec(doc, versionStr)
+	if err != nil {
+		c.JSON(specErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	indexed, ok := spec.(spectype.Indexed)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no index is available for " + spectype.Of(doc.Type).Label() + " documents"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"document_id": doc.ID,
+		"version":     versionStr,
+		"index":       indexed.Index(),
+	})
+}
+
+// ExportDocument renders a version of an OpenAPI document as an API client
+// workspace, one request per operation, foldered by tag.
+// GET /api/document/:id/export/postman|insomnia?version=v1&overlay=name  (default: latest)
+func (h *ApiHandler) ExportDocument(c *gin.Context) {
+	documentID := c.Param("id")
+	exportFormat := c.Param("format")
+	if exportFormat != "postman" && exportFormat != "insomnia" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "export format must be postman or insomnia"})
+		return
+	}
+	doc, err := h.docService.GetDocumentByID(documentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+	if kind := spectype.Of(doc.Type); kind != spectype.OpenAPI {
+		c.JSON(http.StatusBadRequest, gin.H{"error": kind.Label() + " documents cannot be exported to " + exportFormat})
+		return
+	}
+	version := doc.LatestVersion()
+	if v := c.Query("version"); v != "" {
+		version = doc.FindVersion(v)
+	}
+	if version == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
+		return
+	}
+
+	// Exports always describe the OpenAPI 3 layout the clients import best
+	content, err := h.storageService.GetVersionOAS3(version)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot read file"})
+		return
+	}
+	overlays, err := h.storageService.GetVersionOverlays(version, overlayNames(c))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrOverlayNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	content, err = h.specPipeline.Prepare(doc, content, requestOrigin(c), services.AudienceOwner, overlays...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot prepare file: " + err.Error()})
+		return
+	}
+	spec, err := openapi.Parse(content)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	collection := spec.Collection(doc.ID)
+	var out []byte
+	var suffix string
+	if exportFormat == "postman" {
+		out, err = collection.Postman()
+		suffix = ".postman_collection.json"
+	} else {
+		out, err = collection.Insomnia(time.Now())
+		suffix = ".insomnia.json"
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s-%s%s\"", documentID, version.Version, suffix))
+	c.Data(http.StatusOK, "application/json; charset=utf-8", out)
+}
+
+// GetDocumentChangelog lists, newest first, what changed in each version.
+// GET /api/document/:id/changelog?format=markdown|json|atom  (default from Accept, else markdown)
+func (h *ApiHandler) GetDocumentChangelog(c *gin.Context) {
+	documentID := c.Param("id")
+	doc, err := h.docService.GetDocumentByID(documentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+
+	format := strings.ToLower(c.Query("format"))
+	if format == "" {
+		accept := c.GetHeader("Accept")
+		switch {
+		case strings.Contains(accept, "application/atom+xml"):
+			format = "atom"
+		case strings.Contains(accept, "application/json"):
+			format = "json"
+		default:
+			format = "markdown"
+		}
+	}
+
+	changelog := h.changelogService.Build(doc)
+	switch format {
+	case "json":
+		c.JSON(http.StatusOK, changelog)
+	case "markdown", "md":
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", []byte(changelog.Markdown()))
+	case "atom":
+		feed, err := changelog.Atom(requestOrigin(c))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Data(http.StatusOK, "application/atom+xml; charset=utf-8", feed)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be markdown, json or atom"})
+	}
+}
+
+// GetVersionLint returns the lint report stored for a version.
+// GET /api/document/:id/version/:version/lint
+func (h *ApiHandler) GetVersionLint(c *gin.Context) {
+	documentID := c.Param("id")
+	versionStr := c.Param("version")
+	doc, err := h.docService.GetDocumentByID(documentID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "document not found"})
+		return
+	}
+	version := doc.FindVersion(versionStr)
+	if version == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "version not found"})
+		return
+	}
+	if kind := spectype.Of(doc.Type); kind != spectype.OpenAPI {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lint rules do not apply to " + kind.Label() + " documents"})
+		return
+	}
+	report, err := h.lintService.VersionReport(version)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"document_id": doc.ID,
+		"version":     version.Version,
+		"ruleset":     report.Ruleset,
+		"counts":      report.Counts,
+		"findings":    report.Findings,
+	})
+}
+
+// requestOrigin returns the scheme and host the client used to reach us.
+func requestOrigin(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https") {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, c.Request.Host)
+}
+
+// errVersionNotFound marks loadSpec failures that should map to 404.
+var errVersionNotFound = errors.New("version not found")
+
+// loadSpec reads and parses the stored document of one version.
+func (h *ApiHandler) loadSpec(doc *models.Document, version string) (spectype.Spec, error) {
+	v := doc.FindVersion(version)
+	if v == nil {
+		return nil, fmt.Errorf("%w: %s", errVersionNotFound, version)
+	}
+	return h.storageService.ParseVersion(spectype.Of(doc.Type), v)
+}
+
+func specErrorStatus(err error) int {
+	if errors.Is(err, errVersionNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// expiresAtJSON renders a document expiry, nil meaning "never".
+func expiresAtJSON(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// formatTTL renders a lifetime the way ParseTTL accepts it.
+func formatTTL(d time.Duration) string {
+	if d == 0 {
+		return "never"
+	}
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%dd", d/(24*time.Hour))
+	}
+	return d.String()
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"APIScope/internal/models"
+	"APIScope/internal/openapi"
+	"APIScope/internal/services"
+	"APIScope/internal/spectype"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// MockHandler answers requests against a document's operations with their
+// examples, or with data synthesised from the response schemas.
+type MockHandler struct {
+	docService     *services.DocumentService
+	storageService *services.StorageService
+	specPipeline   *services.SpecPipeline
+}
+
+func NewMockHandler(docService *services.DocumentService, storageService *services.StorageService, specPipeline *services.SpecPipeline) *MockHandler {
+	return &MockHandler{
+		docService:     docService,
+		storageService: storageService,
+		specPipeline:   specPipeline,
+	}
+}
+
+// Mock serves /mock/:id/*path.
+func (h *MockHandler) Mock(c *gin.Context) {
+	doc, err := h.docService.GetDocumentByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+	h.respond(c, doc, false)
+}
+
+// MockByShare serves /mock/share/:slug/*path. Redacted operations are not mocked.
+func (h *MockHandler) MockByShare(c *gin.Context) {
+	doc, err := h.docService.GetDocumentByShareSlug(c.Param("slug"))
+	if err != nil || doc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+		return
+	}
+	h.respond(c, doc, true)
+}
+
+// respond answers from the version named by the X-Mock-Version header, or the
+// latest one, redacted for share links. "Prefer: code=404" and
+// "Prefer: example=name" choose the response.
+func (h *MockHandler) respond(c *gin.Context, doc *models.Document, redact bool) {
+	if kind := spectype.Of(doc.Type); kind != spectype.OpenAPI {
+		c.JSON(http.StatusNotFound, gin.H{"error": kind.Label() + " documents cannot be mocked"})
+		return
+	}
+	var version *models.Version
+	if v := c.GetHeader("X-Mock-Version"); v != "" {
+		version = doc.FindVersion(v)
+	} else {
+		version = doc.LatestVersion()
+	}
+	if version == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+	content, err := h.storageService.GetVersionFile(version)
+	if err == nil && redact {
+		content, err = h.specPipeline.Redact(doc, content)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reading document: " + err.Error()})
+		return
+	}
+	spec, err := openapi.Parse(content)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reading document: " + err.Error()})
+		return
+	}
+
+	op, _, err := spec.MatchOperation(c.Request.Method, c.Param("path"))
+	if err != nil {
+		status := http.StatusNotFound
+		if errors.Is(err, openapi.ErrMethodNotAllowed) {
+			status = http.StatusMethodNotAllowed
+		}
+		c.JSON(status, gin.H{"error": fmt.Sprintf("%s %s: %v", c.Request.Method, c.Param("path"), err)})
+		return
+	}
+
+	prefer := c.Request.Header.Values("Prefer")
+	prefs := openapi.ParsePrefer(prefer)
+	resp, err := spec.Mock(op, prefs, acceptedMediaTypes(c.GetHeader("Accept")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("%s: %v", op.Key(), err)})
+		return
+	}
+
+	c.Header("X-Mock-Version", version.Version)
+	c.Header("X-Mock-Operation", op.Key())
+	if len(prefer) > 0 {
+		c.Header("Preference-Applied", strings.Join(prefer, ", "))
+	}
+	for name, value := range resp.Headers {
+		c.Header(name, value)
+	}
+	if resp.ContentType == "" || resp.Body == nil {
+		c.Status(resp.Status)
+		return
+	}
+	body, err := encodeMockBody(resp.ContentType, resp.Body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error encoding mock response: " + err.Error()})
+		return
+	}
+	c.Data(resp.Status, resp.ContentType, body)
+}
+
+// acceptedMediaTypes lists the media types of an Accept header in order,
+// without parameters.
+func acceptedMediaTypes(header string) []string {
+	var out []string
+	for _, part := range strings.Split(header, ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part)); err == nil {
+			out = append(out, mediaType)
+		}
+	}
+	return out
+}
+
+// encodeMockBody serialises a mock body for its media type. Strings are sent
+// as they are to non-JSON media types.
+func encodeMockBody(contentType string, body any) ([]byte, error) {
+	switch {
+	case contentType == "application/json" || strings.HasSuffix(contentType, "+json"):
+		return json.Marshal(body)
+	case strings.Contains(contentType, "yaml"):
+		return yaml.Marshal(body)
+	}
+	if s, ok := body.(string); ok {
+		return []byte(s), nil
+	}
+	return json.Marshal(body)
+}
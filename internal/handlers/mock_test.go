@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"APIScope/internal/config"
+	"APIScope/internal/database"
+	"APIScope/internal/models"
+	"APIScope/internal/services"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const mockSpec = `openapi: 3.0.3
+info: {title: Pets, version: '1'}
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              examples:
+                one: {value: [{name: Rex}]}
+                two: {value: [{name: Rex}, {name: Tom}]}
+        '404':
+          description: not found
+          content:
+            application/json:
+              example: {error: none}
+`
+
+func newTestMockRouter(t *testing.T) (*gin.Engine, *models.Document) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	cfg := &config.Config{StoragePath: filepath.Join(dir, "documents"), MaxFileSize: 1 << 20, LinkExpiration: time.Hour}
+	repo, err := database.NewBoltRepository(filepath.Join(dir, "apiscope.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	docs := services.NewDocumentService(repo, cfg)
+	storage, err := services.NewStorageService(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pipeline, err := services.NewSpecPipeline(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := docs.CreateDocument("Pets", "", "openapi", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := docs.AddVersion(doc.ID, "1.0.0", func(v *models.Version) error {
+		key, err := storage.SaveFile(doc.ID, v.Version, []byte(mockSpec), "yaml")
+		v.ObjectKey = key
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewMockHandler(docs, storage, pipeline)
+	router := gin.New()
+	router.Any("/mock/:id/*path", h.Mock)
+	return router, doc
+}
+
+func TestMockHandler(t *testing.T) {
+	router, doc := newTestMockRouter(t)
+	tests := []struct {
+		name    string
+		method  string
+		path    string // after /mock/{id}
+		prefer  string
+		status  int
+		body    string // substring
+		applied bool   // Preference-Applied is echoed
+	}{
+		{name: "first example", method: "GET", path: "/pets", status: http.StatusOK, body: `[{"name":"Rex"}]`},
+		{name: "Prefer example", method: "GET", path: "/pets", prefer: "example=two", status: http.StatusOK, body: `[{"name":"Rex"},{"name":"Tom"}]`, applied: true},
+		{name: "Prefer code", method: "GET", path: "/pets", prefer: "code=404", status: http.StatusNotFound, body: `{"error":"none"}`, applied: true},
+		{name: "Prefer a code the operation lacks", method: "GET", path: "/pets", prefer: "code=500", status: http.StatusNotFound, body: "no response 500"},
+		{name: "known path, unknown method", method: "DELETE", path: "/pets", status: http.StatusMethodNotAllowed, body: "does not support the method"},
+		{name: "unknown path", method: "GET", path: "/owners", status: http.StatusNotFound, body: "no operation matches"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/mock/"+doc.ID+tt.path, nil)
+		if tt.prefer != "" {
+			req.Header.Set("Prefer", tt.prefer)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("%s: %d %s, want %d with %s", tt.name, w.Code, w.Body, tt.status, tt.body)
+		}
+		want := ""
+		if tt.applied {
+			want = tt.prefer
+		}
+		if got := w.Header().Get("Preference-Applied"); got != want {
+			t.Errorf("%s: Preference-Applied = %q", tt.name, got)
+		}
+		if tt.status < 300 && w.Header().Get("X-Mock-Version") != "1.0.0" {
+			t.Errorf("%s: X-Mock-Version = %q, want 1.0.0", tt.name, w.Header().Get("X-Mock-Version"))
+		}
+	}
+
+	req := httptest.NewRequest("GET", "/mock/missing/pets", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown document: %d, want 404", w.Code)
+	}
+}
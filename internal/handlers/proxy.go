@@ -1,4 +1,176 @@
 package handlers
 
-// Placeholder file (previously empty) to satisfy Go build.
-// Add proxy-related handler implementations here if needed in future.
+import (
+	"APIScope/internal/config"
+	"APIScope/internal/models"
+	"APIScope/internal/openapi"
+	"APIScope/internal/services"
+	"APIScope/internal/spectype"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxProxyBody limits request and response bodies passing through the proxy.
+const maxProxyBody = 10 << 20
+
+// hopHeaders are not forwarded in either direction; cookies and CORS headers
+// belong to the APIScope origin, not the proxied API.
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization", "Proxy-Connection",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade", "Content-Length", "Host",
+	"Cookie", "Set-Cookie", "Origin", "Referer", "Accept-Encoding",
+}
+
+// ProxyHandler forwards Try it out requests to a spec's servers and checks the
+// request and the upstream response against the operation they target.
+type ProxyHandler struct {
+	docService     *services.DocumentService
+	storageService *services.StorageService
+	specPipeline   *services.SpecPipeline
+	config         *config.Config
+	client         *http.Client
+}
+
+func NewProxyHandler(docService *services.DocumentService, storageService *services.StorageService, specPipeline *services.SpecPipeline, cfg *config.Config) *ProxyHandler {
+	return &ProxyHandler{
+		docService:     docService,
+		storageService: storageService,
+		specPipeline:   specPipeline,
+		config:         cfg,
+		client: &http.Client{
+			Timeout: cfg.ProxyTimeout,
+			// Redirects are handed to the caller; following them could leave the allowlist
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// Proxy serves /api/document/:id/proxy?url=<target>&version=<version>. The
+// upstream response is returned as is, with the validation report as base64
+// JSON in the X-Validation-Report header.
+func (h *ProxyHandler) Proxy(c *gin.Context) {
+	target, err := url.Parse(c.Query("url"))
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "url must be an absolute http(s) URL"})
+		return
+	}
+	if !h.hostAllowed(target) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("host %s is not in PROXY_ALLOWED_HOSTS", target.Host)})
+		return
+	}
+
+	doc, err := h.docService.GetDocumentByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+	if kind := spectype.Of(doc.Type); kind != spectype.OpenAPI {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Requests cannot be validated against " + kind.Label() + " documents"})
+		return
+	}
+	if h.specPipeline.EffectiveServerPolicy(doc).Mode == models.ServersStrip {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Servers of this document are stripped"})
+		return
+	}
+	var version *models.Version
+	if v := c.Query("version"); v != "" {
+		version = doc.FindVersion(v)
+	} else {
+		version = doc.LatestVersion()
+	}
+	if version == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+	spec, err := h.storageService.GetVersionSpec(version)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reading document: " + err.Error()})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxProxyBody+1))
+	if err != nil || len(body) > maxProxyBody {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("request body exceeds %d bytes", maxProxyBody)})
+		return
+	}
+	upstream, err := http.NewRequestWithContext(c.Request.Context(), c.Request.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	upstream.Header = c.Request.Header.Clone()
+	removeHopHeaders(upstream.Header)
+
+	report := openapi.ExchangeReport{Request: []openapi.ExchangeProblem{}, Response: []openapi.ExchangeProblem{}}
+	op, pathParams, matchErr := spec.MatchOperation(upstream.Method, target.Path)
+	if matchErr != nil {
+		report.Request = append(report.Request, openapi.ExchangeProblem{In: "path", Message: fmt.Sprintf("%s %s: %v", upstream.Method, target.Path, matchErr)})
+	} else {
+		report.Operation = op.Key()
+		report.Request = spec.ValidateRequest(op, pathParams, upstream, body)
+	}
+
+	resp, err := h.client.Do(upstream)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Upstream request failed: " + err.Error(), "validation": report})
+		return
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxProxyBody+1))
+	if err != nil || len(respBody) > maxProxyBody {
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("upstream response exceeds %d bytes", maxProxyBody), "validation": report})
+		return
+	}
+	if matchErr == nil {
+		report.Response = spec.ValidateResponse(op, resp.StatusCode, resp.Header, respBody)
+	}
+	report.Valid = matchErr == nil && len(report.Request) == 0 && len(report.Response) == 0
+
+	for name, values := range resp.Header {
+		if strings.HasPrefix(name, "Access-Control-") {
+			continue
+		}
+		for _, v := range values {
+			c.Writer.Header().Add(name, v)
+		}
+	}
+	removeHopHeaders(c.Writer.Header())
+	encoded, _ := json.Marshal(report)
+	c.Header("X-Validation-Report", base64.StdEncoding.EncodeToString(encoded))
+	c.Data(resp.StatusCode, resp.Header.Get("Content-Type"), respBody)
+}
+
+// hostAllowed matches the target against PROXY_ALLOWED_HOSTS entries: a host
+// ("api.example.com", any port), a host and port ("localhost:8081"), a wildcard
+// subdomain ("*.example.com") or "*" for any host.
+func (h *ProxyHandler) hostAllowed(target *url.URL) bool {
+	host := strings.ToLower(target.Hostname())
+	for _, entry := range h.config.ProxyAllowedHosts {
+		entry = strings.ToLower(entry)
+		switch {
+		case entry == "*":
+			return true
+		case strings.HasPrefix(entry, "*."):
+			if strings.HasSuffix(host, entry[1:]) {
+				return true
+			}
+		case entry == host || entry == strings.ToLower(target.Host):
+			return true
+		}
+	}
+	return false
+}
+
+func removeHopHeaders(header http.Header) {
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+}
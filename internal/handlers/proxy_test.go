@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"APIScope/internal/config"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestProxyHostAllowed(t *testing.T) {
+	tests := []struct {
+		allowed []string
+		target  string
+		want    bool
+	}{
+		{allowed: nil, target: "https://api.example.com/pets", want: false},
+		{allowed: []string{"*"}, target: "http://10.0.0.1:8080/", want: true},
+		{allowed: []string{"api.example.com"}, target: "https://api.example.com/pets", want: true},
+		{allowed: []string{"api.example.com"}, target: "https://api.example.com:8443/pets", want: true},
+		{allowed: []string{"API.Example.com"}, target: "https://api.EXAMPLE.com/pets", want: true},
+		{allowed: []string{"api.example.com"}, target: "https://example.com/pets", want: false},
+		{allowed: []string{"api.example.com"}, target: "https://api.example.com.evil.com/", want: false},
+		{allowed: []string{"api.example.com"}, target: "https://api.example.com@evil.com/", want: false},
+		{allowed: []string{"localhost:8081"}, target: "http://localhost:8081/pets", want: true},
+		{allowed: []string{"localhost:8081"}, target: "http://localhost:8082/pets", want: false},
+		{allowed: []string{"localhost:8081"}, target: "http://localhost/pets", want: false},
+		{allowed: []string{"*.example.com"}, target: "https://api.example.com/", want: true},
+		{allowed: []string{"*.example.com"}, target: "https://a.b.example.com/", want: true},
+		{allowed: []string{"*.example.com"}, target: "https://example.com/", want: false},
+		{allowed: []string{"*.example.com"}, target: "https://evilexample.com/", want: false},
+		{allowed: []string{"*.example.com"}, target: "https://example.com.evil.com/", want: false},
+		{allowed: []string{"[::1]:8080"}, target: "http://[::1]:8080/", want: true},
+		{allowed: []string{"other.com", "api.example.com"}, target: "https://api.example.com/", want: true},
+	}
+	for _, tt := range tests {
+		h := &ProxyHandler{config: &config.Config{ProxyAllowedHosts: tt.allowed}}
+		target, err := url.Parse(tt.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := h.hostAllowed(target); got != tt.want {
+			t.Errorf("hostAllowed(%v, %s) = %v, want %v", tt.allowed, tt.target, got, tt.want)
+		}
+	}
+}
+
+// TestProxyRejectsTargets checks that bad or disallowed targets are refused
+// before the document is looked up or anything is sent upstream.
+func TestProxyRejectsTargets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewProxyHandler(nil, nil, nil, &config.Config{ProxyAllowedHosts: []string{"api.example.com"}})
+	tests := []struct {
+		target string
+		status int
+	}{
+		{target: "", status: http.StatusBadRequest},
+		{target: "/pets", status: http.StatusBadRequest},
+		{target: "ftp://api.example.com/pets", status: http.StatusBadRequest},
+		{target: "file:///etc/passwd", status: http.StatusBadRequest},
+		{target: "http://169.254.169.254/latest/meta-data/", status: http.StatusForbidden},
+		{target: "https://api.example.com.evil.com/pets", status: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/document/doc/proxy?url="+url.QueryEscape(tt.target), nil)
+		c.Params = gin.Params{{Key: "id", Value: "doc"}}
+		h.Proxy(c)
+		if w.Code != tt.status {
+			t.Errorf("url=%q: status %d, want %d (%s)", tt.target, w.Code, tt.status, w.Body)
+		}
+	}
+}
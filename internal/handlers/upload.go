@@ -3,13 +3,20 @@ package handlers
 import (
 	"APIScope/internal/config"
 	"APIScope/internal/models"
+	"APIScope/internal/openapi"
 	"APIScope/internal/services"
+	"APIScope/internal/spectype"
 	"APIScope/internal/utils"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,13 +24,15 @@ import (
 type UploadHandler struct {
 	docService     *services.DocumentService
 	storageService *services.StorageService
+	lintService    *services.LintService
 	config         *config.Config
 }
 
-func NewUploadHandler(docService *services.DocumentService, storageService *services.StorageService, cfg *config.Config) *UploadHandler {
+func NewUploadHandler(docService *services.DocumentService, storageService *services.StorageService, lintService *services.LintService, cfg *config.Config) *UploadHandler {
 	return &UploadHandler{
 		docService:     docService,
 		storageService: storageService,
+		lintService:    lintService,
 		config:         cfg,
 	}
 }
@@ -33,9 +42,11 @@ func (h *UploadHandler) ShowUploadPage(c *gin.Context) {
 	messageType := c.DefaultQuery("type", "info")
 
 	c.HTML(http.StatusOK, "upload.html", gin.H{
-		"title":       "Upload OpenAPI Document",
-		"Message":     message,
-		"MessageType": messageType,
+		"title":            "Upload OpenAPI Document",
+		"Message":          message,
+		"MessageType":      messageType,
+		"DefaultTTL":       formatTTL(h.config.LinkExpiration),
+		"AllowNeverExpire": h.config.AllowNeverExpire,
 	})
 }
 
@@ -45,8 +56,20 @@ func (h *UploadHandler) HandleUpload(c *gin.Context) {
 	customVersion := c.PostForm("version")
 	yamlContent := c.PostForm("yaml_content")
 	documentID := c.PostForm("document_id") // Check if adding to existing document
+	ttl := c.PostForm("ttl")                // Lifetime of a new document, e.g. "7d" or "never"
+	failOnBreaking := isTruthy(c.DefaultPostForm("fail_on_breaking", c.Query("fail_on_breaking")))
+	convertSwagger := h.config.ConvertSwagger2 || isTruthy(c.DefaultPostForm("convert_oas3", c.Query("convert_oas3")))
+
+	if err := services.CheckVersionName(customVersion); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   err.Error(),
+			"success": false,
+		})
+		return
+	}
 
 	var content []byte
+	var tree map[string][]byte // files of a multi-file upload, keyed by relative path
 	var err error
 
 	// Debug log
@@ -57,6 +80,16 @@ func (h *UploadHandler) HandleUpload(c *gin.Context) {
 	if yamlContent != "" && len(strings.TrimSpace(yamlContent)) > 0 {
 		content = []byte(yamlContent)
 		fmt.Printf("Using pasted content, length: %d\n", len(content))
+	} else if form, _ := c.MultipartForm(); form != nil && len(form.File["files"]) > 0 {
+		tree, err = h.readFileSet(form)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   err.Error(),
+				"success": false,
+			})
+			return
+		}
+		fmt.Printf("File set uploaded - %d files\n", len(tree))
 	} else {
 		// Try to get uploaded file
 		file, err := c.FormFile("file")
@@ -98,24 +131,129 @@ func (h *UploadHandler) HandleUpload(c *gin.Context) {
 			})
 			return
 		}
+
+		if utils.IsZip(content) {
+			tree, err = utils.ReadZip(content, h.config.MaxFileSize)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"error":   err.Error(),
+					"success": false,
+				})
+				return
+			}
+		}
 	}
 
-	err = utils.ValidateOpenAPIContent(content)
-	if err != nil {
-		// If the client expects JSON (AJAX/API), respond JSON; otherwise redirect with message
-		if c.GetHeader("Accept") == "application/json" || c.Query("ajax") == "1" {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "Invalid OpenAPI document: " + err.Error(),
+	// Multi-file specs are bundled into one document, unless their kind keeps
+	// the files separate; the original files are kept next to it either way
+	var source []byte
+	var entry string
+	var fileSet spectype.FileSet
+	if tree != nil {
+		entry = c.PostForm("entry")
+		// A named entry of another kind, like an OpenAPI root next to .proto files, wins
+		if fs, ok := spectype.DetectFiles(tree); ok && (entry == "" || fs.Detect(tree[entry])) {
+			fileSet = fs
+		}
+		switch {
+		case fileSet != nil:
+			if entry == "" {
+				var ok bool
+				if entry, ok = fileSet.DefaultEntry(tree); !ok {
+					rejectUpload(c, "Please name the entry point of the uploaded files (entry field)", nil)
+					return
+				}
+			}
+			content = tree[entry]
+		default:
+			if entry == "" {
+				var ok bool
+				if entry, ok = openapi.DefaultEntry(tree); !ok {
+					rejectUpload(c, "Please name the entry point of the uploaded files (entry field)", nil)
+					return
+				}
+			}
+			content, err = openapi.Bundle(tree, entry)
+			if err != nil {
+				rejectUpload(c, "Cannot resolve references: "+err.Error(), nil)
+				return
+			}
+		}
+		if source, err = utils.WriteZip(tree); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Error archiving uploaded files: " + err.Error(),
 				"success": false,
 			})
-		} else {
-			// Redirect back with message and type=error
-			c.Redirect(http.StatusFound, "/?message="+url.QueryEscape("Invalid OpenAPI document: "+err.Error())+"&type=error")
+			return
+		}
+	}
+
+	// The document declares its kind; each kind validates its own way
+	var kind spectype.Kind = fileSet
+	var problems []openapi.ValidationError
+	if fileSet != nil {
+		problems = fileSet.ValidateFiles(tree)
+	} else {
+		kind = spectype.Detect(content)
+		problems = kind.Validate(content)
+	}
+	if len(problems) > 0 {
+		summary := "Invalid " + kind.Label() + " document: " + problems[0].Error()
+		if len(problems) > 1 {
+			summary += fmt.Sprintf(" (and %d more)", len(problems)-1)
 		}
+		rejectUpload(c, summary, problems)
 		return
 	}
+	var parsed spectype.Spec
+	if fileSet != nil {
+		parsed, err = fileSet.ParseFiles(tree, entry)
+	} else {
+		parsed, err = kind.Parse(content)
+	}
+	if err != nil {
+		rejectUpload(c, "Invalid "+kind.Label()+" document: "+err.Error(), nil)
+		return
+	}
+
+	// Style rules are checked once and the report is stored with the version.
+	// The rulesets are written for OpenAPI.
+	var lintReport *openapi.LintReport
+	var lintJSON []byte
+	if kind == spectype.OpenAPI {
+		spec, err := openapi.Parse(content)
+		if err != nil {
+			rejectUpload(c, "Invalid OpenAPI document: "+err.Error(), nil)
+			return
+		}
+		lintReport = h.lintService.Lint(spec)
+		lintJSON, err = json.Marshal(lintReport)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Error encoding lint report: " + err.Error(),
+				"success": false,
+			})
+			return
+		}
+	}
+
+	// Swagger 2 uploads can be stored together with an OpenAPI 3 conversion;
+	// convert before a new document is created so a failure leaves nothing behind
+	var converted []byte
+	if convertSwagger && kind == spectype.OpenAPI {
+		converted, err = convertToOAS3(content)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Error converting to OpenAPI 3: " + err.Error(),
+				"success": false,
+			})
+			return
+		}
+	}
 
 	var doc *models.Document
+	var compatibility *openapi.CompatibilityReport
+	var comparedTo string
 
 	if documentID != "" {
 		// Adding version to existing document
@@ -128,18 +266,53 @@ func (h *UploadHandler) HandleUpload(c *gin.Context) {
 			})
 			return
 		}
+		if docKind := spectype.Of(doc.Type); docKind != kind {
+			rejectUpload(c, fmt.Sprintf("This document holds %s versions; %s documents cannot be added to it", docKind.Label(), kind.Label()), nil)
+			return
+		}
+
+		// Classify the changes against the current latest version
+		if latest := doc.LatestVersion(); latest != nil {
+			compatibility, err = h.checkCompatibility(kind, latest, parsed)
+			if err != nil {
+				fmt.Printf("Compatibility check skipped for document %s: %v\n", doc.ID, err)
+			}
+			if compatibility != nil && failOnBreaking && compatibility.HasBreaking() {
+				c.JSON(http.StatusUnprocessableEntity, gin.H{
+					"error":         fmt.Sprintf("Upload rejected: %d breaking change(s) against %s", len(compatibility.Breaking), latest.Version),
+					"success":       false,
+					"compared_to":   latest.Version,
+					"compatibility": compatibility,
+				})
+				return
+			}
+			if compatibility != nil {
+				comparedTo = latest.Version
+			}
+		}
 	} else {
 		// Creating new document
 		if name == "" {
-			title, _, err := utils.GetDocumentInfo(content)
-			if err == nil {
-				name = title
-			} else {
+			name = parsed.Title()
+			if name == "" {
 				name = "Untitled API"
 			}
 		}
 
-		doc, err = h.docService.CreateDocument(name, description)
+		now := time.Now()
+		expiresAt, err := h.docService.ResolveExpiry(ttl, now)
+		if err == nil {
+			err = h.docService.CheckLifetime(now, expiresAt)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   err.Error(),
+				"success": false,
+			})
+			return
+		}
+
+		doc, err = h.docService.CreateDocument(name, description, kind.Name(), expiresAt)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"error":   "Error creating document: " + err.Error(),
@@ -149,24 +322,68 @@ func (h *UploadHandler) HandleUpload(c *gin.Context) {
 		}
 	}
 
-	// Generate version identifier for file storage
-	versionID := customVersion
-	if versionID == "" {
-		versionID = fmt.Sprintf("v%d", len(doc.Versions)+1)
-	}
-
-	filePath, err := h.storageService.SaveFile(doc.ID, versionID, content)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Error saving file: " + err.Error(),
-			"success": false,
-		})
-		return
-	}
-
-	version, err := h.docService.AddVersion(doc.ID, filePath, customVersion)
+	// Version number and file names are resolved together under the document lock
+	var storedKeys []string
+	version, err := h.docService.AddVersion(doc.ID, customVersion, func(v *models.Version) error {
+		format := kind.Format(content)
+		key, err := h.storageService.SaveFile(doc.ID, v.Version, content, format)
+		if err != nil {
+			return fmt.Errorf("error saving file: %w", err)
+		}
+		storedKeys = append(storedKeys, key)
+		v.ObjectKey, v.Format = key, format
+		if source != nil {
+			key, err := h.storageService.SaveSourceArchive(doc.ID, v.Version, source)
+			if err != nil {
+				return fmt.Errorf("error saving source files: %w", err)
+			}
+			storedKeys = append(storedKeys, key)
+			v.SourceKey, v.EntryPoint = key, entry
+		}
+		if lintJSON != nil {
+			key, err := h.storageService.SaveLintReport(doc.ID, v.Version, lintJSON)
+			if err != nil {
+				return fmt.Errorf("error saving lint report: %w", err)
+			}
+			storedKeys = append(storedKeys, key)
+			v.LintKey = key
+		}
+		if converted != nil {
+			key, err := h.storageService.SaveConvertedFile(doc.ID, v.Version, converted)
+			if err != nil {
+				return fmt.Errorf("error saving converted file: %w", err)
+			}
+			storedKeys = append(storedKeys, key)
+			v.ConvertedKey = key
+		}
+		// Overlays follow the document from version to version
+		if previous := doc.LatestVersion(); previous != nil {
+			overlays, err := h.storageService.CopyOverlays(doc.ID, previous, v.Version)
+			for _, key := range overlays {
+				storedKeys = append(storedKeys, key)
+			}
+			if err != nil {
+				return fmt.Errorf("error copying overlays: %w", err)
+			}
+			v.Overlays = overlays
+		}
+		return nil
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrVersionConflict) {
+			status = http.StatusConflict
+		} else {
+			// Files were stored but the version record was not; don't leave them behind
+			for _, key := range storedKeys {
+				_ = h.storageService.DeleteFile(key)
+			}
+		}
+		if documentID == "" {
+			// The document was created for this upload and has no version
+			_ = h.docService.DeleteDocument(doc.ID)
+		}
+		c.JSON(status, gin.H{
 			"error":   "Error creating version: " + err.Error(),
 			"success": false,
 		})
@@ -175,15 +392,120 @@ func (h *UploadHandler) HandleUpload(c *gin.Context) {
 
 	// Return JSON with document ID and success info
 	if c.GetHeader("Accept") == "application/json" || c.Query("ajax") == "1" {
-		c.JSON(http.StatusCreated, gin.H{
+		resp := gin.H{
 			"success":     true,
 			"document_id": doc.ID,
 			"version_id":  version.ID,
+			"version":     version.Version,
 			"message":     "Document uploaded successfully",
 			"view_url":    "/view/" + doc.ID,
-		})
+			"expires_at":  expiresAtJSON(doc.ExpiresAt),
+			"type":        kind.Name(),
+		}
+		if lintReport != nil {
+			resp["lint"] = gin.H{
+				"ruleset": lintReport.Ruleset,
+				"counts":  lintReport.Counts,
+				"url":     fmt.Sprintf("/api/document/%s/version/%s/lint", doc.ID, url.PathEscape(version.Version)),
+			}
+		}
+		if version.EntryPoint != "" {
+			resp["entry_point"] = version.EntryPoint
+			resp["files"] = len(tree)
+		}
+		if version.ConvertedKey != "" {
+			resp["converted_to"] = openapi.ConvertedVersion
+		}
+		if compatibility != nil {
+			resp["compared_to"] = comparedTo
+			resp["compatibility"] = compatibility
+		}
+		c.JSON(http.StatusCreated, resp)
 	} else {
 		// Redirect to viewer page for regular form submissions
 		c.Redirect(http.StatusFound, "/view/"+doc.ID)
 	}
 }
+
+// checkCompatibility compares an uploaded document with a stored version.
+func (h *UploadHandler) checkCompatibility(kind spectype.Kind, previous *models.Version, to spectype.Spec) (*openapi.CompatibilityReport, error) {
+	from, err := h.storageService.ParseVersion(kind, previous)
+	if err != nil {
+		return nil, err
+	}
+	return from.CheckCompatibility(to)
+}
+
+// readFileSet reads a multi-file upload: one "files" part per file, with an
+// optional "paths" value per file giving its path inside the tree (browsers and
+// multipart parsers only keep the base name).
+func (h *UploadHandler) readFileSet(form *multipart.Form) (map[string][]byte, error) {
+	paths := form.Value["paths"]
+	tree := map[string][]byte{}
+	var total int64
+	for i, fh := range form.File["files"] {
+		name := fh.Filename
+		if i < len(paths) && paths[i] != "" {
+			name = paths[i]
+		}
+		name, err := utils.CleanArchivePath(name)
+		if err != nil {
+			return nil, err
+		}
+		total += fh.Size
+		if total > h.config.MaxFileSize {
+			return nil, errors.New("Files too large. Maximum size: " + strconv.FormatInt(h.config.MaxFileSize/(1024*1024), 10) + "MB")
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s", name)
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s", name)
+		}
+		tree[name] = data
+	}
+	return tree, nil
+}
+
+// rejectUpload reports an unusable upload as JSON to API clients, or
+// redirects form submissions back with the message.
+func rejectUpload(c *gin.Context, summary string, problems []openapi.ValidationError) {
+	if c.GetHeader("Accept") == "application/json" || c.Query("ajax") == "1" {
+		resp := gin.H{
+			"error":   summary,
+			"success": false,
+		}
+		if problems != nil {
+			resp["errors"] = problems
+		}
+		c.JSON(http.StatusBadRequest, resp)
+		return
+	}
+	// Redirect back with message and type=error
+	c.Redirect(http.StatusFound, "/?message="+url.QueryEscape(summary)+"&type=error")
+}
+
+// convertToOAS3 returns the OpenAPI 3 conversion of a Swagger 2 document, or
+// nil for documents that already are OpenAPI 3.
+func convertToOAS3(content []byte) ([]byte, error) {
+	spec, err := openapi.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	if !spec.IsSwagger2() {
+		return nil, nil
+	}
+	return openapi.ConvertToOAS3(spec).YAML()
+}
+
+// isTruthy accepts the usual spellings of a boolean form flag.
+func isTruthy(v string) bool {
+	switch strings.ToLower(strings.TrimSpace(v)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+	return false
+}
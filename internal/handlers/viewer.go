@@ -4,9 +4,12 @@ import (
 	"APIScope/internal/config"
 	"APIScope/internal/models"
 	"APIScope/internal/services"
+	"APIScope/internal/spectype"
+	"errors"
 	"fmt"
 	"net/http"
 	"sort"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,24 +17,23 @@ import (
 type ViewerHandler struct {
 	docService     *services.DocumentService
 	storageService *services.StorageService
+	specPipeline   *services.SpecPipeline
 	config         *config.Config
 }
 
-func NewViewerHandler(docService *services.DocumentService, storageService *services.StorageService, cfg *config.Config) *ViewerHandler {
+func NewViewerHandler(docService *services.DocumentService, storageService *services.StorageService, specPipeline *services.SpecPipeline, cfg *config.Config) *ViewerHandler {
 	return &ViewerHandler{
 		docService:     docService,
 		storageService: storageService,
+		specPipeline:   specPipeline,
 		config:         cfg,
 	}
 }
 
 func (h *ViewerHandler) ViewDocument(c *gin.Context) {
 	documentID := c.Param("id")
-	selectedVersion := c.Query("version")
-	message := c.Query("message")
-	messageType := c.DefaultQuery("type", "info")
 
-	fmt.Printf("ViewDocument called for ID: %s, version: %s\n", documentID, selectedVersion)
+	fmt.Printf("ViewDocument called for ID: %s, version: %s\n", documentID, c.Query("version"))
 
 	doc, err := h.docService.GetDocumentByID(documentID)
 	if err != nil {
@@ -42,6 +44,16 @@ func (h *ViewerHandler) ViewDocument(c *gin.Context) {
 		})
 		return
 	}
+	h.render(c, doc, services.AudienceOwner)
+}
+
+// render shows a document to its owner, or read-only and redacted to visitors
+// of its share link.
+func (h *ViewerHandler) render(c *gin.Context, doc *models.Document, audience services.Audience) {
+	selectedVersion := c.Query("version")
+	message := c.Query("message")
+	messageType := c.DefaultQuery("type", "info")
+	shared := audience == services.AudiencePublic
 
 	fmt.Printf("Document found: %s, Versions count: %d\n", doc.Name, len(doc.Versions))
 
@@ -88,9 +100,9 @@ func (h *ViewerHandler) ViewDocument(c *gin.Context) {
 		return
 	}
 
-	fmt.Printf("Target version: %s, FilePath: %s\n", targetVersion.Version, targetVersion.FilePath)
+	fmt.Printf("Target version: %s, ObjectKey: %s\n", targetVersion.Version, targetVersion.ObjectKey)
 
-	contentBytes, err := h.storageService.GetFile(targetVersion.FilePath)
+	contentBytes, err := h.storageService.GetVersionFile(targetVersion)
 	if err != nil {
 		fmt.Printf("Error reading file: %v\n", err)
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
@@ -99,6 +111,28 @@ func (h *ViewerHandler) ViewDocument(c *gin.Context) {
 		})
 		return
 	}
+	overlayParam := strings.Join(overlayNames(c), ",")
+	overlays, err := h.storageService.GetVersionOverlays(targetVersion, overlayNames(c))
+	if errors.Is(err, services.ErrOverlayNotFound) {
+		message = err.Error() + ", showing the full specification"
+		messageType = "info"
+		overlayParam, overlays, err = "", nil, nil
+	}
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"error": "Error reading overlays: " + err.Error(),
+			"title": "Error",
+		})
+		return
+	}
+	contentBytes, err = h.specPipeline.Prepare(doc, contentBytes, requestOrigin(c), audience, overlays...)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
+			"error": "Error preparing document content: " + err.Error(),
+			"title": "Error",
+		})
+		return
+	}
 	content = string(contentBytes)
 
 	fmt.Printf("File content loaded successfully, length: %d\n", len(content))
@@ -123,19 +157,39 @@ func (h *ViewerHandler) ViewDocument(c *gin.Context) {
 			"Version":   version.Version,
 			"CreatedAt": version.CreatedAt,
 			"IsLatest":  version.IsLatest,
+			"Pinned":    version.Pinned,
 			"Selected":  version.Version == selectedVersion,
 		})
 	}
 
+	// Overlays of the shown version; a combination given in the URL is listed as its own choice
+	var overlayChoices []gin.H
+	listed := overlayParam == ""
+	for _, name := range sortedOverlayNames(targetVersion) {
+		overlayChoices = append(overlayChoices, gin.H{"Name": name, "Selected": name == overlayParam})
+		listed = listed || name == overlayParam
+	}
+	if !listed {
+		overlayChoices = append(overlayChoices, gin.H{"Name": overlayParam, "Selected": true})
+	}
+
+	// Servers were already stripped or rewritten server-side; the flags only adapt the UI
+	serverPolicy := h.specPipeline.EffectiveServerPolicy(doc)
+	stripServers := serverPolicy.Mode == models.ServersStrip
+	kind := spectype.Of(doc.Type)
+
 	templateData := gin.H{
 		"Title":                   doc.Name,
 		"Document":                doc,
 		"Version":                 targetVersion,
 		"Content":                 content,
-		"DocumentID":              documentID,
+		"DocumentID":              doc.ID,
 		"ShareSlug":               doc.ShareSlug,
+		"Shared":                  shared,
+		"SpecType":                kind.Name(),
 		"SelectedVersion":         selectedVersion,
 		"Versions":                versions,
+		"Overlays":                overlayChoices,
 		"Message":                 message,
 		"MessageType":             messageType,
 		"OpenAPIGeneratorEnabled": h.config.OpenAPIGeneratorEnabled,
@@ -143,15 +197,41 @@ func (h *ViewerHandler) ViewDocument(c *gin.Context) {
 		"AllowVersionDeletion":    h.config.AllowVersionDeletion,
 		"AllowVersionDownload":    h.config.AllowVersionDownload,
 		"AllowServerEditing":      h.config.AllowServerEditing,
-		"AutoAdjustServerOrigin":  h.config.AutoAdjustServerOrigin,
-		"StripServers":            h.config.StripServers,
+		"AutoAdjustServerOrigin":  h.config.AutoAdjustServerOrigin && serverPolicy.Mode == models.ServersRewrite && serverPolicy.URL == "",
+		"StripServers":            stripServers,
 		"AllowCustomShareLink":    h.config.AllowCustomShareLink,
+		"ProxyEnabled":            len(h.config.ProxyAllowedHosts) > 0 && !stripServers,
+		"AllowNeverExpire":        h.config.AllowNeverExpire,
+		"AllowDocumentRenewal":    h.config.AllowDocumentRenewal,
+	}
+	if kind != spectype.OpenAPI {
+		// SDKs, Try it out and server editing only make sense for HTTP APIs
+		for _, key := range []string{"OpenAPIGeneratorEnabled", "AllowServerEditing", "AutoAdjustServerOrigin", "StripServers", "ProxyEnabled"} {
+			templateData[key] = false
+		}
+	}
+	if !kind.Tree() {
+		// Kinds without a client-side viewer are shown as a reference rendered here
+		spec, err := h.storageService.ParseVersion(kind, targetVersion)
+		if err != nil {
+			fmt.Printf("Error parsing version %s: %v\n", targetVersion.Version, err)
+		} else if indexed, ok := spec.(spectype.Indexed); ok {
+			templateData["Reference"] = indexed.Index()
+		}
+	}
+	if shared {
+		// The document ID is the owner's handle: keep it, and everything built on it, out of the page
+		for _, key := range []string{"OpenAPIGeneratorEnabled", "AllowVersionDeletion", "AllowVersionDownload", "AllowCustomShareLink", "ProxyEnabled"} {
+			templateData[key] = false
+		}
+		templateData["DocumentID"] = ""
 	}
 
 	c.HTML(http.StatusOK, "viewer.html", templateData)
 }
 
-// ViewDocumentByShare resolves a share slug and delegates to ViewDocument by internally setting the document id.
+// ViewDocumentByShare shows a document through its share slug, read-only and
+// with its internal parts redacted.
 func (h *ViewerHandler) ViewDocumentByShare(c *gin.Context) {
 	slug := c.Param("slug")
 	if slug == "" {
@@ -163,8 +243,7 @@ func (h *ViewerHandler) ViewDocumentByShare(c *gin.Context) {
 		c.HTML(http.StatusNotFound, "error.html", gin.H{"error": "Share link not found", "title": "Not Found"})
 		return
 	}
-	// Redirect to canonical view route but preserve that share slug may be used for UI copy
-	c.Redirect(http.StatusFound, fmt.Sprintf("/view/%s", doc.ID))
+	h.render(c, doc, services.AudiencePublic)
 }
 
 func (h *ViewerHandler) DeleteDocument(c *gin.Context) {
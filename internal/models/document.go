@@ -5,21 +5,106 @@ import (
 )
 
 type Document struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	ExpiresAt   time.Time `json:"expires_at"`
-	IsActive    bool      `json:"is_active"`
-	ShareSlug   string    `json:"share_slug"`
-	Versions    []Version `json:"versions"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Type is the kind of API description (openapi, asyncapi); documents
+	// stored before kinds were recorded have none and are OpenAPI.
+	Type      string    `json:"type,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"` // zero = never expires
+	IsActive  bool      `json:"is_active"`
+	ShareSlug string    `json:"share_slug"`
+	Versions  []Version `json:"versions"`
+	// Retention overrides the instance retention policy when set.
+	Retention *RetentionPolicy `json:"retention,omitempty"`
+	// Servers overrides the instance server policy when set.
+	Servers *ServerPolicy `json:"servers,omitempty"`
+	// Redaction overrides the instance redaction rules of shared views when set.
+	Redaction *RedactionPolicy `json:"redaction,omitempty"`
+}
+
+// IsExpired reports whether the document's lifetime is over.
+func (d *Document) IsExpired(now time.Time) bool {
+	return !d.ExpiresAt.IsZero() && now.After(d.ExpiresAt)
+}
+
+// FindVersion returns the version with the given version string, or nil.
+func (d *Document) FindVersion(version string) *Version {
+	for i := range d.Versions {
+		if d.Versions[i].Version == version {
+			return &d.Versions[i]
+		}
+	}
+	return nil
+}
+
+// LatestVersion returns the version flagged as latest, or nil.
+func (d *Document) LatestVersion() *Version {
+	for i := range d.Versions {
+		if d.Versions[i].IsLatest {
+			return &d.Versions[i]
+		}
+	}
+	return nil
+}
+
+// RetentionPolicy limits how many versions a document keeps. Zero disables a limit.
+// Latest, pinned and tagged versions are never pruned.
+type RetentionPolicy struct {
+	KeepLast   int `json:"keep_last"`
+	MaxAgeDays int `json:"max_age_days"`
+}
+
+// Server policy modes.
+const (
+	ServersKeep    = "keep"    // serve servers as uploaded
+	ServersStrip   = "strip"   // remove every server
+	ServersRewrite = "rewrite" // replace the origin of absolute server URLs
+)
+
+// ServerPolicy decides what happens to a spec's servers before it leaves the server.
+type ServerPolicy struct {
+	Mode string `json:"mode"`
+	// URL is the origin rewrite mode puts in place; empty means the origin APIScope is reached at.
+	URL string `json:"url,omitempty"`
+}
+
+// RedactionPolicy selects what share links hide: operations, path items,
+// parameters and properties flagged with Extension, operations tagged with one
+// of Tags, and paths matching one of Paths (* within a segment, ** across).
+type RedactionPolicy struct {
+	Extension string   `json:"extension"`
+	Tags      []string `json:"tags"`
+	Paths     []string `json:"paths"`
 }
 
 type Version struct {
 	ID         string    `json:"id"`
 	DocumentID string    `json:"document_id"`
 	Version    string    `json:"version"`
-	FilePath   string    `json:"file_path"`
+	ObjectKey  string    `json:"object_key,omitempty"`
+	FilePath   string    `json:"file_path,omitempty"` // legacy OS path, only set on versions stored before ObjectKey
+	Format     string    `json:"format,omitempty"`    // "json" or "yaml" as uploaded; empty on versions stored before it was recorded
 	CreatedAt  time.Time `json:"created_at"`
 	IsLatest   bool      `json:"is_latest"`
+	Pinned     bool      `json:"pinned,omitempty"`
+	Tags       []string  `json:"tags,omitempty"`
+
+	// ConvertedKey holds the OpenAPI 3 conversion of a Swagger 2 upload, if one was made.
+	ConvertedKey string `json:"converted_key,omitempty"`
+	// SourceKey holds a zip of the original files of a multi-file upload whose
+	// bundled spec is stored under ObjectKey; EntryPoint names its root document.
+	SourceKey  string `json:"source_key,omitempty"`
+	EntryPoint string `json:"entry_point,omitempty"`
+	// LintKey holds the lint report produced when the version was uploaded.
+	LintKey string `json:"lint_key,omitempty"`
+	// Overlays maps overlay names (e.g. "public") to the object keys of the
+	// OpenAPI Overlay documents attached to the version.
+	Overlays map[string]string `json:"overlays,omitempty"`
+}
+
+// Protected reports whether retention must keep this version.
+func (v *Version) Protected() bool {
+	return v.IsLatest || v.Pinned || len(v.Tags) > 0
 }
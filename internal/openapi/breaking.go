@@ -0,0 +1,399 @@
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Severity classifies a change for API consumers.
+type Severity string
+
+const (
+	SeverityBreaking    Severity = "breaking"
+	SeverityNonBreaking Severity = "non-breaking"
+)
+
+// Finding is one classified change.
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`     // stable identifier, e.g. operation-removed
+	Location string   `json:"location"` // e.g. "GET /pets query:limit"
+	Message  string   `json:"message"`
+}
+
+// CompatibilityReport lists the changes between two versions by severity.
+type CompatibilityReport struct {
+	Breaking    []Finding `json:"breaking"`
+	NonBreaking []Finding `json:"non_breaking"`
+}
+
+// HasBreaking reports whether any breaking change was found.
+func (r *CompatibilityReport) HasBreaking() bool {
+	return len(r.Breaking) > 0
+}
+
+// NewCompatibilityReport returns an empty report.
+func NewCompatibilityReport() *CompatibilityReport {
+	return &CompatibilityReport{Breaking: []Finding{}, NonBreaking: []Finding{}}
+}
+
+// Add records a finding; format and args build its message.
+func (r *CompatibilityReport) Add(sev Severity, rule, location, format string, args ...any) {
+	f := Finding{Severity: sev, Rule: rule, Location: location, Message: fmt.Sprintf(format, args...)}
+	if sev == SeverityBreaking {
+		r.Breaking = append(r.Breaking, f)
+	} else {
+		r.NonBreaking = append(r.NonBreaking, f)
+	}
+}
+
+// direction tells schema rules whether clients send (request) or receive (response) the data.
+type direction int
+
+const (
+	requestDirection direction = iota
+	responseDirection
+)
+
+// CheckCompatibility classifies the changes from one version to the next as
+// breaking or non-breaking for existing clients.
+func CheckCompatibility(from, to *Spec) *CompatibilityReport {
+	from, to = MatchLayouts(from, to)
+	r := NewCompatibilityReport()
+	fromOps, toOps := indexOperations(from), indexOperations(to)
+
+	for _, key := range orderedUnion(fromOps, toOps) {
+		a, inFrom := fromOps[key]
+		b, inTo := toOps[key]
+		switch {
+		case !inFrom && b.Webhook:
+			r.Add(SeverityNonBreaking, "webhook-added", key, "webhook %s %s was added", b.Path, b.Method)
+		case !inFrom:
+			r.Add(SeverityNonBreaking, "operation-added", key, "operation %s was added", key)
+		case !inTo && a.Webhook:
+			r.Add(SeverityBreaking, "webhook-removed", key, "webhook %s %s was removed", a.Path, a.Method)
+		case !inTo:
+			r.Add(SeverityBreaking, "operation-removed", key, "operation %s was removed", key)
+		default:
+			c := &checker{report: r, from: from, to: to, seen: map[string]bool{}}
+			c.operation(key, a, b)
+		}
+	}
+	return r
+}
+
+type checker struct {
+	report   *CompatibilityReport
+	from, to *Spec
+	seen     map[string]bool // ref pairs already compared, guards recursive schemas
+}
+
+func (c *checker) operation(key string, a, b Operation) {
+	// Webhook requests are sent by the API to its consumers, so the schema
+	// rules for request and response data are mirrored.
+	sendDir, receiveDir := requestDirection, responseDirection
+	if a.Webhook {
+		sendDir, receiveDir = responseDirection, requestDirection
+	}
+
+	if !isTrue(a.Node["deprecated"]) && isTrue(b.Node["deprecated"]) {
+		c.report.Add(SeverityNonBreaking, "operation-deprecated", key, "operation %s was deprecated", key)
+	}
+
+	// Parameters
+	pa, pb := c.from.Parameters(a), c.to.Parameters(b)
+	for _, name := range SortedKeys(pb) {
+		loc := key + " " + name
+		p, ok := pa[name]
+		if !ok {
+			if isTrue(pb[name]["required"]) {
+				c.report.Add(SeverityBreaking, "required-parameter-added", loc, "new required parameter %s", name)
+			} else {
+				c.report.Add(SeverityNonBreaking, "optional-parameter-added", loc, "new optional parameter %s", name)
+			}
+			continue
+		}
+		if !isTrue(p["required"]) && isTrue(pb[name]["required"]) {
+			c.report.Add(SeverityBreaking, "parameter-became-required", loc, "parameter %s became required", name)
+		} else if isTrue(p["required"]) && !isTrue(pb[name]["required"]) {
+			c.report.Add(SeverityNonBreaking, "parameter-became-optional", loc, "parameter %s became optional", name)
+		}
+		c.schema(sendDir, loc, parameterSchema(p), parameterSchema(pb[name]))
+	}
+	for _, name := range SortedKeys(pa) {
+		if _, ok := pb[name]; !ok {
+			c.report.Add(SeverityBreaking, "parameter-removed", key+" "+name, "parameter %s was removed", name)
+		}
+	}
+
+	// Request body
+	ba, bb := c.from.RequestBody(a), c.to.RequestBody(b)
+	loc := key + " requestBody"
+	switch {
+	case ba == nil && bb != nil:
+		if isTrue(bb["required"]) {
+			c.report.Add(SeverityBreaking, "required-request-body-added", loc, "a required request body was added")
+		} else {
+			c.report.Add(SeverityNonBreaking, "request-body-added", loc, "an optional request body was added")
+		}
+	case ba != nil && bb == nil:
+		c.report.Add(SeverityBreaking, "request-body-removed", loc, "the request body was removed")
+	case ba != nil && bb != nil:
+		if !isTrue(ba["required"]) && isTrue(bb["required"]) {
+			c.report.Add(SeverityBreaking, "request-body-became-required", loc, "the request body became required")
+		}
+		c.content(sendDir, loc, ba["content"], bb["content"])
+	}
+
+	// Responses
+	ra, rb := c.from.Responses(a), c.to.Responses(b)
+	for _, code := range SortedKeys(rb) {
+		if _, ok := ra[code]; !ok {
+			c.report.Add(SeverityNonBreaking, "response-added", key+" "+code, "response %s was added", code)
+		}
+	}
+	for _, code := range SortedKeys(ra) {
+		loc := key + " " + code
+		resp, ok := rb[code]
+		if !ok {
+			if strings.HasPrefix(code, "2") || code == "default" {
+				c.report.Add(SeverityBreaking, "response-removed", loc, "response %s was removed", code)
+			} else {
+				c.report.Add(SeverityNonBreaking, "error-response-removed", loc, "response %s was removed", code)
+			}
+			continue
+		}
+		if c.from.IsSwagger2() || c.to.IsSwagger2() {
+			c.schema(receiveDir, loc, ra[code]["schema"], resp["schema"])
+			continue
+		}
+		c.content(receiveDir, loc, ra[code]["content"], resp["content"])
+	}
+}
+
+// content compares the media types of a request body or response.
+func (c *checker) content(dir direction, loc string, a, b any) {
+	ma, _ := a.(map[string]any)
+	mb, _ := b.(map[string]any)
+	for _, ct := range SortedKeys(ma) {
+		mediaB, ok := mb[ct]
+		if !ok {
+			c.report.Add(SeverityBreaking, "media-type-removed", loc+" "+ct, "media type %s is no longer supported", ct)
+			continue
+		}
+		sa, _ := ma[ct].(map[string]any)
+		sb, _ := mediaB.(map[string]any)
+		c.schema(dir, loc+" "+ct, sa["schema"], sb["schema"])
+	}
+	for _, ct := range SortedKeys(mb) {
+		if _, ok := ma[ct]; !ok {
+			c.report.Add(SeverityNonBreaking, "media-type-added", loc+" "+ct, "media type %s was added", ct)
+		}
+	}
+}
+
+// CheckSchema classifies the changes between two schemas whose $refs resolve
+// against from and to. sent tells whether clients send the data (a request)
+// or receive it (a response).
+func (r *CompatibilityReport) CheckSchema(from, to *Spec, sent bool, location string, a, b any) {
+	dir := responseDirection
+	if sent {
+		dir = requestDirection
+	}
+	c := &checker{report: r, from: from, to: to, seen: map[string]bool{}}
+	c.schema(dir, location, a, b)
+}
+
+// schema compares two schemas from the point of view of dir.
+func (c *checker) schema(dir direction, loc string, a, b any) {
+	if a == nil || b == nil {
+		return
+	}
+	refA, refB := schemaRef(a), schemaRef(b)
+	if refA != "" && refB != "" {
+		pair := fmt.Sprintf("%d|%s|%s", dir, refA, refB)
+		if c.seen[pair] {
+			return
+		}
+		c.seen[pair] = true
+	}
+	sa, _ := c.from.Resolve(a).(map[string]any)
+	sb, _ := c.to.Resolve(b).(map[string]any)
+	if sa == nil || sb == nil {
+		return
+	}
+
+	ta, tb := schemaTypes(sa), schemaTypes(sb)
+	if len(ta) > 0 && len(tb) > 0 && !reflect.DeepEqual(ta, tb) {
+		widened := dir == requestDirection && subset(ta, tb)
+		if widened {
+			c.report.Add(SeverityNonBreaking, "type-widened", loc, "type changed from %s to %s", strings.Join(ta, "|"), strings.Join(tb, "|"))
+		} else {
+			c.report.Add(SeverityBreaking, "type-changed", loc, "type changed from %s to %s", strings.Join(ta, "|"), strings.Join(tb, "|"))
+		}
+		return
+	}
+	if fa, fb := sa["format"], sb["format"]; fa != nil && fb != nil && fa != fb {
+		c.report.Add(SeverityBreaking, "format-changed", loc, "format changed from %v to %v", fa, fb)
+	}
+
+	// Enums: clients must not send removed values nor receive unknown ones
+	ea, eb := enumValues(sa), enumValues(sb)
+	if ea != nil && eb != nil {
+		removed, added := listDelta(ea, eb)
+		if len(removed) > 0 {
+			sev := SeverityNonBreaking
+			if dir == requestDirection {
+				sev = SeverityBreaking
+			}
+			c.report.Add(sev, "enum-narrowed", loc, "enum values removed: %s", strings.Join(removed, ", "))
+		}
+		if len(added) > 0 {
+			sev := SeverityNonBreaking
+			if dir == responseDirection {
+				sev = SeverityBreaking
+			}
+			c.report.Add(sev, "enum-widened", loc, "enum values added: %s", strings.Join(added, ", "))
+		}
+	} else if ea == nil && eb != nil && dir == requestDirection {
+		c.report.Add(SeverityBreaking, "enum-added", loc, "values are now restricted to an enum")
+	}
+
+	// Required properties
+	reqA, reqB := stringSet(sa["required"]), stringSet(sb["required"])
+	for _, name := range SortedKeys(reqB) {
+		if !reqA[name] && dir == requestDirection {
+			c.report.Add(SeverityBreaking, "property-became-required", loc+"."+name, "property %s is now required", name)
+		}
+	}
+	for _, name := range SortedKeys(reqA) {
+		if !reqB[name] && dir == responseDirection {
+			c.report.Add(SeverityBreaking, "response-property-optional", loc+"."+name, "property %s is no longer always returned", name)
+		}
+	}
+
+	// Properties
+	propsA, _ := sa["properties"].(map[string]any)
+	propsB, _ := sb["properties"].(map[string]any)
+	for _, name := range SortedKeys(propsA) {
+		pb, ok := propsB[name]
+		if !ok {
+			if dir == responseDirection {
+				c.report.Add(SeverityBreaking, "response-property-removed", loc+"."+name, "property %s was removed", name)
+			} else {
+				c.report.Add(SeverityNonBreaking, "request-property-removed", loc+"."+name, "property %s was removed", name)
+			}
+			continue
+		}
+		c.schema(dir, loc+"."+name, propsA[name], pb)
+	}
+	for _, name := range SortedKeys(propsB) {
+		if _, ok := propsA[name]; !ok && !(dir == requestDirection && reqB[name]) {
+			c.report.Add(SeverityNonBreaking, "property-added", loc+"."+name, "property %s was added", name)
+		}
+	}
+
+	if ia, ib := sa["items"], sb["items"]; ia != nil && ib != nil {
+		c.schema(dir, loc+"[]", ia, ib)
+	}
+}
+
+// enumValues returns the allowed values of a schema; a JSON Schema 2020-12
+// const counts as a single-value enum.
+func enumValues(s map[string]any) any {
+	if e, ok := s["enum"]; ok {
+		return e
+	}
+	if c, ok := s["const"]; ok {
+		return []any{c}
+	}
+	return nil
+}
+
+// parameterSchema returns the schema of a parameter (Swagger 2 keeps it inline).
+func parameterSchema(p map[string]any) any {
+	if s, ok := p["schema"]; ok {
+		return s
+	}
+	if _, ok := p["type"]; ok {
+		return p
+	}
+	return nil
+}
+
+func schemaRef(s any) string {
+	m, _ := s.(map[string]any)
+	ref, _ := m["$ref"].(string)
+	return ref
+}
+
+// schemaTypes returns the declared type(s) of a schema, sorted.
+func schemaTypes(s map[string]any) []string {
+	var out []string
+	switch t := s["type"].(type) {
+	case string:
+		out = []string{t}
+	case []any:
+		for _, v := range t {
+			out = append(out, fmt.Sprint(v))
+		}
+	}
+	if isTrue(s["nullable"]) {
+		out = append(out, "null")
+	}
+	sort.Strings(out)
+	return out
+}
+
+// subset reports whether every element of a is in b.
+func subset(a, b []string) bool {
+	set := map[string]bool{}
+	for _, v := range b {
+		set[v] = true
+	}
+	for _, v := range a {
+		if !set[v] {
+			return false
+		}
+	}
+	return true
+}
+
+// listDelta returns the values only in a (removed) and only in b (added).
+func listDelta(a, b any) (removed, added []string) {
+	la, _ := a.([]any)
+	lb, _ := b.([]any)
+	setA, setB := map[string]bool{}, map[string]bool{}
+	for _, v := range la {
+		setA[fmt.Sprint(v)] = true
+	}
+	for _, v := range lb {
+		setB[fmt.Sprint(v)] = true
+	}
+	for _, v := range SortedKeys(setA) {
+		if !setB[v] {
+			removed = append(removed, v)
+		}
+	}
+	for _, v := range SortedKeys(setB) {
+		if !setA[v] {
+			added = append(added, v)
+		}
+	}
+	return removed, added
+}
+
+func stringSet(v any) map[string]bool {
+	out := map[string]bool{}
+	for _, s := range stringList(v) {
+		out[s] = true
+	}
+	return out
+}
+
+func isTrue(v any) bool {
+	b, _ := v.(bool)
+	return b
+}
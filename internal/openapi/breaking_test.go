@@ -0,0 +1,305 @@
+package openapi
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testSpec parses an OpenAPI 3.1 document with the given paths section.
+func testSpec(t *testing.T, paths string) *Spec {
+	t.Helper()
+	spec, err := Parse([]byte("openapi: 3.1.0\ninfo: {title: Test, version: '1'}\n" + paths))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func rules(findings []Finding) string {
+	var out []string
+	for _, f := range findings {
+		out = append(out, f.Rule)
+	}
+	sort.Strings(out)
+	return strings.Join(out, ",")
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	const getPets = `
+paths:
+  /pets:
+    get:
+      responses:
+        '200': {description: ok}
+`
+	tests := []struct {
+		name        string
+		from, to    string
+		breaking    string
+		nonBreaking string
+	}{
+		{
+			name: "unchanged",
+			from: getPets, to: getPets,
+		},
+		{
+			name: "operation removed",
+			from: getPets, to: "paths: {}\n",
+			breaking: "operation-removed",
+		},
+		{
+			name: "operation added",
+			from: "paths: {}\n", to: getPets,
+			nonBreaking: "operation-added",
+		},
+		{
+			name: "required parameter added",
+			from: getPets,
+			to: `
+paths:
+  /pets:
+    get:
+      parameters:
+        - {name: limit, in: query, required: true, schema: {type: integer}}
+        - {name: tag, in: query, schema: {type: string}}
+      responses:
+        '200': {description: ok}
+`,
+			breaking:    "required-parameter-added",
+			nonBreaking: "optional-parameter-added",
+		},
+		{
+			name: "parameter became required and removed",
+			from: `
+paths:
+  /pets:
+    get:
+      parameters:
+        - {name: limit, in: query, schema: {type: integer}}
+        - {name: tag, in: query, schema: {type: string}}
+      responses:
+        '200': {description: ok}
+`,
+			to: `
+paths:
+  /pets:
+    get:
+      parameters:
+        - {name: limit, in: query, required: true, schema: {type: integer}}
+      responses:
+        '200': {description: ok}
+`,
+			breaking: "parameter-became-required,parameter-removed",
+		},
+		{
+			name: "request parameter type widened",
+			from: `
+paths:
+  /pets:
+    get:
+      parameters:
+        - {name: limit, in: query, schema: {type: integer}}
+      responses:
+        '200': {description: ok}
+`,
+			to: `
+paths:
+  /pets:
+    get:
+      parameters:
+        - {name: limit, in: query, schema: {type: [integer, string]}}
+      responses:
+        '200': {description: ok}
+`,
+			nonBreaking: "type-widened",
+		},
+		{
+			name: "success response removed, error response removed",
+			from: `
+paths:
+  /pets:
+    get:
+      responses:
+        '200': {description: ok}
+        '201': {description: created}
+        '404': {description: missing}
+`,
+			to:          getPets,
+			breaking:    "response-removed",
+			nonBreaking: "error-response-removed",
+		},
+		{
+			name: "response property removed",
+			from: `
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema: {type: object, properties: {id: {type: integer}, name: {type: string}}}
+`,
+			to: `
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema: {type: object, properties: {id: {type: integer}}}
+`,
+			breaking: "response-property-removed",
+		},
+		{
+			name: "request body became required, media type added",
+			from: `
+paths:
+  /pets:
+    post:
+      requestBody:
+        content:
+          application/json: {schema: {type: object}}
+      responses:
+        '201': {description: created}
+`,
+			to: `
+paths:
+  /pets:
+    post:
+      requestBody:
+        required: true
+        content:
+          application/json: {schema: {type: object}}
+          application/xml: {schema: {type: object}}
+      responses:
+        '201': {description: created}
+`,
+			breaking:    "request-body-became-required",
+			nonBreaking: "media-type-added",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := CheckCompatibility(testSpec(t, tt.from), testSpec(t, tt.to))
+			if got := rules(r.Breaking); got != tt.breaking {
+				t.Errorf("breaking = %q, want %q", got, tt.breaking)
+			}
+			if got := rules(r.NonBreaking); got != tt.nonBreaking {
+				t.Errorf("non-breaking = %q, want %q", got, tt.nonBreaking)
+			}
+			if r.HasBreaking() != (tt.breaking != "") {
+				t.Errorf("HasBreaking = %v", r.HasBreaking())
+			}
+		})
+	}
+}
+
+// TestCheckSchemaDirection covers the rules whose severity depends on whether
+// clients send or receive the data.
+func TestCheckSchemaDirection(t *testing.T) {
+	tests := []struct {
+		name             string
+		from, to         map[string]any
+		rule             string
+		breakingRequest  bool
+		breakingResponse bool
+	}{
+		{
+			name:            "enum narrowed",
+			from:            map[string]any{"type": "string", "enum": []any{"cat", "dog"}},
+			to:              map[string]any{"type": "string", "enum": []any{"cat"}},
+			rule:            "enum-narrowed",
+			breakingRequest: true,
+		},
+		{
+			name:             "enum widened",
+			from:             map[string]any{"type": "string", "enum": []any{"cat"}},
+			to:               map[string]any{"type": "string", "enum": []any{"cat", "dog"}},
+			rule:             "enum-widened",
+			breakingResponse: true,
+		},
+		{
+			name:            "const changed to enum",
+			from:            map[string]any{"type": "string", "const": "cat"},
+			to:              map[string]any{"type": "string", "enum": []any{"dog"}},
+			rule:            "enum-narrowed",
+			breakingRequest: true,
+		},
+		{
+			name:             "type changed",
+			from:             map[string]any{"type": "integer"},
+			to:               map[string]any{"type": "string"},
+			rule:             "type-changed",
+			breakingRequest:  true,
+			breakingResponse: true,
+		},
+		{
+			name:            "property became required",
+			from:            map[string]any{"type": "object", "properties": map[string]any{"id": map[string]any{"type": "integer"}}},
+			to:              map[string]any{"type": "object", "required": []any{"id"}, "properties": map[string]any{"id": map[string]any{"type": "integer"}}},
+			rule:            "property-became-required",
+			breakingRequest: true,
+		},
+		{
+			name:             "property no longer required",
+			from:             map[string]any{"type": "object", "required": []any{"id"}, "properties": map[string]any{"id": map[string]any{"type": "integer"}}},
+			to:               map[string]any{"type": "object", "properties": map[string]any{"id": map[string]any{"type": "integer"}}},
+			rule:             "response-property-optional",
+			breakingResponse: true,
+		},
+	}
+	spec := testSpec(t, "paths: {}\n")
+	for _, tt := range tests {
+		for _, sent := range []bool{true, false} {
+			r := NewCompatibilityReport()
+			r.CheckSchema(spec, spec, sent, "schema", tt.from, tt.to)
+			want := tt.breakingResponse
+			if sent {
+				want = tt.breakingRequest
+			}
+			found := false
+			for _, f := range append(r.Breaking, r.NonBreaking...) {
+				if f.Rule != tt.rule {
+					continue
+				}
+				found = true
+				if got := f.Severity == SeverityBreaking; got != want {
+					t.Errorf("%s (sent=%v): breaking = %v, want %v", tt.name, sent, got, want)
+				}
+			}
+			if !found && want {
+				t.Errorf("%s (sent=%v): no %s finding in %+v", tt.name, sent, tt.rule, r)
+			}
+		}
+	}
+}
+
+func TestCheckCompatibilityWebhookMirrorsDirection(t *testing.T) {
+	webhook := func(enum string) string {
+		return `
+webhooks:
+  petAdopted:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema: {type: string, enum: [` + enum + `]}
+      responses:
+        '200': {description: ok}
+`
+	}
+	// The API sends webhook payloads, so dropping a value cannot break receivers
+	r := CheckCompatibility(testSpec(t, webhook("cat, dog")), testSpec(t, webhook("cat")))
+	if r.HasBreaking() || rules(r.NonBreaking) != "enum-narrowed" {
+		t.Errorf("narrowed webhook enum: %+v", r)
+	}
+	r = CheckCompatibility(testSpec(t, webhook("cat")), testSpec(t, webhook("cat, dog")))
+	if rules(r.Breaking) != "enum-widened" {
+		t.Errorf("widened webhook enum: %+v", r)
+	}
+}
@@ -0,0 +1,237 @@
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrRefCycle is returned when $refs only point at each other and never reach a value.
+var ErrRefCycle = errors.New("$ref cycle")
+
+// bundler inlines the external $refs of a multi-file spec into its entry point.
+type bundler struct {
+	files  map[string][]byte
+	entry  string
+	parsed map[string]any
+	// placed maps "file#pointer" to the pointer of its first copy in the bundle;
+	// later refs to the same target, including recursive ones, point there
+	placed map[string]string
+}
+
+// Bundle resolves every relative $ref of a spec split across files and returns
+// a single YAML document. files is keyed by slash-separated path relative to
+// the tree root, entry names the root document. The first ref to an external
+// target is replaced by its content; further refs to it, including recursive
+// ones, become local refs to that copy. Remote (http/https) refs are kept.
+func Bundle(files map[string][]byte, entry string) ([]byte, error) {
+	entry = path.Clean(entry)
+	if _, ok := files[entry]; !ok {
+		return nil, fmt.Errorf("entry point %s not found", entry)
+	}
+	b := &bundler{files: files, entry: entry, parsed: map[string]any{}, placed: map[string]string{}}
+	root, err := b.load(entry)
+	if err != nil {
+		return nil, err
+	}
+	out, err := b.walk(root, entry, "")
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(out)
+}
+
+// DefaultEntry guesses the entry point of a spec tree: a top-level
+// openapi/swagger/asyncapi file, or the only top-level YAML/JSON file.
+func DefaultEntry(files map[string][]byte) (string, bool) {
+	var candidates []string
+	for name := range files {
+		if strings.Contains(name, "/") || !isSpecFile(name) {
+			continue
+		}
+		base := strings.TrimSuffix(name, path.Ext(name))
+		if base == "openapi" || base == "swagger" || base == "asyncapi" {
+			return name, true
+		}
+		candidates = append(candidates, name)
+	}
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+	return "", false
+}
+
+func isSpecFile(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+func (b *bundler) load(file string) (any, error) {
+	if doc, ok := b.parsed[file]; ok {
+		return doc, nil
+	}
+	content, ok := b.files[file]
+	if !ok {
+		return nil, fmt.Errorf("referenced file %s not found", file)
+	}
+	var raw any
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("%s: invalid YAML or JSON format: %w", file, err)
+	}
+	doc := Normalize(raw)
+	b.parsed[file] = doc
+	return doc, nil
+}
+
+// walk copies node, which lives in file, to pointer in the bundle.
+func (b *bundler) walk(node any, file, pointer string) (any, error) {
+	switch t := node.(type) {
+	case map[string]any:
+		if ref, ok := t["$ref"].(string); ok {
+			return b.ref(t, ref, file, pointer)
+		}
+		// Sorted, so shared targets land in the same place on every upload
+		// (components before paths)
+		out := make(map[string]any, len(t))
+		for _, k := range SortedKeys(t) {
+			copied, err := b.walk(t[k], file, pointer+"/"+escapePointer(k))
+			if err != nil {
+				return nil, err
+			}
+			out[k] = copied
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(t))
+		for i, item := range t {
+			copied, err := b.walk(item, file, fmt.Sprintf("%s/%d", pointer, i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = copied
+		}
+		return out, nil
+	default:
+		return node, nil
+	}
+}
+
+func (b *bundler) ref(node map[string]any, ref, file, pointer string) (any, error) {
+	if isRemoteRef(ref) {
+		return node, nil
+	}
+	targetFile, fragment, err := b.target(file, ref)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.checkCycle(targetFile, fragment); err != nil {
+		return nil, fmt.Errorf("%s: $ref %s: %w", file, ref, err)
+	}
+
+	// The entry point is the bundle root, so its pointers stay valid
+	if targetFile == b.entry {
+		return withSiblings(node, map[string]any{"$ref": "#" + fragment}), nil
+	}
+	key := targetFile + "#" + fragment
+	if at, ok := b.placed[key]; ok {
+		return withSiblings(node, map[string]any{"$ref": "#" + at}), nil
+	}
+	value, err := b.lookup(targetFile, fragment)
+	if err != nil {
+		return nil, fmt.Errorf("%s: $ref %s: %w", file, ref, err)
+	}
+	b.placed[key] = pointer
+	out, err := b.walk(value, targetFile, pointer)
+	if err != nil {
+		return nil, err
+	}
+	return withSiblings(node, out), nil
+}
+
+// target resolves a $ref made in file to the file and JSON pointer it names.
+func (b *bundler) target(file, ref string) (string, string, error) {
+	target, fragment, _ := strings.Cut(ref, "#")
+	if target == "" {
+		return file, fragment, nil
+	}
+	resolved := path.Join(path.Dir(file), target)
+	if resolved == ".." || strings.HasPrefix(resolved, "../") || path.IsAbs(target) {
+		return "", "", fmt.Errorf("%s: $ref %s points outside the uploaded files", file, ref)
+	}
+	return resolved, fragment, nil
+}
+
+// checkCycle follows a chain of $refs that point at other $refs and fails if
+// it loops without ever reaching a value.
+func (b *bundler) checkCycle(file, fragment string) error {
+	var chain []string
+	seen := map[string]bool{}
+	for {
+		key := file + "#" + fragment
+		chain = append(chain, key)
+		if seen[key] {
+			return fmt.Errorf("%w: %s", ErrRefCycle, strings.Join(chain, " -> "))
+		}
+		seen[key] = true
+		v, err := b.lookup(file, fragment)
+		if err != nil {
+			return err
+		}
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok || isRemoteRef(ref) {
+			return nil
+		}
+		if file, fragment, err = b.target(file, ref); err != nil {
+			return err
+		}
+	}
+}
+
+func (b *bundler) lookup(file, fragment string) (any, error) {
+	doc, err := b.load(file)
+	if err != nil {
+		return nil, err
+	}
+	v, ok := lookupPointer(doc, fragment)
+	if !ok {
+		return nil, fmt.Errorf("%s#%s not found", file, fragment)
+	}
+	return v, nil
+}
+
+func isRemoteRef(ref string) bool {
+	return strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://")
+}
+
+// withSiblings keeps keys written next to a $ref (summary, description, ...)
+// on the value that replaces it.
+func withSiblings(ref map[string]any, value any) any {
+	m, ok := value.(map[string]any)
+	if !ok || len(ref) == 1 {
+		return value
+	}
+	out := make(map[string]any, len(m)+len(ref))
+	for k, v := range m {
+		out[k] = v
+	}
+	for k, v := range ref {
+		if k != "$ref" {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,194 @@
+package openapi
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestBundle(t *testing.T) {
+	const entry = "openapi.yaml"
+	const header = "openapi: 3.0.3\ninfo: {title: Pets, version: '1'}\n"
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    map[string]string // JSON pointer -> expected value as YAML
+		cycle   bool              // want ErrRefCycle
+		wantErr string            // want another error containing this
+	}{
+		{
+			name: "external ref is inlined",
+			files: map[string]string{
+				entry:              header + "paths:\n  /pets:\n    $ref: paths/pets.yaml\n",
+				"paths/pets.yaml":  "get:\n  responses:\n    '200':\n      description: ok\n      content:\n        application/json:\n          schema: {$ref: '../schemas/pet.yaml'}\n",
+				"schemas/pet.yaml": "type: object\nproperties: {name: {type: string}}\n",
+			},
+			want: map[string]string{
+				"/paths/~1pets/get/responses/200/content/application~1json/schema": "{type: object, properties: {name: {type: string}}}",
+			},
+		},
+		{
+			name: "second ref to a target points at the first copy",
+			files: map[string]string{
+				entry:        header + "paths: {}\ncomponents:\n  schemas:\n    A: {$ref: 'pet.yaml'}\n    B: {$ref: 'pet.yaml'}\n",
+				"pet.yaml":   "type: object\n",
+				"unused.yml": "type: string\n",
+			},
+			want: map[string]string{
+				"/components/schemas/A": "{type: object}",
+				"/components/schemas/B": "{$ref: '#/components/schemas/A'}",
+			},
+		},
+		{
+			name: "recursive schema across files",
+			files: map[string]string{
+				entry:       header + "paths: {}\ncomponents:\n  schemas:\n    Node: {$ref: 'node.yaml'}\n",
+				"node.yaml": "type: object\nproperties:\n  children:\n    type: array\n    items: {$ref: 'node.yaml'}\n",
+			},
+			want: map[string]string{
+				"/components/schemas/Node/properties/children/items": "{$ref: '#/components/schemas/Node'}",
+			},
+		},
+		{
+			name: "mutually recursive files",
+			files: map[string]string{
+				entry:        header + "paths: {}\ncomponents:\n  schemas:\n    Pet: {$ref: 'pet.yaml'}\n",
+				"pet.yaml":   "type: object\nproperties:\n  owner: {$ref: 'owner.yaml'}\n",
+				"owner.yaml": "type: object\nproperties:\n  pets: {type: array, items: {$ref: 'pet.yaml'}}\n",
+			},
+			want: map[string]string{
+				"/components/schemas/Pet/properties/owner/properties/pets/items": "{$ref: '#/components/schemas/Pet'}",
+			},
+		},
+		{
+			name: "ref to the entry point stays local",
+			files: map[string]string{
+				entry:      header + "paths: {}\ncomponents:\n  schemas:\n    Id: {type: integer}\n    Pet: {$ref: 'pet.yaml'}\n",
+				"pet.yaml": "type: object\nproperties:\n  id: {$ref: 'openapi.yaml#/components/schemas/Id'}\n",
+			},
+			want: map[string]string{
+				"/components/schemas/Pet/properties/id": "{$ref: '#/components/schemas/Id'}",
+			},
+		},
+		{
+			name: "siblings of a ref are kept",
+			files: map[string]string{
+				entry:      header + "paths: {}\ncomponents:\n  schemas:\n    Pet: {$ref: 'pet.yaml', description: A pet}\n",
+				"pet.yaml": "type: object\n",
+			},
+			want: map[string]string{
+				"/components/schemas/Pet": "{type: object, description: A pet}",
+			},
+		},
+		{
+			name: "remote ref is kept",
+			files: map[string]string{
+				entry: header + "paths: {}\ncomponents:\n  schemas:\n    Pet: {$ref: 'https://example.com/pet.yaml'}\n",
+			},
+			want: map[string]string{
+				"/components/schemas/Pet": "{$ref: 'https://example.com/pet.yaml'}",
+			},
+		},
+		{
+			name: "refs pointing only at each other",
+			files: map[string]string{
+				entry:    header + "paths: {}\ncomponents:\n  schemas:\n    A: {$ref: 'a.yaml#/A'}\n",
+				"a.yaml": "A: {$ref: 'b.yaml#/B'}\n",
+				"b.yaml": "B: {$ref: 'a.yaml#/A'}\n",
+			},
+			cycle: true,
+		},
+		{
+			name: "ref pointing at itself",
+			files: map[string]string{
+				entry: header + "paths: {}\ncomponents:\n  schemas:\n    A: {$ref: '#/components/schemas/A'}\n",
+			},
+			cycle: true,
+		},
+		{
+			name: "ref outside the uploaded files",
+			files: map[string]string{
+				entry: header + "paths: {}\ncomponents:\n  schemas:\n    A: {$ref: '../secret.yaml'}\n",
+			},
+			wantErr: "outside the uploaded files",
+		},
+		{
+			name: "missing file",
+			files: map[string]string{
+				entry: header + "paths: {}\ncomponents:\n  schemas:\n    A: {$ref: 'missing.yaml'}\n",
+			},
+			wantErr: "missing.yaml not found",
+		},
+		{
+			name: "missing fragment",
+			files: map[string]string{
+				entry:      header + "paths: {}\ncomponents:\n  schemas:\n    A: {$ref: 'pet.yaml#/Pet'}\n",
+				"pet.yaml": "Dog: {type: object}\n",
+			},
+			wantErr: "not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string][]byte{}
+			for name, content := range tt.files {
+				files[name] = []byte(content)
+			}
+			out, err := Bundle(files, entry)
+			switch {
+			case tt.cycle:
+				if !errors.Is(err, ErrRefCycle) {
+					t.Fatalf("err = %v, want ErrRefCycle", err)
+				}
+				return
+			case tt.wantErr != "":
+				if err == nil || errors.Is(err, ErrRefCycle) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			case err != nil:
+				t.Fatal(err)
+			}
+			bundled, err := Parse(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for pointer, want := range tt.want {
+				var expected any
+				if err := yaml.Unmarshal([]byte(want), &expected); err != nil {
+					t.Fatalf("%s: bad expectation: %v", pointer, err)
+				}
+				value, ok := lookupPointer(bundled.Root, pointer)
+				if !ok || !reflect.DeepEqual(value, Normalize(expected)) {
+					t.Errorf("%s = %#v, want %#v", pointer, value, Normalize(expected))
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultEntry(t *testing.T) {
+	tests := []struct {
+		files []string
+		want  string
+		ok    bool
+	}{
+		{files: []string{"openapi.yaml", "schemas/pet.yaml"}, want: "openapi.yaml", ok: true},
+		{files: []string{"api.yml", "schemas/pet.yaml"}, want: "api.yml", ok: true},
+		{files: []string{"a.yaml", "b.yaml"}},
+		{files: []string{"schemas/pet.yaml"}},
+	}
+	for _, tt := range tests {
+		files := map[string][]byte{}
+		for _, name := range tt.files {
+			files[name] = []byte("type: object\n")
+		}
+		got, ok := DefaultEntry(files)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("DefaultEntry(%v) = %q, %v; want %q, %v", tt.files, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+// Collection is a document laid out as an API client workspace: one request
+// per operation with example values, grouped by tag, plus the servers to call.
+// Postman and Insomnia exports are rendered from it.
+type Collection struct {
+	ID          string // stable seed for the identifiers of the export
+	Name        string
+	Description string
+	Version     string
+	Folders     []CollectionFolder
+	Requests    []CollectionRequest // in operation order; Folder names their group
+	Servers     []CollectionServer
+}
+
+// CollectionFolder is a tag requests are grouped under.
+type CollectionFolder struct {
+	Name        string
+	Description string
+}
+
+// CollectionRequest is one operation as a client sends it.
+type CollectionRequest struct {
+	Key         string // Operation.Key
+	Name        string // summary, else operationId, else Key
+	Description string
+	Folder      string // first tag, empty when untagged
+	Method      string
+	Path        string // path template, e.g. /pets/{id}
+	PathParams  []CollectionParam
+	Query       []CollectionParam
+	Headers     []CollectionParam
+	BodyType    string            // media type of the body, empty when there is none
+	Body        string            // raw body text, unless Form is used
+	Form        []CollectionParam // fields of form-urlencoded and multipart bodies
+}
+
+// CollectionParam is a named value. Optional parameters are Disabled so the
+// client sends them only when switched on.
+type CollectionParam struct {
+	Name        string
+	Value       string
+	Description string
+	Disabled    bool
+	File        bool // multipart field carrying a file
+}
+
+// CollectionServer is a server URL with its variables at their defaults.
+type CollectionServer struct {
+	URL         string // may contain {variable} placeholders
+	Description string
+	Variables   []CollectionParam
+}
+
+// BaseURL returns the server URL with every variable at its default value.
+func (s CollectionServer) BaseURL() string {
+	url := s.URL
+	for _, v := range s.Variables {
+		url = strings.ReplaceAll(url, "{"+v.Name+"}", v.Value)
+	}
+	return url
+}
+
+var templateParam = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// Collection builds the client workspace of the document. Webhooks are not
+// requests a client sends and are left out.
+func (s *Spec) Collection(id string) *Collection {
+	info := asMap(s.Root["info"])
+	c := &Collection{ID: id}
+	c.Name, _ = info["title"].(string)
+	c.Description, _ = info["description"].(string)
+	if v, ok := info["version"]; ok {
+		c.Version = scalarString(v)
+	}
+	if c.Name == "" {
+		c.Name = id
+	}
+
+	folders := map[string]int{}
+	addFolder := func(name, description string) {
+		if _, ok := folders[name]; !ok {
+			folders[name] = len(c.Folders)
+			c.Folders = append(c.Folders, CollectionFolder{Name: name, Description: description})
+		}
+	}
+	for _, t := range asList(s.Root["tags"]) {
+		tag := asMap(t)
+		name, _ := tag["name"].(string)
+		description, _ := tag["description"].(string)
+		if name != "" {
+			addFolder(name, description)
+		}
+	}
+
+	used := map[string]bool{}
+	for _, op := range s.Operations() {
+		if op.Webhook {
+			continue
+		}
+		r := s.collectionRequest(op)
+		if r.Folder != "" {
+			addFolder(r.Folder, "")
+			used[r.Folder] = true
+		}
+		c.Requests = append(c.Requests, r)
+	}
+	// Declared tags no operation uses would export as empty folders.
+	kept := c.Folders[:0]
+	for _, f := range c.Folders {
+		if used[f.Name] {
+			kept = append(kept, f)
+		}
+	}
+	c.Folders = kept
+
+	servers := asList(s.Root["servers"])
+	if s.IsSwagger2() {
+		servers = convertServers(s.Root)
+	}
+	for _, sv := range servers {
+		server := asMap(sv)
+		url, _ := server["url"].(string)
+		if url == "" {
+			continue
+		}
+		cs := CollectionServer{URL: strings.TrimSuffix(url, "/")}
+		cs.Description, _ = server["description"].(string)
+		vars := asMap(server["variables"])
+		for _, name := range SortedKeys(vars) {
+			v := asMap(vars[name])
+			p := CollectionParam{Name: name, Value: scalarString(v["default"])}
+			p.Description, _ = v["description"].(string)
+			if enum := stringList(v["enum"]); len(enum) > 0 {
+				p.Description = strings.TrimSpace(p.Description + " (one of: " + strings.Join(enum, ", ") + ")")
+			}
+			cs.Variables = append(cs.Variables, p)
+		}
+		c.Servers = append(c.Servers, cs)
+	}
+	return c
+}
+
+func (s *Spec) collectionRequest(op Operation) CollectionRequest {
+	r := CollectionRequest{Key: op.Key(), Method: op.Method, Path: op.Path}
+	r.Name, _ = op.Node["summary"].(string)
+	if r.Name == "" {
+		r.Name, _ = op.Node["operationId"].(string)
+	}
+	if r.Name == "" {
+		r.Name = r.Key
+	}
+	r.Description, _ = op.Node["description"].(string)
+	if tags := stringList(op.Node["tags"]); len(tags) > 0 {
+		r.Folder = tags[0]
+	}
+
+	params := s.Parameters(op)
+	var cookies []string
+	for _, key := range SortedKeys(params) {
+		p := params[key]
+		in, _ := p["in"].(string)
+		cp := CollectionParam{Value: s.parameterExample(p)}
+		cp.Name, _ = p["name"].(string)
+		cp.Description, _ = p["description"].(string)
+		cp.Disabled = !isTrue(p["required"])
+		switch in {
+		case "path":
+			cp.Disabled = false
+			r.PathParams = append(r.PathParams, cp)
+		case "query":
+			r.Query = append(r.Query, cp)
+		case "header":
+			// OpenAPI ignores these as parameters; the client derives them.
+			switch strings.ToLower(cp.Name) {
+			case "accept", "content-type", "authorization":
+				continue
+			}
+			r.Headers = append(r.Headers, cp)
+		case "cookie":
+			if !cp.Disabled {
+				cookies = append(cookies, cp.Name+"="+cp.Value)
+			}
+		}
+	}
+	if len(cookies) > 0 {
+		r.Headers = append(r.Headers, CollectionParam{Name: "Cookie", Value: strings.Join(cookies, "; ")})
+	}
+	// Path parameters follow their order in the template.
+	ordered := make([]CollectionParam, 0, len(r.PathParams))
+	for _, m := range templateParam.FindAllStringSubmatch(op.Path, -1) {
+		for _, p := range r.PathParams {
+			if p.Name == m[1] {
+				ordered = append(ordered, p)
+			}
+		}
+	}
+	r.PathParams = ordered
+
+	content := asMap(s.RequestBody(op)["content"])
+	if len(content) == 0 {
+		return r
+	}
+	r.BodyType = requestMediaType(content)
+	media := asMap(content[r.BodyType])
+	example := s.mediaExample(media, "", true)
+	if r.BodyType != "multipart/form-data" {
+		// Clients set multipart headers themselves, with the boundary.
+		r.Headers = append(r.Headers, CollectionParam{Name: "Content-Type", Value: r.BodyType})
+	}
+	switch {
+	case r.BodyType == "application/x-www-form-urlencoded" || r.BodyType == "multipart/form-data":
+		props := asMap(asMap(s.Resolve(media["schema"]))["properties"])
+		values := asMap(example)
+		for _, name := range SortedKeys(values) {
+			field := CollectionParam{Name: name, Value: scalarString(values[name])}
+			prop := asMap(s.Resolve(props[name]))
+			field.Description, _ = prop["description"].(string)
+			if r.BodyType == "multipart/form-data" && (prop["format"] == "binary" || prop["type"] == "file") {
+				field.File = true
+				field.Value = ""
+			}
+			r.Form = append(r.Form, field)
+		}
+	case isJSONMedia(r.BodyType):
+		if example != nil {
+			r.Body = indentedJSON(example)
+		}
+	default:
+		if example != nil {
+			r.Body = scalarString(example)
+		}
+	}
+	return r
+}
+
+// parameterExample returns a parameter's example value, or one synthesised
+// from its schema. Arrays are sent comma-separated.
+func (s *Spec) parameterExample(p map[string]any) string {
+	v, ok := p["example"]
+	if !ok {
+		if examples := asMap(p["examples"]); len(examples) > 0 {
+			v, ok = asMap(s.Resolve(examples[SortedKeys(examples)[0]]))["value"]
+		}
+	}
+	if !ok {
+		for _, media := range asMap(p["content"]) {
+			return scalarString(s.mediaExample(asMap(media), "", true))
+		}
+		v = s.RequestExample(s.parameterSchema(p))
+	}
+	if list, isList := v.([]any); isList {
+		parts := make([]string, len(list))
+		for i, item := range list {
+			parts[i] = scalarString(item)
+		}
+		return strings.Join(parts, ",")
+	}
+	return scalarString(v)
+}
+
+// requestMediaType picks the body media type a client most likely sends:
+// JSON, then forms, then the first declared.
+func requestMediaType(content map[string]any) string {
+	keys := SortedKeys(content)
+	for _, k := range keys {
+		if isJSONMedia(k) {
+			return k
+		}
+	}
+	for _, want := range []string{"application/x-www-form-urlencoded", "multipart/form-data"} {
+		if _, ok := content[want]; ok {
+			return want
+		}
+	}
+	return keys[0]
+}
+
+func isJSONMedia(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// indentedJSON encodes v for a request body editor.
+func indentedJSON(v any) string {
+	encoded, err := exportJSON(v)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// exportJSON encodes v indented, leaving <, > and & unescaped since the
+// result is read by API clients rather than embedded in HTML.
+func exportJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
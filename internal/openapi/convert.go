@@ -0,0 +1,440 @@
+package openapi
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConvertedVersion is the openapi field written by ConvertToOAS3.
+const ConvertedVersion = "3.0.3"
+
+// Swagger 2 parameter fields that move into the OpenAPI 3 parameter schema.
+var parameterSchemaFields = []string{
+	"type", "format", "items", "default", "enum", "maximum", "exclusiveMaximum",
+	"minimum", "exclusiveMinimum", "maxLength", "minLength", "pattern",
+	"maxItems", "minItems", "uniqueItems", "multipleOf",
+}
+
+// Swagger 2 local refs and where OpenAPI 3 keeps the same definitions.
+var refPrefixes = [][2]string{
+	{"#/definitions/", "#/components/schemas/"},
+	{"#/parameters/", "#/components/parameters/"},
+	{"#/responses/", "#/components/responses/"},
+}
+
+// ConvertToOAS3 returns an OpenAPI 3.0 equivalent of a Swagger 2.0 document.
+// Other documents are returned unchanged. The input is never modified.
+func ConvertToOAS3(s *Spec) *Spec {
+	if !s.IsSwagger2() {
+		return s
+	}
+	src := &Spec{Root: deepCopy(s.Root).(map[string]any)}
+
+	root := map[string]any{"openapi": ConvertedVersion}
+	for _, key := range []string{"info", "tags", "externalDocs", "security"} {
+		if v, ok := src.Root[key]; ok {
+			root[key] = v
+		}
+	}
+	for k, v := range src.Root {
+		if strings.HasPrefix(k, "x-") {
+			root[k] = v
+		}
+	}
+	if servers := convertServers(src.Root); len(servers) > 0 {
+		root["servers"] = servers
+	}
+
+	produces := stringList(src.Root["produces"])
+	paths := map[string]any{}
+	for path, raw := range asMap(src.Root["paths"]) {
+		item, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		paths[path] = convertPathItem(src, path, item, produces)
+	}
+	root["paths"] = paths
+
+	components := map[string]any{}
+	if defs := asMap(src.Root["definitions"]); len(defs) > 0 {
+		schemas := map[string]any{}
+		for name, schema := range defs {
+			schemas[name] = convertSchema(schema)
+		}
+		components["schemas"] = schemas
+	}
+	if params := asMap(src.Root["parameters"]); len(params) > 0 {
+		out := map[string]any{}
+		for name, p := range params {
+			// Body and form parameters were inlined into request bodies
+			if m, ok := p.(map[string]any); ok && m["in"] != "body" && m["in"] != "formData" {
+				out[name] = convertParameter(m)
+			}
+		}
+		if len(out) > 0 {
+			components["parameters"] = out
+		}
+	}
+	if responses := asMap(src.Root["responses"]); len(responses) > 0 {
+		out := map[string]any{}
+		for name, r := range responses {
+			out[name] = convertResponse(r, produces)
+		}
+		components["responses"] = out
+	}
+	if defs := asMap(src.Root["securityDefinitions"]); len(defs) > 0 {
+		out := map[string]any{}
+		for name, d := range defs {
+			if m, ok := d.(map[string]any); ok {
+				out[name] = convertSecurityScheme(m)
+			}
+		}
+		components["securitySchemes"] = out
+	}
+	if len(components) > 0 {
+		root["components"] = components
+	}
+	rewriteRefs(root)
+	return &Spec{Root: root}
+}
+
+// MatchLayouts converts a Swagger 2 side to OpenAPI 3 when the other side is
+// already OpenAPI 3, so a document that migrated between versions compares by
+// meaning rather than by layout.
+func MatchLayouts(from, to *Spec) (*Spec, *Spec) {
+	if from.IsSwagger2() != to.IsSwagger2() {
+		return ConvertToOAS3(from), ConvertToOAS3(to)
+	}
+	return from, to
+}
+
+// YAML encodes the document.
+func (s *Spec) YAML() ([]byte, error) {
+	return yaml.Marshal(s.Root)
+}
+
+func convertServers(root map[string]any) []any {
+	host, _ := root["host"].(string)
+	basePath, _ := root["basePath"].(string)
+	if host == "" {
+		if basePath == "" {
+			return nil
+		}
+		return []any{map[string]any{"url": basePath}}
+	}
+	schemes := stringList(root["schemes"])
+	if len(schemes) == 0 {
+		schemes = []string{"https"}
+	}
+	var servers []any
+	for _, scheme := range schemes {
+		servers = append(servers, map[string]any{"url": scheme + "://" + host + basePath})
+	}
+	return servers
+}
+
+func convertPathItem(src *Spec, path string, item map[string]any, produces []string) map[string]any {
+	out := map[string]any{}
+	for k, v := range item {
+		switch {
+		case k == "parameters":
+			if params := convertParameterList(src, v); len(params) > 0 {
+				out[k] = params
+			}
+		case methodIndex(k) < len(HTTPMethods):
+			node, ok := v.(map[string]any)
+			if !ok {
+				continue
+			}
+			op := Operation{Method: strings.ToUpper(k), Path: path, Node: node, PathItem: item}
+			out[k] = convertOperation(src, op, produces)
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func convertOperation(src *Spec, op Operation, produces []string) map[string]any {
+	out := map[string]any{}
+	for k, v := range op.Node {
+		switch k {
+		case "consumes", "produces", "schemes", "parameters", "responses":
+		default:
+			out[k] = v
+		}
+	}
+	if params := convertParameterList(src, op.Node["parameters"]); len(params) > 0 {
+		out["parameters"] = params
+	}
+	if body := src.RequestBody(op); body != nil {
+		for _, media := range asMap(body["content"]) {
+			if m, ok := media.(map[string]any); ok {
+				m["schema"] = convertSchema(m["schema"])
+			}
+		}
+		out["requestBody"] = body
+	}
+	if p := stringList(op.Node["produces"]); len(p) > 0 {
+		produces = p
+	}
+	responses := map[string]any{}
+	for code, r := range asMap(op.Node["responses"]) {
+		responses[code] = convertResponse(r, produces)
+	}
+	out["responses"] = responses
+	return out
+}
+
+// convertParameterList keeps the non-body parameters of a list; body and
+// formData parameters end up in the request body.
+func convertParameterList(src *Spec, v any) []any {
+	var out []any
+	for _, item := range asList(v) {
+		p, ok := src.Resolve(item).(map[string]any)
+		if !ok || p["in"] == "body" || p["in"] == "formData" {
+			continue
+		}
+		if m, ok := item.(map[string]any); ok && m["$ref"] != nil {
+			out = append(out, item)
+			continue
+		}
+		out = append(out, convertParameter(p))
+	}
+	return out
+}
+
+func convertParameter(p map[string]any) map[string]any {
+	if _, ok := p["$ref"]; ok {
+		return p
+	}
+	out := map[string]any{}
+	schema := map[string]any{}
+	for k, v := range p {
+		switch k {
+		case "collectionFormat", "allowEmptyValue":
+		default:
+			out[k] = v
+		}
+	}
+	for _, k := range parameterSchemaFields {
+		if v, ok := p[k]; ok {
+			schema[k] = v
+			delete(out, k)
+		}
+	}
+	if len(schema) > 0 {
+		out["schema"] = convertSchema(schema)
+	}
+	switch p["collectionFormat"] {
+	case "csv":
+		out["style"], out["explode"] = "form", false
+		if p["in"] == "path" || p["in"] == "header" {
+			out["style"] = "simple"
+			delete(out, "explode")
+		}
+	case "ssv":
+		out["style"] = "spaceDelimited"
+	case "pipes":
+		out["style"] = "pipeDelimited"
+	case "multi":
+		out["style"], out["explode"] = "form", true
+	}
+	if allow, ok := p["allowEmptyValue"].(bool); ok && p["in"] == "query" {
+		out["allowEmptyValue"] = allow
+	}
+	return out
+}
+
+func convertResponse(r any, produces []string) any {
+	m, ok := r.(map[string]any)
+	if !ok {
+		return r
+	}
+	if _, ok := m["$ref"]; ok {
+		return m
+	}
+	out := map[string]any{}
+	for k, v := range m {
+		switch k {
+		case "schema", "examples", "headers":
+		default:
+			out[k] = v
+		}
+	}
+	if _, ok := out["description"]; !ok {
+		out["description"] = ""
+	}
+	if headers := asMap(m["headers"]); len(headers) > 0 {
+		converted := map[string]any{}
+		for name, h := range headers {
+			if hm, ok := h.(map[string]any); ok {
+				header := convertParameter(hm)
+				delete(header, "style")
+				delete(header, "explode")
+				converted[name] = header
+			}
+		}
+		out["headers"] = converted
+	}
+	examples := asMap(m["examples"])
+	if schema, ok := m["schema"]; ok {
+		if len(produces) == 0 {
+			produces = []string{"application/json"}
+		}
+		content := map[string]any{}
+		for _, ct := range produces {
+			media := map[string]any{"schema": convertSchema(schema)}
+			if ex, ok := examples[ct]; ok {
+				media["example"] = ex
+			}
+			content[ct] = media
+		}
+		out["content"] = content
+	}
+	return out
+}
+
+func convertSecurityScheme(d map[string]any) map[string]any {
+	out := map[string]any{}
+	if desc, ok := d["description"]; ok {
+		out["description"] = desc
+	}
+	switch d["type"] {
+	case "basic":
+		out["type"], out["scheme"] = "http", "basic"
+	case "apiKey":
+		out["type"], out["name"], out["in"] = "apiKey", d["name"], d["in"]
+	case "oauth2":
+		flow := map[string]any{"scopes": asMap(d["scopes"])}
+		if u, ok := d["authorizationUrl"]; ok {
+			flow["authorizationUrl"] = u
+		}
+		if u, ok := d["tokenUrl"]; ok {
+			flow["tokenUrl"] = u
+		}
+		name := map[any]string{
+			"implicit":    "implicit",
+			"password":    "password",
+			"application": "clientCredentials",
+			"accessCode":  "authorizationCode",
+		}[d["flow"]]
+		out["type"] = "oauth2"
+		if name != "" {
+			out["flows"] = map[string]any{name: flow}
+		}
+	default:
+		for k, v := range d {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// convertSchema rewrites the Swagger 2 only schema keywords of a schema tree.
+func convertSchema(v any) any {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return v
+	}
+	out := map[string]any{}
+	for k, val := range m {
+		switch k {
+		case "x-nullable":
+			if b, ok := val.(bool); ok {
+				out["nullable"] = b
+				continue
+			}
+			out[k] = val
+		case "discriminator":
+			if name, ok := val.(string); ok {
+				out[k] = map[string]any{"propertyName": name}
+				continue
+			}
+			out[k] = val
+		case "properties", "patternProperties":
+			props := map[string]any{}
+			for name, p := range asMap(val) {
+				props[name] = convertSchema(p)
+			}
+			out[k] = props
+		case "items", "additionalProperties", "not":
+			if list, ok := val.([]any); ok {
+				converted := make([]any, len(list))
+				for i, item := range list {
+					converted[i] = convertSchema(item)
+				}
+				out[k] = converted
+				continue
+			}
+			out[k] = convertSchema(val)
+		case "allOf", "anyOf", "oneOf":
+			list := asList(val)
+			converted := make([]any, len(list))
+			for i, item := range list {
+				converted[i] = convertSchema(item)
+			}
+			out[k] = converted
+		default:
+			out[k] = val
+		}
+	}
+	if out["type"] == "file" {
+		out["type"], out["format"] = "string", "binary"
+	}
+	return out
+}
+
+// rewriteRefs points Swagger 2 local refs at their OpenAPI 3 locations.
+func rewriteRefs(v any) {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if ref, ok := val.(string); ok && k == "$ref" {
+				for _, p := range refPrefixes {
+					if strings.HasPrefix(ref, p[0]) {
+						t[k] = p[1] + strings.TrimPrefix(ref, p[0])
+						break
+					}
+				}
+				continue
+			}
+			rewriteRefs(val)
+		}
+	case []any:
+		for _, item := range t {
+			rewriteRefs(item)
+		}
+	}
+}
+
+func deepCopy(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			out[k] = deepCopy(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, val := range t {
+			out[i] = deepCopy(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func asMap(v any) map[string]any {
+	m, _ := v.(map[string]any)
+	return m
+}
+
+func asList(v any) []any {
+	l, _ := v.([]any)
+	return l
+}
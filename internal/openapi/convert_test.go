@@ -0,0 +1,232 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestConvertToOAS3(t *testing.T) {
+	tests := []struct {
+		name    string
+		swagger string
+		want    map[string]string // JSON pointer -> expected value as YAML, "" = absent
+	}{
+		{
+			name:    "servers from host, basePath and schemes",
+			swagger: "host: api.example.com\nbasePath: /v1\nschemes: [http, https]\npaths: {}\n",
+			want: map[string]string{
+				"/servers": "[{url: 'http://api.example.com/v1'}, {url: 'https://api.example.com/v1'}]",
+				"/host":    "",
+			},
+		},
+		{
+			name:    "basePath only",
+			swagger: "basePath: /v1\npaths: {}\n",
+			want:    map[string]string{"/servers": "[{url: /v1}]"},
+		},
+		{
+			name: "definitions move to components and refs follow",
+			swagger: `
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          description: ok
+          schema: {$ref: '#/definitions/Pet'}
+definitions:
+  Pet:
+    type: object
+    discriminator: kind
+    properties:
+      tag: {type: string, x-nullable: true}
+`,
+			want: map[string]string{
+				"/paths/~1pets/get/responses/200/content/application~1json/schema": "{$ref: '#/components/schemas/Pet'}",
+				"/components/schemas/Pet/discriminator":                            "{propertyName: kind}",
+				"/components/schemas/Pet/properties/tag":                           "{type: string, nullable: true}",
+				"/definitions":                                                     "",
+			},
+		},
+		{
+			name: "produces becomes response content",
+			swagger: `
+produces: [application/json, application/xml]
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          description: ok
+          schema: {type: string}
+          examples: {application/json: rex}
+        '404': {description: missing}
+`,
+			want: map[string]string{
+				"/paths/~1pets/get/responses/200/content": "{application/json: {schema: {type: string}, example: rex}, application/xml: {schema: {type: string}}}",
+				"/paths/~1pets/get/responses/404":         "{description: missing}",
+			},
+		},
+		{
+			name: "body parameter becomes requestBody",
+			swagger: `
+consumes: [application/json]
+paths:
+  /pets:
+    post:
+      parameters:
+        - {name: pet, in: body, required: true, schema: {type: object}}
+        - {name: dry, in: query, type: boolean}
+      responses:
+        '201': {description: created}
+`,
+			want: map[string]string{
+				"/paths/~1pets/post/requestBody": "{required: true, content: {application/json: {schema: {type: object}}}}",
+				"/paths/~1pets/post/parameters":  "[{name: dry, in: query, schema: {type: boolean}}]",
+			},
+		},
+		{
+			name: "form parameters and files",
+			swagger: `
+paths:
+  /pets/{id}/photo:
+    post:
+      consumes: [multipart/form-data]
+      parameters:
+        - {name: id, in: path, required: true, type: integer}
+        - {name: file, in: formData, required: true, type: file}
+      responses:
+        '204': {description: stored}
+`,
+			want: map[string]string{
+				"/paths/~1pets~1{id}~1photo/post/requestBody/content/multipart~1form-data/schema/properties/file": "{type: string, format: binary}",
+				"/paths/~1pets~1{id}~1photo/post/requestBody/content/multipart~1form-data/schema/required":        "[file]",
+				"/paths/~1pets~1{id}~1photo/post/parameters":                                                      "[{name: id, in: path, required: true, schema: {type: integer}}]",
+			},
+		},
+		{
+			name: "collection formats",
+			swagger: `
+paths:
+  /pets:
+    get:
+      parameters:
+        - {name: tags, in: query, type: array, items: {type: string}, collectionFormat: multi}
+        - {name: ids, in: header, type: array, items: {type: integer}, collectionFormat: csv}
+        - {name: q, in: query, type: string, allowEmptyValue: true}
+      responses:
+        '200': {description: ok}
+`,
+			want: map[string]string{
+				"/paths/~1pets/get/parameters": `
+- {name: tags, in: query, style: form, explode: true, schema: {type: array, items: {type: string}}}
+- {name: ids, in: header, style: simple, schema: {type: array, items: {type: integer}}}
+- {name: q, in: query, allowEmptyValue: true, schema: {type: string}}
+`,
+			},
+		},
+		{
+			name: "shared parameters and responses",
+			swagger: `
+paths:
+  /pets:
+    get:
+      parameters:
+        - $ref: '#/parameters/limit'
+      responses:
+        default: {$ref: '#/responses/Error'}
+parameters:
+  limit: {name: limit, in: query, type: integer}
+  pet: {name: pet, in: body, schema: {type: object}}
+responses:
+  Error: {description: error}
+`,
+			want: map[string]string{
+				"/paths/~1pets/get/parameters":            "[{$ref: '#/components/parameters/limit'}]",
+				"/paths/~1pets/get/responses/default":     "{$ref: '#/components/responses/Error'}",
+				"/components/parameters":                  "{limit: {name: limit, in: query, schema: {type: integer}}}",
+				"/components/responses/Error/description": "error",
+			},
+		},
+		{
+			name: "security definitions",
+			swagger: `
+paths: {}
+securityDefinitions:
+  basic: {type: basic}
+  key: {type: apiKey, name: X-Key, in: header}
+  oauth:
+    type: oauth2
+    flow: accessCode
+    authorizationUrl: https://auth.example.com/authorize
+    tokenUrl: https://auth.example.com/token
+    scopes: {read: Read pets}
+`,
+			want: map[string]string{
+				"/components/securitySchemes/basic": "{type: http, scheme: basic}",
+				"/components/securitySchemes/key":   "{type: apiKey, name: X-Key, in: header}",
+				"/components/securitySchemes/oauth": `
+type: oauth2
+flows:
+  authorizationCode:
+    authorizationUrl: https://auth.example.com/authorize
+    tokenUrl: https://auth.example.com/token
+    scopes: {read: Read pets}
+`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := Parse([]byte("swagger: '2.0'\ninfo: {title: Pets, version: '1'}\n" + tt.swagger))
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := deepCopy(src.Root)
+			got := ConvertToOAS3(src)
+			if !reflect.DeepEqual(src.Root, before) {
+				t.Error("the input document was modified")
+			}
+			if got.Version() != ConvertedVersion {
+				t.Errorf("openapi = %q, want %q", got.Version(), ConvertedVersion)
+			}
+			for pointer, want := range tt.want {
+				value, ok := lookupPointer(got.Root, pointer)
+				if want == "" {
+					if ok {
+						t.Errorf("%s = %v, want it absent", pointer, value)
+					}
+					continue
+				}
+				var expected any
+				if err := yaml.Unmarshal([]byte(want), &expected); err != nil {
+					t.Fatalf("%s: bad expectation: %v", pointer, err)
+				}
+				if !ok || !reflect.DeepEqual(value, Normalize(expected)) {
+					t.Errorf("%s = %#v, want %#v", pointer, value, Normalize(expected))
+				}
+			}
+
+			// The conversion must be a valid OpenAPI 3.0 document
+			content, err := got.YAML()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if problems := Validate(content); len(problems) > 0 {
+				t.Errorf("converted document is invalid: %v", problems)
+			}
+		})
+	}
+}
+
+func TestConvertToOAS3LeavesOAS3Alone(t *testing.T) {
+	spec, err := Parse([]byte("openapi: 3.0.3\ninfo: {title: Pets, version: '1'}\npaths: {}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ConvertToOAS3(spec) != spec {
+		t.Error("an OpenAPI 3 document was converted")
+	}
+}
@@ -0,0 +1,304 @@
+package openapi
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// maxFieldChanges caps the field-level details reported per modified item.
+const maxFieldChanges = 50
+
+// FieldChange is one leaf-level difference inside a modified item.
+type FieldChange struct {
+	Field string `json:"field"` // dotted path inside the item, e.g. schema.properties.name.type
+	From  any    `json:"from,omitempty"`
+	To    any    `json:"to,omitempty"`
+}
+
+// Change describes one added, removed or modified item.
+type Change struct {
+	Location  string        `json:"location"`            // e.g. "GET /pets", "GET /pets query:limit", "#/components/schemas/Pet"
+	Operation string        `json:"operation,omitempty"` // owning operation, if any
+	Name      string        `json:"name,omitempty"`      // parameter key, status code, media type or schema name
+	Fields    []FieldChange `json:"fields,omitempty"`    // only for modified items
+}
+
+// ChangeSet groups the changes of one kind of item.
+type ChangeSet struct {
+	Added    []Change `json:"added"`
+	Removed  []Change `json:"removed"`
+	Modified []Change `json:"modified"`
+}
+
+// Empty reports whether the set holds no changes.
+func (c *ChangeSet) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+// Count returns the number of changes in the set.
+func (c *ChangeSet) Count() int {
+	return len(c.Added) + len(c.Removed) + len(c.Modified)
+}
+
+// Diff is the semantic difference between two specs.
+type Diff struct {
+	Paths         ChangeSet `json:"paths"`
+	Webhooks      ChangeSet `json:"webhooks"`
+	Operations    ChangeSet `json:"operations"`
+	Parameters    ChangeSet `json:"parameters"`
+	RequestBodies ChangeSet `json:"request_bodies"`
+	Responses     ChangeSet `json:"responses"`
+	Schemas       ChangeSet `json:"schemas"`
+}
+
+// Sections returns the change sets with their display names in report order.
+func (d *Diff) Sections() []struct {
+	Name string
+	Set  *ChangeSet
+} {
+	return []struct {
+		Name string
+		Set  *ChangeSet
+	}{
+		{"Paths", &d.Paths},
+		{"Webhooks", &d.Webhooks},
+		{"Operations", &d.Operations},
+		{"Parameters", &d.Parameters},
+		{"Request bodies", &d.RequestBodies},
+		{"Responses", &d.Responses},
+		{"Schemas", &d.Schemas},
+	}
+}
+
+// Empty reports whether the specs are semantically identical.
+func (d *Diff) Empty() bool {
+	for _, s := range d.Sections() {
+		if !s.Set.Empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare computes the semantic diff from one spec to another.
+func Compare(from, to *Spec) *Diff {
+	from, to = MatchLayouts(from, to)
+	d := &Diff{}
+
+	compareNames(&d.Paths, from.PathItems(), to.PathItems())
+	compareNames(&d.Webhooks, from.Webhooks(), to.Webhooks())
+
+	fromOps, toOps := indexOperations(from), indexOperations(to)
+	for _, key := range orderedUnion(fromOps, toOps) {
+		a, inFrom := fromOps[key]
+		b, inTo := toOps[key]
+		switch {
+		case !inFrom:
+			d.Operations.Added = append(d.Operations.Added, Change{Location: key, Operation: key})
+		case !inTo:
+			d.Operations.Removed = append(d.Operations.Removed, Change{Location: key, Operation: key})
+		default:
+			compareOperation(d, key, from, a, to, b)
+		}
+	}
+
+	compareMaps(&d.Schemas, "", from.Schemas(), to.Schemas(), func(name string) string {
+		if to.IsSwagger2() {
+			return "#/definitions/" + name
+		}
+		return "#/components/schemas/" + name
+	})
+
+	// Empty lists instead of null in JSON output
+	for _, s := range d.Sections() {
+		if s.Set.Added == nil {
+			s.Set.Added = []Change{}
+		}
+		if s.Set.Removed == nil {
+			s.Set.Removed = []Change{}
+		}
+		if s.Set.Modified == nil {
+			s.Set.Modified = []Change{}
+		}
+	}
+	return d
+}
+
+// compareNames records the keys added to or removed from a map of path items.
+func compareNames(set *ChangeSet, a, b map[string]map[string]any) {
+	for _, name := range SortedKeys(b) {
+		if _, ok := a[name]; !ok {
+			set.Added = append(set.Added, Change{Location: name})
+		}
+	}
+	for _, name := range SortedKeys(a) {
+		if _, ok := b[name]; !ok {
+			set.Removed = append(set.Removed, Change{Location: name})
+		}
+	}
+}
+
+func indexOperations(s *Spec) map[string]Operation {
+	out := map[string]Operation{}
+	for _, op := range s.Operations() {
+		out[op.Key()] = op
+	}
+	return out
+}
+
+// orderedUnion returns the keys of both maps, ordered by path then method.
+func orderedUnion(a, b map[string]Operation) []string {
+	var ops []Operation
+	for _, op := range a {
+		ops = append(ops, op)
+	}
+	for k, op := range b {
+		if _, ok := a[k]; !ok {
+			ops = append(ops, op)
+		}
+	}
+	sortOperations(ops)
+	keys := make([]string, len(ops))
+	for i, op := range ops {
+		keys[i] = op.Key()
+	}
+	return keys
+}
+
+func compareOperation(d *Diff, key string, fromSpec *Spec, a Operation, toSpec *Spec, b Operation) {
+	// Operation-level metadata (everything except the parts diffed separately)
+	meta := func(op Operation) map[string]any {
+		m := map[string]any{}
+		for k, v := range op.Node {
+			switch k {
+			case "parameters", "requestBody", "responses", "consumes", "produces":
+				continue
+			}
+			m[k] = v
+		}
+		return m
+	}
+	if fields := FieldDiff(meta(a), meta(b)); len(fields) > 0 {
+		d.Operations.Modified = append(d.Operations.Modified, Change{Location: key, Operation: key, Fields: fields})
+	}
+
+	compareMaps(&d.Parameters, key, toAnyMap(fromSpec.Parameters(a)), toAnyMap(toSpec.Parameters(b)), func(name string) string {
+		return key + " " + name
+	})
+
+	bodyA, bodyB := fromSpec.RequestBody(a), toSpec.RequestBody(b)
+	switch {
+	case bodyA == nil && bodyB != nil:
+		d.RequestBodies.Added = append(d.RequestBodies.Added, Change{Location: key + " requestBody", Operation: key})
+	case bodyA != nil && bodyB == nil:
+		d.RequestBodies.Removed = append(d.RequestBodies.Removed, Change{Location: key + " requestBody", Operation: key})
+	case bodyA != nil && bodyB != nil:
+		if fields := FieldDiff(bodyA, bodyB); len(fields) > 0 {
+			d.RequestBodies.Modified = append(d.RequestBodies.Modified, Change{Location: key + " requestBody", Operation: key, Fields: fields})
+		}
+	}
+
+	compareMaps(&d.Responses, key, toAnyMap(fromSpec.Responses(a)), toAnyMap(toSpec.Responses(b)), func(code string) string {
+		return key + " " + code
+	})
+}
+
+// compareMaps fills set with added/removed/modified entries of two named maps.
+func compareMaps(set *ChangeSet, operation string, a, b map[string]any, location func(string) string) {
+	for _, name := range SortedKeys(b) {
+		if _, ok := a[name]; !ok {
+			set.Added = append(set.Added, Change{Location: location(name), Operation: operation, Name: name})
+		}
+	}
+	for _, name := range SortedKeys(a) {
+		bv, ok := b[name]
+		if !ok {
+			set.Removed = append(set.Removed, Change{Location: location(name), Operation: operation, Name: name})
+			continue
+		}
+		if fields := FieldDiff(a[name], bv); len(fields) > 0 {
+			set.Modified = append(set.Modified, Change{Location: location(name), Operation: operation, Name: name, Fields: fields})
+		}
+	}
+}
+
+func toAnyMap(m map[string]map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// FieldDiff lists the leaf differences between two JSON-like values.
+func FieldDiff(a, b any) []FieldChange {
+	var out []FieldChange
+	fieldDiff("", a, b, &out)
+	return out
+}
+
+func fieldDiff(path string, a, b any, out *[]FieldChange) {
+	if len(*out) >= maxFieldChanges {
+		return
+	}
+	am, aIsMap := a.(map[string]any)
+	bm, bIsMap := b.(map[string]any)
+	if aIsMap && bIsMap {
+		keys := map[string]bool{}
+		for k := range am {
+			keys[k] = true
+		}
+		for k := range bm {
+			keys[k] = true
+		}
+		for _, k := range SortedKeys(keys) {
+			av, aok := am[k]
+			bv, bok := bm[k]
+			child := joinField(path, k)
+			switch {
+			case !aok:
+				*out = append(*out, FieldChange{Field: child, To: bv})
+			case !bok:
+				*out = append(*out, FieldChange{Field: child, From: av})
+			default:
+				fieldDiff(child, av, bv, out)
+			}
+			if len(*out) >= maxFieldChanges {
+				return
+			}
+		}
+		return
+	}
+	al, aIsList := a.([]any)
+	bl, bIsList := b.([]any)
+	if aIsList && bIsList && len(al) == len(bl) && !scalarList(al) {
+		for i := range al {
+			fieldDiff(fmt.Sprintf("%s[%d]", path, i), al[i], bl[i], out)
+		}
+		return
+	}
+	if !reflect.DeepEqual(a, b) {
+		*out = append(*out, FieldChange{Field: path, From: a, To: b})
+	}
+}
+
+func joinField(path, key string) string {
+	if path == "" {
+		return key
+	}
+	if strings.ContainsAny(key, "./") {
+		return path + "[" + key + "]"
+	}
+	return path + "." + key
+}
+
+func scalarList(l []any) bool {
+	for _, v := range l {
+		switch v.(type) {
+		case map[string]any, []any:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,219 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const diffFrom = `
+openapi: 3.0.3
+info: {title: Pets, version: '1'}
+paths:
+  /pets:
+    get:
+      summary: List pets
+      parameters:
+        - {name: limit, in: query, schema: {type: integer}}
+        - {name: X-Trace, in: header, schema: {type: string}}
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/Pet'}
+      responses:
+        '201': {description: created}
+  /pets/{id}:
+    delete:
+      parameters: [{name: id, in: path, required: true, schema: {type: string}}]
+      responses:
+        '204': {description: deleted}
+components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name: {type: string}
+    Error: {type: object}
+`
+
+// diffTo is diffFrom changed in every section, written as JSON.
+const diffTo = `{
+  "openapi": "3.0.3",
+  "info": {"title": "Pets", "version": "2"},
+  "paths": {
+    "/pets": {
+      "get": {
+        "summary": "List all pets",
+        "parameters": [
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "maximum": 100}},
+          {"name": "offset", "in": "query", "schema": {"type": "integer"}}
+        ],
+        "responses": {
+          "200": {"description": "ok", "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Pet"}}}},
+          "400": {"description": "bad request"}
+        }
+      },
+      "post": {
+        "responses": {"201": {"description": "created"}}
+      },
+      "put": {
+        "requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Pet"}}}},
+        "responses": {"200": {"description": "ok"}}
+      }
+    },
+    "/owners": {
+      "get": {"responses": {"200": {"description": "ok"}}}
+    }
+  },
+  "components": {
+    "schemas": {
+      "Pet": {"type": "object", "required": ["name"], "properties": {"name": {"type": "string", "maxLength": 50}}},
+      "Owner": {"type": "object"}
+    }
+  }
+}`
+
+func TestCompare(t *testing.T) {
+	from, err := Parse([]byte(diffFrom))
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := Parse([]byte(diffTo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := Compare(from, to)
+	tests := []struct {
+		section                  string
+		set                      *ChangeSet
+		added, removed, modified string // locations, comma-separated
+	}{
+		{section: "paths", set: &d.Paths, added: "/owners", removed: "/pets/{id}"},
+		{section: "webhooks", set: &d.Webhooks},
+		{section: "operations", set: &d.Operations, added: "GET /owners,PUT /pets", removed: "DELETE /pets/{id}", modified: "GET /pets"},
+		{section: "parameters", set: &d.Parameters, added: "GET /pets query:offset", removed: "GET /pets header:X-Trace", modified: "GET /pets query:limit"},
+		{section: "request bodies", set: &d.RequestBodies, removed: "POST /pets requestBody"},
+		{section: "responses", set: &d.Responses, added: "GET /pets 400"},
+		{section: "schemas", set: &d.Schemas, added: "#/components/schemas/Owner", removed: "#/components/schemas/Error", modified: "#/components/schemas/Pet"},
+	}
+	locations := func(changes []Change) string {
+		var out []string
+		for _, c := range changes {
+			out = append(out, c.Location)
+		}
+		return strings.Join(out, ",")
+	}
+	for _, tt := range tests {
+		if got := locations(tt.set.Added); got != tt.added {
+			t.Errorf("%s added = %q, want %q", tt.section, got, tt.added)
+		}
+		if got := locations(tt.set.Removed); got != tt.removed {
+			t.Errorf("%s removed = %q, want %q", tt.section, got, tt.removed)
+		}
+		if got := locations(tt.set.Modified); got != tt.modified {
+			t.Errorf("%s modified = %q, want %q", tt.section, got, tt.modified)
+		}
+	}
+
+	// Modified items carry their field-level changes
+	fields := []struct {
+		change *Change
+		want   []FieldChange
+	}{
+		{change: &d.Operations.Modified[0], want: []FieldChange{{Field: "summary", From: "List pets", To: "List all pets"}}},
+		{change: &d.Parameters.Modified[0], want: []FieldChange{{Field: "schema.maximum", To: 100}}},
+		{change: &d.Schemas.Modified[0], want: []FieldChange{
+			{Field: "properties.name.maxLength", To: 50},
+			{Field: "required", To: []any{"name"}},
+		}},
+	}
+	for _, f := range fields {
+		if !reflect.DeepEqual(f.change.Fields, f.want) {
+			t.Errorf("%s fields = %+v, want %+v", f.change.Location, f.change.Fields, f.want)
+		}
+	}
+	if c := d.Parameters.Added[0]; c.Operation != "GET /pets" || c.Name != "query:offset" {
+		t.Errorf("added parameter = %+v, want operation GET /pets and name query:offset", c)
+	}
+}
+
+func TestCompareUnchanged(t *testing.T) {
+	tests := map[string][2]string{
+		"YAML and JSON of the same document": {
+			"openapi: 3.0.3\ninfo: {title: Pets, version: '1'}\npaths:\n  /pets:\n    get:\n      responses: {'200': {description: ok}}\n",
+			`{"openapi": "3.0.3", "info": {"title": "Pets", "version": "1"}, "paths": {"/pets": {"get": {"responses": {"200": {"description": "ok"}}}}}}`,
+		},
+		"reordered keys and parameters": {
+			"openapi: 3.0.3\ninfo: {title: Pets, version: '1'}\npaths:\n  /pets:\n    get:\n      parameters: [{name: a, in: query}, {name: b, in: header}]\n      responses: {'200': {description: ok}}\n",
+			"info: {version: '1', title: Pets}\nopenapi: 3.0.3\npaths:\n  /pets:\n    get:\n      responses: {'200': {description: ok}}\n      parameters: [{in: header, name: b}, {in: query, name: a}]\n",
+		},
+		"only info changed": {
+			"openapi: 3.0.3\ninfo: {title: Pets, version: '1'}\npaths: {}\n",
+			"openapi: 3.0.3\ninfo: {title: Pets, version: '2', description: New}\npaths: {}\n",
+		},
+	}
+	for name, docs := range tests {
+		from, err := Parse([]byte(docs[0]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		to, err := Parse([]byte(docs[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := Compare(from, to); !d.Empty() {
+			t.Errorf("%s: %+v", name, d)
+		}
+	}
+}
+
+func TestCompareSwagger2(t *testing.T) {
+	from, err := Parse([]byte("swagger: '2.0'\ninfo: {title: Pets, version: '1'}\npaths:\n  /pets:\n    get:\n      parameters: [{name: limit, in: query, type: integer}]\n      responses: {'200': {description: ok}}\ndefinitions:\n  Pet: {type: object}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	to, err := Parse([]byte("swagger: '2.0'\ninfo: {title: Pets, version: '1'}\npaths:\n  /pets:\n    get:\n      responses: {'200': {description: ok}}\ndefinitions:\n  Pet: {type: object}\n  Owner: {type: object}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := Compare(from, to)
+	if len(d.Schemas.Added) != 1 || d.Schemas.Added[0].Location != "#/definitions/Owner" {
+		t.Errorf("schemas added = %+v, want #/definitions/Owner", d.Schemas.Added)
+	}
+	if len(d.Parameters.Removed) != 1 || d.Parameters.Removed[0].Location != "GET /pets query:limit" {
+		t.Errorf("parameters removed = %+v, want GET /pets query:limit", d.Parameters.Removed)
+	}
+}
+
+func TestFieldDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b any
+		want []FieldChange
+	}{
+		{name: "equal", a: map[string]any{"type": "string"}, b: map[string]any{"type": "string"}},
+		{name: "changed leaf", a: map[string]any{"type": "string"}, b: map[string]any{"type": "integer"}, want: []FieldChange{{Field: "type", From: "string", To: "integer"}}},
+		{name: "keys with dots or slashes", a: map[string]any{"content": map[string]any{"application/json": 1}}, b: map[string]any{"content": map[string]any{"application/json": 2}}, want: []FieldChange{{Field: "content[application/json]", From: 1, To: 2}}},
+		{name: "lists of objects by index", a: []any{map[string]any{"a": 1}}, b: []any{map[string]any{"a": 2}}, want: []FieldChange{{Field: "[0].a", From: 1, To: 2}}},
+		{name: "scalar lists as a whole", a: map[string]any{"enum": []any{"a", "b"}}, b: map[string]any{"enum": []any{"a", "c"}}, want: []FieldChange{{Field: "enum", From: []any{"a", "b"}, To: []any{"a", "c"}}}},
+	}
+	for _, tt := range tests {
+		if got := FieldDiff(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+	many := map[string]any{}
+	for i := 0; i < 2*maxFieldChanges; i++ {
+		many[strings.Repeat("k", i+1)] = i
+	}
+	if got := FieldDiff(map[string]any{}, many); len(got) != maxFieldChanges {
+		t.Errorf("got %d changes, want them capped at %d", len(got), maxFieldChanges)
+	}
+}
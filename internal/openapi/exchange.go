@@ -0,0 +1,355 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// ExchangeProblem is one way a request or response deviates from its operation.
+type ExchangeProblem struct {
+	In      string `json:"in"`                // path, query, header, cookie, body or status
+	Name    string `json:"name,omitempty"`    // parameter or header name
+	Pointer string `json:"pointer,omitempty"` // JSON pointer into the body
+	Message string `json:"message"`
+}
+
+// ExchangeReport is the result of checking a request and its response against
+// the operation they belong to.
+type ExchangeReport struct {
+	Operation string            `json:"operation,omitempty"`
+	Valid     bool              `json:"valid"`
+	Request   []ExchangeProblem `json:"request"`
+	Response  []ExchangeProblem `json:"response"`
+}
+
+// exchangeSpecURL is the resource the spec is compiled as; the checked schemas
+// are placed under exchangeSchemasKey so their $refs resolve against it.
+const (
+	exchangeSpecURL    = "https://apiscope.local/spec.json"
+	exchangeSchemasKey = "x-apiscope-exchange"
+)
+
+// schemaCheck is one instance to validate against a Schema Object.
+type schemaCheck struct {
+	in, name string
+	schema   any
+	instance any
+}
+
+// ValidateRequest checks the parameters and body of a request against op.
+// pathParams are the values MatchOperation extracted from the path.
+func (s *Spec) ValidateRequest(op Operation, pathParams map[string]string, r *http.Request, body []byte) []ExchangeProblem {
+	problems := []ExchangeProblem{}
+	var checks []schemaCheck
+
+	params := s.Parameters(op)
+	for _, key := range SortedKeys(params) {
+		p := params[key]
+		in, _ := p["in"].(string)
+		name, _ := p["name"].(string)
+		var values []string
+		switch in {
+		case "path":
+			if v, ok := pathParams[name]; ok {
+				values = []string{v}
+			}
+		case "query":
+			values = r.URL.Query()[name]
+		case "header":
+			values = r.Header.Values(name)
+		case "cookie":
+			if c, err := r.Cookie(name); err == nil {
+				values = []string{c.Value}
+			}
+		}
+		if len(values) == 0 {
+			if in == "path" || isTrue(p["required"]) {
+				problems = append(problems, ExchangeProblem{In: in, Name: name, Message: "required parameter is missing"})
+			}
+			continue
+		}
+		if schema := s.parameterSchema(p); schema != nil {
+			if instance, ok := s.coerceValues(values, schema); ok {
+				checks = append(checks, schemaCheck{in: in, name: name, schema: schema, instance: instance})
+			}
+		}
+	}
+
+	requestBody := s.RequestBody(op)
+	contentType := r.Header.Get("Content-Type")
+	switch {
+	case len(body) == 0:
+		if isTrue(requestBody["required"]) {
+			problems = append(problems, ExchangeProblem{In: "body", Message: "required request body is missing"})
+		}
+	case requestBody == nil:
+		problems = append(problems, ExchangeProblem{In: "body", Message: "operation does not declare a request body"})
+	default:
+		problems, checks = s.checkBody(asMap(requestBody["content"]), contentType, body, problems, checks)
+	}
+
+	return append(problems, s.runChecks(checks)...)
+}
+
+// ValidateResponse checks the status, headers and body of a response to op.
+func (s *Spec) ValidateResponse(op Operation, status int, header http.Header, body []byte) []ExchangeProblem {
+	problems := []ExchangeProblem{}
+	responses := s.Responses(op)
+	code := strconv.Itoa(status)
+	response, ok := responses[code]
+	if !ok {
+		response, ok = responses[code[:1]+"XX"]
+	}
+	if !ok {
+		response, ok = responses["default"]
+	}
+	if !ok {
+		return append(problems, ExchangeProblem{In: "status", Message: fmt.Sprintf("status %d is not documented", status)})
+	}
+
+	var checks []schemaCheck
+	for _, name := range SortedKeys(asMap(response["headers"])) {
+		h, ok := s.Resolve(asMap(response["headers"])[name]).(map[string]any)
+		if !ok || strings.EqualFold(name, "Content-Type") {
+			continue
+		}
+		values := header.Values(name)
+		if len(values) == 0 {
+			if isTrue(h["required"]) {
+				problems = append(problems, ExchangeProblem{In: "header", Name: name, Message: "required header is missing"})
+			}
+			continue
+		}
+		if schema := s.parameterSchema(h); schema != nil {
+			if instance, ok := s.coerceValues(values, schema); ok {
+				checks = append(checks, schemaCheck{in: "header", name: name, schema: schema, instance: instance})
+			}
+		}
+	}
+
+	content := asMap(response["content"])
+	if s.IsSwagger2() {
+		content = map[string]any{}
+		if schema, ok := response["schema"]; ok {
+			produces := stringList(op.Node["produces"])
+			if len(produces) == 0 {
+				produces = stringList(s.Root["produces"])
+			}
+			if len(produces) == 0 {
+				produces = []string{"application/json"}
+			}
+			for _, ct := range produces {
+				content[ct] = map[string]any{"schema": schema}
+			}
+		}
+	}
+	if len(body) > 0 {
+		if len(content) == 0 {
+			problems = append(problems, ExchangeProblem{In: "body", Message: fmt.Sprintf("response %d does not declare a body", status)})
+		} else {
+			problems, checks = s.checkBody(content, header.Get("Content-Type"), body, problems, checks)
+		}
+	}
+
+	return append(problems, s.runChecks(checks)...)
+}
+
+// checkBody matches a body to a declared media type and, for JSON, queues a
+// schema check.
+func (s *Spec) checkBody(content map[string]any, contentType string, body []byte, problems []ExchangeProblem, checks []schemaCheck) ([]ExchangeProblem, []schemaCheck) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return append(problems, ExchangeProblem{In: "body", Message: "body has no valid Content-Type"}), checks
+	}
+	var media map[string]any
+	for _, declared := range SortedKeys(content) {
+		if mediaMatches(declared, mediaType) {
+			media = asMap(content[declared])
+			if declared == mediaType {
+				break
+			}
+		}
+	}
+	if media == nil {
+		return append(problems, ExchangeProblem{In: "body", Message: fmt.Sprintf("content type %s is not declared (%s)", mediaType, strings.Join(SortedKeys(content), ", "))}), checks
+	}
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return problems, checks // only JSON bodies are checked against their schema
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return append(problems, ExchangeProblem{In: "body", Message: "body is not valid JSON: " + err.Error()}), checks
+	}
+	if schema, ok := media["schema"]; ok {
+		checks = append(checks, schemaCheck{in: "body", schema: schema, instance: instance})
+	}
+	return problems, checks
+}
+
+// parameterSchema returns the schema of a parameter or header. Swagger 2
+// describes non-body parameters with schema keywords on the parameter itself.
+func (s *Spec) parameterSchema(p map[string]any) any {
+	if schema, ok := p["schema"]; ok {
+		return schema
+	}
+	for _, media := range asMap(p["content"]) {
+		return asMap(media)["schema"]
+	}
+	if !s.IsSwagger2() || p["type"] == nil {
+		return nil
+	}
+	schema := map[string]any{}
+	for k, v := range p {
+		switch k {
+		case "in", "name", "required", "description", "collectionFormat", "allowEmptyValue":
+			continue
+		}
+		schema[k] = v
+	}
+	return schema
+}
+
+// coerceValues turns the raw strings of a parameter into the JSON value its
+// schema describes. Values that do not parse stay strings, so the schema check
+// reports them. Object parameters are not checked.
+func (s *Spec) coerceValues(values []string, schema any) (any, bool) {
+	m, _ := s.Resolve(schema).(map[string]any)
+	switch schemaType(m) {
+	case "object":
+		return nil, false
+	case "array":
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+		itemType := schemaType(asMap(s.Resolve(m["items"])))
+		out := make([]any, len(values))
+		for i, v := range values {
+			out[i] = coerceScalar(v, itemType)
+		}
+		return out, true
+	}
+	return coerceScalar(values[0], schemaType(m)), true
+}
+
+func coerceScalar(v, typ string) any {
+	switch typ {
+	case "integer", "number":
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+// runChecks compiles the queued schemas against the spec and validates their
+// instances. OpenAPI 3.0 and Swagger 2 schemas are checked as draft 4 (with
+// nullable), OpenAPI 3.1 schemas as JSON Schema 2020-12.
+func (s *Spec) runChecks(checks []schemaCheck) []ExchangeProblem {
+	if len(checks) == 0 {
+		return nil
+	}
+	root := make(map[string]any, len(s.Root)+1)
+	for k, v := range s.Root {
+		root[k] = v
+	}
+	schemas := make([]any, len(checks))
+	for i, c := range checks {
+		schemas[i] = c.schema
+	}
+	root[exchangeSchemasKey] = schemas
+
+	fail := func(err error) []ExchangeProblem {
+		return []ExchangeProblem{{In: checks[0].in, Message: "schemas cannot be checked: " + err.Error()}}
+	}
+	encoded, err := json.Marshal(root)
+	if err != nil {
+		return fail(err)
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(encoded))
+	if err != nil {
+		return fail(err)
+	}
+	c := jsonschema.NewCompiler()
+	if version, _ := SchemaVersion(s.Root); version == "3.1" {
+		c.DefaultDraft(jsonschema.Draft2020)
+	} else {
+		c.DefaultDraft(jsonschema.Draft4)
+		applyNullable(doc)
+	}
+	if err := c.AddResource(exchangeSpecURL, doc); err != nil {
+		return fail(err)
+	}
+
+	var problems []ExchangeProblem
+	for i, check := range checks {
+		sch, err := c.Compile(fmt.Sprintf("%s#/%s/%d", exchangeSpecURL, exchangeSchemasKey, i))
+		if err != nil {
+			problems = append(problems, ExchangeProblem{In: check.in, Name: check.name, Message: "schema cannot be checked: " + err.Error()})
+			continue
+		}
+		instance := check.instance
+		if check.in != "body" {
+			// Parameter values are plain Go values; give them the validator's types
+			raw, _ := json.Marshal(instance)
+			instance, _ = jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+		}
+		err = sch.Validate(instance)
+		if err == nil {
+			continue
+		}
+		ve, ok := err.(*jsonschema.ValidationError)
+		if !ok {
+			problems = append(problems, ExchangeProblem{In: check.in, Name: check.name, Message: err.Error()})
+			continue
+		}
+		seen := map[string]bool{}
+		for _, leaf := range relevantCauses(ve) {
+			pointer := jsonPointer(leaf.InstanceLocation)
+			msg := leaf.ErrorKind.LocalizedString(printer)
+			if seen[pointer+"\x00"+msg] {
+				continue
+			}
+			seen[pointer+"\x00"+msg] = true
+			p := ExchangeProblem{In: check.in, Name: check.name, Message: msg}
+			if check.in == "body" {
+				p.Pointer = pointer
+			}
+			problems = append(problems, p)
+		}
+	}
+	return problems
+}
+
+// applyNullable rewrites the OpenAPI 3.0 nullable (and Swagger 2 x-nullable)
+// keywords into a type that also allows null, which draft 4 understands.
+func applyNullable(v any) {
+	switch t := v.(type) {
+	case map[string]any:
+		if isTrue(t["nullable"]) || isTrue(t["x-nullable"]) {
+			if typ, ok := t["type"].(string); ok {
+				t["type"] = []any{typ, "null"}
+			}
+			if enum, ok := t["enum"].([]any); ok {
+				t["enum"] = append(enum, nil)
+			}
+		}
+		for _, child := range t {
+			applyNullable(child)
+		}
+	case []any:
+		for _, child := range t {
+			applyNullable(child)
+		}
+	}
+}
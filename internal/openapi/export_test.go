@@ -0,0 +1,56 @@
+package openapi
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestExportGolden compares the Postman and Insomnia exports of
+// testdata/export/petstore.yaml with the golden files next to it. Run
+// go test ./internal/openapi -run TestExportGolden -update to refresh them.
+func TestExportGolden(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("testdata", "export", "petstore.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collection := spec.Collection("petstore")
+	tests := []struct {
+		golden string
+		export func() ([]byte, error)
+	}{
+		{golden: "petstore.postman.json", export: collection.Postman},
+		{golden: "petstore.insomnia.json", export: func() ([]byte, error) {
+			return collection.Insomnia(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+		}},
+	}
+	for _, tt := range tests {
+		got, err := tt.export()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.golden, err)
+		}
+		path := filepath.Join("testdata", "export", tt.golden)
+		if *update {
+			if err := os.WriteFile(path, got, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s differs from the export:\n%s", tt.golden, got)
+		}
+	}
+}
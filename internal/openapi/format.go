@@ -0,0 +1,180 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Serialization formats of a stored document. FormatProto is Protocol
+// Buffers source and FormatGraphQL GraphQL SDL; neither is ever reformatted.
+const (
+	FormatYAML    = "yaml"
+	FormatJSON    = "json"
+	FormatProto   = "proto"
+	FormatGraphQL = "graphql"
+)
+
+// DetectFormat reports whether content is JSON or YAML (anything that is not valid JSON).
+func DetectFormat(content []byte) string {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+// FormatExtension returns the file extension of a format, including the dot.
+func FormatExtension(format string) string {
+	switch format {
+	case FormatJSON:
+		return ".json"
+	case FormatProto:
+		return ".proto"
+	case FormatGraphQL:
+		return ".graphql"
+	}
+	return ".yaml"
+}
+
+// FormatMIMEType returns the media type a format is served as.
+func FormatMIMEType(format string) string {
+	switch format {
+	case FormatJSON:
+		return "application/json"
+	case FormatProto, FormatGraphQL:
+		return "text/plain; charset=utf-8"
+	}
+	return "application/yaml"
+}
+
+// Reformat converts a YAML or JSON document to the given format, keeping the
+// order of its keys. Content already in that format is returned unchanged.
+func Reformat(content []byte, format string) ([]byte, error) {
+	if DetectFormat(content) == format {
+		return content, nil
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(content, &node); err != nil {
+		return nil, fmt.Errorf("invalid YAML or JSON format: %w", err)
+	}
+	if format == FormatYAML {
+		plainStyle(&node)
+	}
+	return encodeNode(&node, format)
+}
+
+// encodeNode writes a parsed document in the given format, keeping the node styles.
+func encodeNode(node *yaml.Node, format string) ([]byte, error) {
+	if format == FormatYAML {
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(node); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, node); err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// plainStyle drops the flow style JSON input is parsed with, so it is written
+// as block YAML.
+func plainStyle(n *yaml.Node) *yaml.Node {
+	n.Style &^= yaml.FlowStyle
+	if n.Kind == yaml.ScalarNode && n.Tag == "!!str" {
+		n.Style &^= yaml.DoubleQuotedStyle
+	}
+	for _, c := range n.Content {
+		plainStyle(c)
+	}
+	return n
+}
+
+// writeJSON encodes a YAML node as compact JSON, preserving mapping order.
+func writeJSON(buf *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			buf.WriteString("null")
+			return nil
+		}
+		return writeJSON(buf, n.Content[0])
+	case yaml.AliasNode:
+		return writeJSON(buf, n.Alias)
+	case yaml.MappingNode:
+		buf.WriteByte('{')
+		first := true
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			if key.Tag == "!!merge" {
+				return errors.New("YAML merge keys cannot be converted to JSON")
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			first = false
+			encoded, _ := json.Marshal(key.Value)
+			buf.Write(encoded)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range n.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case yaml.ScalarNode:
+		return writeScalar(buf, n)
+	}
+	return nil
+}
+
+func writeScalar(buf *bytes.Buffer, n *yaml.Node) error {
+	switch n.ShortTag() {
+	case "!!null":
+		buf.WriteString("null")
+		return nil
+	case "!!bool", "!!int", "!!float":
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return err
+		}
+		// JSON has no infinity or NaN
+		if f, ok := v.(float64); ok && (math.IsInf(f, 0) || math.IsNaN(f)) {
+			v = n.Value
+		}
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+		return nil
+	default:
+		encoded, _ := json.Marshal(n.Value)
+		buf.Write(encoded)
+		return nil
+	}
+}
@@ -0,0 +1,171 @@
+package openapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+type insomniaExport struct {
+	Type      string `json:"_type"`
+	Format    int    `json:"__export_format"`
+	Date      string `json:"__export_date"`
+	Source    string `json:"__export_source"`
+	Resources []any  `json:"resources"`
+}
+
+type insomniaWorkspace struct {
+	ID          string  `json:"_id"`
+	Type        string  `json:"_type"`
+	ParentID    *string `json:"parentId"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Scope       string  `json:"scope"`
+}
+
+type insomniaEnvironment struct {
+	ID       string            `json:"_id"`
+	Type     string            `json:"_type"`
+	ParentID string            `json:"parentId"`
+	Name     string            `json:"name"`
+	Data     map[string]string `json:"data"`
+}
+
+type insomniaGroup struct {
+	ID          string `json:"_id"`
+	Type        string `json:"_type"`
+	ParentID    string `json:"parentId"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type insomniaRequest struct {
+	ID          string          `json:"_id"`
+	Type        string          `json:"_type"`
+	ParentID    string          `json:"parentId"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Method      string          `json:"method"`
+	URL         string          `json:"url"`
+	Body        insomniaBody    `json:"body"`
+	Parameters  []insomniaParam `json:"parameters"`
+	Headers     []insomniaParam `json:"headers"`
+}
+
+type insomniaBody struct {
+	MimeType string          `json:"mimeType,omitempty"`
+	Text     string          `json:"text,omitempty"`
+	Params   []insomniaParam `json:"params,omitempty"`
+}
+
+type insomniaParam struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	Description string `json:"description,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+	Type        string `json:"type,omitempty"`
+}
+
+// Insomnia renders the collection as an Insomnia v4 export: a workspace with
+// a request group per tag. The base environment holds baseUrl (the first
+// server) and an example value per path parameter; every server gets a
+// sub-environment overriding baseUrl.
+func (c *Collection) Insomnia(exported time.Time) ([]byte, error) {
+	workspace := insomniaID("wrk", c.ID)
+	base := insomniaEnvironment{
+		ID:       insomniaID("env", c.ID),
+		Type:     "environment",
+		ParentID: workspace,
+		Name:     "Base Environment",
+		Data:     map[string]string{"baseUrl": ""},
+	}
+	if len(c.Servers) > 0 {
+		base.Data["baseUrl"] = c.Servers[0].BaseURL()
+	}
+	resources := []any{
+		insomniaWorkspace{ID: workspace, Type: "workspace", Name: c.Name, Description: c.Description, Scope: "collection"},
+	}
+	var subEnvironments []any
+	for _, server := range c.Servers {
+		name := server.Description
+		if name == "" {
+			name = server.URL
+		}
+		subEnvironments = append(subEnvironments, insomniaEnvironment{
+			ID:       insomniaID("env", c.ID, server.URL),
+			Type:     "environment",
+			ParentID: base.ID,
+			Name:     name,
+			Data:     map[string]string{"baseUrl": server.BaseURL()},
+		})
+	}
+
+	groups := map[string]string{}
+	for _, f := range c.Folders {
+		groups[f.Name] = insomniaID("fld", c.ID, f.Name)
+		resources = append(resources, insomniaGroup{ID: groups[f.Name], Type: "request_group", ParentID: workspace, Name: f.Name, Description: f.Description})
+	}
+	for _, r := range c.Requests {
+		parent, ok := groups[r.Folder]
+		if !ok {
+			parent = workspace
+		}
+		url := "{{ _.baseUrl }}" + templateParam.ReplaceAllString(r.Path, "{{ _.$1 }}")
+		for _, p := range r.PathParams {
+			if _, ok := base.Data[p.Name]; !ok {
+				base.Data[p.Name] = p.Value
+			}
+		}
+		req := insomniaRequest{
+			ID:          insomniaID("req", c.ID, r.Key),
+			Type:        "request",
+			ParentID:    parent,
+			Name:        r.Name,
+			Description: r.Description,
+			Method:      r.Method,
+			URL:         url,
+			Body:        insomniaBody{MimeType: r.BodyType},
+			Parameters:  insomniaParams(r.Query),
+			Headers:     insomniaParams(r.Headers),
+		}
+		if r.Form != nil {
+			req.Body.Params = insomniaParams(r.Form)
+		} else {
+			req.Body.Text = r.Body
+		}
+		resources = append(resources, req)
+	}
+
+	resources = append(resources, base)
+	resources = append(resources, subEnvironments...)
+	return exportJSON(insomniaExport{
+		Type:      "export",
+		Format:    4,
+		Date:      exported.UTC().Format(time.RFC3339),
+		Source:    "apiscope",
+		Resources: resources,
+	})
+}
+
+func insomniaParams(params []CollectionParam) []insomniaParam {
+	out := make([]insomniaParam, 0, len(params))
+	for _, p := range params {
+		ip := insomniaParam{Name: p.Name, Value: p.Value, Description: p.Description, Disabled: p.Disabled}
+		if p.File {
+			ip.Type = "file"
+		}
+		out = append(out, ip)
+	}
+	return out
+}
+
+// insomniaID derives a stable resource id, so re-importing an export updates
+// the workspace instead of duplicating it.
+func insomniaID(prefix string, parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return prefix + "_" + hex.EncodeToString(h.Sum(nil))[:32]
+}
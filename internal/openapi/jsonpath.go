@@ -0,0 +1,691 @@
+package openapi
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JSONPath is a compiled JSONPath query (RFC 9535) over parsed YAML or JSON
+// documents. Name, wildcard, index, slice and filter selectors are supported,
+// with child and descendant segments; function extensions are not.
+type JSONPath struct {
+	expr     string
+	segments []pathSegment
+}
+
+type pathSegment struct {
+	descendant bool
+	selectors  []pathSelector
+}
+
+type selectorKind int
+
+const (
+	selectName selectorKind = iota
+	selectWildcard
+	selectIndex
+	selectSlice
+	selectFilter
+)
+
+type pathSelector struct {
+	kind             selectorKind
+	name             string
+	index            int
+	start, end, step int
+	hasStart, hasEnd bool
+	filter           filterExpr
+}
+
+// nodeMatch is a node selected by a query: its parent and its position in the
+// parent's Content (the value position for mappings). The root has no parent.
+type nodeMatch struct {
+	node   *yaml.Node
+	parent *yaml.Node
+	index  int
+}
+
+// CompileJSONPath parses a JSONPath query such as
+// $.paths['/pets'].get or $.paths.*[?@.tags[0] == 'admin'].
+func CompileJSONPath(expr string) (*JSONPath, error) {
+	p := &pathParser{src: strings.TrimSpace(expr)}
+	if !p.consume("$") {
+		return nil, fmt.Errorf("JSONPath %q must start with $", expr)
+	}
+	segments, err := p.segments()
+	if err == nil && p.pos < len(p.src) {
+		err = p.errorf("unexpected %q", p.src[p.pos:])
+	}
+	if err != nil {
+		return nil, fmt.Errorf("JSONPath %q: %w", expr, err)
+	}
+	return &JSONPath{expr: expr, segments: segments}, nil
+}
+
+func (q *JSONPath) String() string {
+	return q.expr
+}
+
+// Select returns the nodes of a document the query selects, in document order
+// and without duplicates.
+func (q *JSONPath) Select(doc *yaml.Node) []*yaml.Node {
+	var out []*yaml.Node
+	for _, m := range q.selectMatches(doc) {
+		out = append(out, m.node)
+	}
+	return out
+}
+
+func (q *JSONPath) selectMatches(doc *yaml.Node) []nodeMatch {
+	root := doc
+	if root.Kind == yaml.DocumentNode {
+		if len(root.Content) == 0 {
+			return nil
+		}
+		root = root.Content[0]
+	}
+	return runSegments(q.segments, []nodeMatch{{node: root, index: -1}}, root)
+}
+
+func runSegments(segments []pathSegment, current []nodeMatch, root *yaml.Node) []nodeMatch {
+	for _, seg := range segments {
+		var next []nodeMatch
+		seen := map[*yaml.Node]bool{}
+		add := func(m nodeMatch) {
+			if !seen[m.node] {
+				seen[m.node] = true
+				next = append(next, m)
+			}
+		}
+		for _, m := range current {
+			if seg.descendant {
+				walkDescendants(m, func(d nodeMatch) {
+					for _, sel := range seg.selectors {
+						selectChildren(d.node, sel, root, add)
+					}
+				})
+				continue
+			}
+			for _, sel := range seg.selectors {
+				selectChildren(m.node, sel, root, add)
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// walkDescendants visits a node and all nodes below it, parents first.
+func walkDescendants(m nodeMatch, fn func(nodeMatch)) {
+	fn(m)
+	switch m.node.Kind {
+	case yaml.MappingNode:
+		for i := 1; i < len(m.node.Content); i += 2 {
+			walkDescendants(nodeMatch{node: m.node.Content[i], parent: m.node, index: i}, fn)
+		}
+	case yaml.SequenceNode:
+		for i, c := range m.node.Content {
+			walkDescendants(nodeMatch{node: c, parent: m.node, index: i}, fn)
+		}
+	}
+}
+
+func selectChildren(n *yaml.Node, sel pathSelector, root *yaml.Node, add func(nodeMatch)) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			child := nodeMatch{node: n.Content[i+1], parent: n, index: i + 1}
+			switch sel.kind {
+			case selectName:
+				if n.Content[i].Value == sel.name {
+					add(child)
+				}
+			case selectWildcard:
+				add(child)
+			case selectFilter:
+				if sel.filter.eval(child.node, root) {
+					add(child)
+				}
+			}
+		}
+	case yaml.SequenceNode:
+		length := len(n.Content)
+		switch sel.kind {
+		case selectWildcard, selectFilter:
+			for i, c := range n.Content {
+				if sel.kind == selectWildcard || sel.filter.eval(c, root) {
+					add(nodeMatch{node: c, parent: n, index: i})
+				}
+			}
+		case selectIndex:
+			i := sel.index
+			if i < 0 {
+				i += length
+			}
+			if i >= 0 && i < length {
+				add(nodeMatch{node: n.Content[i], parent: n, index: i})
+			}
+		case selectSlice:
+			for _, i := range sliceIndices(sel, length) {
+				add(nodeMatch{node: n.Content[i], parent: n, index: i})
+			}
+		}
+	}
+}
+
+// sliceIndices resolves start:end:step against a sequence length as RFC 9535
+// section 2.3.4.2 describes.
+func sliceIndices(sel pathSelector, length int) []int {
+	step := sel.step
+	if step == 0 {
+		return nil
+	}
+	normalize := func(i int) int {
+		if i < 0 {
+			return i + length
+		}
+		return i
+	}
+	clamp := func(i, lo, hi int) int {
+		return min(max(i, lo), hi)
+	}
+	var out []int
+	if step > 0 {
+		start, end := 0, length
+		if sel.hasStart {
+			start = clamp(normalize(sel.start), 0, length)
+		}
+		if sel.hasEnd {
+			end = clamp(normalize(sel.end), 0, length)
+		}
+		for i := start; i < end; i += step {
+			out = append(out, i)
+		}
+		return out
+	}
+	start, end := length-1, -1
+	if sel.hasStart {
+		start = clamp(normalize(sel.start), -1, length-1)
+	}
+	if sel.hasEnd {
+		end = clamp(normalize(sel.end), -1, length-1)
+	}
+	for i := start; i > end; i += step {
+		out = append(out, i)
+	}
+	return out
+}
+
+// filterExpr is a logical expression of a filter selector, evaluated against
+// one candidate node.
+type filterExpr interface {
+	eval(current, root *yaml.Node) bool
+}
+
+type orExpr struct{ left, right filterExpr }
+type andExpr struct{ left, right filterExpr }
+type notExpr struct{ expr filterExpr }
+type existsExpr struct{ query filterQuery }
+type compareExpr struct {
+	op          string
+	left, right filterOperand
+}
+
+func (e orExpr) eval(c, r *yaml.Node) bool  { return e.left.eval(c, r) || e.right.eval(c, r) }
+func (e andExpr) eval(c, r *yaml.Node) bool { return e.left.eval(c, r) && e.right.eval(c, r) }
+func (e notExpr) eval(c, r *yaml.Node) bool { return !e.expr.eval(c, r) }
+func (e existsExpr) eval(c, r *yaml.Node) bool {
+	return len(e.query.run(c, r)) > 0
+}
+
+func (e compareExpr) eval(c, r *yaml.Node) bool {
+	left, lok := e.left.value(c, r)
+	right, rok := e.right.value(c, r)
+	equal := func() bool {
+		if !lok || !rok {
+			return !lok && !rok
+		}
+		return reflect.DeepEqual(left, right)
+	}
+	less := func(a, b any, aok, bok bool) bool {
+		if !aok || !bok {
+			return false
+		}
+		switch x := a.(type) {
+		case float64:
+			y, ok := b.(float64)
+			return ok && x < y
+		case string:
+			y, ok := b.(string)
+			return ok && x < y
+		}
+		return false
+	}
+	switch e.op {
+	case "==":
+		return equal()
+	case "!=":
+		return !equal()
+	case "<":
+		return less(left, right, lok, rok)
+	case "<=":
+		return less(left, right, lok, rok) || equal()
+	case ">":
+		return less(right, left, rok, lok)
+	case ">=":
+		return less(right, left, rok, lok) || equal()
+	}
+	return false
+}
+
+// filterQuery is a query inside a filter, relative to the candidate (@) or to
+// the document root ($).
+type filterQuery struct {
+	relative bool
+	segments []pathSegment
+}
+
+func (q filterQuery) run(current, root *yaml.Node) []nodeMatch {
+	start := root
+	if q.relative {
+		start = current
+	}
+	return runSegments(q.segments, []nodeMatch{{node: start, index: -1}}, root)
+}
+
+// filterOperand is a literal or a query yielding at most one value; ok is false
+// when a query yields nothing.
+type filterOperand struct {
+	query   *filterQuery
+	literal any
+}
+
+func (o filterOperand) value(current, root *yaml.Node) (any, bool) {
+	if o.query == nil {
+		return o.literal, true
+	}
+	matches := o.query.run(current, root)
+	if len(matches) != 1 {
+		return nil, false
+	}
+	return nodeValue(matches[0].node), true
+}
+
+// nodeValue decodes a node into plain Go values with all numbers as float64,
+// so comparisons do not depend on how a number was written.
+func nodeValue(n *yaml.Node) any {
+	var v any
+	if err := n.Decode(&v); err != nil {
+		return nil
+	}
+	return normalizeNumbers(Normalize(v))
+}
+
+func normalizeNumbers(v any) any {
+	switch t := v.(type) {
+	case int:
+		return float64(t)
+	case int64:
+		return float64(t)
+	case uint64:
+		return float64(t)
+	case float64:
+		return t
+	case map[string]any:
+		for k, c := range t {
+			t[k] = normalizeNumbers(c)
+		}
+	case []any:
+		for i, c := range t {
+			t[i] = normalizeNumbers(c)
+		}
+	}
+	return v
+}
+
+type pathParser struct {
+	src string
+	pos int
+}
+
+func (p *pathParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *pathParser) consume(s string) bool {
+	if strings.HasPrefix(p.src[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *pathParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *pathParser) skipSpace() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// segments parses the segments following $ or @.
+func (p *pathParser) segments() ([]pathSegment, error) {
+	var out []pathSegment
+	for {
+		mark := p.pos
+		p.skipSpace()
+		var seg pathSegment
+		switch {
+		case p.consume(".."):
+			seg.descendant = true
+			if p.peek() == '[' {
+				sels, err := p.bracket()
+				if err != nil {
+					return nil, err
+				}
+				seg.selectors = sels
+			} else if sel, err := p.shorthand(); err != nil {
+				return nil, err
+			} else {
+				seg.selectors = []pathSelector{sel}
+			}
+		case p.consume("."):
+			sel, err := p.shorthand()
+			if err != nil {
+				return nil, err
+			}
+			seg.selectors = []pathSelector{sel}
+		case p.peek() == '[':
+			sels, err := p.bracket()
+			if err != nil {
+				return nil, err
+			}
+			seg.selectors = sels
+		default:
+			p.pos = mark
+			return out, nil
+		}
+		out = append(out, seg)
+	}
+}
+
+// shorthand parses the * or member name after . or ..
+func (p *pathParser) shorthand() (pathSelector, error) {
+	if p.consume("*") {
+		return pathSelector{kind: selectWildcard}, nil
+	}
+	start := p.pos
+	for p.pos < len(p.src) {
+		r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+		if r == '_' || r == '-' || r == '$' || r >= 0x80 || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (p.pos > start && r >= '0' && r <= '9') {
+			p.pos += size
+			continue
+		}
+		break
+	}
+	if p.pos == start {
+		return pathSelector{}, p.errorf("expected a member name")
+	}
+	return pathSelector{kind: selectName, name: p.src[start:p.pos]}, nil
+}
+
+// bracket parses [selector, selector, ...].
+func (p *pathParser) bracket() ([]pathSelector, error) {
+	p.consume("[")
+	var out []pathSelector
+	for {
+		p.skipSpace()
+		sel, err := p.selector()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, sel)
+		p.skipSpace()
+		if p.consume("]") {
+			return out, nil
+		}
+		if !p.consume(",") {
+			return nil, p.errorf("expected , or ]")
+		}
+	}
+}
+
+func (p *pathParser) selector() (pathSelector, error) {
+	switch c := p.peek(); {
+	case c == '\'' || c == '"':
+		s, err := p.stringLiteral()
+		return pathSelector{kind: selectName, name: s}, err
+	case c == '*':
+		p.pos++
+		return pathSelector{kind: selectWildcard}, nil
+	case c == '?':
+		p.pos++
+		p.skipSpace()
+		expr, err := p.orExpr()
+		return pathSelector{kind: selectFilter, filter: expr}, err
+	}
+
+	sel := pathSelector{kind: selectIndex, step: 1}
+	n, ok, err := p.integer()
+	if err != nil {
+		return sel, err
+	}
+	sel.index, sel.start, sel.hasStart = n, n, ok
+	p.skipSpace()
+	if !p.consume(":") {
+		if !ok {
+			return sel, p.errorf("expected a selector")
+		}
+		return sel, nil
+	}
+	sel.kind = selectSlice
+	p.skipSpace()
+	if sel.end, sel.hasEnd, err = p.integer(); err != nil {
+		return sel, err
+	}
+	p.skipSpace()
+	if p.consume(":") {
+		p.skipSpace()
+		step, ok, err := p.integer()
+		if err != nil {
+			return sel, err
+		}
+		if ok {
+			sel.step = step
+		}
+	}
+	return sel, nil
+}
+
+// integer parses an optional integer; ok reports whether there was one.
+func (p *pathParser) integer() (int, bool, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+	if p.pos == start {
+		return 0, false, nil
+	}
+	n, err := strconv.Atoi(p.src[start:p.pos])
+	if err != nil {
+		return 0, false, p.errorf("invalid integer %q", p.src[start:p.pos])
+	}
+	return n, true, nil
+}
+
+func (p *pathParser) stringLiteral() (string, error) {
+	quote := p.src[p.pos]
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		p.pos++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c != '\\':
+			b.WriteByte(c)
+		case p.pos >= len(p.src):
+			return "", p.errorf("unterminated string")
+		default:
+			e := p.src[p.pos]
+			p.pos++
+			switch e {
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if p.pos+4 > len(p.src) {
+					return "", p.errorf("invalid \\u escape")
+				}
+				r, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+				if err != nil {
+					return "", p.errorf("invalid \\u escape")
+				}
+				b.WriteRune(rune(r))
+				p.pos += 4
+			default:
+				b.WriteByte(e)
+			}
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *pathParser) orExpr() (filterExpr, error) {
+	left, err := p.andExpr()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if !p.consume("||") {
+			return left, nil
+		}
+		p.skipSpace()
+		right, err := p.andExpr()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+}
+
+func (p *pathParser) andExpr() (filterExpr, error) {
+	left, err := p.unaryExpr()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if !p.consume("&&") {
+			return left, nil
+		}
+		p.skipSpace()
+		right, err := p.unaryExpr()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+}
+
+func (p *pathParser) unaryExpr() (filterExpr, error) {
+	if p.peek() == '!' && !strings.HasPrefix(p.src[p.pos:], "!=") {
+		p.pos++
+		p.skipSpace()
+		expr, err := p.unaryExpr()
+		return notExpr{expr}, err
+	}
+	if p.consume("(") {
+		p.skipSpace()
+		expr, err := p.orExpr()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if !p.consume(")") {
+			return nil, p.errorf("expected )")
+		}
+		return expr, nil
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if !p.consume(op) {
+			continue
+		}
+		p.skipSpace()
+		right, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return compareExpr{op: op, left: left, right: right}, nil
+	}
+	if left.query == nil {
+		return nil, p.errorf("a literal must be compared")
+	}
+	return existsExpr{*left.query}, nil
+}
+
+func (p *pathParser) operand() (filterOperand, error) {
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.pos++
+		segments, err := p.segments()
+		if err != nil {
+			return filterOperand{}, err
+		}
+		return filterOperand{query: &filterQuery{relative: c == '@', segments: segments}}, nil
+	case c == '\'' || c == '"':
+		s, err := p.stringLiteral()
+		return filterOperand{literal: s}, err
+	case p.consume("true"):
+		return filterOperand{literal: true}, nil
+	case p.consume("false"):
+		return filterOperand{literal: false}, nil
+	case p.consume("null"):
+		return filterOperand{literal: nil}, nil
+	}
+	start := p.pos
+	for p.pos < len(p.src) && strings.IndexByte("+-.0123456789eE", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+	n, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+	if p.pos == start || err != nil || math.IsInf(n, 0) {
+		if p.pos < len(p.src) && isLetter(p.src[p.pos]) {
+			return filterOperand{}, p.errorf("function extensions are not supported")
+		}
+		return filterOperand{}, p.errorf("expected a query or a literal")
+	}
+	return filterOperand{literal: n}, nil
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,107 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const jsonPathDoc = `
+openapi: 3.1.0
+paths:
+  /pets:
+    get: {operationId: listPets, tags: [pets], x-internal: false}
+    post: {operationId: createPet, tags: [pets, admin], x-internal: true}
+  /users:
+    get: {operationId: listUsers, tags: [admin]}
+list: [0, 1, 2, 3, 4, 5]
+'odd key': {"it's": quoted}
+`
+
+func TestJSONPathSelect(t *testing.T) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(jsonPathDoc), &doc); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query string
+		want  string // selected values as a YAML list
+	}{
+		{query: "$.paths['/pets'].get.operationId", want: "[listPets]"},
+		{query: `$.paths["/pets"].post.operationId`, want: "[createPet]"},
+		{query: "$.paths.*.*.operationId", want: "[listPets, createPet, listUsers]"},
+		{query: "$..operationId", want: "[listPets, createPet, listUsers]"},
+		{query: "$.paths['/pets', '/users'].get.operationId", want: "[listPets, listUsers]"},
+		{query: "$['odd key']['it\\'s']", want: "[quoted]"},
+		{query: "$.paths.*[?@.tags[0] == 'admin'].operationId", want: "[listUsers]"},
+		{query: "$.paths.*[?@.tags[-1] == 'admin'].operationId", want: "[createPet, listUsers]"},
+		{query: "$.paths.*[?@['x-internal'] == true].operationId", want: "[createPet]"},
+		{query: "$.paths.*[?@['x-internal']].operationId", want: "[listPets, createPet]"},
+		{query: "$.paths.*[?!@['x-internal']].operationId", want: "[listUsers]"},
+		{query: "$.paths.*[?@.operationId == 'listPets' || @.operationId == 'listUsers'].tags[0]", want: "[pets, admin]"},
+		{query: "$.paths[?@.get && @.post].get.operationId", want: "[listPets]"},
+		{query: "$.paths.*[?@.operationId == $.paths['/users'].get.operationId].tags", want: "[[admin]]"},
+		{query: "$.list[1]", want: "[1]"},
+		{query: "$.list[-1]", want: "[5]"},
+		{query: "$.list[1:4]", want: "[1, 2, 3]"},
+		{query: "$.list[-2:]", want: "[4, 5]"},
+		{query: "$.list[:2]", want: "[0, 1]"},
+		{query: "$.list[::2]", want: "[0, 2, 4]"},
+		{query: "$.list[::-2]", want: "[5, 3, 1]"},
+		{query: "$.list[1:4:0]", want: "[]"},
+		{query: "$.list[0, 0]", want: "[0]"},
+		{query: "$.list[?@ > 3]", want: "[4, 5]"},
+		{query: "$.list[?@ >= 2 && @ < 4]", want: "[2, 3]"},
+		{query: "$.list[?@ == 2.0]", want: "[2]"},
+		{query: "$.list[?(@ != 1 && @ <= 2)]", want: "[0, 2]"},
+		{query: "$.missing", want: "[]"},
+		{query: "$.list[10]", want: "[]"},
+		{query: "$.openapi.foo", want: "[]"},
+	}
+	for _, tt := range tests {
+		q, err := CompileJSONPath(tt.query)
+		if err != nil {
+			t.Errorf("CompileJSONPath(%q): %v", tt.query, err)
+			continue
+		}
+		got := []any{}
+		for _, n := range q.Select(&doc) {
+			var v any
+			if err := n.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, Normalize(v))
+		}
+		var want []any
+		if err := yaml.Unmarshal([]byte(tt.want), &want); err != nil {
+			t.Fatal(err)
+		}
+		if want == nil {
+			want = []any{}
+		}
+		if !reflect.DeepEqual(got, Normalize(want)) {
+			t.Errorf("%s selected %v, want %v", tt.query, got, want)
+		}
+	}
+}
+
+func TestCompileJSONPathErrors(t *testing.T) {
+	for _, query := range []string{
+		"",
+		"paths",
+		"$.",
+		"$[",
+		"$['unterminated",
+		"$.paths[?]",
+		"$.list[?@ ==]",
+		"$.list[?@ == 1",
+		"$.list[1:x]",
+		"$.list[?length(@) > 1]",
+		"$ trailing",
+	} {
+		if q, err := CompileJSONPath(query); err == nil {
+			t.Errorf("CompileJSONPath(%q) = %v, want an error", query, q)
+		}
+	}
+}
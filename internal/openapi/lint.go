@@ -0,0 +1,295 @@
+package openapi
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// LintSeverity is how much a lint rule violation matters.
+type LintSeverity string
+
+const (
+	LintError LintSeverity = "error"
+	LintWarn  LintSeverity = "warn"
+	LintInfo  LintSeverity = "info"
+	LintOff   LintSeverity = "off"
+)
+
+// ParseLintSeverity accepts error, warn (warning), info (hint) and off.
+func ParseLintSeverity(s string) (LintSeverity, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "error":
+		return LintError, nil
+	case "warn", "warning":
+		return LintWarn, nil
+	case "info", "hint":
+		return LintInfo, nil
+	case "off", "none":
+		return LintOff, nil
+	}
+	return "", fmt.Errorf("unknown severity %q (error, warn, info or off)", s)
+}
+
+// LintFinding is one rule violation.
+type LintFinding struct {
+	Rule     string       `json:"rule"`
+	Severity LintSeverity `json:"severity"`
+	Location string       `json:"location"` // e.g. "GET /pets" or "#/components/schemas/Pet"
+	Message  string       `json:"message"`
+}
+
+// LintReport is the result of linting one document.
+type LintReport struct {
+	Ruleset  string         `json:"ruleset"`
+	Counts   map[string]int `json:"counts"` // findings per severity
+	Findings []LintFinding  `json:"findings"`
+}
+
+// LintRule checks one style convention.
+type LintRule struct {
+	ID          string
+	Description string
+	Severity    LintSeverity // default severity in the recommended ruleset
+	check       func(s *Spec, report func(location, format string, args ...any))
+}
+
+// Ruleset assigns a severity to lint rules; rules it does not mention are off.
+type Ruleset struct {
+	Name  string
+	Rules map[string]LintSeverity
+}
+
+// LintRules lists the built-in rules.
+var LintRules = []LintRule{
+	{"operation-operationId", "Every operation has an operationId.", LintWarn, checkOperationIDPresent},
+	{"operation-operationId-unique", "operationIds are unique across the document.", LintError, checkOperationIDUnique},
+	{"operation-description", "Every operation has a description.", LintWarn, checkOperationDescription},
+	{"operation-tags", "Every operation has at least one tag.", LintInfo, checkOperationTags},
+	{"path-kebab-case", "Path segments are kebab-case.", LintWarn, checkPathKebabCase},
+	{"error-response-schema", "Every 4xx/5xx response describes its error body with a schema.", LintWarn, checkErrorResponseSchema},
+	{"no-unused-components", "Every component is referenced somewhere.", LintWarn, checkUnusedComponents},
+}
+
+// LintRuleByID returns the built-in rule with the given ID.
+func LintRuleByID(id string) (LintRule, bool) {
+	for _, r := range LintRules {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return LintRule{}, false
+}
+
+// BuiltinRuleset returns one of the built-in rulesets: recommended (every rule
+// at its default severity), strict (every rule is an error) or off.
+func BuiltinRuleset(name string) (*Ruleset, bool) {
+	rs := &Ruleset{Name: name, Rules: map[string]LintSeverity{}}
+	switch name {
+	case "recommended":
+		for _, r := range LintRules {
+			rs.Rules[r.ID] = r.Severity
+		}
+	case "strict":
+		for _, r := range LintRules {
+			rs.Rules[r.ID] = LintError
+		}
+	case "off":
+	default:
+		return nil, false
+	}
+	return rs, true
+}
+
+// Lint runs the enabled rules of a ruleset.
+func Lint(s *Spec, rs *Ruleset) *LintReport {
+	report := &LintReport{Ruleset: rs.Name, Counts: map[string]int{}, Findings: []LintFinding{}}
+	for _, rule := range LintRules {
+		severity := rs.Rules[rule.ID]
+		if severity == "" || severity == LintOff {
+			continue
+		}
+		rule.check(s, func(location, format string, args ...any) {
+			report.Findings = append(report.Findings, LintFinding{
+				Rule:     rule.ID,
+				Severity: severity,
+				Location: location,
+				Message:  fmt.Sprintf(format, args...),
+			})
+		})
+	}
+	rank := map[LintSeverity]int{LintError: 0, LintWarn: 1, LintInfo: 2}
+	sort.SliceStable(report.Findings, func(i, j int) bool {
+		return rank[report.Findings[i].Severity] < rank[report.Findings[j].Severity]
+	})
+	for _, f := range report.Findings {
+		report.Counts[string(f.Severity)]++
+	}
+	return report
+}
+
+func checkOperationIDPresent(s *Spec, report func(string, string, ...any)) {
+	for _, op := range s.Operations() {
+		if id, _ := op.Node["operationId"].(string); strings.TrimSpace(id) == "" {
+			report(op.Key(), "operation has no operationId")
+		}
+	}
+}
+
+func checkOperationIDUnique(s *Spec, report func(string, string, ...any)) {
+	first := map[string]string{}
+	for _, op := range s.Operations() {
+		id, _ := op.Node["operationId"].(string)
+		if id == "" {
+			continue
+		}
+		if prev, ok := first[id]; ok {
+			report(op.Key(), "operationId %q is already used by %s", id, prev)
+			continue
+		}
+		first[id] = op.Key()
+	}
+}
+
+func checkOperationDescription(s *Spec, report func(string, string, ...any)) {
+	for _, op := range s.Operations() {
+		if d, _ := op.Node["description"].(string); strings.TrimSpace(d) == "" {
+			report(op.Key(), "operation has no description")
+		}
+	}
+}
+
+func checkOperationTags(s *Spec, report func(string, string, ...any)) {
+	for _, op := range s.Operations() {
+		if len(stringList(op.Node["tags"])) == 0 {
+			report(op.Key(), "operation has no tags")
+		}
+	}
+}
+
+var kebabSegment = regexp.MustCompile(`^[a-z0-9]+(?:[-.][a-z0-9]+)*$`)
+
+func checkPathKebabCase(s *Spec, report func(string, string, ...any)) {
+	for _, path := range SortedKeys(s.PathItems()) {
+		for _, segment := range strings.Split(path, "/") {
+			if segment == "" || strings.Contains(segment, "{") {
+				continue
+			}
+			if !kebabSegment.MatchString(segment) {
+				report(path, "path segment %q is not kebab-case", segment)
+				break
+			}
+		}
+	}
+}
+
+func checkErrorResponseSchema(s *Spec, report func(string, string, ...any)) {
+	for _, op := range s.Operations() {
+		responses := s.Responses(op)
+		for _, code := range SortedKeys(responses) {
+			if !strings.HasPrefix(code, "4") && !strings.HasPrefix(code, "5") {
+				continue
+			}
+			if !responseHasSchema(s, responses[code]) {
+				report(op.Key()+" "+code, "error response %s has no schema", code)
+			}
+		}
+	}
+}
+
+func responseHasSchema(s *Spec, response map[string]any) bool {
+	if s.IsSwagger2() {
+		return response["schema"] != nil
+	}
+	for _, media := range asMap(response["content"]) {
+		if m, ok := media.(map[string]any); ok && m["schema"] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// checkUnusedComponents reports reusable definitions that cannot be reached by
+// following $refs from outside the component sections.
+func checkUnusedComponents(s *Spec, report func(string, string, ...any)) {
+	var sections []string
+	if s.IsSwagger2() {
+		sections = []string{"definitions", "parameters", "responses"}
+	} else {
+		for _, name := range SortedKeys(asMap(s.Root["components"])) {
+			if name != "securitySchemes" {
+				sections = append(sections, "components/"+name)
+			}
+		}
+	}
+	if len(sections) == 0 {
+		return
+	}
+
+	// Refs from everything that is not a reusable definition are roots
+	used := map[string]bool{}
+	var queue []string
+	visit := func(v any) {
+		collectRefs(v, func(ref string) {
+			if !used[ref] {
+				used[ref] = true
+				queue = append(queue, ref)
+			}
+		})
+	}
+	for k, v := range s.Root {
+		switch k {
+		case "components", "definitions", "parameters", "responses":
+			continue
+		}
+		visit(v)
+	}
+	for len(queue) > 0 {
+		ref := queue[0]
+		queue = queue[1:]
+		if target, ok := s.Lookup(ref); ok {
+			visit(target)
+		}
+	}
+
+	for _, section := range sections {
+		items, _ := lookupPointer(s.Root, "/"+section)
+		for _, name := range SortedKeys(asMap(items)) {
+			ref := "#/" + section + "/" + escapePointer(name)
+			if !usedWithin(used, ref) {
+				report(ref, "%s is never referenced", name)
+			}
+		}
+	}
+}
+
+// usedWithin reports whether ref, or anything inside it, is referenced.
+func usedWithin(used map[string]bool, ref string) bool {
+	if used[ref] {
+		return true
+	}
+	for r := range used {
+		if strings.HasPrefix(r, ref+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func collectRefs(v any, fn func(ref string)) {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			if ref, ok := val.(string); ok && k == "$ref" && strings.HasPrefix(ref, "#") {
+				fn(ref)
+				continue
+			}
+			collectRefs(val, fn)
+		}
+	case []any:
+		for _, item := range t {
+			collectRefs(item, fn)
+		}
+	}
+}
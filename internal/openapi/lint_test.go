@@ -0,0 +1,215 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLintRules(t *testing.T) {
+	tests := []struct {
+		rule  string
+		paths string // appended to an OpenAPI 3.1 header, see testSpec
+		want  string // locations reported, comma-separated
+	}{
+		{
+			rule: "operation-operationId",
+			paths: `paths:
+  /pets:
+    get: {operationId: listPets, responses: {'200': {description: ok}}}
+    post: {responses: {'201': {description: created}}}
+    put: {operationId: '  ', responses: {'200': {description: ok}}}
+`,
+			want: "PUT /pets,POST /pets",
+		},
+		{
+			rule: "operation-operationId-unique",
+			paths: `paths:
+  /pets:
+    get: {operationId: listPets, responses: {'200': {description: ok}}}
+    post: {responses: {'201': {description: created}}}
+  /dogs:
+    get: {operationId: listPets, responses: {'200': {description: ok}}}
+    post: {responses: {'201': {description: created}}}
+`,
+			want: "GET /pets",
+		},
+		{
+			rule: "operation-description",
+			paths: `paths:
+  /pets:
+    get: {description: Lists pets, responses: {'200': {description: ok}}}
+    post: {summary: Adds a pet, responses: {'201': {description: created}}}
+`,
+			want: "POST /pets",
+		},
+		{
+			rule: "operation-tags",
+			paths: `paths:
+  /pets:
+    get: {tags: [pets], responses: {'200': {description: ok}}}
+    post: {tags: [], responses: {'201': {description: created}}}
+`,
+			want: "POST /pets",
+		},
+		{
+			rule: "path-kebab-case",
+			paths: `paths:
+  /pet-owners/{ownerId}/v1.2: {}
+  /petOwners: {}
+  /pet_owners/{id}/Dogs: {}
+  /: {}
+`,
+			want: "/petOwners,/pet_owners/{id}/Dogs",
+		},
+		{
+			rule: "error-response-schema",
+			paths: `paths:
+  /pets:
+    get:
+      responses:
+        '200': {description: ok}
+        '400':
+          description: bad request
+          content: {application/json: {schema: {$ref: '#/components/schemas/Error'}}}
+        '404': {description: not found}
+        '500': {$ref: '#/components/responses/ServerError'}
+        default: {description: error}
+components:
+  schemas:
+    Error: {type: object}
+  responses:
+    ServerError: {description: server error, content: {text/plain: {}}}
+`,
+			want: "GET /pets 404,GET /pets 500",
+		},
+		{
+			rule: "no-unused-components",
+			paths: `paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          description: ok
+          content: {application/json: {schema: {$ref: '#/components/schemas/Pet'}}}
+components:
+  schemas:
+    Pet: {type: object, properties: {owner: {$ref: '#/components/schemas/Owner'}}}
+    Owner: {type: object, properties: {address: {$ref: '#/components/schemas/Address/properties/street'}}}
+    Address: {type: object, properties: {street: {type: string}}}
+    Orphan: {type: object, properties: {self: {$ref: '#/components/schemas/Orphan'}, other: {$ref: '#/components/schemas/OrphanChild'}}}
+    OrphanChild: {type: object}
+  securitySchemes:
+    token: {type: http, scheme: bearer}
+  parameters:
+    Limit: {name: limit, in: query}
+`,
+			want: "#/components/parameters/Limit,#/components/schemas/Orphan,#/components/schemas/OrphanChild",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rs := &Ruleset{Name: "test", Rules: map[string]LintSeverity{tt.rule: LintWarn}}
+			report := Lint(testSpec(t, tt.paths), rs)
+			var locations []string
+			for _, f := range report.Findings {
+				if f.Rule != tt.rule || f.Severity != LintWarn {
+					t.Errorf("unexpected finding %+v", f)
+				}
+				locations = append(locations, f.Location)
+			}
+			if got := strings.Join(locations, ","); got != tt.want {
+				t.Errorf("locations = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintUnusedComponentsSwagger2(t *testing.T) {
+	spec, err := Parse([]byte(`swagger: '2.0'
+info: {title: Pets, version: '1'}
+paths:
+  /pets:
+    get:
+      parameters: [{$ref: '#/parameters/Limit'}]
+      responses:
+        '200': {description: ok, schema: {$ref: '#/definitions/Pet'}}
+definitions:
+  Pet: {type: object}
+  Unused: {type: object}
+parameters:
+  Limit: {name: limit, in: query, type: integer}
+responses:
+  NotFound: {description: not found}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := Lint(spec, &Ruleset{Rules: map[string]LintSeverity{"no-unused-components": LintError}})
+	var locations []string
+	for _, f := range report.Findings {
+		locations = append(locations, f.Location)
+	}
+	if got := strings.Join(locations, ","); got != "#/definitions/Unused,#/responses/NotFound" {
+		t.Errorf("locations = %q", got)
+	}
+}
+
+func TestLintRulesets(t *testing.T) {
+	// No operationId, description or tags, a camelCase path and an unused schema
+	spec := testSpec(t, `paths:
+  /petOwners:
+    get: {responses: {'200': {description: ok}}}
+components:
+  schemas:
+    Unused: {type: object}
+`)
+	tests := []struct {
+		ruleset string
+		counts  map[string]int
+	}{
+		{ruleset: "recommended", counts: map[string]int{"warn": 4, "info": 1}},
+		{ruleset: "strict", counts: map[string]int{"error": 5}},
+		{ruleset: "off", counts: map[string]int{}},
+	}
+	for _, tt := range tests {
+		rs, ok := BuiltinRuleset(tt.ruleset)
+		if !ok {
+			t.Fatalf("ruleset %s missing", tt.ruleset)
+		}
+		report := Lint(spec, rs)
+		if report.Ruleset != tt.ruleset || !reflect.DeepEqual(report.Counts, tt.counts) {
+			t.Errorf("%s: ruleset %q, counts %v, want %v", tt.ruleset, report.Ruleset, report.Counts, tt.counts)
+		}
+	}
+	if _, ok := BuiltinRuleset("lenient"); ok {
+		t.Error("an unknown ruleset was found")
+	}
+
+	// Findings are ordered by severity; off rules do not run
+	rs := &Ruleset{Rules: map[string]LintSeverity{
+		"operation-operationId": LintInfo,
+		"path-kebab-case":       LintError,
+		"operation-tags":        LintWarn,
+		"no-unused-components":  LintOff,
+	}}
+	var got []string
+	for _, f := range Lint(spec, rs).Findings {
+		got = append(got, string(f.Severity)+" "+f.Rule)
+	}
+	want := []string{"error path-kebab-case", "warn operation-tags", "info operation-operationId"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findings = %v, want %v", got, want)
+	}
+}
+
+func TestParseLintSeverity(t *testing.T) {
+	for in, want := range map[string]LintSeverity{"error": LintError, "Warning": LintWarn, " hint ": LintInfo, "none": LintOff} {
+		if got, err := ParseLintSeverity(in); err != nil || got != want {
+			t.Errorf("ParseLintSeverity(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseLintSeverity("fatal"); err == nil {
+		t.Error("an unknown severity was accepted")
+	}
+}
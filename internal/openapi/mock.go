@@ -0,0 +1,531 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// maxExampleDepth bounds schema synthesis of deeply nested schemas.
+const maxExampleDepth = 16
+
+// ErrNoOperation and ErrMethodNotAllowed are returned by MatchOperation.
+var (
+	ErrNoOperation      = errors.New("no operation matches the path")
+	ErrMethodNotAllowed = errors.New("path does not support the method")
+)
+
+// MatchOperation finds the operation serving a request. The path may include
+// the base path of one of the spec's servers (or the Swagger 2 basePath).
+// Literal segments win over templated ones, so /pets/mine beats /pets/{id}.
+func (s *Spec) MatchOperation(method, path string) (Operation, map[string]string, error) {
+	candidates := []string{path}
+	for _, base := range s.BasePaths() {
+		if base != "" && (path == base || strings.HasPrefix(path, base+"/")) {
+			candidates = append(candidates, "/"+strings.TrimPrefix(strings.TrimPrefix(path, base), "/"))
+		}
+	}
+
+	items := s.PathItems()
+	templates := SortedKeys(items)
+	var pathFound bool
+	for _, candidate := range candidates {
+		var best string
+		var bestParams map[string]string
+		bestScore := -1
+		for _, tmpl := range templates {
+			params, score, ok := matchTemplate(tmpl, candidate)
+			if ok && score > bestScore {
+				best, bestParams, bestScore = tmpl, params, score
+			}
+		}
+		if bestScore < 0 {
+			continue
+		}
+		pathFound = true
+		item := items[best]
+		if node, ok := item[strings.ToLower(method)].(map[string]any); ok {
+			return Operation{Method: strings.ToUpper(method), Path: best, Node: node, PathItem: item}, bestParams, nil
+		}
+	}
+	if pathFound {
+		return Operation{}, nil, ErrMethodNotAllowed
+	}
+	return Operation{}, nil, ErrNoOperation
+}
+
+// BasePaths returns the path part of every server URL (server variables take
+// their default), or the Swagger 2 basePath.
+func (s *Spec) BasePaths() []string {
+	if s.IsSwagger2() {
+		base, _ := s.Root["basePath"].(string)
+		return []string{strings.TrimSuffix(base, "/")}
+	}
+	var out []string
+	for _, srv := range asList(s.Root["servers"]) {
+		m, ok := srv.(map[string]any)
+		if !ok {
+			continue
+		}
+		raw, _ := m["url"].(string)
+		for name, v := range asMap(m["variables"]) {
+			def, _ := asMap(v)["default"].(string)
+			raw = strings.ReplaceAll(raw, "{"+name+"}", def)
+		}
+		u, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		out = append(out, strings.TrimSuffix(u.Path, "/"))
+	}
+	return out
+}
+
+// matchTemplate matches a path against a template like /pets/{id}. The score
+// counts literal segments.
+func matchTemplate(tmpl, path string) (map[string]string, int, bool) {
+	ts := strings.Split(strings.Trim(tmpl, "/"), "/")
+	ps := strings.Split(strings.Trim(path, "/"), "/")
+	if len(ts) != len(ps) {
+		return nil, 0, false
+	}
+	params := map[string]string{}
+	score := 0
+	for i, t := range ts {
+		open := strings.Index(t, "{")
+		if open < 0 {
+			if t != ps[i] {
+				return nil, 0, false
+			}
+			score++
+			continue
+		}
+		// A segment may mix text and one parameter, e.g. report.{format}
+		closing := strings.Index(t, "}")
+		if closing < open {
+			return nil, 0, false
+		}
+		prefix, suffix := t[:open], t[closing+1:]
+		seg := ps[i]
+		if !strings.HasPrefix(seg, prefix) || !strings.HasSuffix(seg, suffix) || len(seg) <= len(prefix)+len(suffix) {
+			return nil, 0, false
+		}
+		value, _ := url.PathUnescape(seg[len(prefix) : len(seg)-len(suffix)])
+		params[t[open+1:closing]] = value
+	}
+	return params, score, true
+}
+
+// MockResponse is a canned answer to an operation.
+type MockResponse struct {
+	Status      int
+	ContentType string // empty when the response has no body
+	Body        any
+	Headers     map[string]string
+}
+
+// MockPreferences come from a Prefer header, e.g. "code=404, example=notFound".
+type MockPreferences struct {
+	Code    string
+	Example string
+}
+
+// ParsePrefer reads the code and example preferences of Prefer headers.
+func ParsePrefer(headers []string) MockPreferences {
+	var p MockPreferences
+	for _, h := range headers {
+		for _, part := range strings.FieldsFunc(h, func(r rune) bool { return r == ',' || r == ';' }) {
+			key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+			switch strings.ToLower(strings.TrimSpace(key)) {
+			case "code", "status":
+				p.Code = value
+			case "example":
+				p.Example = value
+			}
+		}
+	}
+	return p
+}
+
+// Mock builds the response of an operation from its examples or, failing
+// that, from its response schema. accept lists acceptable media types in
+// preference order (empty accepts anything).
+func (s *Spec) Mock(op Operation, prefs MockPreferences, accept []string) (*MockResponse, error) {
+	responses := s.Responses(op)
+	key, status, ok := pickStatus(responses, prefs.Code)
+	if !ok {
+		if prefs.Code != "" {
+			return nil, errors.New("operation has no response " + prefs.Code)
+		}
+		return &MockResponse{Status: 204}, nil
+	}
+	response := responses[key]
+	out := &MockResponse{Status: status, Headers: map[string]string{}}
+
+	for name, h := range asMap(response["headers"]) {
+		header, ok := s.Resolve(h).(map[string]any)
+		if !ok {
+			continue
+		}
+		v, ok := header["example"]
+		if !ok {
+			schema := header["schema"]
+			if s.IsSwagger2() {
+				schema = header
+			}
+			v = s.Example(schema)
+		}
+		if v != nil {
+			out.Headers[name] = scalarString(v)
+		}
+	}
+
+	if s.IsSwagger2() {
+		schema, hasSchema := response["schema"]
+		examples := asMap(response["examples"])
+		if !hasSchema && len(examples) == 0 {
+			return out, nil
+		}
+		produces := stringList(op.Node["produces"])
+		if len(produces) == 0 {
+			produces = stringList(s.Root["produces"])
+		}
+		if len(produces) == 0 {
+			produces = []string{"application/json"}
+		}
+		out.ContentType = negotiate(produces, accept)
+		if ex, ok := examples[out.ContentType]; ok {
+			out.Body = ex
+		} else {
+			out.Body = s.Example(schema)
+		}
+		return out, nil
+	}
+
+	content := asMap(response["content"])
+	if len(content) == 0 {
+		return out, nil
+	}
+	out.ContentType = negotiate(SortedKeys(content), accept)
+	media := asMap(content[out.ContentType])
+	out.Body = s.mediaExample(media, prefs.Example, false)
+	return out, nil
+}
+
+// pickStatus chooses the requested status code, else the first 2xx, else
+// default. It returns the responses key describing the status.
+func pickStatus(responses map[string]map[string]any, requested string) (string, int, bool) {
+	if requested != "" {
+		status, err := strconv.Atoi(requested)
+		if err != nil {
+			return "", 0, false
+		}
+		if _, ok := responses[requested]; ok {
+			return requested, status, true
+		}
+		// A range like 4XX answers any code in it
+		for _, key := range []string{requested[:1] + "XX", requested[:1] + "xx"} {
+			if _, ok := responses[key]; ok {
+				return key, status, true
+			}
+		}
+		return "", 0, false
+	}
+	codes := SortedKeys(responses)
+	for _, code := range codes {
+		if strings.HasPrefix(code, "2") {
+			status, err := strconv.Atoi(code)
+			if err != nil {
+				status = 200
+			}
+			return code, status, true
+		}
+	}
+	if _, ok := responses["default"]; ok {
+		return "default", 200, true
+	}
+	for _, code := range codes {
+		if status, err := strconv.Atoi(code); err == nil {
+			return code, status, true
+		}
+	}
+	return "", 0, false
+}
+
+// negotiate picks the offered media type the client prefers, favouring JSON.
+func negotiate(offered, accept []string) string {
+	for _, a := range accept {
+		for _, o := range offered {
+			if mediaMatches(a, o) {
+				return o
+			}
+		}
+	}
+	for _, o := range offered {
+		if o == "application/json" || strings.HasSuffix(o, "+json") {
+			return o
+		}
+	}
+	return offered[0]
+}
+
+func mediaMatches(pattern, mediaType string) bool {
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*"))
+	}
+	return false
+}
+
+// mediaExample returns the named (or first) example of a media type object,
+// its single example, or data synthesised from its schema as a request (or
+// else a response) carries it.
+func (s *Spec) mediaExample(media map[string]any, name string, request bool) any {
+	if examples := asMap(media["examples"]); len(examples) > 0 {
+		key := name
+		if _, ok := examples[key]; !ok {
+			key = SortedKeys(examples)[0]
+		}
+		if ex, ok := s.Resolve(examples[key]).(map[string]any); ok {
+			if v, ok := ex["value"]; ok {
+				return v
+			}
+		}
+	}
+	if v, ok := media["example"]; ok {
+		return v
+	}
+	if request {
+		return s.RequestExample(media["schema"])
+	}
+	return s.Example(media["schema"])
+}
+
+// Example returns the example, default or first enum value of a schema, or
+// synthesises a value from its type as a response carries it.
+func (s *Spec) Example(schema any) any {
+	return s.example(schema, "writeOnly", map[string]bool{}, 0)
+}
+
+// RequestExample is Example for a value a client sends: readOnly properties
+// are left out instead of writeOnly ones.
+func (s *Spec) RequestExample(schema any) any {
+	return s.example(schema, "readOnly", map[string]bool{}, 0)
+}
+
+// example synthesises a value, leaving out properties flagged omit; refs on
+// the current branch are not followed again, so a recursive schema yields nil
+// where it would recurse.
+func (s *Spec) example(schema any, omit string, active map[string]bool, depth int) any {
+	if ref, ok := asMap(schema)["$ref"].(string); ok {
+		if active[ref] {
+			return nil
+		}
+		active[ref] = true
+		defer delete(active, ref)
+	}
+	m, ok := s.Resolve(schema).(map[string]any)
+	if !ok || depth > maxExampleDepth {
+		return nil
+	}
+	if v, ok := m["example"]; ok {
+		return v
+	}
+	if list := asList(m["examples"]); len(list) > 0 {
+		return list[0]
+	}
+	if v, ok := m["const"]; ok {
+		return v
+	}
+	if v, ok := m["default"]; ok {
+		return v
+	}
+	if list := asList(m["enum"]); len(list) > 0 {
+		return list[0]
+	}
+	if all := asList(m["allOf"]); len(all) > 0 {
+		merged := map[string]any{}
+		for _, part := range all {
+			if obj, ok := s.example(part, omit, active, depth+1).(map[string]any); ok {
+				for k, v := range obj {
+					merged[k] = v
+				}
+			}
+		}
+		if props := s.exampleObject(m, omit, active, depth); props != nil {
+			for k, v := range props {
+				merged[k] = v
+			}
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if list := asList(m[key]); len(list) > 0 {
+			return s.example(list[0], omit, active, depth+1)
+		}
+	}
+
+	switch schemaType(m) {
+	case "object":
+		return s.exampleObject(m, omit, active, depth)
+	case "array":
+		item := s.example(m["items"], omit, active, depth+1)
+		if item == nil {
+			return []any{}
+		}
+		n := 1
+		if min, ok := m["minItems"].(int); ok && min > 1 {
+			n = min
+		}
+		out := make([]any, n)
+		for i := range out {
+			out[i] = item
+		}
+		return out
+	case "integer":
+		return numberExample(m, true)
+	case "number":
+		return numberExample(m, false)
+	case "boolean":
+		return true
+	case "string":
+		return stringExample(m)
+	case "null":
+		return nil
+	}
+	return nil
+}
+
+// exampleObject fills every property but those flagged omit. Optional
+// properties without a value (recursion) are left out.
+func (s *Spec) exampleObject(m map[string]any, omit string, active map[string]bool, depth int) map[string]any {
+	props := asMap(m["properties"])
+	if props == nil {
+		if _, ok := m["additionalProperties"].(map[string]any); ok {
+			return map[string]any{"key": s.example(m["additionalProperties"], omit, active, depth+1)}
+		}
+		return map[string]any{}
+	}
+	required := map[string]bool{}
+	for _, name := range stringList(m["required"]) {
+		required[name] = true
+	}
+	out := map[string]any{}
+	for name, p := range props {
+		if ps, ok := s.Resolve(p).(map[string]any); ok && isTrue(ps[omit]) {
+			continue
+		}
+		v := s.example(p, omit, active, depth+1)
+		if v == nil && !required[name] {
+			continue
+		}
+		out[name] = v
+	}
+	return out
+}
+
+// schemaType returns the schema's type, inferring object/array from keywords.
+// For OpenAPI 3.1 type arrays the first non-null type wins.
+func schemaType(m map[string]any) string {
+	switch t := m["type"].(type) {
+	case string:
+		return t
+	case []any:
+		for _, v := range t {
+			if s, ok := v.(string); ok && s != "null" {
+				return s
+			}
+		}
+		return "null"
+	}
+	if m["properties"] != nil || m["additionalProperties"] != nil {
+		return "object"
+	}
+	if m["items"] != nil {
+		return "array"
+	}
+	return ""
+}
+
+func numberExample(m map[string]any, integer bool) any {
+	var n float64
+	if v, ok := toFloat(m["minimum"]); ok {
+		n = v
+		if isTrue(m["exclusiveMinimum"]) {
+			n++
+		}
+	} else if v, ok := toFloat(m["exclusiveMinimum"]); ok {
+		n = v + 1
+	} else if v, ok := toFloat(m["maximum"]); ok && v < 0 {
+		n = v
+	}
+	if integer {
+		return int64(n)
+	}
+	return n
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func stringExample(m map[string]any) string {
+	var v string
+	switch m["format"] {
+	case "date-time":
+		v = "2024-01-01T00:00:00Z"
+	case "date":
+		v = "2024-01-01"
+	case "time":
+		v = "00:00:00Z"
+	case "email":
+		v = "user@example.com"
+	case "uuid":
+		v = "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+	case "uri", "url":
+		v = "https://example.com"
+	case "hostname":
+		v = "example.com"
+	case "ipv4":
+		v = "192.0.2.1"
+	case "ipv6":
+		v = "2001:db8::1"
+	case "byte":
+		v = "c3RyaW5n"
+	case "binary":
+		v = ""
+	default:
+		v = "string"
+	}
+	if min, ok := m["minLength"].(int); ok && len(v) < min {
+		v += strings.Repeat("x", min-len(v))
+	}
+	if max, ok := m["maxLength"].(int); ok && max >= 0 && len(v) > max {
+		v = v[:max]
+	}
+	return v
+}
+
+func scalarString(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case nil:
+		return ""
+	}
+	encoded, _ := json.Marshal(v)
+	return string(encoded)
+}
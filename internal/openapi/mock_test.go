@@ -0,0 +1,334 @@
+package openapi
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const mockDoc = `
+openapi: 3.0.3
+info: {title: Pets, version: '1'}
+servers:
+  - url: 'https://{region}.example.com/{version}'
+    variables:
+      region: {default: eu}
+      version: {default: v1}
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          description: ok
+          headers:
+            X-Total: {schema: {type: integer, minimum: 1}}
+            X-Page: {example: '1', schema: {type: string}}
+          content:
+            application/json:
+              schema: {type: array, items: {$ref: '#/components/schemas/Pet'}}
+              examples:
+                two: {value: [{id: 1, name: Rex}, {id: 2, name: Tom}]}
+                one: {value: [{id: 1, name: Rex}]}
+            application/xml:
+              example: '<pets/>'
+        4XX:
+          description: client error
+          content:
+            application/json:
+              example: {error: bad request}
+        '404':
+          description: not found
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Error'}
+    post:
+      responses:
+        '201': {description: created}
+        default:
+          description: error
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Error'}
+  /pets/{id}:
+    get:
+      responses:
+        default:
+          description: the pet
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+    delete:
+      responses: {}
+  /pets/mine:
+    get:
+      responses:
+        '200': {description: mine}
+  /reports/report.{format}:
+    get:
+      responses:
+        '200': {description: ok}
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [id]
+      properties:
+        id: {type: integer, minimum: 1}
+        name: {type: string, example: Rex}
+        secret: {type: string, writeOnly: true}
+        parent: {$ref: '#/components/schemas/Pet'}
+    Error:
+      type: object
+      properties:
+        code: {type: integer, default: 404}
+        message: {type: string, enum: [Not found, Gone]}
+`
+
+func parseMockDoc(t *testing.T) *Spec {
+	t.Helper()
+	spec, err := Parse([]byte(mockDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestMatchOperation(t *testing.T) {
+	spec := parseMockDoc(t)
+	tests := []struct {
+		method, path string
+		want         string // operation key
+		params       map[string]string
+		err          error
+	}{
+		{method: "GET", path: "/pets", want: "GET /pets"},
+		{method: "post", path: "/pets/", want: "POST /pets"},
+		{method: "GET", path: "/pets/42", want: "GET /pets/{id}", params: map[string]string{"id": "42"}},
+		{method: "GET", path: "/pets/a%20b", want: "GET /pets/{id}", params: map[string]string{"id": "a b"}},
+		{method: "GET", path: "/pets/mine", want: "GET /pets/mine"},
+		{method: "GET", path: "/v1/pets/7", want: "GET /pets/{id}", params: map[string]string{"id": "7"}},
+		{method: "GET", path: "/reports/report.csv", want: "GET /reports/report.{format}", params: map[string]string{"format": "csv"}},
+		{method: "PUT", path: "/pets", err: ErrMethodNotAllowed},
+		{method: "DELETE", path: "/pets/mine", err: ErrMethodNotAllowed},
+		{method: "PUT", path: "/v1/pets", err: ErrMethodNotAllowed},
+		{method: "GET", path: "/owners", err: ErrNoOperation},
+		{method: "GET", path: "/pets/1/toys", err: ErrNoOperation},
+		{method: "GET", path: "/reports/summary.", err: ErrNoOperation},
+		{method: "GET", path: "/v2/pets", err: ErrNoOperation},
+	}
+	for _, tt := range tests {
+		op, params, err := spec.MatchOperation(tt.method, tt.path)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s %s: err = %v, want %v", tt.method, tt.path, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || op.Key() != tt.want {
+			t.Errorf("%s %s = %q, %v, want %q", tt.method, tt.path, op.Key(), err, tt.want)
+			continue
+		}
+		if tt.params == nil {
+			tt.params = map[string]string{}
+		}
+		if !reflect.DeepEqual(params, tt.params) {
+			t.Errorf("%s %s: params = %v, want %v", tt.method, tt.path, params, tt.params)
+		}
+	}
+}
+
+func TestParsePrefer(t *testing.T) {
+	tests := []struct {
+		headers []string
+		want    MockPreferences
+	}{
+		{headers: nil},
+		{headers: []string{"code=404"}, want: MockPreferences{Code: "404"}},
+		{headers: []string{`example="one", code=200`}, want: MockPreferences{Code: "200", Example: "one"}},
+		{headers: []string{"respond-async; status=202"}, want: MockPreferences{Code: "202"}},
+		{headers: []string{"code=400", "example=two"}, want: MockPreferences{Code: "400", Example: "two"}},
+		{headers: []string{"return=minimal"}},
+	}
+	for _, tt := range tests {
+		if got := ParsePrefer(tt.headers); got != tt.want {
+			t.Errorf("ParsePrefer(%q) = %+v, want %+v", tt.headers, got, tt.want)
+		}
+	}
+}
+
+func TestMock(t *testing.T) {
+	spec := parseMockDoc(t)
+	pet := map[string]any{"id": 1, "name": "Rex"}
+	tests := []struct {
+		name        string
+		operation   string // method and path
+		prefs       MockPreferences
+		accept      []string
+		status      int
+		contentType string
+		body        any
+		headers     map[string]string
+		err         bool
+	}{
+		{
+			name:        "first 2xx with its first named example",
+			operation:   "GET /pets",
+			status:      200,
+			contentType: "application/json",
+			body:        []any{pet},
+			headers:     map[string]string{"X-Total": "1", "X-Page": "1"},
+		},
+		{
+			name:        "Prefer example",
+			operation:   "GET /pets",
+			prefs:       MockPreferences{Example: "two"},
+			status:      200,
+			contentType: "application/json",
+			body:        []any{pet, map[string]any{"id": 2, "name": "Tom"}},
+			headers:     map[string]string{"X-Total": "1", "X-Page": "1"},
+		},
+		{
+			name:        "unknown Prefer example falls back to the first",
+			operation:   "GET /pets",
+			prefs:       MockPreferences{Example: "three"},
+			status:      200,
+			contentType: "application/json",
+			body:        []any{pet},
+			headers:     map[string]string{"X-Total": "1", "X-Page": "1"},
+		},
+		{
+			name:        "Accept picks the media type",
+			operation:   "GET /pets",
+			accept:      []string{"text/html", "application/*"},
+			status:      200,
+			contentType: "application/json",
+			body:        []any{pet},
+			headers:     map[string]string{"X-Total": "1", "X-Page": "1"},
+		},
+		{
+			name:        "Accept picks a single example",
+			operation:   "GET /pets",
+			accept:      []string{"application/xml"},
+			status:      200,
+			contentType: "application/xml",
+			body:        "<pets/>",
+			headers:     map[string]string{"X-Total": "1", "X-Page": "1"},
+		},
+		{
+			name:        "Prefer code with a schema",
+			operation:   "GET /pets",
+			prefs:       MockPreferences{Code: "404"},
+			status:      404,
+			contentType: "application/json",
+			body:        map[string]any{"code": 404, "message": "Not found"},
+			headers:     map[string]string{},
+		},
+		{
+			name:        "Prefer code answered by a range",
+			operation:   "GET /pets",
+			prefs:       MockPreferences{Code: "429"},
+			status:      429,
+			contentType: "application/json",
+			body:        map[string]any{"error": "bad request"},
+			headers:     map[string]string{},
+		},
+		{
+			name:      "Prefer code the operation does not have",
+			operation: "GET /pets",
+			prefs:     MockPreferences{Code: "500"},
+			err:       true,
+		},
+		{
+			name:      "Prefer code that is not a number",
+			operation: "GET /pets",
+			prefs:     MockPreferences{Code: "teapot"},
+			err:       true,
+		},
+		{
+			name:      "2xx without content",
+			operation: "POST /pets",
+			status:    201,
+			headers:   map[string]string{},
+		},
+		{
+			name:        "default, synthesised from a recursive schema without write-only properties",
+			operation:   "GET /pets/{id}",
+			status:      200,
+			contentType: "application/json",
+			body:        map[string]any{"id": int64(1), "name": "Rex"},
+			headers:     map[string]string{},
+		},
+		{
+			name:      "no responses",
+			operation: "DELETE /pets/{id}",
+			status:    204,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var op Operation
+			for _, o := range spec.Operations() {
+				if o.Key() == tt.operation {
+					op = o
+				}
+			}
+			resp, err := spec.Mock(op, tt.prefs, tt.accept)
+			if tt.err {
+				if err == nil {
+					t.Errorf("got %+v, want an error", resp)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != tt.status || resp.ContentType != tt.contentType {
+				t.Errorf("status, content type = %d, %q, want %d, %q", resp.Status, resp.ContentType, tt.status, tt.contentType)
+			}
+			if !reflect.DeepEqual(Normalize(resp.Body), Normalize(tt.body)) {
+				t.Errorf("body = %#v, want %#v", resp.Body, tt.body)
+			}
+			if !reflect.DeepEqual(resp.Headers, tt.headers) {
+				t.Errorf("headers = %v, want %v", resp.Headers, tt.headers)
+			}
+		})
+	}
+}
+
+func TestMockSwagger2(t *testing.T) {
+	spec, err := Parse([]byte(`swagger: '2.0'
+info: {title: Pets, version: '1'}
+basePath: /api
+produces: [application/json, text/plain]
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          description: ok
+          schema: {type: array, items: {type: string, format: email}}
+          examples:
+            text/plain: rex@example.com
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	op, _, err := spec.MatchOperation("GET", "/api/pets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := spec.Mock(op, MockPreferences{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ContentType != "application/json" || !reflect.DeepEqual(resp.Body, []any{"user@example.com"}) {
+		t.Errorf("got %q %#v", resp.ContentType, resp.Body)
+	}
+	resp, err = spec.Mock(op, MockPreferences{}, []string{"text/plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.ContentType != "text/plain" || resp.Body != "rex@example.com" {
+		t.Errorf("got %q %#v", resp.ContentType, resp.Body)
+	}
+}
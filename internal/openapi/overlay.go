@@ -0,0 +1,175 @@
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Overlay is an OpenAPI Overlay 1.0 document: an ordered list of actions that
+// update or remove the parts of a spec their JSONPath targets select.
+type Overlay struct {
+	Version string // value of the overlay field, e.g. 1.0.0
+	Title   string
+	Extends string
+	Actions []OverlayAction
+}
+
+// OverlayAction updates or removes the nodes Target selects.
+type OverlayAction struct {
+	Target      *JSONPath
+	Description string
+	Update      *yaml.Node
+	Remove      bool
+}
+
+// ParseOverlay reads and checks an Overlay 1.x document in YAML or JSON.
+func ParseOverlay(content []byte) (*Overlay, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("invalid YAML or JSON format: %w", err)
+	}
+	root := documentRoot(&doc)
+	if root == nil {
+		return nil, errors.New("overlay must be an object")
+	}
+	o := &Overlay{}
+	if v := mappingValue(root, "overlay"); v != nil {
+		o.Version = v.Value
+	}
+	if !strings.HasPrefix(o.Version, "1.") {
+		return nil, fmt.Errorf("unsupported overlay version %q (expected 1.x)", o.Version)
+	}
+	info := mappingValue(root, "info")
+	if info == nil || info.Kind != yaml.MappingNode {
+		return nil, errors.New("overlay info is required")
+	}
+	if v := mappingValue(info, "title"); v != nil {
+		o.Title = v.Value
+	}
+	if o.Title == "" || mappingValue(info, "version") == nil {
+		return nil, errors.New("overlay info needs a title and a version")
+	}
+	if v := mappingValue(root, "extends"); v != nil {
+		o.Extends = v.Value
+	}
+
+	actions := mappingValue(root, "actions")
+	if actions == nil || actions.Kind != yaml.SequenceNode || len(actions.Content) == 0 {
+		return nil, errors.New("overlay needs at least one action")
+	}
+	for i, a := range actions.Content {
+		if a.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("actions[%d] must be an object", i)
+		}
+		target := mappingValue(a, "target")
+		if target == nil || target.Value == "" {
+			return nil, fmt.Errorf("actions[%d] has no target", i)
+		}
+		q, err := CompileJSONPath(target.Value)
+		if err != nil {
+			return nil, fmt.Errorf("actions[%d]: %w", i, err)
+		}
+		action := OverlayAction{Target: q, Update: mappingValue(a, "update")}
+		if v := mappingValue(a, "description"); v != nil {
+			action.Description = v.Value
+		}
+		if v := mappingValue(a, "remove"); v != nil {
+			action.Remove = v.Kind == yaml.ScalarNode && v.Value == "true"
+		}
+		if !action.Remove && action.Update == nil {
+			return nil, fmt.Errorf("actions[%d] neither updates nor removes", i)
+		}
+		o.Actions = append(o.Actions, action)
+	}
+	return o, nil
+}
+
+// Transform applies the actions in order. Updates merge into the selected
+// objects (nested objects are merged, arrays concatenated, other values
+// replaced) and are appended to selected arrays; removals delete the selected
+// nodes from their parents. Targets that select nothing are skipped.
+func (o *Overlay) Transform() NodeTransform {
+	return func(doc *yaml.Node) bool {
+		if documentRoot(doc) == nil {
+			return false
+		}
+		changed := false
+		for _, action := range o.Actions {
+			matches := action.Target.selectMatches(doc)
+			if len(matches) == 0 {
+				continue
+			}
+			if action.Remove {
+				changed = removeMatches(matches) || changed
+				continue
+			}
+			for _, m := range matches {
+				switch m.node.Kind {
+				case yaml.MappingNode, yaml.SequenceNode:
+					mergeNode(m.node, action.Update)
+					changed = true
+				}
+			}
+		}
+		return changed
+	}
+}
+
+// removeMatches deletes selected nodes, the last ones of each parent first so
+// the recorded positions stay valid.
+func removeMatches(matches []nodeMatch) bool {
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].index > matches[j].index })
+	changed := false
+	for _, m := range matches {
+		p := m.parent
+		if p == nil || m.index >= len(p.Content) || p.Content[m.index] != m.node {
+			continue
+		}
+		if p.Kind == yaml.MappingNode {
+			p.Content = append(p.Content[:m.index-1], p.Content[m.index+1:]...)
+		} else {
+			p.Content = append(p.Content[:m.index], p.Content[m.index+1:]...)
+		}
+		changed = true
+	}
+	return changed
+}
+
+func mergeNode(target, update *yaml.Node) {
+	if target.Kind == yaml.SequenceNode {
+		if update.Kind == yaml.SequenceNode {
+			for _, c := range update.Content {
+				target.Content = append(target.Content, copyNode(c))
+			}
+		} else {
+			target.Content = append(target.Content, copyNode(update))
+		}
+		return
+	}
+	if update.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(update.Content); i += 2 {
+		key, value := update.Content[i].Value, update.Content[i+1]
+		existing := mappingValue(target, key)
+		switch {
+		case existing != nil && existing.Kind == value.Kind && (value.Kind == yaml.MappingNode || value.Kind == yaml.SequenceNode):
+			mergeNode(existing, value)
+		default:
+			setMappingKey(target, key, copyNode(value))
+		}
+	}
+}
+
+func copyNode(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = copyNode(child)
+	}
+	return &c
+}
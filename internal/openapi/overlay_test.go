@@ -0,0 +1,174 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const overlayBase = `
+openapi: 3.1.0
+info: {title: Pets, version: '1'}
+tags: [{name: pets}]
+paths:
+  /pets:
+    get: {operationId: listPets, tags: [pets]}
+    post: {operationId: createPet, tags: [pets], x-internal: true}
+`
+
+func TestOverlayTransform(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions string
+		want    map[string]string // JSON pointer -> expected value as YAML, "" = absent
+		same    bool              // the document is returned unchanged
+	}{
+		{
+			name: "update merges into objects",
+			actions: `
+- target: $.info
+  update: {title: Public pets, x-audience: public}
+`,
+			want: map[string]string{"/info": "{title: Public pets, version: '1', x-audience: public}"},
+		},
+		{
+			name: "update is appended to arrays",
+			actions: `
+- target: $.tags
+  update: {name: admin}
+- target: $.paths['/pets'].get.tags
+  update: [extra, more]
+`,
+			want: map[string]string{
+				"/tags":                  "[{name: pets}, {name: admin}]",
+				"/paths/~1pets/get/tags": "[pets, extra, more]",
+			},
+		},
+		{
+			name: "nested objects merge, arrays concatenate, scalars are replaced",
+			actions: `
+- target: $.paths['/pets'].get
+  update: {operationId: getPets, tags: [read], responses: {'200': {description: ok}}}
+`,
+			want: map[string]string{"/paths/~1pets/get": "{operationId: getPets, tags: [pets, read], responses: {'200': {description: ok}}}"},
+		},
+		{
+			name: "remove with a filter",
+			actions: `
+- target: $.paths.*[?@['x-internal'] == true]
+  remove: true
+`,
+			want: map[string]string{
+				"/paths/~1pets/post": "",
+				"/paths/~1pets/get":  "{operationId: listPets, tags: [pets]}",
+			},
+		},
+		{
+			name: "remove several members of one object",
+			actions: `
+- target: $.paths['/pets'].*
+  remove: true
+`,
+			want: map[string]string{"/paths/~1pets": "{}"},
+		},
+		{
+			name: "remove array items",
+			actions: `
+- target: $.paths.*.*.tags[?@ == 'pets']
+  remove: true
+`,
+			want: map[string]string{"/paths/~1pets/get/tags": "[]", "/paths/~1pets/post/tags": "[]"},
+		},
+		{
+			name: "actions apply in order",
+			actions: `
+- target: $.paths['/pets'].post
+  remove: true
+- target: $.paths['/pets']
+  update: {post: {operationId: addPet}}
+`,
+			want: map[string]string{"/paths/~1pets/post": "{operationId: addPet}"},
+		},
+		{
+			name: "updates do not apply to scalars",
+			actions: `
+- target: $.info.title
+  update: {x: y}
+`,
+			same: true,
+		},
+		{
+			name: "targets that select nothing are skipped",
+			actions: `
+- target: $.paths['/users']
+  update: {x-internal: true}
+- target: $.components
+  remove: true
+`,
+			same: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := ParseOverlay([]byte("overlay: 1.0.0\ninfo: {title: Test, version: '1'}\nactions:" + tt.actions))
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := Transform([]byte(overlayBase), o.Transform())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.same {
+				if string(out) != overlayBase {
+					t.Errorf("document changed:\n%s", out)
+				}
+				return
+			}
+			spec, err := Parse(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for pointer, want := range tt.want {
+				value, ok := lookupPointer(spec.Root, pointer)
+				if want == "" {
+					if ok {
+						t.Errorf("%s = %v, want it removed", pointer, value)
+					}
+					continue
+				}
+				var expected any
+				if err := yaml.Unmarshal([]byte(want), &expected); err != nil {
+					t.Fatalf("%s: bad expectation: %v", pointer, err)
+				}
+				if !ok || !reflect.DeepEqual(value, Normalize(expected)) {
+					t.Errorf("%s = %#v, want %#v", pointer, value, Normalize(expected))
+				}
+			}
+		})
+	}
+}
+
+func TestParseOverlayErrors(t *testing.T) {
+	tests := []struct {
+		overlay string
+		wantErr string
+	}{
+		{overlay: "- a list", wantErr: "must be an object"},
+		{overlay: "overlay: 2.0.0\ninfo: {title: T, version: '1'}\nactions: [{target: $, remove: true}]", wantErr: "unsupported overlay version"},
+		{overlay: "overlay: 1.0.0\nactions: [{target: $, remove: true}]", wantErr: "info is required"},
+		{overlay: "overlay: 1.0.0\ninfo: {title: T}\nactions: [{target: $, remove: true}]", wantErr: "title and a version"},
+		{overlay: "overlay: 1.0.0\ninfo: {title: T, version: '1'}\nactions: []", wantErr: "at least one action"},
+		{overlay: "overlay: 1.0.0\ninfo: {title: T, version: '1'}\nactions: [{remove: true}]", wantErr: "no target"},
+		{overlay: "overlay: 1.0.0\ninfo: {title: T, version: '1'}\nactions: [{target: '$.['}]", wantErr: "actions[0]"},
+		{overlay: "overlay: 1.0.0\ninfo: {title: T, version: '1'}\nactions: [{target: $.info}]", wantErr: "neither updates nor removes"},
+		{overlay: "overlay: 1.0.0\ninfo: {title: T, version: '1'}\nactions: [{target: $.info, remove: false}]", wantErr: "neither updates nor removes"},
+	}
+	for _, tt := range tests {
+		_, err := ParseOverlay([]byte(tt.overlay))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ParseOverlay(%q) = %v, want an error containing %q", tt.overlay, err, tt.wantErr)
+		}
+	}
+}
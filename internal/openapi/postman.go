@@ -0,0 +1,177 @@
+package openapi
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+)
+
+// PostmanSchema is the Collection Format the Postman export follows.
+const PostmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+type postmanCollection struct {
+	Info     postmanInfo       `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Variable []postmanKeyValue `json:"variable,omitempty"`
+}
+
+type postmanInfo struct {
+	PostmanID   string `json:"_postman_id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Schema      string `json:"schema"`
+}
+
+// postmanItem is a request or, when Item is set, a folder.
+type postmanItem struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Item        []postmanItem   `json:"item,omitempty"`
+	Request     *postmanRequest `json:"request,omitempty"`
+}
+
+type postmanRequest struct {
+	Method      string            `json:"method"`
+	Header      []postmanKeyValue `json:"header"`
+	URL         postmanURL        `json:"url"`
+	Body        *postmanBody      `json:"body,omitempty"`
+	Description string            `json:"description,omitempty"`
+}
+
+type postmanURL struct {
+	Raw      string            `json:"raw"`
+	Host     []string          `json:"host"`
+	Path     []string          `json:"path,omitempty"`
+	Query    []postmanKeyValue `json:"query,omitempty"`
+	Variable []postmanKeyValue `json:"variable,omitempty"`
+}
+
+type postmanBody struct {
+	Mode       string            `json:"mode"`
+	Raw        string            `json:"raw,omitempty"`
+	URLEncoded []postmanKeyValue `json:"urlencoded,omitempty"`
+	FormData   []postmanKeyValue `json:"formdata,omitempty"`
+	Options    map[string]any    `json:"options,omitempty"`
+}
+
+type postmanKeyValue struct {
+	Key         string `json:"key"`
+	Value       string `json:"value"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	Disabled    bool   `json:"disabled,omitempty"`
+}
+
+// Postman renders the collection in Postman Collection Format v2.1. Requests
+// call {{baseUrl}}, set to the first server; its variables become collection
+// variables and path parameters become :name segments.
+func (c *Collection) Postman() ([]byte, error) {
+	out := postmanCollection{
+		Info: postmanInfo{
+			PostmanID:   exportUUID(c.ID),
+			Name:        c.Name,
+			Description: c.Description,
+			Version:     c.Version,
+			Schema:      PostmanSchema,
+		},
+		Item: []postmanItem{},
+	}
+
+	folders := map[string]int{}
+	for _, f := range c.Folders {
+		folders[f.Name] = len(out.Item)
+		out.Item = append(out.Item, postmanItem{Name: f.Name, Description: f.Description, Item: []postmanItem{}})
+	}
+	for _, r := range c.Requests {
+		item := postmanItem{Name: r.Name, Request: postmanRequestOf(r)}
+		if i, ok := folders[r.Folder]; ok {
+			out.Item[i].Item = append(out.Item[i].Item, item)
+		} else {
+			out.Item = append(out.Item, item)
+		}
+	}
+
+	base := postmanKeyValue{Key: "baseUrl", Value: "", Type: "string"}
+	if len(c.Servers) > 0 {
+		server := c.Servers[0]
+		base.Value = templateParam.ReplaceAllString(server.URL, "{{$1}}")
+		base.Description = server.Description
+		for _, v := range server.Variables {
+			out.Variable = append(out.Variable, postmanKeyValue{Key: v.Name, Value: v.Value, Type: "string", Description: v.Description})
+		}
+	}
+	out.Variable = append([]postmanKeyValue{base}, out.Variable...)
+	return exportJSON(out)
+}
+
+func postmanRequestOf(r CollectionRequest) *postmanRequest {
+	req := &postmanRequest{Method: r.Method, Header: postmanValues(r.Headers, ""), Description: r.Description}
+
+	path := templateParam.ReplaceAllString(r.Path, ":$1")
+	req.URL = postmanURL{
+		Host:     []string{"{{baseUrl}}"},
+		Query:    postmanValues(r.Query, ""),
+		Variable: postmanValues(r.PathParams, ""),
+	}
+	for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+		if segment != "" {
+			req.URL.Path = append(req.URL.Path, segment)
+		}
+	}
+	req.URL.Raw = "{{baseUrl}}" + path + queryString(r.Query)
+
+	switch {
+	case r.BodyType == "":
+	case r.BodyType == "application/x-www-form-urlencoded":
+		req.Body = &postmanBody{Mode: "urlencoded", URLEncoded: postmanValues(r.Form, "")}
+	case r.BodyType == "multipart/form-data":
+		req.Body = &postmanBody{Mode: "formdata", FormData: postmanValues(r.Form, "text")}
+	default:
+		req.Body = &postmanBody{Mode: "raw", Raw: r.Body}
+		if isJSONMedia(r.BodyType) {
+			req.Body.Options = map[string]any{"raw": map[string]any{"language": "json"}}
+		}
+	}
+	return req
+}
+
+// postmanValues converts parameters; fieldType, when set, types form fields
+// as text or file.
+func postmanValues(params []CollectionParam, fieldType string) []postmanKeyValue {
+	out := make([]postmanKeyValue, 0, len(params))
+	for _, p := range params {
+		kv := postmanKeyValue{Key: p.Name, Value: p.Value, Description: p.Description, Disabled: p.Disabled}
+		if fieldType != "" {
+			kv.Type = fieldType
+			if p.File {
+				kv.Type = "file"
+			}
+		}
+		out = append(out, kv)
+	}
+	return out
+}
+
+// queryString joins the enabled query parameters for a raw URL.
+func queryString(query []CollectionParam) string {
+	var parts []string
+	for _, q := range query {
+		if !q.Disabled {
+			parts = append(parts, q.Name+"="+q.Value)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "?" + strings.Join(parts, "&")
+}
+
+// exportUUID derives a stable UUID-shaped identifier from seed, so
+// re-importing an export updates the collection instead of duplicating it.
+func exportUUID(seed string) string {
+	sum := sha256.Sum256([]byte("postman\x00" + seed))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
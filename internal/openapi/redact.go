@@ -0,0 +1,428 @@
+package openapi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RedactionRules select the internal parts of a document.
+type RedactionRules struct {
+	Extension string   // flag extension such as x-internal; empty disables flags
+	Tags      []string // operations with one of these tags are internal
+	Paths     []string // path patterns; * matches within a segment, ** across segments
+}
+
+// Empty reports whether the rules redact nothing.
+func (r RedactionRules) Empty() bool {
+	return r.Extension == "" && len(r.Tags) == 0 && len(r.Paths) == 0
+}
+
+// CompilePathPattern turns a path pattern like /admin/** or /users/*/audit
+// into a regular expression matching whole paths.
+func CompilePathPattern(pattern string) (*regexp.Regexp, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return nil, fmt.Errorf("path pattern %q must start with /", pattern)
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			b.WriteString("[^/]*")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// Redact removes the internal parts of a document: path items matching a path
+// pattern or flagged with the extension, operations flagged or carrying an
+// internal tag, flagged parameters and schema properties, and the internal
+// tags themselves. Components only the removed parts referenced are removed
+// too, as are flagged components nothing public references any more.
+// AsyncAPI channels are redacted like path items, without path patterns.
+func Redact(rules RedactionRules) (NodeTransform, error) {
+	var patterns []*regexp.Regexp
+	for _, p := range rules.Paths {
+		re, err := CompilePathPattern(p)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, re)
+	}
+	r := &redactor{rules: rules, patterns: patterns, tags: map[string]bool{}}
+	for _, t := range rules.Tags {
+		r.tags[t] = true
+	}
+	return r.apply, nil
+}
+
+type redactor struct {
+	rules    RedactionRules
+	patterns []*regexp.Regexp
+	tags     map[string]bool
+	root     *yaml.Node
+	changed  bool
+}
+
+func (r *redactor) apply(doc *yaml.Node) bool {
+	r.root = documentRoot(doc)
+	if r.root == nil || r.rules.Empty() {
+		return false
+	}
+	r.changed = false
+	before := reachableComponents(r.root)
+
+	if mappingValue(r.root, "asyncapi") != nil {
+		r.filterChannels()
+	} else {
+		if paths := mappingValue(r.root, "paths"); paths != nil && paths.Kind == yaml.MappingNode {
+			r.filterItems(paths, true, isHTTPMethod)
+		}
+		if webhooks := mappingValue(r.root, "webhooks"); webhooks != nil && webhooks.Kind == yaml.MappingNode {
+			r.filterItems(webhooks, false, isHTTPMethod)
+		}
+	}
+	r.filterProperties(r.root)
+	for _, owner := range []*yaml.Node{r.root, mappingValue(r.root, "info")} {
+		if owner == nil || owner.Kind != yaml.MappingNode {
+			continue
+		}
+		if tags := mappingValue(owner, "tags"); tags != nil && tags.Kind == yaml.SequenceNode {
+			r.filterSequence(tags, func(tag *yaml.Node) bool {
+				name := mappingValue(tag, "name")
+				return r.flagged(tag) || (name != nil && r.tags[name.Value])
+			})
+		}
+	}
+
+	after := reachableComponents(r.root)
+	for _, section := range componentSections(r.root) {
+		for i := 0; i+1 < len(section.node.Content); i += 2 {
+			key := section.prefix + escapePointer(section.node.Content[i].Value)
+			if after[key] {
+				continue
+			}
+			if before[key] || r.flagged(section.node.Content[i+1]) {
+				section.node.Content = append(section.node.Content[:i], section.node.Content[i+2:]...)
+				i -= 2
+				r.changed = true
+			}
+		}
+	}
+	return r.changed
+}
+
+// filterItems removes internal path items (or webhooks, or AsyncAPI 2.x
+// channels) and operations, and items left without operations. isOperation
+// tells the operation keys of an item apart.
+func (r *redactor) filterItems(items *yaml.Node, matchPaths bool, isOperation func(key string) bool) {
+	for i := 0; i+1 < len(items.Content); i += 2 {
+		name, item := items.Content[i].Value, items.Content[i+1]
+		internal := r.flagged(item)
+		if matchPaths {
+			for _, re := range r.patterns {
+				if re.MatchString(name) {
+					internal = true
+				}
+			}
+		}
+		if !internal && item.Kind == yaml.MappingNode {
+			hadOperations, operations := false, 0
+			for j := 0; j+1 < len(item.Content); j += 2 {
+				if !isOperation(item.Content[j].Value) {
+					continue
+				}
+				hadOperations = true
+				op := item.Content[j+1]
+				if r.flagged(op) || r.hasInternalTag(op) {
+					item.Content = append(item.Content[:j], item.Content[j+2:]...)
+					j -= 2
+					r.changed = true
+					continue
+				}
+				operations++
+				r.filterParameters(op)
+			}
+			r.filterParameters(item)
+			internal = hadOperations && operations == 0
+		}
+		if internal {
+			items.Content = append(items.Content[:i], items.Content[i+2:]...)
+			i -= 2
+			r.changed = true
+		}
+	}
+}
+
+// filterChannels removes internal AsyncAPI channels and operations. In 2.x
+// the publish and subscribe operations live in their channel, as HTTP
+// operations do in a path item. In 3.x operations are listed on their own and
+// reference their channel: operations on a removed channel go, and so do
+// channels whose operations were all removed.
+func (r *redactor) filterChannels() {
+	channels := mappingValue(r.root, "channels")
+	if channels == nil || channels.Kind != yaml.MappingNode {
+		channels = &yaml.Node{Kind: yaml.MappingNode}
+	}
+	operations := mappingValue(r.root, "operations")
+	if operations == nil || operations.Kind != yaml.MappingNode {
+		r.filterItems(channels, false, func(key string) bool { return key == "publish" || key == "subscribe" })
+		return
+	}
+	r.filterItems(channels, false, func(string) bool { return false })
+
+	channelOf := func(op *yaml.Node) string {
+		if ch := mappingValue(op, "channel"); ch != nil && ch.Kind == yaml.MappingNode {
+			if ref := mappingValue(ch, "$ref"); ref != nil && strings.HasPrefix(ref.Value, "#/channels/") {
+				return ref.Value
+			}
+		}
+		return ""
+	}
+	had, kept := map[string]bool{}, map[string]bool{}
+	for i := 0; i+1 < len(operations.Content); i += 2 {
+		op := operations.Content[i+1]
+		ref := channelOf(op)
+		had[ref] = true
+		if r.flagged(op) || r.hasInternalTag(op) || (ref != "" && nodeAtPointer(r.root, ref) == nil) {
+			operations.Content = append(operations.Content[:i], operations.Content[i+2:]...)
+			i -= 2
+			r.changed = true
+			continue
+		}
+		kept[ref] = true
+	}
+	for i := 0; i+1 < len(channels.Content); i += 2 {
+		ref := "#/channels/" + escapePointer(channels.Content[i].Value)
+		if had[ref] && !kept[ref] {
+			channels.Content = append(channels.Content[:i], channels.Content[i+2:]...)
+			i -= 2
+			r.changed = true
+		}
+	}
+}
+
+// filterParameters drops flagged parameters, inline or referenced, and the
+// parameters list if nothing is left.
+func (r *redactor) filterParameters(owner *yaml.Node) {
+	params := mappingValue(owner, "parameters")
+	if params == nil || params.Kind != yaml.SequenceNode {
+		return
+	}
+	had := len(params.Content)
+	r.filterSequence(params, func(p *yaml.Node) bool {
+		if ref := mappingValue(p, "$ref"); ref != nil {
+			if target := nodeAtPointer(r.root, ref.Value); target != nil {
+				return r.flagged(target)
+			}
+		}
+		return r.flagged(p)
+	})
+	if had > 0 && len(params.Content) == 0 {
+		deleteMappingKey(owner, "parameters")
+	}
+}
+
+// filterProperties drops flagged schema properties everywhere, along with
+// their entries in the sibling required list.
+func (r *redactor) filterProperties(n *yaml.Node) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		if props := mappingValue(n, "properties"); props != nil && props.Kind == yaml.MappingNode {
+			removed := map[string]bool{}
+			for i := 0; i+1 < len(props.Content); i += 2 {
+				if r.flagged(props.Content[i+1]) {
+					removed[props.Content[i].Value] = true
+					props.Content = append(props.Content[:i], props.Content[i+2:]...)
+					i -= 2
+					r.changed = true
+				}
+			}
+			if required := mappingValue(n, "required"); len(removed) > 0 && required != nil && required.Kind == yaml.SequenceNode {
+				r.filterSequence(required, func(c *yaml.Node) bool { return removed[c.Value] })
+			}
+		}
+		for i := 1; i < len(n.Content); i += 2 {
+			r.filterProperties(n.Content[i])
+		}
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			r.filterProperties(c)
+		}
+	}
+}
+
+func (r *redactor) filterSequence(seq *yaml.Node, drop func(*yaml.Node) bool) {
+	kept := seq.Content[:0]
+	for _, c := range seq.Content {
+		if drop(c) {
+			r.changed = true
+			continue
+		}
+		kept = append(kept, c)
+	}
+	seq.Content = kept
+}
+
+func (r *redactor) flagged(n *yaml.Node) bool {
+	if r.rules.Extension == "" || n.Kind != yaml.MappingNode {
+		return false
+	}
+	v := mappingValue(n, r.rules.Extension)
+	return v != nil && v.Kind == yaml.ScalarNode && v.Value == "true"
+}
+
+func (r *redactor) hasInternalTag(op *yaml.Node) bool {
+	tags := mappingValue(op, "tags")
+	if tags == nil || tags.Kind != yaml.SequenceNode {
+		return false
+	}
+	for _, t := range tags.Content {
+		// AsyncAPI lists Tag Objects instead of tag names
+		if name := mappingValue(t, "name"); t.Kind == yaml.MappingNode && name != nil {
+			t = name
+		}
+		if r.tags[t.Value] {
+			return true
+		}
+	}
+	return false
+}
+
+func isHTTPMethod(key string) bool {
+	for _, m := range HTTPMethods {
+		if strings.EqualFold(m, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// componentSection is a map of reusable definitions; prefix is the JSON
+// pointer of its entries, e.g. "#/components/schemas/".
+type componentSection struct {
+	prefix string
+	node   *yaml.Node
+}
+
+// componentSections lists the reusable definition maps of a document.
+// Security schemes are referenced by name, not $ref, so they are left out.
+func componentSections(root *yaml.Node) []componentSection {
+	var out []componentSection
+	if mappingValue(root, "swagger") != nil {
+		for _, name := range []string{"definitions", "parameters", "responses"} {
+			if n := mappingValue(root, name); n != nil && n.Kind == yaml.MappingNode {
+				out = append(out, componentSection{"#/" + name + "/", n})
+			}
+		}
+		return out
+	}
+	components := mappingValue(root, "components")
+	if components == nil || components.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(components.Content); i += 2 {
+		name, n := components.Content[i].Value, components.Content[i+1]
+		if name != "securitySchemes" && n.Kind == yaml.MappingNode {
+			out = append(out, componentSection{"#/components/" + name + "/", n})
+		}
+	}
+	return out
+}
+
+// reachableComponents returns the components (as "#/components/schemas/Pet"
+// or "#/definitions/Pet") reachable by $ref from outside the component sections.
+func reachableComponents(root *yaml.Node) map[string]bool {
+	sections := componentSections(root)
+	prefixes := make([]string, len(sections))
+	for i, s := range sections {
+		prefixes[i] = s.prefix
+	}
+	componentOf := func(ref string) string {
+		for _, p := range prefixes {
+			if strings.HasPrefix(ref, p) {
+				name, _, _ := strings.Cut(strings.TrimPrefix(ref, p), "/")
+				return p + name
+			}
+		}
+		return ""
+	}
+
+	reached := map[string]bool{}
+	var queue []string
+	visit := func(n *yaml.Node) {
+		collectNodeRefs(n, func(ref string) {
+			if key := componentOf(ref); key != "" && !reached[key] {
+				reached[key] = true
+				queue = append(queue, key)
+			}
+		})
+	}
+	swagger := mappingValue(root, "swagger") != nil
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		switch root.Content[i].Value {
+		case "components":
+			if !swagger {
+				continue
+			}
+		case "definitions", "parameters", "responses":
+			if swagger {
+				continue
+			}
+		}
+		visit(root.Content[i+1])
+	}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		if n := nodeAtPointer(root, key); n != nil {
+			visit(n)
+		}
+	}
+	return reached
+}
+
+func collectNodeRefs(n *yaml.Node, fn func(ref string)) {
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if v := n.Content[i+1]; n.Content[i].Value == "$ref" && v.Kind == yaml.ScalarNode && strings.HasPrefix(v.Value, "#") {
+				fn(v.Value)
+				continue
+			}
+			collectNodeRefs(n.Content[i+1], fn)
+		}
+	case yaml.SequenceNode:
+		for _, c := range n.Content {
+			collectNodeRefs(c, fn)
+		}
+	}
+}
+
+// nodeAtPointer resolves a local ref like "#/components/schemas/Pet".
+func nodeAtPointer(root *yaml.Node, ref string) *yaml.Node {
+	n := root
+	pointer := strings.TrimPrefix(ref, "#")
+	if pointer == "" {
+		return n
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		if n = mappingValue(n, token); n == nil {
+			return nil
+		}
+	}
+	return n
+}
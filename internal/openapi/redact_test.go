@@ -0,0 +1,335 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const redactBase = `
+openapi: 3.1.0
+info: {title: Pets, version: '1'}
+tags: [{name: pets}, {name: admin}]
+paths:
+  /pets:
+    get:
+      tags: [pets]
+      parameters:
+        - {name: limit, in: query, schema: {type: integer}}
+        - {name: debug, in: query, x-internal: true, schema: {type: boolean}}
+        - $ref: '#/components/parameters/Trace'
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+    post:
+      tags: [pets, admin]
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: '#/components/schemas/NewPet'}
+      responses:
+        '201': {description: created}
+  /pets/{id}:
+    x-internal: true
+    get:
+      responses:
+        '200': {description: ok}
+  /admin/users/{id}/audit:
+    get:
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Audit'}
+webhooks:
+  petAdopted:
+    post:
+      x-internal: true
+      responses:
+        '200': {description: ok}
+components:
+  parameters:
+    Trace: {name: X-Trace, in: header, x-internal: true, schema: {type: string}}
+  schemas:
+    Pet:
+      type: object
+      required: [id, secret]
+      properties:
+        id: {type: integer}
+        secret: {type: string, x-internal: true}
+    NewPet:
+      type: object
+      properties:
+        owner: {$ref: '#/components/schemas/Owner'}
+    Owner: {type: object}
+    Audit: {type: object}
+    Unused: {type: object}
+    Hidden: {type: object, x-internal: true}
+`
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules RedactionRules
+		doc   string            // defaults to redactBase
+		want  map[string]string // JSON pointer -> expected value as YAML, "" = removed
+		same  bool              // the document is returned unchanged
+	}{
+		{
+			name:  "flagged path items, operations and webhooks",
+			rules: RedactionRules{Extension: "x-internal"},
+			want: map[string]string{
+				"/paths/~1pets~1{id}":    "",
+				"/paths/~1pets/get/tags": "[pets]",
+				"/paths/~1admin~1users~1{id}~1audit/get/responses/200/description": "ok",
+				"/webhooks/petAdopted": "",
+			},
+		},
+		{
+			name:  "operations with an internal tag, and the tag",
+			rules: RedactionRules{Tags: []string{"admin"}},
+			want: map[string]string{
+				"/paths/~1pets/post":                          "",
+				"/paths/~1pets/get/responses/200/description": "ok",
+				"/tags": "[{name: pets}]",
+			},
+		},
+		{
+			name:  "path items left without operations",
+			rules: RedactionRules{Tags: []string{"pets"}},
+			want: map[string]string{
+				"/paths/~1pets":       "",
+				"/paths/~1pets~1{id}": "{x-internal: true, get: {responses: {'200': {description: ok}}}}",
+			},
+		},
+		{
+			name:  "path patterns",
+			rules: RedactionRules{Paths: []string{"/admin/**", "/pets/*"}},
+			want: map[string]string{
+				"/paths/~1admin~1users~1{id}~1audit": "",
+				"/paths/~1pets~1{id}":                "",
+				"/paths/~1pets/get/tags":             "[pets]",
+			},
+		},
+		{
+			name:  "patterns match whole segments",
+			rules: RedactionRules{Paths: []string{"/admin/*", "/pet"}},
+			want: map[string]string{
+				"/paths/~1admin~1users~1{id}~1audit/get/responses/200/description": "ok",
+				"/paths/~1pets/get/tags": "[pets]",
+			},
+		},
+		{
+			name:  "parameters, inline and referenced",
+			rules: RedactionRules{Extension: "x-internal"},
+			want: map[string]string{
+				"/paths/~1pets/get/parameters": "[{name: limit, in: query, schema: {type: integer}}]",
+				"/components/parameters":       "{}",
+			},
+		},
+		{
+			name:  "parameter lists left empty",
+			rules: RedactionRules{Extension: "x-internal"},
+			doc: `
+openapi: 3.0.3
+info: {title: Pets, version: '1'}
+paths:
+  /pets:
+    parameters: [{name: debug, in: query, x-internal: true, schema: {type: boolean}}]
+    get:
+      responses:
+        '200': {description: ok}
+`,
+			want: map[string]string{"/paths/~1pets/parameters": ""},
+		},
+		{
+			name:  "properties and their required entries",
+			rules: RedactionRules{Extension: "x-internal"},
+			want: map[string]string{
+				"/components/schemas/Pet": "{type: object, required: [id], properties: {id: {type: integer}}}",
+			},
+		},
+		{
+			name:  "components only removed operations referenced",
+			rules: RedactionRules{Tags: []string{"admin"}, Paths: []string{"/admin/**"}},
+			want: map[string]string{
+				"/components/schemas/NewPet":                "",
+				"/components/schemas/Owner":                 "",
+				"/components/schemas/Audit":                 "",
+				"/components/schemas/Pet/properties/secret": "{type: string, x-internal: true}",
+				"/components/schemas/Unused":                "{type: object}",
+				"/components/schemas/Hidden":                "{type: object, x-internal: true}",
+			},
+		},
+		{
+			name:  "flagged components nothing references",
+			rules: RedactionRules{Extension: "x-internal"},
+			want: map[string]string{
+				"/components/schemas/Hidden": "",
+				"/components/schemas/Unused": "{type: object}",
+				"/components/schemas/Owner":  "{type: object}",
+			},
+		},
+		{
+			name:  "Swagger 2 definitions",
+			rules: RedactionRules{Extension: "x-internal"},
+			doc: `
+swagger: '2.0'
+info: {title: Pets, version: '1'}
+paths:
+  /pets:
+    get:
+      responses:
+        '200': {description: ok, schema: {$ref: '#/definitions/Pet'}}
+  /stats:
+    get:
+      x-internal: true
+      responses:
+        '200': {description: ok, schema: {$ref: '#/definitions/Stats'}}
+definitions:
+  Pet: {type: object}
+  Stats: {type: object}
+`,
+			want: map[string]string{
+				"/paths/~1stats":     "",
+				"/definitions/Stats": "",
+				"/definitions/Pet":   "{type: object}",
+			},
+		},
+		{
+			name:  "AsyncAPI 2 channels and operations",
+			rules: RedactionRules{Extension: "x-internal", Tags: []string{"admin"}},
+			doc: `
+asyncapi: 2.6.0
+info: {title: Events, version: '1'}
+channels:
+  user/signedup:
+    subscribe:
+      message: {$ref: '#/components/messages/UserSignedUp'}
+    publish:
+      x-internal: true
+      message: {$ref: '#/components/messages/Audit'}
+  user/deleted:
+    subscribe:
+      tags: [{name: admin}]
+      message: {payload: {type: object}}
+components:
+  messages:
+    UserSignedUp: {payload: {type: object}}
+    Audit: {payload: {type: object}}
+`,
+			want: map[string]string{
+				"/channels/user~1signedup/publish":   "",
+				"/channels/user~1signedup/subscribe": "{message: {$ref: '#/components/messages/UserSignedUp'}}",
+				"/channels/user~1deleted":            "",
+				"/components/messages/Audit":         "",
+			},
+		},
+		{
+			name:  "AsyncAPI 3 channels and operations",
+			rules: RedactionRules{Extension: "x-internal", Tags: []string{"admin"}},
+			doc: `
+asyncapi: 3.0.0
+info: {title: Events, version: '1', tags: [{name: users}, {name: admin}]}
+channels:
+  signedUp: {address: user/signedup}
+  deleted: {address: user/deleted}
+  audit: {address: audit, x-internal: true}
+operations:
+  onSignedUp: {action: send, channel: {$ref: '#/channels/signedUp'}}
+  auditSignedUp: {action: send, channel: {$ref: '#/channels/signedUp'}, x-internal: true}
+  onDeleted: {action: send, channel: {$ref: '#/channels/deleted'}, tags: [{name: admin}]}
+  onAudit: {action: receive, channel: {$ref: '#/channels/audit'}}
+`,
+			want: map[string]string{
+				"/operations": "{onSignedUp: {action: send, channel: {$ref: '#/channels/signedUp'}}}",
+				"/channels":   "{signedUp: {address: user/signedup}}",
+				"/info/tags":  "[{name: users}]",
+			},
+		},
+		{
+			name:  "empty rules change nothing",
+			rules: RedactionRules{},
+			same:  true,
+		},
+		{
+			name:  "rules that match nothing change nothing",
+			rules: RedactionRules{Extension: "x-hidden", Tags: []string{"billing"}, Paths: []string{"/billing/**"}},
+			same:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := tt.doc
+			if doc == "" {
+				doc = redactBase
+			}
+			redact, err := Redact(tt.rules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := Transform([]byte(doc), redact)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.same {
+				if string(out) != doc {
+					t.Errorf("document changed:\n%s", out)
+				}
+				return
+			}
+			var root any
+			if err := yaml.Unmarshal(out, &root); err != nil {
+				t.Fatal(err)
+			}
+			root = Normalize(root)
+			for pointer, want := range tt.want {
+				value, ok := lookupPointer(root, pointer)
+				if want == "" {
+					if ok {
+						t.Errorf("%s = %v, want it removed", pointer, value)
+					}
+					continue
+				}
+				var expected any
+				if err := yaml.Unmarshal([]byte(want), &expected); err != nil {
+					t.Fatalf("%s: bad expectation: %v", pointer, err)
+				}
+				if !ok || !reflect.DeepEqual(value, Normalize(expected)) {
+					t.Errorf("%s = %#v, want %#v", pointer, value, Normalize(expected))
+				}
+			}
+		})
+	}
+}
+
+func TestCompilePathPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "/admin/**", path: "/admin/users/1", want: true},
+		{pattern: "/admin/**", path: "/administrators", want: false},
+		{pattern: "/users/*/audit", path: "/users/{id}/audit", want: true},
+		{pattern: "/users/*/audit", path: "/users/1/2/audit", want: false},
+		{pattern: "/v1.0/*", path: "/v1x0/pets", want: false},
+	}
+	for _, tt := range tests {
+		re, err := CompilePathPattern(tt.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := re.MatchString(tt.path); got != tt.want {
+			t.Errorf("%s matches %s = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+	if _, err := CompilePathPattern("admin/**"); err == nil {
+		t.Error("a pattern without a leading slash was accepted")
+	}
+}
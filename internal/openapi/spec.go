@@ -0,0 +1,354 @@
+// Package openapi works on OpenAPI 3.x and Swagger 2.0 documents in their
+// generic (map) form: parsing, operation indexing, diffing and transforms.
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HTTPMethods lists the operation keys of a path item in display order.
+var HTTPMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Spec is a parsed OpenAPI or Swagger document.
+type Spec struct {
+	Root map[string]any
+}
+
+// Parse reads a YAML or JSON document (JSON is valid YAML).
+func Parse(content []byte) (*Spec, error) {
+	var raw any
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("invalid YAML or JSON format: %w", err)
+	}
+	root, ok := Normalize(raw).(map[string]any)
+	if !ok {
+		return nil, errors.New("document root must be an object")
+	}
+	return &Spec{Root: root}, nil
+}
+
+// Normalize converts YAML decoder output into JSON-compatible values:
+// map keys become strings (e.g. unquoted response codes like 200).
+func Normalize(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			t[k] = Normalize(val)
+		}
+		return t
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, val := range t {
+			m[fmt.Sprint(k)] = Normalize(val)
+		}
+		return m
+	case []any:
+		for i := range t {
+			t[i] = Normalize(t[i])
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// Version returns the value of the openapi or swagger field.
+func (s *Spec) Version() string {
+	if v, ok := s.Root["openapi"]; ok {
+		return fmt.Sprint(v)
+	}
+	if v, ok := s.Root["swagger"]; ok {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// IsSwagger2 reports whether the document uses the Swagger 2.0 layout.
+func (s *Spec) IsSwagger2() bool {
+	_, ok := s.Root["swagger"]
+	return ok
+}
+
+// Lookup resolves a local JSON pointer such as "#/components/schemas/Pet".
+func (s *Spec) Lookup(ref string) (any, bool) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, false
+	}
+	return lookupPointer(s.Root, strings.TrimPrefix(ref, "#"))
+}
+
+// lookupPointer resolves a JSON pointer such as "/components/schemas/Pet" in a document.
+func lookupPointer(doc any, pointer string) (any, bool) {
+	cur := doc
+	if pointer == "" {
+		return cur, true
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := cur.(type) {
+		case map[string]any:
+			next, ok := node[token]
+			if !ok {
+				return nil, false
+			}
+			cur = next
+		case []any:
+			var i int
+			if _, err := fmt.Sscanf(token, "%d", &i); err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			cur = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// Resolve follows local $ref chains of a node. Unresolvable or cyclic refs
+// return the last node reached.
+func (s *Spec) Resolve(node any) any {
+	seen := map[string]bool{}
+	for {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return node
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok || seen[ref] {
+			return node
+		}
+		seen[ref] = true
+		target, ok := s.Lookup(ref)
+		if !ok {
+			return node
+		}
+		node = target
+	}
+}
+
+// Operation is one HTTP method on one path, or on one OpenAPI 3.1 webhook.
+type Operation struct {
+	Method   string         // upper case, e.g. GET
+	Path     string         // e.g. /pets/{id}, or the webhook name
+	Webhook  bool           // Path names an entry of the top-level webhooks map
+	Node     map[string]any // the operation object
+	PathItem map[string]any // the enclosing (resolved) path item
+}
+
+// Key identifies the operation, e.g. "GET /pets/{id}" or "WEBHOOK POST newPet".
+func (o Operation) Key() string {
+	if o.Webhook {
+		return "WEBHOOK " + o.Method + " " + o.Path
+	}
+	return o.Method + " " + o.Path
+}
+
+// PathItems returns the resolved path items keyed by path.
+func (s *Spec) PathItems() map[string]map[string]any {
+	return s.resolvedItems("paths")
+}
+
+// Webhooks returns the resolved OpenAPI 3.1 webhook path items keyed by name.
+func (s *Spec) Webhooks() map[string]map[string]any {
+	return s.resolvedItems("webhooks")
+}
+
+func (s *Spec) resolvedItems(section string) map[string]map[string]any {
+	out := map[string]map[string]any{}
+	items, _ := s.Root[section].(map[string]any)
+	for name, item := range items {
+		if m, ok := s.Resolve(item).(map[string]any); ok {
+			out[name] = m
+		}
+	}
+	return out
+}
+
+// Operations lists every operation sorted by path and method, paths before webhooks.
+func (s *Spec) Operations() []Operation {
+	var ops []Operation
+	add := func(items map[string]map[string]any, webhook bool) {
+		for path, item := range items {
+			for _, method := range HTTPMethods {
+				if node, ok := item[method].(map[string]any); ok {
+					ops = append(ops, Operation{Method: strings.ToUpper(method), Path: path, Webhook: webhook, Node: node, PathItem: item})
+				}
+			}
+		}
+	}
+	add(s.PathItems(), false)
+	add(s.Webhooks(), true)
+	sortOperations(ops)
+	return ops
+}
+
+// sortOperations orders operations by path, then by HTTPMethods order.
+func sortOperations(ops []Operation) {
+	sort.Slice(ops, func(i, j int) bool {
+		if ops[i].Webhook != ops[j].Webhook {
+			return !ops[i].Webhook
+		}
+		if ops[i].Path != ops[j].Path {
+			return ops[i].Path < ops[j].Path
+		}
+		return methodIndex(ops[i].Method) < methodIndex(ops[j].Method)
+	})
+}
+
+func methodIndex(method string) int {
+	for i, m := range HTTPMethods {
+		if strings.EqualFold(m, method) {
+			return i
+		}
+	}
+	return len(HTTPMethods)
+}
+
+// Parameters returns the effective (path-level merged with operation-level)
+// parameters of an operation keyed by "in:name". Swagger 2 body and formData
+// parameters are excluded; they are part of RequestBody.
+func (s *Spec) Parameters(op Operation) map[string]map[string]any {
+	out := map[string]map[string]any{}
+	add := func(list any) {
+		items, _ := list.([]any)
+		for _, item := range items {
+			p, ok := s.Resolve(item).(map[string]any)
+			if !ok {
+				continue
+			}
+			in, _ := p["in"].(string)
+			name, _ := p["name"].(string)
+			if in == "body" || in == "formData" {
+				continue
+			}
+			out[in+":"+name] = p
+		}
+	}
+	add(op.PathItem["parameters"])
+	add(op.Node["parameters"])
+	return out
+}
+
+// RequestBody returns the operation's request body in OpenAPI 3 shape. For
+// Swagger 2 it is synthesized from body/formData parameters.
+func (s *Spec) RequestBody(op Operation) map[string]any {
+	if !s.IsSwagger2() {
+		body, _ := s.Resolve(op.Node["requestBody"]).(map[string]any)
+		return body
+	}
+
+	var params []any
+	if l, ok := op.PathItem["parameters"].([]any); ok {
+		params = append(params, l...)
+	}
+	if l, ok := op.Node["parameters"].([]any); ok {
+		params = append(params, l...)
+	}
+	consumes := stringList(op.Node["consumes"])
+	if len(consumes) == 0 {
+		consumes = stringList(s.Root["consumes"])
+	}
+
+	var body map[string]any
+	form := map[string]any{}
+	var formRequired []any
+	for _, item := range params {
+		p, ok := s.Resolve(item).(map[string]any)
+		if !ok {
+			continue
+		}
+		switch p["in"] {
+		case "body":
+			if len(consumes) == 0 {
+				consumes = []string{"application/json"}
+			}
+			content := map[string]any{}
+			for _, ct := range consumes {
+				content[ct] = map[string]any{"schema": p["schema"]}
+			}
+			body = map[string]any{"content": content}
+			if req, ok := p["required"].(bool); ok {
+				body["required"] = req
+			}
+			if d, ok := p["description"]; ok {
+				body["description"] = d
+			}
+		case "formData":
+			name, _ := p["name"].(string)
+			prop := map[string]any{}
+			for k, v := range p {
+				if k != "in" && k != "name" && k != "required" {
+					prop[k] = v
+				}
+			}
+			form[name] = prop
+			if req, _ := p["required"].(bool); req {
+				formRequired = append(formRequired, name)
+			}
+		}
+	}
+	if body == nil && len(form) > 0 {
+		ct := "application/x-www-form-urlencoded"
+		for _, c := range consumes {
+			if c == "multipart/form-data" {
+				ct = c
+			}
+		}
+		schema := map[string]any{"type": "object", "properties": form}
+		if len(formRequired) > 0 {
+			schema["required"] = formRequired
+		}
+		body = map[string]any{"content": map[string]any{ct: map[string]any{"schema": schema}}}
+	}
+	return body
+}
+
+// Responses returns the resolved responses of an operation keyed by status code.
+func (s *Spec) Responses(op Operation) map[string]map[string]any {
+	out := map[string]map[string]any{}
+	responses, _ := op.Node["responses"].(map[string]any)
+	for code, r := range responses {
+		if m, ok := s.Resolve(r).(map[string]any); ok {
+			out[code] = m
+		}
+	}
+	return out
+}
+
+// Schemas returns the named schemas (components.schemas or Swagger 2 definitions).
+func (s *Spec) Schemas() map[string]any {
+	if s.IsSwagger2() {
+		defs, _ := s.Root["definitions"].(map[string]any)
+		return defs
+	}
+	components, _ := s.Root["components"].(map[string]any)
+	schemas, _ := components["schemas"].(map[string]any)
+	return schemas
+}
+
+func stringList(v any) []string {
+	items, _ := v.([]any)
+	var out []string
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// SortedKeys returns the keys of a map in lexical order.
+func SortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
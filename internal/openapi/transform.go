@@ -0,0 +1,243 @@
+package openapi
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NodeTransform edits a parsed YAML or JSON document in place and reports
+// whether it changed anything.
+type NodeTransform func(doc *yaml.Node) bool
+
+// Transform applies transforms to a document. The result keeps the format,
+// key order and comments of the document; content that no transform changed
+// is returned as is.
+func Transform(content []byte, transforms ...NodeTransform) ([]byte, error) {
+	if len(transforms) == 0 {
+		return content, nil
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(content, &node); err != nil {
+		return nil, fmt.Errorf("invalid YAML or JSON format: %w", err)
+	}
+	changed := false
+	for _, t := range transforms {
+		if t(&node) {
+			changed = true
+		}
+	}
+	if !changed {
+		return content, nil
+	}
+	return encodeNode(&node, DetectFormat(content))
+}
+
+// StripServers removes every Server Object: the servers of the document, its
+// path items and operations, and the server of Link Objects. For Swagger 2 the
+// host and schemes are removed.
+func StripServers() NodeTransform {
+	return func(doc *yaml.Node) bool {
+		root := documentRoot(doc)
+		if root == nil {
+			return false
+		}
+		changed := false
+		if mappingValue(root, "swagger") != nil {
+			changed = deleteMappingKey(root, "host")
+			changed = deleteMappingKey(root, "schemes") || changed
+			return changed
+		}
+		forEachServerEntry(root, func(owner *yaml.Node, key string) {
+			changed = deleteMappingKey(owner, key) || changed
+		})
+		return changed
+	}
+}
+
+// RewriteServers replaces the scheme and host of every absolute server URL
+// with those of origin, keeping the paths. Relative URLs are left alone. For
+// Swagger 2 the host and schemes are set.
+func RewriteServers(origin *url.URL) NodeTransform {
+	return func(doc *yaml.Node) bool {
+		root := documentRoot(doc)
+		if root == nil {
+			return false
+		}
+		if mappingValue(root, "swagger") != nil {
+			changed := setMappingKey(root, "host", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: origin.Host})
+			schemes := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{
+				{Kind: yaml.ScalarNode, Tag: "!!str", Value: origin.Scheme},
+			}}
+			return setMappingKey(root, "schemes", schemes) || changed
+		}
+		changed := false
+		rewrite := func(server *yaml.Node) {
+			u := mappingValue(server, "url")
+			if u == nil || u.Kind != yaml.ScalarNode {
+				return
+			}
+			if rewritten, ok := rewriteOrigin(u.Value, origin); ok {
+				u.Value = rewritten
+				changed = true
+			}
+		}
+		forEachServerEntry(root, func(owner *yaml.Node, key string) {
+			value := mappingValue(owner, key)
+			if key == "server" {
+				rewrite(value)
+				return
+			}
+			for _, server := range value.Content {
+				rewrite(server)
+			}
+		})
+		return changed
+	}
+}
+
+// rewriteOrigin swaps the scheme and authority of an absolute URL (which may
+// contain server variables) for those of origin.
+func rewriteOrigin(raw string, origin *url.URL) (string, bool) {
+	i := strings.Index(raw, "://")
+	if i < 0 {
+		return raw, false
+	}
+	rest := raw[i+3:]
+	path := ""
+	if slash := strings.IndexByte(rest, '/'); slash >= 0 {
+		path = rest[slash:]
+	}
+	rewritten := origin.Scheme + "://" + origin.Host + path
+	return rewritten, rewritten != raw
+}
+
+// forEachServerEntry calls fn for every "servers" list and Link Object
+// "server" in the places OpenAPI allows them: the document, path items
+// (including webhooks, callbacks and components.pathItems), operations, and
+// links of responses and components. Examples, defaults and extensions are
+// not searched, so payloads that happen to look like servers stay as they
+// are. Entries are collected first, so fn may delete them.
+func forEachServerEntry(root *yaml.Node, fn func(owner *yaml.Node, key string)) {
+	type entry struct {
+		owner *yaml.Node
+		key   string
+	}
+	var entries []entry
+	add := func(owner *yaml.Node, key string, valid func(*yaml.Node) bool) {
+		if value := mappingValue(owner, key); value != nil && valid(value) {
+			entries = append(entries, entry{owner, key})
+		}
+	}
+	links := func(m *yaml.Node) {
+		forEachMappingValue(m, func(link *yaml.Node) { add(link, "server", isServerObject) })
+	}
+	responses := func(m *yaml.Node) {
+		forEachMappingValue(m, func(response *yaml.Node) { links(mappingValue(response, "links")) })
+	}
+	var pathItem func(item *yaml.Node)
+	callbacks := func(m *yaml.Node) {
+		forEachMappingValue(m, func(callback *yaml.Node) { forEachMappingValue(callback, pathItem) })
+	}
+	pathItem = func(item *yaml.Node) {
+		add(item, "servers", isServerList)
+		for i := 0; i+1 < len(item.Content); i += 2 {
+			if op := item.Content[i+1]; isHTTPMethod(item.Content[i].Value) && op.Kind == yaml.MappingNode {
+				add(op, "servers", isServerList)
+				responses(mappingValue(op, "responses"))
+				callbacks(mappingValue(op, "callbacks"))
+			}
+		}
+	}
+
+	add(root, "servers", isServerList)
+	forEachMappingValue(mappingValue(root, "paths"), pathItem)
+	forEachMappingValue(mappingValue(root, "webhooks"), pathItem)
+	if components := mappingValue(root, "components"); components != nil {
+		forEachMappingValue(mappingValue(components, "pathItems"), pathItem)
+		callbacks(mappingValue(components, "callbacks"))
+		responses(mappingValue(components, "responses"))
+		links(mappingValue(components, "links"))
+	}
+	for _, e := range entries {
+		fn(e.owner, e.key)
+	}
+}
+
+// forEachMappingValue calls fn for every mapping value of m; m may be nil or
+// not a mapping, in which case nothing happens.
+func forEachMappingValue(m *yaml.Node, fn func(value *yaml.Node)) {
+	if m == nil || m.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 1; i < len(m.Content); i += 2 {
+		if m.Content[i].Kind == yaml.MappingNode {
+			fn(m.Content[i])
+		}
+	}
+}
+
+func isServerList(n *yaml.Node) bool {
+	if n.Kind != yaml.SequenceNode {
+		return false
+	}
+	for _, c := range n.Content {
+		if !isServerObject(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func isServerObject(n *yaml.Node) bool {
+	return n.Kind == yaml.MappingNode && mappingValue(n, "url") != nil
+}
+
+// documentRoot returns the top-level mapping of a parsed document, or nil.
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	n := doc
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	return n
+}
+
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func deleteMappingKey(m *yaml.Node, key string) bool {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// setMappingKey sets or appends a key and reports whether the value changed.
+func setMappingKey(m *yaml.Node, key string, value *yaml.Node) bool {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			old := m.Content[i+1]
+			if old.Kind == yaml.ScalarNode && value.Kind == yaml.ScalarNode && old.Value == value.Value {
+				return false
+			}
+			m.Content[i+1] = value
+			return true
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+	return true
+}
@@ -0,0 +1,176 @@
+package openapi
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const serversDoc = `
+openapi: 3.1.0
+info: {title: Pets, version: '1'}
+servers:
+  - url: https://api.example.com/v1
+  - url: '{scheme}://{region}.example.com:8443/v2'
+    variables:
+      scheme: {default: https}
+      region: {default: eu}
+  - url: /relative
+paths:
+  /pets:
+    servers: [{url: 'https://pets.example.com'}]
+    get:
+      servers: [{url: 'http://localhost:8080/pets'}]
+      responses:
+        '200':
+          description: ok
+          links:
+            next: {operationId: listPets, server: {url: 'https://next.example.com/page'}}
+          content:
+            application/json:
+              schema:
+                type: object
+                default: {servers: [{url: 'https://default.example.com'}]}
+              example: {servers: [{url: 'https://example.example.com'}]}
+              examples:
+                linked: {value: {server: {url: 'https://examples.example.com'}}}
+      callbacks:
+        onEvent:
+          '{$request.body#/callback}':
+            post:
+              servers: [{url: 'https://callback.example.com'}]
+              responses:
+                '200': {description: ok}
+      x-sample: {servers: [{url: 'https://extension.example.com'}]}
+webhooks:
+  adopted:
+    servers: [{url: 'https://hooks.example.com'}]
+    post:
+      responses:
+        '200': {description: ok}
+components:
+  links:
+    Self: {operationId: listPets, server: {url: 'https://link.example.com/self'}}
+  schemas:
+    Config:
+      type: object
+      properties:
+        servers: {type: array, items: {type: object, properties: {url: {type: string}}}}
+      example: {server: {url: 'https://schema.example.com'}}
+`
+
+// untouched are the server-shaped payloads that StripServers and
+// RewriteServers must leave alone.
+var untouched = map[string]string{
+	"/paths/~1pets/get/responses/200/content/application~1json/schema/default":        "{servers: [{url: 'https://default.example.com'}]}",
+	"/paths/~1pets/get/responses/200/content/application~1json/example":               "{servers: [{url: 'https://example.example.com'}]}",
+	"/paths/~1pets/get/responses/200/content/application~1json/examples/linked/value": "{server: {url: 'https://examples.example.com'}}",
+	"/paths/~1pets/get/x-sample":                                                                    "{servers: [{url: 'https://extension.example.com'}]}",
+	"/components/schemas/Config/example":                                                            "{server: {url: 'https://schema.example.com'}}",
+	"/components/schemas/Config/properties/servers/items/properties/url":                            "{type: string}",
+	"/paths/~1pets/get/callbacks/onEvent/{$request.body#~1callback}/post/responses/200/description": "ok",
+}
+
+func TestStripServers(t *testing.T) {
+	want := map[string]string{
+		"/servers":                                   "",
+		"/paths/~1pets/servers":                      "",
+		"/paths/~1pets/get/servers":                  "",
+		"/paths/~1pets/get/responses/200/links/next": "{operationId: listPets}",
+		"/paths/~1pets/get/callbacks/onEvent/{$request.body#~1callback}/post/servers": "",
+		"/webhooks/adopted/servers": "",
+		"/components/links/Self":    "{operationId: listPets}",
+	}
+	for pointer, value := range untouched {
+		want[pointer] = value
+	}
+	checkTransform(t, serversDoc, StripServers(), want)
+}
+
+func TestRewriteServers(t *testing.T) {
+	origin, _ := url.Parse("https://apiscope.example.org")
+	want := map[string]string{
+		"/servers/0/url":                                    "https://apiscope.example.org/v1",
+		"/servers/1/url":                                    "https://apiscope.example.org/v2",
+		"/servers/1/variables/region":                       "{default: eu}",
+		"/servers/2/url":                                    "/relative",
+		"/paths/~1pets/servers":                             "[{url: 'https://apiscope.example.org'}]",
+		"/paths/~1pets/get/servers":                         "[{url: 'https://apiscope.example.org/pets'}]",
+		"/paths/~1pets/get/responses/200/links/next/server": "{url: 'https://apiscope.example.org/page'}",
+		"/paths/~1pets/get/callbacks/onEvent/{$request.body#~1callback}/post/servers": "[{url: 'https://apiscope.example.org'}]",
+		"/webhooks/adopted/servers":         "[{url: 'https://apiscope.example.org'}]",
+		"/components/links/Self/server/url": "https://apiscope.example.org/self",
+	}
+	for pointer, value := range untouched {
+		want[pointer] = value
+	}
+	checkTransform(t, serversDoc, RewriteServers(origin), want)
+}
+
+func TestServerTransformsSwagger2(t *testing.T) {
+	const swagger = "swagger: '2.0'\ninfo: {title: Pets, version: '1'}\nhost: api.example.com\nbasePath: /v1\nschemes: [http]\npaths: {}\n"
+	checkTransform(t, swagger, StripServers(), map[string]string{
+		"/host":     "",
+		"/schemes":  "",
+		"/basePath": "/v1",
+	})
+	origin, _ := url.Parse("https://apiscope.example.org")
+	checkTransform(t, swagger, RewriteServers(origin), map[string]string{
+		"/host":     "apiscope.example.org",
+		"/schemes":  "[https]",
+		"/basePath": "/v1",
+	})
+}
+
+func TestServerTransformsUnchanged(t *testing.T) {
+	origin, _ := url.Parse("https://api.example.com")
+	for name, tt := range map[string]struct {
+		doc       string
+		transform NodeTransform
+	}{
+		"strip without servers":      {doc: "openapi: 3.0.3\ninfo: {title: Pets, version: '1'}\npaths: {}\n", transform: StripServers()},
+		"rewrite to the same origin": {doc: "openapi: 3.0.3\ninfo: {title: Pets, version: '1'}\nservers: [{url: 'https://api.example.com/v1'}, {url: /v2}]\npaths: {}\n", transform: RewriteServers(origin)},
+		"example payloads only":      {doc: "openapi: 3.0.3\ninfo: {title: Pets, version: '1'}\npaths: {}\ncomponents:\n  examples:\n    Cfg: {value: {servers: [{url: 'http://x'}]}}\n", transform: StripServers()},
+	} {
+		out, err := Transform([]byte(tt.doc), tt.transform)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != tt.doc {
+			t.Errorf("%s: document changed:\n%s", name, out)
+		}
+	}
+}
+
+// checkTransform applies transform to doc and compares the values at the JSON
+// pointers of want, given as YAML ("" = absent).
+func checkTransform(t *testing.T, doc string, transform NodeTransform, want map[string]string) {
+	t.Helper()
+	out, err := Transform([]byte(doc), transform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root any
+	if err := yaml.Unmarshal(out, &root); err != nil {
+		t.Fatal(err)
+	}
+	root = Normalize(root)
+	for pointer, want := range want {
+		value, ok := lookupPointer(root, pointer)
+		if want == "" {
+			if ok {
+				t.Errorf("%s = %v, want it removed", pointer, value)
+			}
+			continue
+		}
+		var expected any
+		if err := yaml.Unmarshal([]byte(want), &expected); err != nil {
+			t.Fatalf("%s: bad expectation: %v", pointer, err)
+		}
+		if !ok || !reflect.DeepEqual(value, Normalize(expected)) {
+			t.Errorf("%s = %#v, want %#v", pointer, value, Normalize(expected))
+		}
+	}
+}
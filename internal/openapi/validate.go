@@ -0,0 +1,284 @@
+package openapi
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"gopkg.in/yaml.v3"
+)
+
+// Official OpenAPI Initiative schemas (Apache-2.0), see schemas/README.md.
+//
+//go:embed schemas/*.json
+var schemaFS embed.FS
+
+var officialSchemas = map[string]struct{ file, id string }{
+	"2.0": {"schemas/oas-2.0.json", "http://swagger.io/v2/schema.json"},
+	"3.0": {"schemas/oas-3.0.json", "https://spec.openapis.org/oas/3.0/schema/2021-09-28"},
+	"3.1": {"schemas/oas-3.1.json", "https://spec.openapis.org/oas/3.1/schema/2022-10-07"},
+}
+
+var (
+	compileOnce     sync.Once
+	compiledSchemas map[string]*jsonschema.Schema
+	compileErr      error
+	printer         = message.NewPrinter(language.English)
+)
+
+// ValidationError is one problem found in a document.
+type ValidationError struct {
+	Pointer string `json:"pointer"`          // JSON pointer into the document, "" for the root
+	Path    string `json:"path,omitempty"`   // location in documents that are not JSON, e.g. a GraphQL schema coordinate
+	Line    int    `json:"line,omitempty"`   // 1-based, 0 when unknown
+	Column  int    `json:"column,omitempty"` // 1-based, 0 when unknown
+	Message string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	loc := e.Pointer
+	if e.Path != "" {
+		loc = e.Path
+	} else if loc == "" {
+		loc = "/"
+	}
+	if e.Line > 0 {
+		return fmt.Sprintf("%s (line %d, column %d): %s", loc, e.Line, e.Column, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", loc, e.Message)
+}
+
+func loadSchemas() (map[string]*jsonschema.Schema, error) {
+	compileOnce.Do(func() {
+		c := jsonschema.NewCompiler()
+		for _, s := range officialSchemas {
+			raw, err := schemaFS.ReadFile(s.file)
+			if err != nil {
+				compileErr = err
+				return
+			}
+			doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+			if err != nil {
+				compileErr = fmt.Errorf("%s: %w", s.file, err)
+				return
+			}
+			if err := c.AddResource(s.id, doc); err != nil {
+				compileErr = fmt.Errorf("%s: %w", s.file, err)
+				return
+			}
+		}
+		compiled := map[string]*jsonschema.Schema{}
+		for version, s := range officialSchemas {
+			sch, err := c.Compile(s.id)
+			if err != nil {
+				compileErr = fmt.Errorf("%s: %w", s.file, err)
+				return
+			}
+			compiled[version] = sch
+		}
+		compiledSchemas = compiled
+	})
+	return compiledSchemas, compileErr
+}
+
+// SchemaVersion maps the openapi/swagger field of a document to the official
+// schema that validates it ("2.0", "3.0" or "3.1").
+func SchemaVersion(root map[string]any) (string, *ValidationError) {
+	if v, ok := root["swagger"]; ok {
+		if s, _ := v.(string); s == "2.0" {
+			return "2.0", nil
+		}
+		return "", &ValidationError{Pointer: "/swagger", Message: fmt.Sprintf("unsupported swagger version %v (only 2.0)", v)}
+	}
+	v, ok := root["openapi"]
+	if !ok {
+		return "", &ValidationError{Message: "missing 'openapi' or 'swagger' version field"}
+	}
+	s, _ := v.(string)
+	switch {
+	case strings.HasPrefix(s, "3.0."):
+		return "3.0", nil
+	case strings.HasPrefix(s, "3.1."):
+		return "3.1", nil
+	}
+	return "", &ValidationError{Pointer: "/openapi", Message: fmt.Sprintf("unsupported openapi version %v (expected a string like 3.0.3 or 3.1.0)", v)}
+}
+
+// Validate checks a YAML or JSON document against the official schema of its
+// declared version and returns every problem found, ordered by position.
+func Validate(content []byte) []ValidationError {
+	if len(bytes.TrimSpace(content)) == 0 {
+		return []ValidationError{{Message: "document is empty"}}
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(content, &node); err != nil {
+		return []ValidationError{YAMLError(err)}
+	}
+	var raw any
+	if err := node.Decode(&raw); err != nil {
+		return []ValidationError{YAMLError(err)}
+	}
+	root, ok := Normalize(raw).(map[string]any)
+	if !ok {
+		return []ValidationError{{Message: "document root must be an object"}}
+	}
+
+	version, verr := SchemaVersion(root)
+	if verr != nil {
+		verr.Line, verr.Column = Position(&node, verr.Pointer)
+		return []ValidationError{*verr}
+	}
+	schemas, err := loadSchemas()
+	if err != nil {
+		return []ValidationError{{Message: "validator unavailable: " + err.Error()}}
+	}
+
+	// Round-trip through JSON so numbers and maps have the types the validator expects
+	encoded, err := json.Marshal(root)
+	if err != nil {
+		return []ValidationError{{Message: "document cannot be represented as JSON: " + err.Error()}}
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(encoded))
+	if err != nil {
+		return []ValidationError{{Message: err.Error()}}
+	}
+
+	out := SchemaErrors(schemas[version].Validate(instance), &node)
+	if version == "3.1" {
+		out = append(out, validate31(instance, &node)...)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Line != out[j].Line {
+			return out[i].Line < out[j].Line
+		}
+		return out[i].Column < out[j].Column
+	})
+	return out
+}
+
+// SchemaErrors converts the error of a JSON Schema validation of doc into
+// problems located in doc, one per distinct pointer and message. A nil error
+// gives none.
+func SchemaErrors(err error, doc *yaml.Node) []ValidationError {
+	if err == nil {
+		return nil
+	}
+	ve, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return []ValidationError{{Message: err.Error()}}
+	}
+	var out []ValidationError
+	seen := map[string]bool{}
+	for _, leaf := range relevantCauses(ve) {
+		pointer := jsonPointer(leaf.InstanceLocation)
+		msg := leaf.ErrorKind.LocalizedString(printer)
+		if seen[pointer+"\x00"+msg] {
+			continue
+		}
+		seen[pointer+"\x00"+msg] = true
+		line, col := Position(doc, pointer)
+		out = append(out, ValidationError{Pointer: pointer, Line: line, Column: col, Message: msg})
+	}
+	return out
+}
+
+// relevantCauses flattens the error tree to its leaves. For oneOf/anyOf only
+// the branch that got furthest into the document is kept, so a bad parameter
+// reports its real problem rather than also "missing $ref".
+func relevantCauses(e *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(e.Causes) == 0 {
+		return []*jsonschema.ValidationError{e}
+	}
+	switch e.ErrorKind.(type) {
+	case *kind.OneOf, *kind.AnyOf:
+		var best []*jsonschema.ValidationError
+		bestDepth := -1
+		for _, cause := range e.Causes {
+			leaves := relevantCauses(cause)
+			depth := 0
+			for _, l := range leaves {
+				depth = max(depth, len(l.InstanceLocation))
+			}
+			if depth > bestDepth || (depth == bestDepth && len(leaves) < len(best)) {
+				best, bestDepth = leaves, depth
+			}
+		}
+		return best
+	}
+	var out []*jsonschema.ValidationError
+	for _, cause := range e.Causes {
+		out = append(out, relevantCauses(cause)...)
+	}
+	return out
+}
+
+func jsonPointer(tokens []string) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteByte('/')
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(t, "~", "~0"), "/", "~1"))
+	}
+	return b.String()
+}
+
+// Position finds the line and column of the node a JSON pointer addresses.
+// Object members report the position of their key.
+func Position(doc *yaml.Node, pointer string) (int, int) {
+	n := doc
+	if n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	line, col := n.Line, n.Column
+	if pointer == "" {
+		return line, col
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		for n.Kind == yaml.AliasNode && n.Alias != nil {
+			n = n.Alias
+		}
+		var next *yaml.Node
+		switch n.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				if n.Content[i].Value == token {
+					line, col = n.Content[i].Line, n.Content[i].Column
+					next = n.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if i, err := strconv.Atoi(token); err == nil && i >= 0 && i < len(n.Content) {
+				next = n.Content[i]
+				line, col = next.Line, next.Column
+			}
+		}
+		if next == nil {
+			break
+		}
+		n = next
+	}
+	return line, col
+}
+
+var yamlLineRe = regexp.MustCompile(`line (\d+)`)
+
+// YAMLError converts a YAML syntax error, keeping its line number.
+func YAMLError(err error) ValidationError {
+	e := ValidationError{Message: "invalid YAML or JSON format: " + strings.TrimPrefix(err.Error(), "yaml: ")}
+	if m := yamlLineRe.FindStringSubmatch(err.Error()); m != nil {
+		e.Line, _ = strconv.Atoi(m[1])
+	}
+	return e
+}
@@ -0,0 +1,125 @@
+package openapi
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DialectJSONSchema202012 is the JSON Schema 2020-12 meta-schema.
+	DialectJSONSchema202012 = "https://json-schema.org/draft/2020-12/schema"
+	// DialectOAS31Base is the default dialect of OpenAPI 3.1 Schema Objects:
+	// 2020-12 plus the OpenAPI vocabulary (discriminator, xml, externalDocs, example).
+	DialectOAS31Base = "https://spec.openapis.org/oas/3.1/dialect/base"
+)
+
+var (
+	metaSchemaOnce sync.Once
+	metaSchema     *jsonschema.Schema
+	metaSchemaErr  error
+)
+
+// schemaDialectValidator returns the 2020-12 meta-schema. The OpenAPI base
+// dialect only adds annotation keywords, which 2020-12 already allows.
+func schemaDialectValidator() (*jsonschema.Schema, error) {
+	metaSchemaOnce.Do(func() {
+		metaSchema, metaSchemaErr = jsonschema.NewCompiler().Compile(DialectJSONSchema202012)
+	})
+	return metaSchema, metaSchemaErr
+}
+
+// knownDialect reports whether Schema Objects written in dialect can be checked.
+func knownDialect(dialect string) bool {
+	d := strings.TrimSuffix(dialect, "#")
+	return d == DialectJSONSchema202012 || d == DialectOAS31Base
+}
+
+// validate31 applies the OpenAPI 3.1 rules the official schema leaves out:
+// jsonSchemaDialect must be an absolute URI, and Schema Objects must be valid
+// JSON Schema 2020-12 when written in the default (or 2020-12) dialect.
+func validate31(instance any, node *yaml.Node) []ValidationError {
+	root, _ := instance.(map[string]any)
+	var out []ValidationError
+
+	dialect := DialectOAS31Base
+	if v, ok := root["jsonSchemaDialect"]; ok {
+		s, _ := v.(string)
+		if u, err := url.Parse(s); err != nil || !u.IsAbs() {
+			line, col := Position(node, "/jsonSchemaDialect")
+			out = append(out, ValidationError{Pointer: "/jsonSchemaDialect", Line: line, Column: col, Message: "jsonSchemaDialect must be an absolute URI"})
+			return out
+		}
+		dialect = s
+	}
+
+	meta, err := schemaDialectValidator()
+	if err != nil {
+		return append(out, ValidationError{Message: "JSON Schema 2020-12 validator unavailable: " + err.Error()})
+	}
+
+	forEachSchema(root, "", func(pointer string, schema any) {
+		effective := dialect
+		if m, ok := schema.(map[string]any); ok {
+			if s, ok := m["$schema"].(string); ok {
+				effective = s
+			}
+		}
+		if !knownDialect(effective) {
+			return // custom dialects cannot be checked here
+		}
+		err := meta.Validate(schema)
+		if err == nil {
+			return
+		}
+		ve, ok := err.(*jsonschema.ValidationError)
+		if !ok {
+			out = append(out, ValidationError{Pointer: pointer, Message: err.Error()})
+			return
+		}
+		for _, leaf := range relevantCauses(ve) {
+			p := pointer + jsonPointer(leaf.InstanceLocation)
+			line, col := Position(node, p)
+			out = append(out, ValidationError{
+				Pointer: p, Line: line, Column: col,
+				Message: fmt.Sprintf("invalid JSON Schema: %s", leaf.ErrorKind.LocalizedString(printer)),
+			})
+		}
+	})
+	return out
+}
+
+// forEachSchema calls fn for every top-level Schema Object: components.schemas
+// entries and every "schema" member outside of examples and extensions.
+// Nested schemas are covered by validating their root.
+func forEachSchema(v any, pointer string, fn func(pointer string, schema any)) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		if list, ok := v.([]any); ok {
+			for i, item := range list {
+				forEachSchema(item, fmt.Sprintf("%s/%d", pointer, i), fn)
+			}
+		}
+		return
+	}
+	for key, child := range m {
+		childPointer := pointer + jsonPointer([]string{key})
+		switch {
+		case strings.HasPrefix(key, "x-"), key == "example", key == "examples", key == "default", key == "enum", key == "const":
+			continue
+		case key == "schema":
+			fn(childPointer, child)
+		case key == "schemas" && pointer == "/components":
+			schemas, _ := child.(map[string]any)
+			for name, s := range schemas {
+				fn(childPointer+jsonPointer([]string{name}), s)
+			}
+		default:
+			forEachSchema(child, childPointer, fn)
+		}
+	}
+}
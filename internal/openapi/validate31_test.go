@@ -0,0 +1,129 @@
+package openapi
+
+import (
+	"strings"
+	"testing"
+)
+
+const validate31Header = "openapi: 3.1.0\ninfo: {title: Pets, version: '1'}\n"
+
+func TestValidate31Schemas(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string            // appended to validate31Header
+		want []ValidationError // Message is matched as a substring
+	}{
+		{
+			name: "2020-12 keywords",
+			doc: `components:
+  schemas:
+    Pair:
+      type: array
+      prefixItems: [{type: string}, {type: integer}]
+      items: false
+      unevaluatedProperties: false
+      dependentRequired: {a: [b]}
+      discriminator: {propertyName: kind}
+`,
+		},
+		{
+			name: "bad keyword in a component",
+			doc: `components:
+  schemas:
+    Pet:
+      type: object
+      properties:
+        name: {type: string, minLength: -1}
+`,
+			want: []ValidationError{{Pointer: "/components/schemas/Pet/properties/name/minLength", Line: 8, Column: 30, Message: "invalid JSON Schema"}},
+		},
+		{
+			name: "bad keyword in a media type schema",
+			doc: `paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema:
+                type: 5
+`,
+			want: []ValidationError{{Pointer: "/paths/~1pets/get/responses/200/content/application~1json/schema/type", Line: 12, Column: 17, Message: "invalid JSON Schema"}},
+		},
+		{
+			name: "bad keyword in a webhook parameter",
+			doc: `webhooks:
+  newPet:
+    post:
+      parameters:
+        - name: id
+          in: query
+          schema: {required: id}
+      responses:
+        '200': {description: ok}
+`,
+			want: []ValidationError{{Pointer: "/webhooks/newPet/post/parameters/0/schema/required", Line: 9, Column: 20, Message: "invalid JSON Schema"}},
+		},
+		{
+			name: "schema-shaped examples are not checked",
+			doc: `components:
+  schemas:
+    Pet:
+      type: object
+      examples: [{schema: {type: 5}}]
+      x-sample: {schema: {type: 5}}
+`,
+		},
+		{
+			name: "schemas in another dialect are not checked",
+			doc: `components:
+  schemas:
+    Legacy:
+      $schema: 'http://json-schema.org/draft-04/schema#'
+      type: 5
+`,
+		},
+		{
+			name: "a custom jsonSchemaDialect is not checked",
+			doc: `jsonSchemaDialect: 'https://example.com/dialect'
+components:
+  schemas:
+    Pet: {type: 5}
+`,
+		},
+		{
+			name: "a $schema back to 2020-12 is checked",
+			doc: `jsonSchemaDialect: 'https://example.com/dialect'
+components:
+  schemas:
+    Pet: {$schema: 'https://json-schema.org/draft/2020-12/schema', type: 5}
+`,
+			want: []ValidationError{{Pointer: "/components/schemas/Pet/type", Line: 6, Column: 68, Message: "invalid JSON Schema"}},
+		},
+		{
+			name: "a relative jsonSchemaDialect",
+			doc: `jsonSchemaDialect: dialect.json
+components:
+  schemas:
+    Pet: {type: 5}
+`,
+			want: []ValidationError{{Pointer: "/jsonSchemaDialect", Line: 3, Column: 1, Message: "must be an absolute URI"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Validate([]byte(validate31Header + tt.doc))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d problems, want %d: %v", len(got), len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				g := got[i]
+				if g.Pointer != want.Pointer || g.Line != want.Line || g.Column != want.Column || !strings.Contains(g.Message, want.Message) {
+					t.Errorf("problem %d = %+v, want %+v", i, g, want)
+				}
+			}
+		})
+	}
+}
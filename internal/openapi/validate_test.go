@@ -0,0 +1,237 @@
+package openapi
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestValidateValidDocuments(t *testing.T) {
+	tests := map[string]string{
+		"Swagger 2.0": `
+swagger: '2.0'
+info: {title: Pets, version: '1'}
+paths:
+  /pets:
+    get:
+      parameters: [{name: limit, in: query, type: integer}]
+      responses:
+        '200': {description: ok, schema: {$ref: '#/definitions/Pet'}}
+definitions:
+  Pet: {type: object, properties: {id: {type: integer}}}
+`,
+		"OpenAPI 3.0": `
+openapi: 3.0.3
+info: {title: Pets, version: '1'}
+paths:
+  /pets:
+    get:
+      parameters: [{name: limit, in: query, schema: {type: integer}}]
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema: {$ref: '#/components/schemas/Pet'}
+components:
+  schemas:
+    Pet: {type: object, nullable: true, properties: {id: {type: integer}}}
+`,
+		"OpenAPI 3.1": `
+openapi: 3.1.0
+info: {title: Pets, version: '1', license: {name: MIT, identifier: MIT}}
+paths:
+  /pets:
+    get:
+      responses:
+        '200':
+          description: ok
+          content:
+            application/json:
+              schema: {type: [object, 'null'], properties: {id: {type: integer}}}
+`,
+		"OpenAPI 3.1 with only webhooks": `
+openapi: 3.1.0
+info: {title: Events, version: '1'}
+webhooks:
+  newPet:
+    post:
+      requestBody:
+        content:
+          application/json:
+            schema: {type: object}
+      responses:
+        '200': {description: ok}
+`,
+		"OpenAPI 3.1 with only components": `
+openapi: 3.1.0
+info: {title: Models, version: '1'}
+components:
+  schemas:
+    Pet: {type: object}
+`,
+		"JSON": `{"openapi": "3.0.3", "info": {"title": "Pets", "version": "1"}, "paths": {}}`,
+	}
+	for name, doc := range tests {
+		if problems := Validate([]byte(doc)); len(problems) > 0 {
+			t.Errorf("%s: %v", name, problems)
+		}
+	}
+}
+
+func TestValidateProblems(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []ValidationError // Message is matched as a substring
+	}{
+		{
+			name: "empty",
+			doc:  "  \n",
+			want: []ValidationError{{Message: "document is empty"}},
+		},
+		{
+			name: "not an object",
+			doc:  "- a\n- b\n",
+			want: []ValidationError{{Message: "root must be an object"}},
+		},
+		{
+			name: "YAML syntax",
+			doc:  "openapi: 3.0.3\ninfo:\n  title: Pets\n   version: 1\n",
+			want: []ValidationError{{Line: 4, Message: "invalid YAML or JSON format"}},
+		},
+		{
+			name: "no version",
+			doc:  "info: {title: Pets, version: '1'}\npaths: {}\n",
+			want: []ValidationError{{Line: 1, Column: 1, Message: "missing 'openapi' or 'swagger'"}},
+		},
+		{
+			name: "unsupported version",
+			doc:  "info: {title: Pets, version: '1'}\nopenapi: 4.0.0\npaths: {}\n",
+			want: []ValidationError{{Pointer: "/openapi", Line: 2, Column: 1, Message: "unsupported openapi version"}},
+		},
+		{
+			name: "OpenAPI 3.0 without paths",
+			doc:  "openapi: 3.0.3\ninfo: {title: Pets, version: '1'}\n",
+			want: []ValidationError{{Pointer: "", Line: 1, Column: 1, Message: "missing property 'paths'"}},
+		},
+		{
+			name: "OpenAPI 3.1 without paths, webhooks or components",
+			doc:  "openapi: 3.1.0\ninfo: {title: Pets, version: '1'}\n",
+			want: []ValidationError{{Pointer: "", Line: 1, Column: 1}},
+		},
+		{
+			name: "missing info version, reported at info",
+			doc:  "openapi: 3.0.3\npaths: {}\ninfo:\n  title: Pets\n",
+			want: []ValidationError{{Pointer: "/info", Line: 3, Column: 1, Message: "missing property 'version'"}},
+		},
+		{
+			name: "wrong type, reported at the member's key",
+			doc: `openapi: 3.0.3
+info: {title: Pets, version: '1'}
+paths:
+  /pets:
+    get:
+      parameters:
+        - name: limit
+          in: query
+          required: maybe
+          schema: {type: integer}
+      responses:
+        '200': {description: ok}
+`,
+			want: []ValidationError{{Pointer: "/paths/~1pets/get/parameters/0/required", Line: 9, Column: 11, Message: "got string, want boolean"}},
+		},
+		{
+			name: "unknown parameter location",
+			doc: `openapi: 3.0.3
+info: {title: Pets, version: '1'}
+paths:
+  /pets:
+    get:
+      parameters: [{name: limit, in: body, schema: {type: integer}}]
+      responses:
+        '200': {description: ok}
+`,
+			want: []ValidationError{{Pointer: "/paths/~1pets/get/parameters/0/in", Line: 6, Column: 34}},
+		},
+		{
+			name: "Swagger 2.0 response without description",
+			doc:  "swagger: '2.0'\ninfo: {title: Pets, version: '1'}\npaths:\n  /pets:\n    get:\n      responses:\n        '200': {schema: {type: string}}\n",
+			want: []ValidationError{{Pointer: "/paths/~1pets/get/responses/200", Line: 7, Column: 9}},
+		},
+		{
+			name: "JSON positions",
+			doc:  "{\n  \"openapi\": \"3.0.3\",\n  \"info\": {\"title\": \"Pets\", \"version\": 1},\n  \"paths\": {}\n}\n",
+			want: []ValidationError{{Pointer: "/info/version", Line: 3, Column: 29, Message: "got number, want string"}},
+		},
+		{
+			name: "several problems, in document order",
+			doc: `openapi: 3.0.3
+info: {title: Pets, version: '1'}
+paths:
+  /b:
+    get:
+      responses:
+        '200': {description: 5}
+  /a:
+    get:
+      deprecated: 'yes'
+      responses:
+        '200': {description: ok}
+`,
+			want: []ValidationError{
+				{Pointer: "/paths/~1b/get/responses/200/description", Line: 7, Column: 17, Message: "got number, want string"},
+				{Pointer: "/paths/~1a/get/deprecated", Line: 10, Column: 7, Message: "got string, want boolean"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Validate([]byte(tt.doc))
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d problems, want %d: %v", len(got), len(tt.want), got)
+			}
+			for i, want := range tt.want {
+				g := got[i]
+				if g.Pointer != want.Pointer || g.Line != want.Line || g.Column != want.Column || !strings.Contains(g.Message, want.Message) {
+					t.Errorf("problem %d = %+v, want %+v", i, g, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPosition(t *testing.T) {
+	doc := "a:\n  b: [x, {c: 1}]\n  'd/e': &anchor\n    f: 2\n  g: *anchor\n"
+	node := parseNode(t, doc)
+	tests := []struct {
+		pointer      string
+		line, column int
+	}{
+		{pointer: "", line: 1, column: 1},
+		{pointer: "/a", line: 1, column: 1},
+		{pointer: "/a/b", line: 2, column: 3},
+		{pointer: "/a/b/1", line: 2, column: 10},
+		{pointer: "/a/b/1/c", line: 2, column: 11},
+		{pointer: "/a/d~1e/f", line: 4, column: 5},
+		{pointer: "/a/g/f", line: 4, column: 5},
+		{pointer: "/a/missing/deeper", line: 1, column: 1},
+		{pointer: "/a/b/9", line: 2, column: 3},
+	}
+	for _, tt := range tests {
+		if line, column := Position(node, tt.pointer); line != tt.line || column != tt.column {
+			t.Errorf("Position(%q) = %d:%d, want %d:%d", tt.pointer, line, column, tt.line, tt.column)
+		}
+	}
+}
+
+func parseNode(t *testing.T, doc string) *yaml.Node {
+	t.Helper()
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte(doc), &node); err != nil {
+		t.Fatal(err)
+	}
+	return &node
+}
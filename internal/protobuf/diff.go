@@ -0,0 +1,422 @@
+package protobuf
+
+import (
+	"strconv"
+	"strings"
+
+	"APIScope/internal/openapi"
+)
+
+// Diff is the semantic difference between two sets of .proto files.
+type Diff struct {
+	Services openapi.ChangeSet `json:"services"`
+	Methods  openapi.ChangeSet `json:"methods"`
+	Messages openapi.ChangeSet `json:"messages"`
+	Enums    openapi.ChangeSet `json:"enums"`
+}
+
+// Sections returns the change sets with their display names in report order.
+func (d *Diff) Sections() []struct {
+	Name string
+	Set  *openapi.ChangeSet
+} {
+	return []struct {
+		Name string
+		Set  *openapi.ChangeSet
+	}{
+		{"Services", &d.Services},
+		{"Methods", &d.Methods},
+		{"Messages", &d.Messages},
+		{"Enums", &d.Enums},
+	}
+}
+
+// Empty reports whether the schemas are semantically identical.
+func (d *Diff) Empty() bool {
+	for _, s := range d.Sections() {
+		if !s.Set.Empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// Compare computes the semantic diff from one schema to another. Methods are
+// matched by gRPC path, messages and enums by full name and fields by number,
+// which is what identifies them on the wire; moving a declaration to another
+// file is not a change.
+func Compare(from, to *Schema) *Diff {
+	d := &Diff{}
+	compare(&d.Services, serviceNodes(from), serviceNodes(to))
+	compare(&d.Methods, methodNodes(from), methodNodes(to))
+	compare(&d.Messages, messageNodes(from), messageNodes(to))
+	compare(&d.Enums, enumNodes(from), enumNodes(to))
+
+	// Empty lists instead of null in JSON output
+	for _, s := range d.Sections() {
+		if s.Set.Added == nil {
+			s.Set.Added = []openapi.Change{}
+		}
+		if s.Set.Removed == nil {
+			s.Set.Removed = []openapi.Change{}
+		}
+		if s.Set.Modified == nil {
+			s.Set.Modified = []openapi.Change{}
+		}
+	}
+	return d
+}
+
+// compare fills set with added/removed/modified entries of two named maps.
+func compare(set *openapi.ChangeSet, a, b map[string]any) {
+	for _, name := range openapi.SortedKeys(b) {
+		if _, ok := a[name]; !ok {
+			set.Added = append(set.Added, openapi.Change{Location: name, Name: name})
+		}
+	}
+	for _, name := range openapi.SortedKeys(a) {
+		bv, ok := b[name]
+		if !ok {
+			set.Removed = append(set.Removed, openapi.Change{Location: name, Name: name})
+			continue
+		}
+		if fields := openapi.FieldDiff(a[name], bv); len(fields) > 0 {
+			set.Modified = append(set.Modified, openapi.Change{Location: name, Name: name, Fields: fields})
+		}
+	}
+}
+
+func serviceNodes(s *Schema) map[string]any {
+	out := map[string]any{}
+	for _, svc := range s.Services() {
+		methods := make([]any, len(svc.Methods))
+		for i, m := range svc.Methods {
+			methods[i] = m.Name
+		}
+		out[svc.FullName] = map[string]any{"doc": svc.Doc, "methods": methods}
+	}
+	return out
+}
+
+func methodNodes(s *Schema) map[string]any {
+	out := map[string]any{}
+	for _, svc := range s.Services() {
+		for _, m := range svc.Methods {
+			out[m.Path] = map[string]any{
+				"input":            m.InputResolved,
+				"output":           m.OutputResolved,
+				"client_streaming": m.ClientStreaming,
+				"server_streaming": m.ServerStreaming,
+				"deprecated":       m.Deprecated,
+				"doc":              m.Doc,
+			}
+		}
+	}
+	return out
+}
+
+func messageNodes(s *Schema) map[string]any {
+	out := map[string]any{}
+	for _, m := range s.Messages() {
+		fields := map[string]any{}
+		for _, f := range m.Fields {
+			fields[strconv.Itoa(f.Number)] = map[string]any{
+				"name":       f.Name,
+				"type":       f.TypeName(),
+				"oneof":      f.OneOf,
+				"deprecated": f.Deprecated,
+				"doc":        f.Doc,
+			}
+		}
+		out[m.FullName] = map[string]any{"doc": m.Doc, "fields": fields}
+	}
+	return out
+}
+
+func enumNodes(s *Schema) map[string]any {
+	out := map[string]any{}
+	for _, e := range s.Enums() {
+		values := map[string]any{}
+		for _, v := range e.Values {
+			values[v.Name] = v.Number
+		}
+		out[e.FullName] = map[string]any{"doc": e.Doc, "values": values}
+	}
+	return out
+}
+
+// CheckCompatibility classifies the changes from one version to the next as
+// breaking or non-breaking for clients and servers built from the older
+// version: anything that changes how existing messages are encoded or which
+// RPCs exist is breaking.
+func CheckCompatibility(from, to *Schema) *openapi.CompatibilityReport {
+	r := openapi.NewCompatibilityReport()
+
+	fromServices := byName(from.Services(), func(s *Service) string { return s.FullName })
+	toServices := byName(to.Services(), func(s *Service) string { return s.FullName })
+	for _, name := range openapi.SortedKeys(toServices) {
+		if _, ok := fromServices[name]; !ok {
+			r.Add(openapi.SeverityNonBreaking, "service-added", name, "service %s was added", name)
+		}
+	}
+	for _, name := range openapi.SortedKeys(fromServices) {
+		if _, ok := toServices[name]; !ok {
+			r.Add(openapi.SeverityBreaking, "service-removed", name, "service %s was removed", name)
+		}
+	}
+
+	fromMethods, toMethods := methods(from), methods(to)
+	for _, path := range openapi.SortedKeys(toMethods) {
+		// RPCs of a new service come with it
+		if _, ok := fromMethods[path]; !ok && fromServices[serviceOf(path)] != nil {
+			r.Add(openapi.SeverityNonBreaking, "rpc-added", path, "rpc %s was added", path)
+		}
+	}
+	for _, path := range openapi.SortedKeys(fromMethods) {
+		a := fromMethods[path]
+		b, ok := toMethods[path]
+		if !ok {
+			if toServices[serviceOf(path)] != nil {
+				r.Add(openapi.SeverityBreaking, "rpc-removed", path, "rpc %s was removed", path)
+			}
+			continue
+		}
+		if a.InputResolved != b.InputResolved {
+			r.Add(openapi.SeverityBreaking, "rpc-input-changed", path, "request type changed from %s to %s", a.InputResolved, b.InputResolved)
+		}
+		if a.OutputResolved != b.OutputResolved {
+			r.Add(openapi.SeverityBreaking, "rpc-output-changed", path, "response type changed from %s to %s", a.OutputResolved, b.OutputResolved)
+		}
+		if a.ClientStreaming != b.ClientStreaming || a.ServerStreaming != b.ServerStreaming {
+			r.Add(openapi.SeverityBreaking, "rpc-streaming-changed", path, "streaming changed from %s to %s", streaming(a), streaming(b))
+		}
+		if !a.Deprecated && b.Deprecated {
+			r.Add(openapi.SeverityNonBreaking, "rpc-deprecated", path, "rpc %s was deprecated", path)
+		}
+	}
+
+	fromMessages := byName(from.Messages(), func(m *Message) string { return m.FullName })
+	toMessages := byName(to.Messages(), func(m *Message) string { return m.FullName })
+	for _, name := range openapi.SortedKeys(toMessages) {
+		if _, ok := fromMessages[name]; !ok {
+			r.Add(openapi.SeverityNonBreaking, "message-added", name, "message %s was added", name)
+		}
+	}
+	for _, name := range openapi.SortedKeys(fromMessages) {
+		b, ok := toMessages[name]
+		if !ok {
+			r.Add(openapi.SeverityBreaking, "message-removed", name, "message %s was removed", name)
+			continue
+		}
+		checkFields(r, fromMessages[name], b)
+	}
+
+	fromEnums := byName(from.Enums(), func(e *Enum) string { return e.FullName })
+	toEnums := byName(to.Enums(), func(e *Enum) string { return e.FullName })
+	for _, name := range openapi.SortedKeys(toEnums) {
+		if _, ok := fromEnums[name]; !ok {
+			r.Add(openapi.SeverityNonBreaking, "enum-added", name, "enum %s was added", name)
+		}
+	}
+	for _, name := range openapi.SortedKeys(fromEnums) {
+		b, ok := toEnums[name]
+		if !ok {
+			r.Add(openapi.SeverityBreaking, "enum-removed", name, "enum %s was removed", name)
+			continue
+		}
+		checkValues(r, fromEnums[name], b)
+	}
+	return r
+}
+
+// checkFields compares the fields of a message, matched by number.
+func checkFields(r *openapi.CompatibilityReport, a, b *Message) {
+	fromFields, toFields := map[int]*Field{}, map[int]*Field{}
+	fromNames, toNames := map[string]*Field{}, map[string]*Field{}
+	for _, f := range a.Fields {
+		fromFields[f.Number], fromNames[f.Name] = f, f
+	}
+	for _, f := range b.Fields {
+		toFields[f.Number], toNames[f.Name] = f, f
+	}
+	location := func(f *Field) string {
+		return a.FullName + " field " + strconv.Itoa(f.Number) + " (" + f.Name + ")"
+	}
+
+	for _, f := range b.Fields {
+		if _, ok := fromFields[f.Number]; ok {
+			continue
+		}
+		if old, ok := fromNames[f.Name]; ok && toFields[old.Number] == nil {
+			continue // renumbered, reported below
+		}
+		if a.Reserved.HasNumber(f.Number) {
+			// Data written before the number was retired decodes into the new field
+			r.Add(openapi.SeverityBreaking, "field-number-reused", location(f), "field %s reuses the reserved number %d", f.Name, f.Number)
+			continue
+		}
+		sev := openapi.SeverityNonBreaking
+		if f.Label == "required" {
+			sev = openapi.SeverityBreaking
+		}
+		r.Add(sev, "field-added", location(f), "field %s = %d was added", f.Name, f.Number)
+	}
+
+	for _, f := range a.Fields {
+		g, ok := toFields[f.Number]
+		if !ok {
+			if moved, ok := toNames[f.Name]; ok && fromFields[moved.Number] == nil {
+				r.Add(openapi.SeverityBreaking, "field-renumbered", location(f), "field %s was renumbered from %d to %d", f.Name, f.Number, moved.Number)
+				continue
+			}
+			if b.Reserved.HasNumber(f.Number) {
+				r.Add(openapi.SeverityNonBreaking, "field-removed", location(f), "field %s = %d was removed and its number reserved", f.Name, f.Number)
+			} else {
+				r.Add(openapi.SeverityBreaking, "field-removed", location(f), "field %s = %d was removed without reserving its number", f.Name, f.Number)
+			}
+			continue
+		}
+		loc := location(f)
+		if f.Name != g.Name {
+			r.Add(openapi.SeverityNonBreaking, "field-renamed", loc, "field %d was renamed from %s to %s (the binary encoding is unchanged, the JSON one is not)", f.Number, f.Name, g.Name)
+		}
+		if ta, tb := fieldType(f), fieldType(g); ta != tb {
+			if wireCompatible(f, g) {
+				r.Add(openapi.SeverityNonBreaking, "field-type-changed", loc, "type changed from %s to %s, which encode the same way", ta, tb)
+			} else {
+				r.Add(openapi.SeverityBreaking, "field-type-changed", loc, "type changed from %s to %s", ta, tb)
+			}
+		}
+		if la, lb := cardinality(f), cardinality(g); la != lb {
+			sev := openapi.SeverityBreaking
+			// proto3 optional only adds presence tracking
+			if la != "repeated" && lb != "repeated" && la != "required" && lb != "required" {
+				sev = openapi.SeverityNonBreaking
+			}
+			r.Add(sev, "field-label-changed", loc, "label changed from %s to %s", la, lb)
+		}
+		if f.OneOf != g.OneOf {
+			r.Add(openapi.SeverityBreaking, "field-oneof-changed", loc, "oneof membership changed from %s to %s", oneofName(f.OneOf), oneofName(g.OneOf))
+		}
+		if !f.Deprecated && g.Deprecated {
+			r.Add(openapi.SeverityNonBreaking, "field-deprecated", loc, "field %s was deprecated", g.Name)
+		}
+	}
+}
+
+// checkValues compares the values of an enum, matched by name.
+func checkValues(r *openapi.CompatibilityReport, a, b *Enum) {
+	fromValues, toValues := map[string]*EnumValue{}, map[string]*EnumValue{}
+	for _, v := range a.Values {
+		fromValues[v.Name] = v
+	}
+	for _, v := range b.Values {
+		toValues[v.Name] = v
+	}
+	for _, v := range b.Values {
+		if _, ok := fromValues[v.Name]; !ok {
+			r.Add(openapi.SeverityNonBreaking, "enum-value-added", a.FullName+"."+v.Name, "value %s = %d was added", v.Name, v.Number)
+		}
+	}
+	for _, v := range a.Values {
+		loc := a.FullName + "." + v.Name
+		w, ok := toValues[v.Name]
+		switch {
+		case !ok && (b.Reserved.HasNumber(v.Number) || b.Reserved.HasName(v.Name)):
+			r.Add(openapi.SeverityNonBreaking, "enum-value-removed", loc, "value %s = %d was removed and reserved", v.Name, v.Number)
+		case !ok:
+			r.Add(openapi.SeverityBreaking, "enum-value-removed", loc, "value %s = %d was removed without reserving it", v.Name, v.Number)
+		case v.Number != w.Number:
+			r.Add(openapi.SeverityBreaking, "enum-value-renumbered", loc, "value %s was renumbered from %d to %d", v.Name, v.Number, w.Number)
+		case !v.Deprecated && w.Deprecated:
+			r.Add(openapi.SeverityNonBreaking, "enum-value-deprecated", loc, "value %s was deprecated", v.Name)
+		}
+	}
+}
+
+// fieldType is the type of a field without its label.
+func fieldType(f *Field) string {
+	if f.MapKey != "" {
+		return f.TypeName()
+	}
+	return f.Resolved
+}
+
+// cardinality is the label of a field, "singular" when it has none. Map
+// fields are repeated on the wire.
+func cardinality(f *Field) string {
+	switch {
+	case f.MapKey != "":
+		return "repeated"
+	case f.Label == "":
+		return "singular"
+	}
+	return f.Label
+}
+
+func oneofName(name string) string {
+	if name == "" {
+		return "none"
+	}
+	return name
+}
+
+// wireGroups are the scalar types that share an encoding, so old and new
+// readers still decode each other's values (possibly truncated). Enums are
+// varints too.
+var wireGroups = map[string]string{
+	"int32": "varint", "uint32": "varint", "int64": "varint", "uint64": "varint", "bool": "varint",
+	"sint32": "zigzag", "sint64": "zigzag",
+	"fixed32": "fixed32", "sfixed32": "fixed32",
+	"fixed64": "fixed64", "sfixed64": "fixed64",
+	"string": "bytes", "bytes": "bytes",
+}
+
+func wireCompatible(a, b *Field) bool {
+	if a.MapKey != "" || b.MapKey != "" {
+		return false
+	}
+	group := func(f *Field) string {
+		if f.IsEnum {
+			return "varint"
+		}
+		return wireGroups[f.Resolved]
+	}
+	ga, gb := group(a), group(b)
+	return ga != "" && ga == gb
+}
+
+func streaming(m *Method) string {
+	switch {
+	case m.ClientStreaming && m.ServerStreaming:
+		return "bidirectional streaming"
+	case m.ClientStreaming:
+		return "client streaming"
+	case m.ServerStreaming:
+		return "server streaming"
+	}
+	return "unary"
+}
+
+func methods(s *Schema) map[string]*Method {
+	out := map[string]*Method{}
+	for _, svc := range s.Services() {
+		for _, m := range svc.Methods {
+			out[m.Path] = m
+		}
+	}
+	return out
+}
+
+// serviceOf returns the full name of the service of a gRPC path.
+func serviceOf(path string) string {
+	return path[1:strings.LastIndexByte(path, '/')]
+}
+
+func byName[T any](items []T, name func(T) string) map[string]T {
+	out := make(map[string]T, len(items))
+	for _, item := range items {
+		out[name(item)] = item
+	}
+	return out
+}
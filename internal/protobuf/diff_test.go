@@ -0,0 +1,293 @@
+package protobuf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"APIScope/internal/openapi"
+)
+
+func parseProto(t *testing.T, files map[string]string) *Schema {
+	t.Helper()
+	sources := map[string][]byte{}
+	entry := ""
+	for name, src := range files {
+		sources[name] = []byte(src)
+		if entry == "" || name < entry {
+			entry = name
+		}
+	}
+	schema, err := Parse(sources, entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+// finding is the part of an openapi.Finding a test pins down; messages are free text.
+type finding struct {
+	severity openapi.Severity
+	rule     string
+	location string
+}
+
+// findings flattens a report into its findings, breaking ones first.
+func findings(r *openapi.CompatibilityReport) []finding {
+	var out []finding
+	for _, list := range [][]openapi.Finding{r.Breaking, r.NonBreaking} {
+		for _, f := range list {
+			out = append(out, finding{f.Severity, f.Rule, f.Location})
+		}
+	}
+	return out
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	const header = "syntax = \"proto3\";\npackage pets;\n"
+	const service = `
+service PetService {
+  rpc GetPet(GetPetRequest) returns (Pet);
+}
+message GetPetRequest { string id = 1; }
+`
+	tests := []struct {
+		name     string
+		from, to string
+		want     []finding
+	}{
+		{
+			name: "unchanged",
+			from: service + "message Pet { string name = 1; }",
+			to:   service + "message Pet { string name = 1; }",
+		},
+		{
+			name: "rpc added",
+			from: service + "message Pet {}",
+			to:   strings.Replace(service, "returns (Pet);", "returns (Pet);\n  rpc ListPets(GetPetRequest) returns (Pet);", 1) + "message Pet {}",
+			want: []finding{
+				{openapi.SeverityNonBreaking, "rpc-added", "/pets.PetService/ListPets"},
+			},
+		},
+		{
+			name: "rpc removed",
+			from: strings.Replace(service, "returns (Pet);", "returns (Pet);\n  rpc ListPets(GetPetRequest) returns (Pet);", 1) + "message Pet {}",
+			to:   service + "message Pet {}",
+			want: []finding{
+				{openapi.SeverityBreaking, "rpc-removed", "/pets.PetService/ListPets"},
+			},
+		},
+		{
+			name: "rpc becomes server streaming",
+			from: service + "message Pet {}",
+			to:   strings.Replace(service, "returns (Pet)", "returns (stream Pet)", 1) + "message Pet {}",
+			want: []finding{
+				{openapi.SeverityBreaking, "rpc-streaming-changed", "/pets.PetService/GetPet"},
+			},
+		},
+		{
+			name: "rpc response type changed",
+			from: service + "message Pet {}\nmessage Dog {}",
+			to:   strings.Replace(service, "returns (Pet)", "returns (Dog)", 1) + "message Pet {}\nmessage Dog {}",
+			want: []finding{
+				{openapi.SeverityBreaking, "rpc-output-changed", "/pets.PetService/GetPet"},
+			},
+		},
+		{
+			name: "optional field added",
+			from: service + "message Pet { string name = 1; }",
+			to:   service + "message Pet { string name = 1; string tag = 2; }",
+			want: []finding{
+				{openapi.SeverityNonBreaking, "field-added", "pets.Pet field 2 (tag)"},
+			},
+		},
+		{
+			name: "field removed without reserving its number",
+			from: service + "message Pet { string name = 1; string tag = 2; }",
+			to:   service + "message Pet { string name = 1; }",
+			want: []finding{
+				{openapi.SeverityBreaking, "field-removed", "pets.Pet field 2 (tag)"},
+			},
+		},
+		{
+			name: "field removed and reserved",
+			from: service + "message Pet { string name = 1; string tag = 2; }",
+			to:   service + "message Pet { string name = 1; reserved 2; }",
+			want: []finding{
+				{openapi.SeverityNonBreaking, "field-removed", "pets.Pet field 2 (tag)"},
+			},
+		},
+		{
+			name: "reserved number reused",
+			from: service + "message Pet { string name = 1; reserved 2; }",
+			to:   service + "message Pet { string name = 1; int32 age = 2; }",
+			want: []finding{
+				{openapi.SeverityBreaking, "field-number-reused", "pets.Pet field 2 (age)"},
+			},
+		},
+		{
+			name: "number reused by a field of another type",
+			from: service + "message Pet { string name = 1; string tag = 2; }",
+			to:   service + "message Pet { string name = 1; int32 age = 2; }",
+			want: []finding{
+				{openapi.SeverityBreaking, "field-type-changed", "pets.Pet field 2 (tag)"},
+				{openapi.SeverityNonBreaking, "field-renamed", "pets.Pet field 2 (tag)"},
+			},
+		},
+		{
+			name: "field renumbered",
+			from: service + "message Pet { string name = 1; }",
+			to:   service + "message Pet { string name = 2; }",
+			want: []finding{
+				{openapi.SeverityBreaking, "field-renumbered", "pets.Pet field 1 (name)"},
+			},
+		},
+		{
+			name: "field renamed",
+			from: service + "message Pet { string name = 1; }",
+			to:   service + "message Pet { string title = 1; }",
+			want: []finding{
+				{openapi.SeverityNonBreaking, "field-renamed", "pets.Pet field 1 (name)"},
+			},
+		},
+		{
+			name: "wire-compatible type change",
+			from: service + "message Pet { int32 age = 1; }",
+			to:   service + "message Pet { int64 age = 1; }",
+			want: []finding{
+				{openapi.SeverityNonBreaking, "field-type-changed", "pets.Pet field 1 (age)"},
+			},
+		},
+		{
+			name: "incompatible type change",
+			from: service + "message Pet { int32 age = 1; }",
+			to:   service + "message Pet { string age = 1; }",
+			want: []finding{
+				{openapi.SeverityBreaking, "field-type-changed", "pets.Pet field 1 (age)"},
+			},
+		},
+		{
+			name: "field becomes repeated",
+			from: service + "message Pet { string tag = 1; }",
+			to:   service + "message Pet { repeated string tag = 1; }",
+			want: []finding{
+				{openapi.SeverityBreaking, "field-label-changed", "pets.Pet field 1 (tag)"},
+			},
+		},
+		{
+			name: "field becomes proto3 optional",
+			from: service + "message Pet { string tag = 1; }",
+			to:   service + "message Pet { optional string tag = 1; }",
+			want: []finding{
+				{openapi.SeverityNonBreaking, "field-label-changed", "pets.Pet field 1 (tag)"},
+			},
+		},
+		{
+			name: "field moves into a oneof",
+			from: service + "message Pet { string name = 1; int32 id = 2; }",
+			to:   service + "message Pet { oneof key { string name = 1; int32 id = 2; } }",
+			want: []finding{
+				{openapi.SeverityBreaking, "field-oneof-changed", "pets.Pet field 1 (name)"},
+				{openapi.SeverityBreaking, "field-oneof-changed", "pets.Pet field 2 (id)"},
+			},
+		},
+		{
+			name: "enum value added",
+			from: service + "message Pet {}\nenum Kind { KIND_UNSPECIFIED = 0; DOG = 1; }",
+			to:   service + "message Pet {}\nenum Kind { KIND_UNSPECIFIED = 0; DOG = 1; CAT = 2; }",
+			want: []finding{
+				{openapi.SeverityNonBreaking, "enum-value-added", "pets.Kind.CAT"},
+			},
+		},
+		{
+			name: "enum value removed without reserving it",
+			from: service + "message Pet {}\nenum Kind { KIND_UNSPECIFIED = 0; DOG = 1; CAT = 2; }",
+			to:   service + "message Pet {}\nenum Kind { KIND_UNSPECIFIED = 0; DOG = 1; }",
+			want: []finding{
+				{openapi.SeverityBreaking, "enum-value-removed", "pets.Kind.CAT"},
+			},
+		},
+		{
+			name: "enum value removed and reserved",
+			from: service + "message Pet {}\nenum Kind { KIND_UNSPECIFIED = 0; DOG = 1; CAT = 2; }",
+			to:   service + "message Pet {}\nenum Kind { KIND_UNSPECIFIED = 0; DOG = 1; reserved 2; }",
+			want: []finding{
+				{openapi.SeverityNonBreaking, "enum-value-removed", "pets.Kind.CAT"},
+			},
+		},
+		{
+			name: "message removed",
+			from: service + "message Pet {}\nmessage Owner {}",
+			to:   service + "message Pet {}",
+			want: []finding{
+				{openapi.SeverityBreaking, "message-removed", "pets.Owner"},
+			},
+		},
+		{
+			name: "field deprecated",
+			from: service + "message Pet { string tag = 1; }",
+			to:   service + "message Pet { string tag = 1 [deprecated = true]; }",
+			want: []finding{
+				{openapi.SeverityNonBreaking, "field-deprecated", "pets.Pet field 1 (tag)"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := parseProto(t, map[string]string{"pets.proto": header + tt.from})
+			to := parseProto(t, map[string]string{"pets.proto": header + tt.to})
+			if got := findings(CheckCompatibility(from, to)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findings = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	const header = "syntax = \"proto3\";\npackage pets;\n"
+	from := parseProto(t, map[string]string{
+		"pets.proto": header + "import \"kind.proto\";\nservice PetService { rpc GetPet(Pet) returns (Pet); }\nmessage Pet { string name = 1; Kind kind = 2; }\n",
+		"kind.proto": header + "enum Kind { KIND_UNSPECIFIED = 0; }\n",
+	})
+
+	// Moving a declaration to another file is not a change
+	moved := parseProto(t, map[string]string{
+		"pets.proto": header + "service PetService { rpc GetPet(Pet) returns (Pet); }\nmessage Pet { string name = 1; Kind kind = 2; }\nenum Kind { KIND_UNSPECIFIED = 0; }\n",
+	})
+	if d := Compare(from, moved); !d.Empty() {
+		t.Errorf("moving an enum between files: %+v", d)
+	}
+
+	changed := parseProto(t, map[string]string{
+		"pets.proto": header + "service PetService { rpc GetPet(Pet) returns (Pet); rpc Ping(Pet) returns (Pet); }\nmessage Pet { string title = 1; }\nmessage Owner {}\n",
+	})
+	d := Compare(from, changed)
+	tests := []struct {
+		section                  string
+		set                      *openapi.ChangeSet
+		added, removed, modified string
+	}{
+		{section: "services", set: &d.Services, modified: "pets.PetService"},
+		{section: "methods", set: &d.Methods, added: "/pets.PetService/Ping"},
+		{section: "messages", set: &d.Messages, added: "pets.Owner", modified: "pets.Pet"},
+		{section: "enums", set: &d.Enums, removed: "pets.Kind"},
+	}
+	names := func(changes []openapi.Change) string {
+		var out []string
+		for _, c := range changes {
+			out = append(out, c.Name)
+		}
+		return strings.Join(out, ",")
+	}
+	for _, tt := range tests {
+		if got := names(tt.set.Added); got != tt.added {
+			t.Errorf("%s added = %q, want %q", tt.section, got, tt.added)
+		}
+		if got := names(tt.set.Removed); got != tt.removed {
+			t.Errorf("%s removed = %q, want %q", tt.section, got, tt.removed)
+		}
+		if got := names(tt.set.Modified); got != tt.modified {
+			t.Errorf("%s modified = %q, want %q", tt.section, got, tt.modified)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package protobuf
+
+// Index lists what a set of .proto files declares, for the reference view
+// and the index endpoint.
+type Index struct {
+	Entry    string          `json:"entry,omitempty"`
+	Files    []*File         `json:"files"`
+	Services []*Service      `json:"services"`
+	Messages []*Message      `json:"messages"`
+	Enums    []*Enum         `json:"enums"`
+	Defined  map[string]bool `json:"-"` // full names of the messages and enums listed, for linking
+}
+
+// Index returns the services, messages and enums of the schema. Well-known
+// types the files import are referenced but not listed.
+func (s *Schema) Index() *Index {
+	idx := &Index{
+		Entry:    s.Entry,
+		Files:    s.Files,
+		Services: s.Services(),
+		Messages: s.Messages(),
+		Enums:    s.Enums(),
+		Defined:  map[string]bool{},
+	}
+	// Empty lists instead of null in JSON output
+	if idx.Services == nil {
+		idx.Services = []*Service{}
+	}
+	if idx.Messages == nil {
+		idx.Messages = []*Message{}
+	}
+	if idx.Enums == nil {
+		idx.Enums = []*Enum{}
+	}
+	for _, m := range idx.Messages {
+		idx.Defined[m.FullName] = true
+	}
+	for _, e := range idx.Enums {
+		idx.Defined[e.FullName] = true
+	}
+	return idx
+}
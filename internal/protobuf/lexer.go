@@ -0,0 +1,185 @@
+package protobuf
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokInt
+	tokFloat
+	tokString
+	tokPunct
+)
+
+type token struct {
+	kind      tokenKind
+	text      string // identifier, number or punctuation as written; string value unquoted
+	line, col int
+	doc       string // comment block right before the token
+}
+
+// SyntaxError is a problem found while reading a .proto file.
+type SyntaxError struct {
+	File      string
+	Line, Col int
+	Message   string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Col, e.Message)
+	}
+	return fmt.Sprintf("line %d, column %d: %s", e.Line, e.Col, e.Message)
+}
+
+// lex splits a .proto source into tokens. Comments are not tokens; the
+// comment lines directly above a token become its doc.
+func lex(file string, src []byte) ([]token, error) {
+	s := []rune(string(src))
+	var tokens []token
+	line, col := 1, 1
+	var doc []string
+	docLine := 0 // line the pending doc comment ended on
+
+	advance := func(n int) {
+		for i := 0; i < n; i++ {
+			if s[0] == '\n' {
+				line++
+				col = 1
+			} else {
+				col++
+			}
+			s = s[1:]
+		}
+	}
+	fail := func(format string, args ...any) error {
+		return &SyntaxError{File: file, Line: line, Col: col, Message: fmt.Sprintf(format, args...)}
+	}
+
+	for len(s) > 0 {
+		r := s[0]
+		switch {
+		case r == '\n':
+			advance(1)
+			if docLine < line-1 {
+				doc = nil // a blank line detaches the comment
+			}
+		case unicode.IsSpace(r):
+			advance(1)
+		case r == '/' && len(s) > 1 && s[1] == '/':
+			end := 0
+			for end < len(s) && s[end] != '\n' {
+				end++
+			}
+			text := strings.TrimSpace(string(s[2:end]))
+			if docLine != line-1 && docLine != line {
+				doc = nil
+			}
+			if !trailing(tokens, line) {
+				doc = append(doc, text)
+				docLine = line
+			}
+			advance(end)
+		case r == '/' && len(s) > 1 && s[1] == '*':
+			end := 2
+			for end+1 < len(s) && !(s[end] == '*' && s[end+1] == '/') {
+				end++
+			}
+			if end+1 >= len(s) {
+				return nil, fail("unterminated comment")
+			}
+			body := string(s[2:end])
+			isTrailing := trailing(tokens, line)
+			advance(end + 2)
+			if !isTrailing {
+				doc = nil
+				for _, l := range strings.Split(body, "\n") {
+					doc = append(doc, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l), "*")))
+				}
+				docLine = line
+			}
+		case r == '"' || r == '\'':
+			startLine, startCol := line, col
+			var b strings.Builder
+			i := 1
+			for ; i < len(s) && s[i] != r; i++ {
+				if s[i] == '\n' {
+					return nil, fail("unterminated string")
+				}
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+					switch s[i] {
+					case 'n':
+						b.WriteRune('\n')
+					case 't':
+						b.WriteRune('\t')
+					case 'r':
+						b.WriteRune('\r')
+					default:
+						b.WriteRune(s[i])
+					}
+					continue
+				}
+				b.WriteRune(s[i])
+			}
+			if i >= len(s) {
+				return nil, fail("unterminated string")
+			}
+			advance(i + 1)
+			// Adjacent strings are concatenated
+			if n := len(tokens); n > 0 && tokens[n-1].kind == tokString {
+				tokens[n-1].text += b.String()
+				continue
+			}
+			tokens = append(tokens, token{kind: tokString, text: b.String(), line: startLine, col: startCol, doc: takeDoc(&doc)})
+		case r == '_' || unicode.IsLetter(r):
+			end := 0
+			for end < len(s) && (s[end] == '_' || unicode.IsLetter(s[end]) || unicode.IsDigit(s[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: string(s[:end]), line: line, col: col, doc: takeDoc(&doc)})
+			advance(end)
+		case unicode.IsDigit(r) || (r == '.' && len(s) > 1 && unicode.IsDigit(s[1])):
+			hex := len(s) > 1 && r == '0' && (s[1] == 'x' || s[1] == 'X')
+			end := 0
+			for end < len(s) {
+				c := s[end]
+				exponentSign := (c == '+' || c == '-') && !hex && end > 0 && (s[end-1] == 'e' || s[end-1] == 'E')
+				if !(c == '_' || c == '.' || unicode.IsLetter(c) || unicode.IsDigit(c) || exponentSign) {
+					break
+				}
+				end++
+			}
+			text := string(s[:end])
+			kind := tokInt
+			if strings.Contains(text, ".") || (!hex && strings.ContainsAny(text, "eE")) {
+				kind = tokFloat
+			}
+			tokens = append(tokens, token{kind: kind, text: text, line: line, col: col, doc: takeDoc(&doc)})
+			advance(end)
+		default:
+			tokens = append(tokens, token{kind: tokPunct, text: string(r), line: line, col: col, doc: takeDoc(&doc)})
+			advance(1)
+		}
+	}
+	tokens = append(tokens, token{kind: tokEOF, line: line, col: col})
+	return tokens, nil
+}
+
+// trailing reports whether a comment starting on line follows a token on the
+// same line; such comments describe that token, not the next one.
+func trailing(tokens []token, line int) bool {
+	return len(tokens) > 0 && tokens[len(tokens)-1].line == line
+}
+
+func takeDoc(doc *[]string) string {
+	text := strings.TrimSpace(strings.Join(*doc, "\n"))
+	*doc = nil
+	return text
+}
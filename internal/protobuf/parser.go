@@ -0,0 +1,557 @@
+package protobuf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Field numbers are 29 bits; enum values are int32.
+const (
+	maxFieldNumber = 536870911
+	maxEnumNumber  = 2147483647
+)
+
+type parser struct {
+	file   *File
+	tokens []token
+	pos    int
+}
+
+// ParseFile parses one .proto file. Type references are left unresolved; see
+// Parse for a set of files.
+func ParseFile(name string, src []byte) (*File, error) {
+	tokens, err := lex(name, src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{file: &File{Name: name, Syntax: "proto2"}, tokens: tokens}
+	if err := p.parseFile(); err != nil {
+		return nil, err
+	}
+	return p.file, nil
+}
+
+// parseError aborts parsing; it is recovered in parseFile.
+type parseError struct{ err *SyntaxError }
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) failAt(t token, format string, args ...any) {
+	panic(parseError{&SyntaxError{File: p.file.Name, Line: t.line, Col: t.col, Message: fmt.Sprintf(format, args...)}})
+}
+
+func describe(t token) string {
+	switch t.kind {
+	case tokEOF:
+		return "end of file"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return "'" + t.text + "'"
+}
+
+// is reports whether the next token is the punctuation or keyword text.
+func (p *parser) is(text string) bool {
+	t := p.peek()
+	return (t.kind == tokPunct || t.kind == tokIdent) && t.text == text
+}
+
+func (p *parser) accept(text string) bool {
+	if p.is(text) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) token {
+	t := p.next()
+	if (t.kind != tokPunct && t.kind != tokIdent) || t.text != text {
+		p.failAt(t, "expected '%s', found %s", text, describe(t))
+	}
+	return t
+}
+
+func (p *parser) ident() token {
+	t := p.next()
+	if t.kind != tokIdent {
+		p.failAt(t, "expected identifier, found %s", describe(t))
+	}
+	return t
+}
+
+func (p *parser) stringLit() token {
+	t := p.next()
+	if t.kind != tokString {
+		p.failAt(t, "expected string, found %s", describe(t))
+	}
+	return t
+}
+
+// fullIdent reads a.b.c.
+func (p *parser) fullIdent() string {
+	parts := []string{p.ident().text}
+	for p.is(".") {
+		p.next()
+		parts = append(parts, p.ident().text)
+	}
+	return strings.Join(parts, ".")
+}
+
+// typeName reads a possibly fully-qualified type reference, e.g. .a.b.C.
+func (p *parser) typeName() string {
+	if p.accept(".") {
+		return "." + p.fullIdent()
+	}
+	return p.fullIdent()
+}
+
+func (p *parser) intLit(max int) int {
+	negative := p.accept("-")
+	n := p.next()
+	if n.kind != tokInt {
+		p.failAt(n, "expected integer, found %s", describe(n))
+	}
+	v, err := strconv.ParseInt(n.text, 0, 64)
+	if err != nil {
+		p.failAt(n, "invalid integer %s", n.text)
+	}
+	if negative {
+		v = -v
+	}
+	if v > int64(max) || v < -int64(max)-1 {
+		p.failAt(n, "integer %s out of range", n.text)
+	}
+	return int(v)
+}
+
+func (p *parser) parseFile() (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			pe, ok := r.(parseError)
+			if !ok {
+				panic(r)
+			}
+			err = pe.err
+		}
+	}()
+
+	first := true
+	for p.peek().kind != tokEOF {
+		t := p.peek()
+		switch {
+		case p.accept(";"):
+		case t.text == "syntax" && t.kind == tokIdent:
+			if !first {
+				p.failAt(t, "syntax must be the first statement")
+			}
+			p.next()
+			p.expect("=")
+			v := p.stringLit()
+			if v.text != "proto2" && v.text != "proto3" {
+				p.failAt(v, "unsupported syntax %q (expected proto2 or proto3)", v.text)
+			}
+			p.file.Syntax = v.text
+			p.expect(";")
+		case t.text == "edition" && t.kind == tokIdent:
+			if !first {
+				p.failAt(t, "edition must be the first statement")
+			}
+			p.next()
+			p.expect("=")
+			p.file.Syntax, p.file.Edition = "editions", p.stringLit().text
+			p.expect(";")
+		case t.text == "package" && t.kind == tokIdent:
+			p.next()
+			if p.file.Package != "" {
+				p.failAt(t, "multiple package statements")
+			}
+			p.file.Package = p.fullIdent()
+			p.expect(";")
+		case t.text == "import" && t.kind == tokIdent:
+			p.next()
+			imp := Import{Line: t.line, Col: t.col}
+			if p.accept("public") {
+				imp.Public = true
+			} else {
+				p.accept("weak")
+			}
+			imp.Path = p.stringLit().text
+			p.expect(";")
+			p.file.Imports = append(p.file.Imports, imp)
+		case t.text == "option" && t.kind == tokIdent:
+			p.option()
+		case t.text == "message" && t.kind == tokIdent:
+			p.file.Messages = append(p.file.Messages, p.message(nil))
+		case t.text == "enum" && t.kind == tokIdent:
+			p.file.Enums = append(p.file.Enums, p.enum(nil))
+		case t.text == "service" && t.kind == tokIdent:
+			p.file.Services = append(p.file.Services, p.service())
+		case t.text == "extend" && t.kind == tokIdent:
+			p.extend()
+		default:
+			p.failAt(t, "unexpected %s", describe(t))
+		}
+		first = false
+	}
+	return nil
+}
+
+// option reads "option name = value;" and returns the name and value.
+func (p *parser) option() (string, string) {
+	p.expect("option")
+	name := p.optionName()
+	p.expect("=")
+	value := p.constant()
+	p.expect(";")
+	return name, value
+}
+
+// optionName reads a plain or (extension) option name, e.g. (google.api.http).get.
+func (p *parser) optionName() string {
+	var b strings.Builder
+	for {
+		if p.accept("(") {
+			b.WriteString("(" + p.typeName() + ")")
+			p.expect(")")
+		} else {
+			b.WriteString(p.ident().text)
+		}
+		if !p.accept(".") {
+			return b.String()
+		}
+		b.WriteString(".")
+	}
+}
+
+// constant reads an option value. Aggregate values ({...}) are skipped and
+// returned as "{...}".
+func (p *parser) constant() string {
+	t := p.peek()
+	switch {
+	case p.is("{"):
+		p.skipBlock("{", "}")
+		return "{...}"
+	case p.is("["):
+		p.skipBlock("[", "]")
+		return "[...]"
+	case p.is("-") || p.is("+"):
+		sign := p.next().text
+		n := p.next()
+		if n.kind != tokInt && n.kind != tokFloat && !(n.kind == tokIdent && (n.text == "inf" || n.text == "nan")) {
+			p.failAt(n, "expected number, found %s", describe(n))
+		}
+		return sign + n.text
+	case t.kind == tokIdent:
+		return p.fullIdent()
+	case t.kind == tokInt || t.kind == tokFloat || t.kind == tokString:
+		return p.next().text
+	}
+	p.failAt(t, "expected a value, found %s", describe(t))
+	return ""
+}
+
+func (p *parser) skipBlock(open, close string) {
+	p.expect(open)
+	depth := 1
+	for depth > 0 {
+		t := p.next()
+		switch {
+		case t.kind == tokEOF:
+			p.failAt(t, "missing '%s'", close)
+		case t.kind == tokPunct && t.text == open:
+			depth++
+		case t.kind == tokPunct && t.text == close:
+			depth--
+		}
+	}
+}
+
+// fieldOptions reads [name = value, ...] and reports whether deprecated = true is among them.
+func (p *parser) fieldOptions() (deprecated bool) {
+	if !p.accept("[") {
+		return false
+	}
+	for {
+		name := p.optionName()
+		p.expect("=")
+		if value := p.constant(); name == "deprecated" && value == "true" {
+			deprecated = true
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	p.expect("]")
+	return deprecated
+}
+
+func (p *parser) qualify(parent *Message, name string) string {
+	if parent != nil {
+		return parent.FullName + "." + name
+	}
+	if p.file.Package != "" {
+		return p.file.Package + "." + name
+	}
+	return name
+}
+
+func (p *parser) message(parent *Message) *Message {
+	start := p.expect("message")
+	name := p.ident()
+	m := &Message{Name: name.text, File: p.file.Name, Doc: start.doc, Line: start.line, Col: start.col, parent: parent}
+	m.FullName = p.qualify(parent, m.Name)
+	p.expect("{")
+	p.messageBody(m, "")
+	return m
+}
+
+// messageBody reads declarations up to the closing brace. Inside a oneof only
+// fields and options are allowed and the fields belong to it.
+func (p *parser) messageBody(m *Message, oneof string) {
+	for !p.accept("}") {
+		t := p.peek()
+		if t.kind == tokEOF {
+			p.failAt(t, "missing '}'")
+		}
+		if t.kind != tokIdent {
+			if p.accept(";") {
+				continue
+			}
+			p.failAt(t, "unexpected %s", describe(t))
+		}
+		switch {
+		case t.text == "option":
+			p.option()
+		case oneof != "":
+			p.field(m, oneof)
+		case t.text == "message":
+			m.Messages = append(m.Messages, p.message(m))
+		case t.text == "enum":
+			m.Enums = append(m.Enums, p.enum(m))
+		case t.text == "extend":
+			p.extend()
+		case t.text == "extensions":
+			p.next()
+			p.ranges(maxFieldNumber)
+			p.fieldOptions()
+			p.expect(";")
+		case t.text == "reserved":
+			p.reserved(&m.Reserved, maxFieldNumber)
+		case t.text == "oneof":
+			p.next()
+			name := p.ident().text
+			p.expect("{")
+			p.messageBody(m, name)
+		default:
+			p.field(m, "")
+		}
+	}
+}
+
+func (p *parser) field(m *Message, oneof string) {
+	start := p.peek()
+	f := &Field{OneOf: oneof, Doc: start.doc, Line: start.line, Col: start.col}
+	if oneof == "" && (p.is("optional") || p.is("required") || p.is("repeated")) {
+		// "optional" alone could also be a message type; a label is followed by a type
+		if n := p.tokens[p.pos+1]; n.kind == tokIdent || (n.kind == tokPunct && n.text == ".") {
+			f.Label = p.next().text
+		}
+	}
+
+	switch {
+	case p.is("map") && p.tokens[p.pos+1].text == "<":
+		p.next()
+		p.expect("<")
+		key := p.ident()
+		if !IsScalar(key.text) || key.text == "double" || key.text == "float" || key.text == "bytes" {
+			p.failAt(key, "invalid map key type %s", key.text)
+		}
+		p.expect(",")
+		value := p.typeName()
+		p.expect(">")
+		f.MapKey, f.Type = key.text, value
+	case p.is("group"):
+		// proto2 group: a nested message and a field of that type
+		p.next()
+		name := p.ident()
+		f.Name, f.Type = strings.ToLower(name.text), name.text
+		p.expect("=")
+		f.Number = p.intLit(maxFieldNumber)
+		f.Deprecated = p.fieldOptions()
+		g := &Message{Name: name.text, FullName: m.FullName + "." + name.text, File: p.file.Name, Line: name.line, Col: name.col, parent: m}
+		p.expect("{")
+		p.messageBody(g, "")
+		m.Messages = append(m.Messages, g)
+		m.Fields = append(m.Fields, f)
+		return
+	default:
+		f.Type = p.typeName()
+	}
+
+	f.Name = p.ident().text
+	p.expect("=")
+	f.Number = p.intLit(maxFieldNumber)
+	f.Deprecated = p.fieldOptions()
+	p.expect(";")
+	m.Fields = append(m.Fields, f)
+}
+
+func (p *parser) enum(parent *Message) *Enum {
+	start := p.expect("enum")
+	name := p.ident()
+	e := &Enum{Name: name.text, File: p.file.Name, Doc: start.doc, Line: start.line, Col: start.col}
+	e.FullName = p.qualify(parent, e.Name)
+	p.expect("{")
+	for !p.accept("}") {
+		t := p.peek()
+		switch {
+		case t.kind == tokEOF:
+			p.failAt(t, "missing '}'")
+		case p.accept(";"):
+		case t.kind == tokIdent && t.text == "option":
+			if name, value := p.option(); name == "allow_alias" && value == "true" {
+				e.AllowAlias = true
+			}
+		case t.kind == tokIdent && t.text == "reserved":
+			p.reserved(&e.Reserved, maxEnumNumber)
+		default:
+			name := p.ident()
+			v := &EnumValue{Name: name.text, Doc: name.doc, Line: name.line, Col: name.col}
+			p.expect("=")
+			v.Number = p.intLit(maxEnumNumber)
+			v.Deprecated = p.fieldOptions()
+			p.expect(";")
+			e.Values = append(e.Values, v)
+		}
+	}
+	return e
+}
+
+// reserved reads "reserved 2, 9 to 11;" or "reserved "foo", "bar";" (or bare
+// names, as editions write them).
+func (p *parser) reserved(r *Reserved, max int) {
+	p.expect("reserved")
+	t := p.peek()
+	if t.kind == tokString || t.kind == tokIdent {
+		for {
+			n := p.next()
+			if n.kind != tokString && n.kind != tokIdent {
+				p.failAt(n, "expected a field name, found %s", describe(n))
+			}
+			r.Names = append(r.Names, n.text)
+			if !p.accept(",") {
+				break
+			}
+		}
+	} else {
+		r.Ranges = append(r.Ranges, p.ranges(max)...)
+	}
+	p.expect(";")
+}
+
+func (p *parser) ranges(max int) []Range {
+	var out []Range
+	for {
+		from := p.intLit(max)
+		to := from
+		if p.accept("to") {
+			if p.accept("max") {
+				to = max
+			} else {
+				to = p.intLit(max)
+			}
+		}
+		out = append(out, Range{From: from, To: to})
+		if !p.accept(",") {
+			return out
+		}
+	}
+}
+
+func (p *parser) service() *Service {
+	start := p.expect("service")
+	name := p.ident()
+	s := &Service{Name: name.text, File: p.file.Name, Doc: start.doc, Line: start.line, Col: start.col}
+	s.FullName = p.qualify(nil, s.Name)
+	p.expect("{")
+	for !p.accept("}") {
+		t := p.peek()
+		switch {
+		case t.kind == tokEOF:
+			p.failAt(t, "missing '}'")
+		case p.accept(";"):
+		case t.kind == tokIdent && t.text == "option":
+			p.option()
+		case t.kind == tokIdent && t.text == "rpc":
+			m := p.rpc()
+			m.Path = "/" + s.FullName + "/" + m.Name
+			s.Methods = append(s.Methods, m)
+		default:
+			p.failAt(t, "unexpected %s in service", describe(t))
+		}
+	}
+	return s
+}
+
+func (p *parser) rpc() *Method {
+	start := p.expect("rpc")
+	name := p.ident()
+	m := &Method{Name: name.text, Doc: start.doc, Line: start.line, Col: start.col}
+	p.expect("(")
+	m.ClientStreaming = p.stream()
+	m.Input = p.typeName()
+	p.expect(")")
+	p.expect("returns")
+	p.expect("(")
+	m.ServerStreaming = p.stream()
+	m.Output = p.typeName()
+	p.expect(")")
+	if p.accept("{") {
+		for !p.accept("}") {
+			t := p.peek()
+			switch {
+			case t.kind == tokEOF:
+				p.failAt(t, "missing '}'")
+			case p.accept(";"):
+			case t.kind == tokIdent && t.text == "option":
+				if name, value := p.option(); name == "deprecated" && value == "true" {
+					m.Deprecated = true
+				}
+			default:
+				p.failAt(t, "unexpected %s in rpc", describe(t))
+			}
+		}
+	} else {
+		p.expect(";")
+	}
+	return m
+}
+
+// stream accepts the stream keyword of an rpc argument; a message named
+// stream is not one.
+func (p *parser) stream() bool {
+	if n := p.tokens[p.pos+1]; p.is("stream") && (n.kind == tokIdent || n.text == ".") {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// extend reads an extension block. Extensions only add options here, so the
+// fields are checked for syntax and dropped.
+func (p *parser) extend() {
+	p.expect("extend")
+	p.typeName()
+	p.expect("{")
+	p.messageBody(&Message{File: p.file.Name}, "")
+}
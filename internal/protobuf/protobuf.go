@@ -0,0 +1,204 @@
+// Package protobuf reads Protocol Buffers service definitions: .proto files,
+// possibly several importing each other. It parses them into services,
+// messages and enums, resolves type references across files, and compares
+// two versions for wire-breaking changes.
+package protobuf
+
+import (
+	"regexp"
+	"strings"
+)
+
+// File is one parsed .proto file.
+type File struct {
+	Name     string     `json:"name"`   // path inside the uploaded set, "" for a single pasted file
+	Syntax   string     `json:"syntax"` // proto2, proto3 or editions
+	Edition  string     `json:"edition,omitempty"`
+	Package  string     `json:"package,omitempty"`
+	Imports  []Import   `json:"imports,omitempty"`
+	Messages []*Message `json:"-"`
+	Enums    []*Enum    `json:"-"`
+	Services []*Service `json:"-"`
+}
+
+// Import is an import statement.
+type Import struct {
+	Path   string `json:"path"`
+	Public bool   `json:"public,omitempty"`
+	Line   int    `json:"-"`
+	Col    int    `json:"-"`
+}
+
+// Message is a message definition; nested messages and enums are listed on it.
+type Message struct {
+	Name     string     `json:"name"`
+	FullName string     `json:"full_name"` // package-qualified, without leading dot, e.g. acme.v1.User.Address
+	File     string     `json:"file,omitempty"`
+	Doc      string     `json:"doc,omitempty"`
+	Line     int        `json:"line"`
+	Col      int        `json:"column"`
+	Fields   []*Field   `json:"fields"`
+	Messages []*Message `json:"-"`
+	Enums    []*Enum    `json:"-"`
+	Reserved Reserved   `json:"reserved"`
+	parent   *Message
+}
+
+// Field is a message field.
+type Field struct {
+	Name       string `json:"name"`
+	Number     int    `json:"number"`
+	Label      string `json:"label,omitempty"` // optional, required, repeated or "" (singular)
+	Type       string `json:"type"`            // as written, e.g. string, .acme.v1.User or Address; the value type of a map
+	Resolved   string `json:"resolved"`        // scalar type name or full name of the message or enum
+	IsEnum     bool   `json:"enum,omitempty"`  // Resolved names an enum
+	OneOf      string `json:"oneof,omitempty"` // oneof the field belongs to
+	MapKey     string `json:"map_key,omitempty"`
+	Deprecated bool   `json:"deprecated,omitempty"`
+	Doc        string `json:"doc,omitempty"`
+	Line       int    `json:"line"`
+	Col        int    `json:"column"`
+}
+
+// TypeName renders the resolved type of the field as written in a reference,
+// e.g. "repeated acme.v1.User" or "map<string, int32>".
+func (f *Field) TypeName() string {
+	if f.MapKey != "" {
+		return "map<" + f.MapKey + ", " + f.Resolved + ">"
+	}
+	if f.Label == "repeated" || f.Label == "optional" || f.Label == "required" {
+		return f.Label + " " + f.Resolved
+	}
+	return f.Resolved
+}
+
+// Named reports whether the field (or map value) type is a message or enum.
+func (f *Field) Named() bool {
+	return !IsScalar(f.Resolved)
+}
+
+// Enum is an enum definition.
+type Enum struct {
+	Name       string       `json:"name"`
+	FullName   string       `json:"full_name"`
+	File       string       `json:"file,omitempty"`
+	Doc        string       `json:"doc,omitempty"`
+	Line       int          `json:"line"`
+	Col        int          `json:"column"`
+	Values     []*EnumValue `json:"values"`
+	Reserved   Reserved     `json:"reserved"`
+	AllowAlias bool         `json:"allow_alias,omitempty"`
+}
+
+// EnumValue is one enum constant.
+type EnumValue struct {
+	Name       string `json:"name"`
+	Number     int    `json:"number"`
+	Deprecated bool   `json:"deprecated,omitempty"`
+	Doc        string `json:"doc,omitempty"`
+	Line       int    `json:"line"`
+	Col        int    `json:"column"`
+}
+
+// Reserved lists reserved field numbers (or enum values) and names.
+type Reserved struct {
+	Ranges []Range  `json:"ranges,omitempty"`
+	Names  []string `json:"names,omitempty"`
+}
+
+// Range is an inclusive number range.
+type Range struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// HasNumber reports whether n is reserved.
+func (r Reserved) HasNumber(n int) bool {
+	for _, rg := range r.Ranges {
+		if n >= rg.From && n <= rg.To {
+			return true
+		}
+	}
+	return false
+}
+
+// HasName reports whether name is reserved.
+func (r Reserved) HasName(name string) bool {
+	for _, n := range r.Names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Service is a service definition.
+type Service struct {
+	Name     string    `json:"name"`
+	FullName string    `json:"full_name"`
+	File     string    `json:"file,omitempty"`
+	Doc      string    `json:"doc,omitempty"`
+	Line     int       `json:"line"`
+	Col      int       `json:"column"`
+	Methods  []*Method `json:"methods"`
+}
+
+// Method is an RPC of a service.
+type Method struct {
+	Name            string `json:"name"`
+	Path            string `json:"path"` // gRPC path, e.g. /acme.v1.Users/Get
+	Input           string `json:"-"`    // as written
+	Output          string `json:"-"`
+	InputResolved   string `json:"input"` // full message names
+	OutputResolved  string `json:"output"`
+	ClientStreaming bool   `json:"client_streaming,omitempty"`
+	ServerStreaming bool   `json:"server_streaming,omitempty"`
+	Deprecated      bool   `json:"deprecated,omitempty"`
+	Doc             string `json:"doc,omitempty"`
+	Line            int    `json:"line"`
+	Col             int    `json:"column"`
+}
+
+// scalarTypes are the built-in field types.
+var scalarTypes = map[string]bool{
+	"double": true, "float": true, "int32": true, "int64": true, "uint32": true, "uint64": true,
+	"sint32": true, "sint64": true, "fixed32": true, "fixed64": true, "sfixed32": true, "sfixed64": true,
+	"bool": true, "string": true, "bytes": true,
+}
+
+// IsScalar reports whether a type name is a built-in scalar type.
+func IsScalar(t string) bool {
+	return scalarTypes[t]
+}
+
+var protoSourceRe = regexp.MustCompile(`^(syntax|edition|package|import|option|message|enum|service)\b`)
+
+// Detect reports whether content looks like a .proto file: its first
+// statement, after comments, is one a .proto file can start with.
+func Detect(content []byte) bool {
+	s := string(content)
+	for {
+		s = strings.TrimLeft(s, " \t\r\n\ufeff")
+		switch {
+		case strings.HasPrefix(s, "//"):
+			if i := strings.IndexByte(s, '\n'); i >= 0 {
+				s = s[i:]
+				continue
+			}
+			return false
+		case strings.HasPrefix(s, "/*"):
+			if i := strings.Index(s, "*/"); i >= 0 {
+				s = s[i+2:]
+				continue
+			}
+			return false
+		}
+		m := protoSourceRe.FindString(s)
+		if m == "" {
+			return false
+		}
+		// "syntax: ..." or "message: ..." is YAML, not a statement
+		rest := strings.TrimLeft(s[len(m):], " \t")
+		return !strings.HasPrefix(rest, ":")
+	}
+}
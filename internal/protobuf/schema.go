@@ -0,0 +1,493 @@
+package protobuf
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"APIScope/internal/openapi"
+)
+
+// Field numbers from 19000 to 19999 are reserved for the protobuf implementation.
+const (
+	firstImplementationNumber = 19000
+	lastImplementationNumber  = 19999
+)
+
+// Schema is a set of .proto files with their type references resolved.
+type Schema struct {
+	Files    []*File // the uploaded files, in path order
+	Entry    string  // the file the set is presented by
+	messages map[string]*Message
+	enums    map[string]*Enum
+}
+
+// Message returns the message with the given full name, including the
+// well-known types the files import.
+func (s *Schema) Message(name string) *Message { return s.messages[name] }
+
+// Enum returns the enum with the given full name.
+func (s *Schema) Enum(name string) *Enum { return s.enums[name] }
+
+// Title names the set: the package of its entry file, else its first service.
+func (s *Schema) Title() string {
+	for _, f := range s.Files {
+		if f.Name != s.Entry {
+			continue
+		}
+		if f.Package != "" {
+			return f.Package
+		}
+		if len(f.Services) > 0 {
+			return f.Services[0].Name
+		}
+	}
+	return ""
+}
+
+// Services returns the services of all files ordered by full name.
+func (s *Schema) Services() []*Service {
+	var out []*Service
+	for _, f := range s.Files {
+		out = append(out, f.Services...)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].FullName < out[j].FullName })
+	return out
+}
+
+// Messages returns the messages of all files, nested ones included, ordered
+// by full name.
+func (s *Schema) Messages() []*Message {
+	var out []*Message
+	var walk func([]*Message)
+	walk = func(ms []*Message) {
+		for _, m := range ms {
+			out = append(out, m)
+			walk(m.Messages)
+		}
+	}
+	for _, f := range s.Files {
+		walk(f.Messages)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].FullName < out[j].FullName })
+	return out
+}
+
+// Enums returns the enums of all files, nested ones included, ordered by
+// full name.
+func (s *Schema) Enums() []*Enum {
+	var out []*Enum
+	var walk func([]*Message)
+	walk = func(ms []*Message) {
+		for _, m := range ms {
+			out = append(out, m.Enums...)
+			walk(m.Messages)
+		}
+	}
+	for _, f := range s.Files {
+		out = append(out, f.Enums...)
+		walk(f.Messages)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].FullName < out[j].FullName })
+	return out
+}
+
+// Parse reads a set of .proto files keyed by path and resolves the types
+// they reference. entry names the file the set is presented by. The first
+// problem found is returned as the error; see Validate for all of them.
+func Parse(files map[string][]byte, entry string) (*Schema, error) {
+	schema, problems := load(files, entry)
+	if len(problems) > 0 {
+		return nil, problems[0]
+	}
+	return schema, nil
+}
+
+// Validate checks a set of .proto files and returns every problem found,
+// ordered by file and position. The path of a problem is the file it is in.
+func Validate(files map[string][]byte) []openapi.ValidationError {
+	_, problems := load(files, "")
+	return problems
+}
+
+// symbol is a name declared by the files: a message, an enum, a service or a
+// package (or a prefix of one).
+type symbol struct {
+	kind string // message, enum, service or package
+	file string
+	line int
+}
+
+type loader struct {
+	schema   *Schema
+	files    map[string]*File // uploaded and well-known files by path
+	symbols  map[string]symbol
+	problems []openapi.ValidationError
+}
+
+func (l *loader) fail(file string, line, col int, format string, args ...any) {
+	l.problems = append(l.problems, openapi.ValidationError{Path: file, Line: line, Column: col, Message: fmt.Sprintf(format, args...)})
+}
+
+func load(sources map[string][]byte, entry string) (*Schema, []openapi.ValidationError) {
+	l := &loader{
+		schema:  &Schema{Entry: entry, messages: map[string]*Message{}, enums: map[string]*Enum{}},
+		files:   map[string]*File{},
+		symbols: map[string]symbol{},
+	}
+	if len(sources) == 0 {
+		return nil, []openapi.ValidationError{{Message: "no .proto files"}}
+	}
+
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f, err := ParseFile(name, sources[name])
+		if err != nil {
+			if se, ok := err.(*SyntaxError); ok {
+				l.fail(name, se.Line, se.Col, "%s", se.Message)
+			} else {
+				l.fail(name, 0, 0, "%s", err.Error())
+			}
+			continue
+		}
+		l.files[name] = f
+		l.schema.Files = append(l.schema.Files, f)
+	}
+	// References are only checked in files that read correctly
+	if len(l.problems) > 0 {
+		return nil, l.sorted()
+	}
+
+	for _, f := range l.schema.Files {
+		l.declare(f)
+	}
+	for _, f := range l.schema.Files {
+		visible := l.visible(f)
+		l.checkFile(f, visible)
+	}
+	if len(l.problems) > 0 {
+		return nil, l.sorted()
+	}
+	return l.schema, nil
+}
+
+func (l *loader) sorted() []openapi.ValidationError {
+	sort.SliceStable(l.problems, func(i, j int) bool {
+		a, b := l.problems[i], l.problems[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return l.problems
+}
+
+// declare adds the package and types of a file to the symbol table.
+func (l *loader) declare(f *File) {
+	if f.Package != "" {
+		parts := strings.Split(f.Package, ".")
+		for i := range parts {
+			name := strings.Join(parts[:i+1], ".")
+			if existing, ok := l.symbols[name]; ok && existing.kind != "package" {
+				l.fail(f.Name, 0, 0, "package %s conflicts with %s %s in %s", f.Package, existing.kind, name, displayName(existing.file))
+				return
+			}
+			l.symbols[name] = symbol{kind: "package", file: f.Name}
+		}
+	}
+	var messages func([]*Message)
+	enums := func(es []*Enum) {
+		for _, e := range es {
+			if l.define(e.FullName, symbol{kind: "enum", file: f.Name, line: e.Line}, e.Col) {
+				l.schema.enums[e.FullName] = e
+			}
+		}
+	}
+	messages = func(ms []*Message) {
+		for _, m := range ms {
+			if l.define(m.FullName, symbol{kind: "message", file: f.Name, line: m.Line}, m.Col) {
+				l.schema.messages[m.FullName] = m
+			}
+			messages(m.Messages)
+			enums(m.Enums)
+		}
+	}
+	messages(f.Messages)
+	enums(f.Enums)
+	for _, s := range f.Services {
+		l.define(s.FullName, symbol{kind: "service", file: f.Name, line: s.Line}, s.Col)
+	}
+}
+
+func (l *loader) define(name string, sym symbol, col int) bool {
+	if existing, ok := l.symbols[name]; ok {
+		where := displayName(existing.file)
+		if existing.line > 0 {
+			where += fmt.Sprintf(" line %d", existing.line)
+		}
+		l.fail(sym.file, sym.line, col, "%s is already defined as a %s (%s)", name, existing.kind, where)
+		return false
+	}
+	l.symbols[name] = sym
+	return true
+}
+
+func displayName(file string) string {
+	if file == "" {
+		return "this file"
+	}
+	return file
+}
+
+// importFile finds an imported file: in the uploaded set, by its path or by
+// a unique path ending (uploads often keep the directory the import paths
+// are relative to), then among the well-known files.
+func (l *loader) importFile(importPath string) *File {
+	if f, ok := l.files[importPath]; ok {
+		return f
+	}
+	var match *File
+	for _, f := range l.schema.Files {
+		if strings.HasSuffix(f.Name, "/"+importPath) {
+			if match != nil {
+				return nil
+			}
+			match = f
+		}
+	}
+	if match != nil {
+		return match
+	}
+	src, ok := wellKnown[importPath]
+	if !ok {
+		return nil
+	}
+	f, err := ParseFile(importPath, []byte(src))
+	if err != nil {
+		return nil
+	}
+	l.files[importPath] = f
+	l.declare(f)
+	return f
+}
+
+// visible returns the files whose types f can use: itself, its imports and
+// whatever those import publicly. Missing imports are reported.
+func (l *loader) visible(f *File) map[string]bool {
+	visible := map[string]bool{f.Name: true}
+	var public func(*File)
+	public = func(imported *File) {
+		for _, imp := range imported.Imports {
+			if !imp.Public {
+				continue
+			}
+			if g := l.importFile(imp.Path); g != nil && !visible[g.Name] {
+				visible[g.Name] = true
+				public(g)
+			}
+		}
+	}
+	for _, imp := range f.Imports {
+		g := l.importFile(imp.Path)
+		if g == nil {
+			l.fail(f.Name, imp.Line, imp.Col, "import %q not found among the uploaded files", imp.Path)
+			continue
+		}
+		if !visible[g.Name] {
+			visible[g.Name] = true
+			public(g)
+		}
+	}
+	return visible
+}
+
+// resolve finds the type a reference names, searching from the innermost
+// scope outward like protoc; a leading dot makes the name absolute.
+func (l *loader) resolve(file string, scope, name string, visible map[string]bool) (symbol, string, string) {
+	var candidates []string
+	if strings.HasPrefix(name, ".") {
+		candidates = []string{name[1:]}
+	} else {
+		for {
+			candidates = append(candidates, join(scope, name))
+			if scope == "" {
+				break
+			}
+			scope = parentScope(scope)
+		}
+	}
+	for _, full := range candidates {
+		sym, ok := l.symbols[full]
+		if !ok || (sym.kind != "message" && sym.kind != "enum") {
+			continue
+		}
+		if !visible[sym.file] {
+			return symbol{}, "", fmt.Sprintf("%s is defined in %s, which %s does not import", name, sym.file, displayName(file))
+		}
+		return sym, full, ""
+	}
+	return symbol{}, "", fmt.Sprintf("unknown type %s", name)
+}
+
+func join(scope, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func parentScope(scope string) string {
+	if i := strings.LastIndexByte(scope, '.'); i >= 0 {
+		return scope[:i]
+	}
+	return ""
+}
+
+func (l *loader) checkFile(f *File, visible map[string]bool) {
+	var messages func([]*Message)
+	messages = func(ms []*Message) {
+		for _, m := range ms {
+			l.checkMessage(f, m, visible)
+			for _, e := range m.Enums {
+				l.checkEnum(f, e)
+			}
+			messages(m.Messages)
+		}
+	}
+	messages(f.Messages)
+	for _, e := range f.Enums {
+		l.checkEnum(f, e)
+	}
+	for _, s := range f.Services {
+		l.checkService(f, s, visible)
+	}
+}
+
+func (l *loader) checkMessage(f *File, m *Message, visible map[string]bool) {
+	numbers := map[int]string{}
+	names := map[string]bool{}
+	for _, field := range m.Fields {
+		switch {
+		case field.Number < 1:
+			l.fail(f.Name, field.Line, field.Col, "field %s.%s: number %d must be positive", m.Name, field.Name, field.Number)
+		case field.Number >= firstImplementationNumber && field.Number <= lastImplementationNumber:
+			l.fail(f.Name, field.Line, field.Col, "field %s.%s: numbers %d to %d are reserved for the protobuf implementation", m.Name, field.Name, firstImplementationNumber, lastImplementationNumber)
+		case m.Reserved.HasNumber(field.Number):
+			l.fail(f.Name, field.Line, field.Col, "field %s.%s: number %d is reserved", m.Name, field.Name, field.Number)
+		}
+		if other, ok := numbers[field.Number]; ok {
+			l.fail(f.Name, field.Line, field.Col, "field %s.%s: number %d is already used by %s", m.Name, field.Name, field.Number, other)
+		}
+		numbers[field.Number] = field.Name
+		if names[field.Name] {
+			l.fail(f.Name, field.Line, field.Col, "field %s.%s is declared twice", m.Name, field.Name)
+		}
+		names[field.Name] = true
+		if m.Reserved.HasName(field.Name) {
+			l.fail(f.Name, field.Line, field.Col, "field %s.%s: name %s is reserved", m.Name, field.Name, field.Name)
+		}
+		if f.Syntax == "proto3" && field.Label == "required" {
+			l.fail(f.Name, field.Line, field.Col, "field %s.%s: required fields are not allowed in proto3", m.Name, field.Name)
+		}
+
+		if IsScalar(field.Type) {
+			field.Resolved = field.Type
+			continue
+		}
+		sym, full, problem := l.resolve(f.Name, m.FullName, field.Type, visible)
+		if problem != "" {
+			l.fail(f.Name, field.Line, field.Col, "field %s.%s: %s", m.Name, field.Name, problem)
+			continue
+		}
+		field.Resolved, field.IsEnum = full, sym.kind == "enum"
+	}
+}
+
+func (l *loader) checkEnum(f *File, e *Enum) {
+	if len(e.Values) == 0 {
+		l.fail(f.Name, e.Line, e.Col, "enum %s has no values", e.Name)
+		return
+	}
+	if first := e.Values[0]; f.Syntax == "proto3" && first.Number != 0 {
+		l.fail(f.Name, first.Line, first.Col, "enum %s: the first value of a proto3 enum must be zero", e.Name)
+	}
+	numbers := map[int]string{}
+	names := map[string]bool{}
+	for _, v := range e.Values {
+		if other, ok := numbers[v.Number]; ok && !e.AllowAlias {
+			l.fail(f.Name, v.Line, v.Col, "enum %s: %s uses number %d already used by %s (set option allow_alias = true to allow aliases)", e.Name, v.Name, v.Number, other)
+		}
+		if _, ok := numbers[v.Number]; !ok {
+			numbers[v.Number] = v.Name
+		}
+		if names[v.Name] {
+			l.fail(f.Name, v.Line, v.Col, "enum %s: %s is declared twice", e.Name, v.Name)
+		}
+		names[v.Name] = true
+		if e.Reserved.HasNumber(v.Number) {
+			l.fail(f.Name, v.Line, v.Col, "enum %s: number %d is reserved", e.Name, v.Number)
+		}
+		if e.Reserved.HasName(v.Name) {
+			l.fail(f.Name, v.Line, v.Col, "enum %s: name %s is reserved", e.Name, v.Name)
+		}
+	}
+}
+
+func (l *loader) checkService(f *File, s *Service, visible map[string]bool) {
+	names := map[string]bool{}
+	for _, m := range s.Methods {
+		if names[m.Name] {
+			l.fail(f.Name, m.Line, m.Col, "rpc %s.%s is declared twice", s.Name, m.Name)
+		}
+		names[m.Name] = true
+		for _, arg := range []struct {
+			name     string
+			resolved *string
+		}{{m.Input, &m.InputResolved}, {m.Output, &m.OutputResolved}} {
+			sym, full, problem := l.resolve(f.Name, f.Package, arg.name, visible)
+			switch {
+			case problem != "":
+				l.fail(f.Name, m.Line, m.Col, "rpc %s.%s: %s", s.Name, m.Name, problem)
+			case sym.kind != "message":
+				l.fail(f.Name, m.Line, m.Col, "rpc %s.%s: %s is not a message", s.Name, m.Name, full)
+			default:
+				*arg.resolved = full
+			}
+		}
+	}
+}
+
+// IsProtoFile reports whether a path names a .proto file.
+func IsProtoFile(name string) bool {
+	return strings.EqualFold(path.Ext(name), ".proto")
+}
+
+var serviceRe = regexp.MustCompile(`(?m)^\s*service\s+\w+\s*\{`)
+
+// DefaultEntry picks the file a set is presented by: the only file, else the
+// only one declaring services.
+func DefaultEntry(files map[string][]byte) (string, bool) {
+	var only, withService []string
+	for name, content := range files {
+		only = append(only, name)
+		if serviceRe.Match(content) {
+			withService = append(withService, name)
+		}
+	}
+	switch {
+	case len(only) == 1:
+		return only[0], true
+	case len(withService) == 1:
+		return withService[0], true
+	}
+	return "", false
+}
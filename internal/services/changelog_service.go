@@ -0,0 +1,194 @@
+package services
+
+import (
+	"APIScope/internal/models"
+	"APIScope/internal/openapi"
+	"APIScope/internal/spectype"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ChangelogEntry summarises what changed in one version compared with the previous one.
+type ChangelogEntry struct {
+	Version     string            `json:"version"`
+	VersionID   string            `json:"version_id"`
+	CreatedAt   time.Time         `json:"created_at"`
+	ComparedTo  string            `json:"compared_to,omitempty"` // empty for the first version
+	Breaking    []openapi.Finding `json:"breaking"`
+	NonBreaking []openapi.Finding `json:"non_breaking"`
+	Error       string            `json:"error,omitempty"` // set when a version could not be compared
+}
+
+// Changelog lists the changes of a document, newest version first.
+type Changelog struct {
+	DocumentID string           `json:"document_id"`
+	Name       string           `json:"name"`
+	Entries    []ChangelogEntry `json:"entries"`
+}
+
+// ChangelogService derives a changelog from a document's version history.
+type ChangelogService struct {
+	storageService *StorageService
+}
+
+func NewChangelogService(storageService *StorageService) *ChangelogService {
+	return &ChangelogService{storageService: storageService}
+}
+
+// Build compares every version with its predecessor. Each stored spec is parsed once.
+func (s *ChangelogService) Build(doc *models.Document) *Changelog {
+	changelog := &Changelog{DocumentID: doc.ID, Name: doc.Name, Entries: []ChangelogEntry{}}
+
+	// doc.Versions is ordered oldest first
+	kind := spectype.Of(doc.Type)
+	specs := make([]spectype.Spec, len(doc.Versions))
+	errs := make([]error, len(doc.Versions))
+	for i := range doc.Versions {
+		specs[i], errs[i] = s.storageService.ParseVersion(kind, &doc.Versions[i])
+	}
+
+	for i := len(doc.Versions) - 1; i >= 0; i-- {
+		v := doc.Versions[i]
+		entry := ChangelogEntry{
+			Version:     v.Version,
+			VersionID:   v.ID,
+			CreatedAt:   v.CreatedAt,
+			Breaking:    []openapi.Finding{},
+			NonBreaking: []openapi.Finding{},
+		}
+		if i > 0 {
+			entry.ComparedTo = doc.Versions[i-1].Version
+			switch {
+			case errs[i] != nil:
+				entry.Error = errs[i].Error()
+			case errs[i-1] != nil:
+				entry.Error = errs[i-1].Error()
+			default:
+				report, err := specs[i-1].CheckCompatibility(specs[i])
+				if err != nil {
+					entry.Error = err.Error()
+					break
+				}
+				entry.Breaking, entry.NonBreaking = report.Breaking, report.NonBreaking
+			}
+		}
+		changelog.Entries = append(changelog.Entries, entry)
+	}
+	return changelog
+}
+
+// Markdown renders the changelog as a Markdown document.
+func (l *Changelog) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Changelog: %s\n", l.Name)
+	for _, e := range l.Entries {
+		fmt.Fprintf(&b, "\n## %s (%s)\n\n", e.Version, e.CreatedAt.UTC().Format("2006-01-02"))
+		writeEntryMarkdown(&b, e)
+	}
+	return b.String()
+}
+
+func writeEntryMarkdown(b *strings.Builder, e ChangelogEntry) {
+	switch {
+	case e.ComparedTo == "":
+		b.WriteString("Initial version.\n")
+		return
+	case e.Error != "":
+		fmt.Fprintf(b, "Could not compare with %s: %s\n", e.ComparedTo, e.Error)
+		return
+	case len(e.Breaking) == 0 && len(e.NonBreaking) == 0:
+		fmt.Fprintf(b, "No API changes since %s.\n", e.ComparedTo)
+		return
+	}
+	fmt.Fprintf(b, "Changes since %s.\n", e.ComparedTo)
+	if len(e.Breaking) > 0 {
+		b.WriteString("\n### Breaking changes\n\n")
+		for _, f := range e.Breaking {
+			fmt.Fprintf(b, "- `%s`: %s\n", f.Location, f.Message)
+		}
+	}
+	if len(e.NonBreaking) > 0 {
+		b.WriteString("\n### Other changes\n\n")
+		for _, f := range e.NonBreaking {
+			fmt.Fprintf(b, "- `%s`: %s\n", f.Location, f.Message)
+		}
+	}
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Link    []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// atomAuthor is the feed's author, which RFC 4287 requires when entries have none.
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Content atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// Atom renders the changelog as an Atom feed. baseURL is the public origin,
+// e.g. https://apiscope.example.com, used for links and IDs.
+func (l *Changelog) Atom(baseURL string) ([]byte, error) {
+	viewURL := fmt.Sprintf("%s/view/%s", baseURL, l.DocumentID)
+	feed := atomFeed{
+		ID:     fmt.Sprintf("%s/api/document/%s/changelog", baseURL, l.DocumentID),
+		Title:  "Changelog: " + l.Name,
+		Author: atomAuthor{Name: "APIScope"},
+		Link: []atomLink{
+			{Href: viewURL, Rel: "alternate"},
+			{Href: fmt.Sprintf("%s/api/document/%s/changelog?format=atom", baseURL, l.DocumentID), Rel: "self"},
+		},
+	}
+	for _, e := range l.Entries {
+		updated := e.CreatedAt.UTC().Format(time.RFC3339)
+		if feed.Updated == "" {
+			feed.Updated = updated // entries are newest first
+		}
+		title := e.Version
+		if len(e.Breaking) > 0 {
+			title += fmt.Sprintf(" (%d breaking)", len(e.Breaking))
+		}
+		var body strings.Builder
+		writeEntryMarkdown(&body, e)
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:      "urn:uuid:" + e.VersionID,
+			Title:   title,
+			Updated: updated,
+			Link:    atomLink{Href: viewURL + "?version=" + url.QueryEscape(e.Version)},
+			Content: atomContent{Type: "text", Body: body.String()},
+		})
+	}
+	if feed.Updated == "" {
+		feed.Updated = time.Now().UTC().Format(time.RFC3339)
+	}
+	out, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"APIScope/internal/models"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestChangelog(t *testing.T) *Changelog {
+	t.Helper()
+	_, storage := newTestDocumentService(t)
+	const header = "openapi: 3.0.3\ninfo: {title: Pets, version: '1'}\npaths:\n"
+	contents := []string{
+		header + "  /pets:\n    get:\n      responses: {'200': {description: ok}}\n  /pets/{id}:\n    get:\n      parameters: [{name: id, in: path, required: true, schema: {type: string}}]\n      responses: {'200': {description: ok}}\n",
+		header + "  /pets:\n    get:\n      responses: {'200': {description: ok}}\n    post:\n      responses: {'201': {description: created}}\n",
+		header + "  /pets:\n    get:\n      responses: {'200': {description: ok}}\n    post:\n      responses: {'201': {description: created}}\n",
+		"openapi: [",
+	}
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	doc := &models.Document{ID: "doc", Name: "Pets", Type: "openapi"}
+	for i, content := range contents {
+		v := models.Version{ID: fmt.Sprintf("v%d", i), DocumentID: doc.ID, Version: fmt.Sprintf("1.%d", i), CreatedAt: created.AddDate(0, 0, i)}
+		key, err := storage.SaveFile(doc.ID, v.Version, []byte(content), "yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		v.ObjectKey = key
+		doc.Versions = append(doc.Versions, v)
+	}
+	return NewChangelogService(storage).Build(doc)
+}
+
+func TestChangelogBuild(t *testing.T) {
+	changelog := newTestChangelog(t)
+	tests := []struct {
+		version, comparedTo   string
+		breaking, nonBreaking string // rule and location of each finding
+		error                 bool
+	}{
+		{version: "1.3", comparedTo: "1.2", error: true},
+		{version: "1.2", comparedTo: "1.1"},
+		{version: "1.1", comparedTo: "1.0", breaking: "operation-removed GET /pets/{id}", nonBreaking: "operation-added POST /pets"},
+		{version: "1.0"},
+	}
+	if len(changelog.Entries) != len(tests) {
+		t.Fatalf("got %d entries, want %d", len(changelog.Entries), len(tests))
+	}
+	for i, tt := range tests {
+		e := changelog.Entries[i]
+		if e.Version != tt.version || e.ComparedTo != tt.comparedTo {
+			t.Errorf("entry %d = %s compared to %q, want %s compared to %q", i, e.Version, e.ComparedTo, tt.version, tt.comparedTo)
+		}
+		if (e.Error != "") != tt.error {
+			t.Errorf("%s: error = %q, want one = %v", e.Version, e.Error, tt.error)
+		}
+		var breaking, nonBreaking []string
+		for _, f := range e.Breaking {
+			breaking = append(breaking, f.Rule+" "+f.Location)
+		}
+		for _, f := range e.NonBreaking {
+			nonBreaking = append(nonBreaking, f.Rule+" "+f.Location)
+		}
+		if got := strings.Join(breaking, ", "); got != tt.breaking {
+			t.Errorf("%s: breaking = %q, want %q", e.Version, got, tt.breaking)
+		}
+		if got := strings.Join(nonBreaking, ", "); got != tt.nonBreaking {
+			t.Errorf("%s: non-breaking = %q, want %q", e.Version, got, tt.nonBreaking)
+		}
+	}
+}
+
+func TestChangelogMarkdown(t *testing.T) {
+	md := newTestChangelog(t).Markdown()
+	for _, want := range []string{
+		"# Changelog: Pets\n",
+		"\n## 1.3 (2026-10-04)\n\nCould not compare with 1.2: ",
+		"\n## 1.2 (2026-10-03)\n\nNo API changes since 1.1.\n",
+		"\n## 1.1 (2026-10-02)\n\nChanges since 1.0.\n\n### Breaking changes\n\n- `GET /pets/{id}`: ",
+		"\n### Other changes\n\n- `POST /pets`: ",
+		"\n## 1.0 (2026-10-01)\n\nInitial version.\n",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown lacks %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestChangelogAtom(t *testing.T) {
+	out, err := newTestChangelog(t).Atom("https://apiscope.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed struct {
+		ID      string `xml:"id"`
+		Title   string `xml:"title"`
+		Updated string `xml:"updated"`
+		Author  struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Title   string `xml:"title"`
+			Updated string `xml:"updated"`
+			Link    struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+			Content struct {
+				Type string `xml:"type,attr"`
+				Body string `xml:",chardata"`
+			} `xml:"content"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(out, &feed); err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	if feed.Author.Name != "APIScope" {
+		t.Errorf("author = %q, want APIScope", feed.Author.Name)
+	}
+	if feed.ID != "https://apiscope.example.com/api/document/doc/changelog" || feed.Title != "Changelog: Pets" {
+		t.Errorf("id, title = %q, %q", feed.ID, feed.Title)
+	}
+	if feed.Updated != "2026-10-04T12:00:00Z" {
+		t.Errorf("updated = %q, want the newest version's date", feed.Updated)
+	}
+	if len(feed.Links) != 2 || feed.Links[0].Href != "https://apiscope.example.com/view/doc" || feed.Links[1].Rel != "self" {
+		t.Errorf("links = %+v", feed.Links)
+	}
+	if len(feed.Entries) != 4 {
+		t.Fatalf("got %d entries, want 4", len(feed.Entries))
+	}
+	e := feed.Entries[2]
+	if e.ID != "urn:uuid:v1" || e.Title != "1.1 (1 breaking)" || e.Updated != "2026-10-02T12:00:00Z" {
+		t.Errorf("entry = %+v", e)
+	}
+	if e.Link.Href != "https://apiscope.example.com/view/doc?version=1.1" {
+		t.Errorf("entry link = %q", e.Link.Href)
+	}
+	if e.Content.Type != "text" || !strings.HasPrefix(e.Content.Body, "Changes since 1.0.") {
+		t.Errorf("entry content = %+v", e.Content)
+	}
+}
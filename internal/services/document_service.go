@@ -1,47 +1,96 @@
 package services
 
 import (
+	"APIScope/internal/config"
 	"APIScope/internal/database"
 	"APIScope/internal/models"
+	"APIScope/internal/openapi"
+	"APIScope/internal/spectype"
 	"APIScope/internal/utils"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 )
 
-type DocumentService struct{}
+type DocumentService struct {
+	repo   database.Repository
+	config *config.Config
+}
+
+func NewDocumentService(repo database.Repository, cfg *config.Config) *DocumentService {
+	return &DocumentService{repo: repo, config: cfg}
+}
+
+// ErrInvalidTTL is returned when a requested document lifetime is malformed or not allowed.
+var ErrInvalidTTL = errors.New("invalid ttl")
+
+// ResolveExpiry turns a requested lifetime ("" = instance default, "never",
+// "14d", "36h") into an absolute expiry, enforcing the operator limits.
+// A zero time means the document never expires.
+func (s *DocumentService) ResolveExpiry(requested string, now time.Time) (time.Time, error) {
+	var ttl time.Duration
+	never := false
+	if strings.TrimSpace(requested) == "" {
+		ttl = s.config.LinkExpiration
+		never = ttl == 0
+	} else {
+		var err error
+		ttl, never, err = utils.ParseTTL(requested)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidTTL, requested)
+		}
+		if never && !s.config.AllowNeverExpire {
+			return time.Time{}, fmt.Errorf("%w: documents that never expire are not allowed", ErrInvalidTTL)
+		}
+		if !never && s.config.MaxLinkExpiration > 0 && ttl > s.config.MaxLinkExpiration {
+			return time.Time{}, fmt.Errorf("%w: maximum is %s", ErrInvalidTTL, s.config.MaxLinkExpiration)
+		}
+	}
+	if never {
+		return time.Time{}, nil
+	}
+	return now.Add(ttl), nil
+}
 
-func NewDocumentService() *DocumentService {
-	return &DocumentService{}
+// CheckLifetime enforces MAX_DOCUMENT_LIFETIME: no document may expire later
+// than its creation plus the lifetime, nor never expire, however it is renewed.
+func (s *DocumentService) CheckLifetime(createdAt, expiresAt time.Time) error {
+	max := s.config.MaxDocumentLifetime
+	if max <= 0 {
+		return nil
+	}
+	if expiresAt.IsZero() || expiresAt.Sub(createdAt) > max {
+		return fmt.Errorf("%w: documents live at most %s, until %s", ErrInvalidTTL, max, createdAt.Add(max).UTC().Format(time.RFC3339))
+	}
+	return nil
 }
 
-func (s *DocumentService) CreateDocument(name, description string) (*models.Document, error) {
+// CreateDocument stores a new document that lives until expiresAt (zero = never).
+func (s *DocumentService) CreateDocument(name, description, docType string, expiresAt time.Time) (*models.Document, error) {
 	doc := &models.Document{
 		ID:          utils.GenerateDocumentID(),
 		Name:        name,
 		Description: description,
+		Type:        docType,
 		CreatedAt:   time.Now(),
-		ExpiresAt:   time.Now().Add(time.Hour * 24 * 30),
+		ExpiresAt:   expiresAt,
 		IsActive:    true,
 		Versions:    []models.Version{},
 	}
 
-	docJSON, err := json.Marshal(doc)
-	if err != nil {
-		return nil, err
-	}
-
-	key := fmt.Sprintf("document:%s", doc.ID)
-	err = database.GetRedisClient().Set(database.GetContext(), key, docJSON, time.Until(doc.ExpiresAt)).Err()
+	err := s.repo.SaveDocument(doc)
 	if err != nil {
 		return nil, err
 	}
 
 	// Add to active documents set
-	err = database.GetRedisClient().SAdd(database.GetContext(), "active_documents", doc.ID).Err()
+	err = s.repo.AddActiveDocument(doc.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -50,21 +99,16 @@ func (s *DocumentService) CreateDocument(name, description string) (*models.Docu
 }
 
 func (s *DocumentService) GetDocumentByID(id string) (*models.Document, error) {
-	key := fmt.Sprintf("document:%s", id)
-
-	docJSON, err := database.GetRedisClient().Get(database.GetContext(), key).Result()
-	if err != nil {
+	doc, err := s.repo.GetDocument(id)
+	if errors.Is(err, database.ErrNotFound) {
 		return nil, errors.New("document not found or expired")
 	}
-
-	var doc models.Document
-	err = json.Unmarshal([]byte(docJSON), &doc)
 	if err != nil {
 		return nil, err
 	}
 
 	// Check if document is still active and not expired
-	if !doc.IsActive || time.Now().After(doc.ExpiresAt) {
+	if !doc.IsActive || doc.IsExpired(time.Now()) {
 		return nil, errors.New("document not found or expired")
 	}
 
@@ -74,7 +118,7 @@ func (s *DocumentService) GetDocumentByID(id string) (*models.Document, error) {
 		doc.Versions = versions
 	}
 
-	return &doc, nil
+	return doc, nil
 }
 
 // GetDocumentByShareSlug retrieves a document using its share slug.
@@ -82,8 +126,7 @@ func (s *DocumentService) GetDocumentByShareSlug(slug string) (*models.Document,
 	if slug == "" {
 		return nil, errors.New("empty slug")
 	}
-	slugKey := fmt.Sprintf("share:%s", slug)
-	docID, err := database.GetRedisClient().Get(database.GetContext(), slugKey).Result()
+	docID, err := s.repo.GetShareSlug(slug)
 	if err != nil || docID == "" {
 		return nil, errors.New("share link not found")
 	}
@@ -92,27 +135,222 @@ func (s *DocumentService) GetDocumentByShareSlug(slug string) (*models.Document,
 
 // SetShareSlug assigns a one-time share slug to a document.
 func (s *DocumentService) SetShareSlug(doc *models.Document, slug string) error {
-	if doc.ShareSlug != "" {
-		return errors.New("share slug already set")
+	return s.updateDocument(doc, func(current *models.Document) error {
+		if current.ShareSlug != "" {
+			return errors.New("share slug already set")
+		}
+		// Map slug -> docID (same TTL as doc); fails if the slug is already used
+		claimed, err := s.repo.ClaimShareSlug(slug, current.ID, current.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			return errors.New("slug already taken")
+		}
+		current.ShareSlug = slug
+		return nil
+	})
+}
+
+// RenewDocument moves the document's expiry (zero = never) and applies it to
+// its versions and share slug in the same transaction. Like updateDocument it
+// works on a fresh copy of the document and refreshes doc with it.
+func (s *DocumentService) RenewDocument(doc *models.Document, expiresAt time.Time) error {
+	unlock, err := s.repo.LockDocument(doc.ID)
+	if err != nil {
+		return err
 	}
-	// Ensure slug not used
-	slugKey := fmt.Sprintf("share:%s", slug)
-	exists, _ := database.GetRedisClient().Exists(database.GetContext(), slugKey).Result()
-	if exists == 1 {
-		return errors.New("slug already taken")
+	defer unlock()
+
+	current, err := s.GetDocumentByID(doc.ID)
+	if err != nil {
+		return err
 	}
-	doc.ShareSlug = slug
-	// Persist updated doc
-	docJSON, err := json.Marshal(doc)
+	current.ExpiresAt = expiresAt
+	if err := s.repo.UpdateDocumentExpiry(current); err != nil {
+		return err
+	}
+	*doc = *current
+	return nil
+}
+
+// ListActiveDocumentIDs returns the IDs of documents that have not been deleted.
+// Some may have expired since; GetDocumentByID filters those out.
+func (s *DocumentService) ListActiveDocumentIDs() ([]string, error) {
+	return s.repo.ListActiveDocuments()
+}
+
+// updateDocument applies change to the stored document while it is locked,
+// re-reading it first, so a stale copy never overwrites what a concurrent
+// renewal, upload or policy change wrote. doc is refreshed with the result.
+func (s *DocumentService) updateDocument(doc *models.Document, change func(current *models.Document) error) error {
+	unlock, err := s.repo.LockDocument(doc.ID)
 	if err != nil {
 		return err
 	}
-	key := fmt.Sprintf("document:%s", doc.ID)
-	if err := database.GetRedisClient().Set(database.GetContext(), key, docJSON, time.Until(doc.ExpiresAt)).Err(); err != nil {
+	defer unlock()
+
+	current, err := s.GetDocumentByID(doc.ID)
+	if err != nil {
+		return err
+	}
+	if err := change(current); err != nil {
 		return err
 	}
-	// Map slug -> docID (same TTL as doc)
-	return database.GetRedisClient().Set(database.GetContext(), slugKey, doc.ID, time.Until(doc.ExpiresAt)).Err()
+	if err := s.repo.SaveDocument(current); err != nil {
+		return err
+	}
+	*doc = *current
+	return nil
+}
+
+// SetRetentionPolicy stores a per-document retention override (nil restores the instance default).
+func (s *DocumentService) SetRetentionPolicy(doc *models.Document, policy *models.RetentionPolicy) error {
+	if policy != nil && (policy.KeepLast < 0 || policy.MaxAgeDays < 0) {
+		return errors.New("retention limits must not be negative")
+	}
+	return s.updateDocument(doc, func(current *models.Document) error {
+		current.Retention = policy
+		return nil
+	})
+}
+
+// SetServerPolicy stores a per-document server policy (nil restores the instance
+// default). Under STRIP_OPENAPI_SERVERS a document may rewrite its servers but
+// not keep them.
+func (s *DocumentService) SetServerPolicy(doc *models.Document, policy *models.ServerPolicy) error {
+	if policy != nil {
+		switch policy.Mode {
+		case models.ServersKeep:
+			if s.config.StripServers {
+				return errors.New("servers cannot be kept while STRIP_OPENAPI_SERVERS is enabled")
+			}
+		case models.ServersStrip:
+		case models.ServersRewrite:
+			if policy.URL != "" {
+				u, err := url.Parse(policy.URL)
+				if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+					return errors.New("rewrite url must be an absolute http(s) URL")
+				}
+			}
+		default:
+			return fmt.Errorf("unknown server policy mode %q (keep, strip or rewrite)", policy.Mode)
+		}
+	}
+	return s.updateDocument(doc, func(current *models.Document) error {
+		current.Servers = policy
+		return nil
+	})
+}
+
+// SetRedactionPolicy stores per-document redaction rules for shared views (nil
+// restores the instance default). Only YAML and JSON documents can be redacted.
+func (s *DocumentService) SetRedactionPolicy(doc *models.Document, policy *models.RedactionPolicy) error {
+	if policy != nil {
+		for _, p := range policy.Paths {
+			if _, err := openapi.CompilePathPattern(p); err != nil {
+				return err
+			}
+		}
+	}
+	return s.updateDocument(doc, func(current *models.Document) error {
+		if kind := spectype.Of(current.Type); policy != nil && !kind.Tree() {
+			return fmt.Errorf("redaction does not apply to %s documents", kind.Label())
+		}
+		current.Redaction = policy
+		return nil
+	})
+}
+
+// UpdateVersionMetadata changes the pin flag and/or tags of a version. Nil arguments are left untouched.
+func (s *DocumentService) UpdateVersionMetadata(documentID, versionString string, pinned *bool, tags []string) (*models.Version, error) {
+	unlock, err := s.repo.LockDocument(documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	versions, err := s.getVersionsByDocumentID(documentID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range versions {
+		if versions[i].Version != versionString {
+			continue
+		}
+		if pinned != nil {
+			versions[i].Pinned = *pinned
+		}
+		if tags != nil {
+			versions[i].Tags = normalizeTags(tags)
+		}
+		if err := s.saveVersion(&versions[i]); err != nil {
+			return nil, err
+		}
+		return &versions[i], nil
+	}
+	return nil, errors.New("version not found")
+}
+
+// overlayNamePattern restricts overlay names to what fits a query parameter and an object key.
+var overlayNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,39}$`)
+
+// CheckOverlayName rejects names that cannot be used for an overlay.
+func CheckOverlayName(name string) error {
+	if !overlayNamePattern.MatchString(name) {
+		return errors.New("invalid overlay name (1-40 chars, a-z, 0-9, dashes, underscores)")
+	}
+	return nil
+}
+
+// SetVersionOverlay attaches the overlay stored under key to a version as name,
+// or detaches it when key is empty. It returns the key the name had before.
+func (s *DocumentService) SetVersionOverlay(documentID, versionString, name, key string) (string, error) {
+	if err := CheckOverlayName(name); err != nil {
+		return "", err
+	}
+	unlock, err := s.repo.LockDocument(documentID)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	versions, err := s.getVersionsByDocumentID(documentID)
+	if err != nil {
+		return "", err
+	}
+	for i := range versions {
+		if versions[i].Version != versionString {
+			continue
+		}
+		previous, ok := versions[i].Overlays[name]
+		if key == "" {
+			if !ok {
+				return "", ErrOverlayNotFound
+			}
+			delete(versions[i].Overlays, name)
+		} else {
+			if versions[i].Overlays == nil {
+				versions[i].Overlays = map[string]string{}
+			}
+			versions[i].Overlays[name] = key
+		}
+		return previous, s.saveVersion(&versions[i])
+	}
+	return "", errors.New("version not found")
+}
+
+func normalizeTags(tags []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
 }
 
 func (s *DocumentService) DeleteDocument(id string) error {
@@ -124,48 +362,98 @@ func (s *DocumentService) DeleteDocument(id string) error {
 
 	doc.IsActive = false
 
-	docJSON, err := json.Marshal(doc)
+	err = s.repo.SaveDocument(doc)
 	if err != nil {
 		return err
 	}
 
-	key := fmt.Sprintf("document:%s", id)
-	err = database.GetRedisClient().Set(database.GetContext(), key, docJSON, time.Until(doc.ExpiresAt)).Err()
+	// Remove from active documents set
+	err = s.repo.RemoveActiveDocument(id)
 	if err != nil {
 		return err
 	}
 
-	// Remove from active documents set
-	err = database.GetRedisClient().SRem(database.GetContext(), "active_documents", id).Err()
-	return err
+	// Versions and the share slug live and die with the document
+	if doc.ShareSlug != "" {
+		if err := s.repo.DeleteShareSlug(doc.ShareSlug); err != nil {
+			return err
+		}
+	}
+	return s.repo.DeleteDocumentVersions(id)
 }
 
-func (s *DocumentService) AddVersion(documentID string, filePath string, customVersion string) (*models.Version, error) {
-	// Mark all existing versions as not latest
-	versions, _ := s.getVersionsByDocumentID(documentID)
-	for _, v := range versions {
-		v.IsLatest = false
-		s.saveVersion(&v)
+// ErrVersionConflict is returned when a version string is already used by the document.
+var ErrVersionConflict = errors.New("version already exists")
+
+// CheckVersionName rejects custom version strings that cannot be used in a
+// /version/:version route or would escape the document's key prefix.
+func CheckVersionName(version string) error {
+	if version == "" {
+		return nil
+	}
+	if len(version) > 64 || strings.HasPrefix(version, ".") || strings.Contains(version, "..") ||
+		strings.ContainsAny(version, "/\\?#%") || strings.IndexFunc(version, unicode.IsControl) >= 0 {
+		return errors.New("invalid version (at most 64 chars, no leading dot, '..', slashes, '?', '#' or '%')")
+	}
+	return nil
+}
+
+// AddVersion creates a new latest version of a document. The version string is
+// resolved (customVersion or the next vN) and store is called with the new
+// version to persist its files and set their object keys while the document is
+// locked, so concurrent uploads can neither reuse a version number nor leave
+// two versions flagged as latest.
+func (s *DocumentService) AddVersion(documentID string, customVersion string, store func(version *models.Version) error) (*models.Version, error) {
+	unlock, err := s.repo.LockDocument(documentID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	expiresAt, err := s.documentExpiry(documentID)
+	if err != nil {
+		return nil, err
 	}
 
+	versions, err := s.getVersionsByDocumentID(documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	var existingVersions []string
+	for _, v := range versions {
+		existingVersions = append(existingVersions, v.Version)
+	}
 	if customVersion == "" {
-		var existingVersions []string
-		for _, v := range versions {
-			existingVersions = append(existingVersions, v.Version)
-		}
 		customVersion = utils.GenerateVersionNumber(existingVersions)
 	}
+	for _, v := range existingVersions {
+		if v == customVersion {
+			return nil, fmt.Errorf("%w: %s", ErrVersionConflict, customVersion)
+		}
+	}
 
 	newVersion := &models.Version{
 		ID:         uuid.New().String(),
 		DocumentID: documentID,
 		Version:    customVersion,
-		FilePath:   filePath,
 		CreatedAt:  time.Now(),
 		IsLatest:   true,
 	}
+	if err := store(newVersion); err != nil {
+		return nil, err
+	}
+
+	// Mark all existing versions as not latest in the same write as the new one
+	var demoted []models.Version
+	for _, v := range versions {
+		if v.IsLatest {
+			v.IsLatest = false
+			demoted = append(demoted, v)
+		}
+	}
 
-	err := s.saveVersion(newVersion)
+	err = s.repo.CreateVersion(newVersion, demoted, expiresAt)
 	if err != nil {
 		return nil, err
 	}
@@ -174,44 +462,62 @@ func (s *DocumentService) AddVersion(documentID string, filePath string, customV
 }
 
 func (s *DocumentService) getVersionsByDocumentID(documentID string) ([]models.Version, error) {
-	pattern := fmt.Sprintf("version:%s:*", documentID)
-	keys, err := database.GetRedisClient().Keys(database.GetContext(), pattern).Result()
+	return s.repo.GetVersions(documentID)
+}
+
+// saveVersion rewrites a version record with the same expiry as its document.
+func (s *DocumentService) saveVersion(version *models.Version) error {
+	expiresAt, err := s.documentExpiry(version.DocumentID)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	return s.repo.SaveVersion(version, expiresAt)
+}
 
-	var versions []models.Version
-	for _, key := range keys {
-		versionJSON, err := database.GetRedisClient().Get(database.GetContext(), key).Result()
-		if err != nil {
-			continue
-		}
-
-		var version models.Version
-		err = json.Unmarshal([]byte(versionJSON), &version)
-		if err != nil {
-			continue
-		}
-
-		versions = append(versions, version)
+// documentExpiry returns the expiry of a live document.
+func (s *DocumentService) documentExpiry(documentID string) (time.Time, error) {
+	doc, err := s.repo.GetDocument(documentID)
+	if errors.Is(err, database.ErrNotFound) {
+		return time.Time{}, errors.New("document not found or expired")
 	}
-
-	return versions, nil
+	if err != nil {
+		return time.Time{}, err
+	}
+	return doc.ExpiresAt, nil
 }
 
-func (s *DocumentService) saveVersion(version *models.Version) error {
-	versionJSON, err := json.Marshal(version)
+// IsDocumentGone reports whether a document was deleted or has expired. Lookup
+// failures are returned as errors so callers never treat an outage as "gone".
+func (s *DocumentService) IsDocumentGone(documentID string) (bool, error) {
+	doc, err := s.repo.GetDocument(documentID)
+	if errors.Is(err, database.ErrNotFound) {
+		return true, nil
+	}
 	if err != nil {
-		return err
+		return false, err
 	}
+	return !doc.IsActive || doc.IsExpired(time.Now()), nil
+}
+
+// ListVersionedDocumentIDs returns every document ID that still has version records.
+func (s *DocumentService) ListVersionedDocumentIDs() ([]string, error) {
+	return s.repo.ListVersionedDocuments()
+}
 
-	key := fmt.Sprintf("version:%s:%s", version.DocumentID, version.ID)
-	return database.GetRedisClient().Set(database.GetContext(), key, versionJSON, 0).Err()
+// PurgeVersions removes all version records of a document.
+func (s *DocumentService) PurgeVersions(documentID string) error {
+	return s.repo.DeleteDocumentVersions(documentID)
 }
 
 // DeleteVersion removes a version identified by its human version string (e.g., v1, v2)
 // and updates the latest flag on the newest remaining version (by CreatedAt) if needed.
 func (s *DocumentService) DeleteVersion(documentID, versionString string) error {
+	unlock, err := s.repo.LockDocument(documentID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
 	versions, err := s.getVersionsByDocumentID(documentID)
 	if err != nil {
 		return err
@@ -231,9 +537,8 @@ func (s *DocumentService) DeleteVersion(documentID, versionString string) error
 		return errors.New("version not found")
 	}
 
-	// Delete the Redis key for the version
-	versionKey := fmt.Sprintf("version:%s:%s", target.DocumentID, target.ID)
-	if err := database.GetRedisClient().Del(database.GetContext(), versionKey).Err(); err != nil {
+	// Delete the stored version record
+	if err := s.repo.DeleteVersion(target.DocumentID, target.ID); err != nil {
 		return err
 	}
 
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"APIScope/internal/config"
+	"APIScope/internal/database"
+	"APIScope/internal/models"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestDocumentService(t *testing.T) (*DocumentService, *StorageService) {
+	t.Helper()
+	dir := t.TempDir()
+	repo, err := database.NewBoltRepository(filepath.Join(dir, "apiscope.db"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { repo.Close() })
+	cfg := &config.Config{LinkExpiration: time.Hour, MaxVersions: 100}
+	return NewDocumentService(repo, cfg), &StorageService{backend: NewFilesystemStorage(filepath.Join(dir, "documents"))}
+}
+
+func TestAddVersionConcurrent(t *testing.T) {
+	docs, storage := newTestDocumentService(t)
+	doc, err := docs.CreateDocument("Pets", "", "openapi", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const uploads = 20
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		contents = map[string]string{} // version ID -> what its upload stored
+		errs     []error
+		start    = make(chan struct{})
+	)
+	for i := 0; i < uploads; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			content := fmt.Sprintf("openapi: 3.0.0\ninfo: {title: upload %d, version: '1'}\n", i)
+			v, err := docs.AddVersion(doc.ID, "", func(v *models.Version) error {
+				key := doc.ID + "/" + v.Version + ".yaml"
+				if _, err := storage.backend.Get(key); !errors.Is(err, ErrObjectNotFound) {
+					return fmt.Errorf("%s already stored (%v)", key, err)
+				}
+				time.Sleep(time.Millisecond) // a storage write takes a while
+				key, err := storage.SaveFile(doc.ID, v.Version, []byte(content), "yaml")
+				v.ObjectKey = key
+				return err
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			contents[v.ID] = content
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	for _, err := range errs {
+		t.Error(err)
+	}
+
+	doc, err = docs.GetDocumentByID(doc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Versions) != uploads {
+		t.Fatalf("got %d versions, want %d", len(doc.Versions), uploads)
+	}
+	seen := map[string]bool{}
+	latest := 0
+	for _, v := range doc.Versions {
+		if seen[v.Version] {
+			t.Errorf("version %s used twice", v.Version)
+		}
+		seen[v.Version] = true
+		if v.IsLatest {
+			latest++
+		}
+		stored, err := storage.GetVersionFile(&v)
+		if err != nil {
+			t.Errorf("%s: %v", v.Version, err)
+		} else if string(stored) != contents[v.ID] {
+			t.Errorf("%s holds %q, want %q", v.Version, stored, contents[v.ID])
+		}
+	}
+	for i := 1; i <= uploads; i++ {
+		if !seen[fmt.Sprintf("v%d", i)] {
+			t.Errorf("version v%d missing", i)
+		}
+	}
+	if latest != 1 {
+		t.Errorf("%d versions flagged latest, want 1", latest)
+	}
+}
+
+func TestAddVersionConflict(t *testing.T) {
+	docs, _ := newTestDocumentService(t)
+	doc, err := docs.CreateDocument("Pets", "", "openapi", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	noop := func(*models.Version) error { return nil }
+	if _, err := docs.AddVersion(doc.ID, "1.0.0", noop); err != nil {
+		t.Fatal(err)
+	}
+
+	stored := false
+	_, err = docs.AddVersion(doc.ID, "1.0.0", func(*models.Version) error {
+		stored = true
+		return nil
+	})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("err = %v, want ErrVersionConflict", err)
+	}
+	if stored {
+		t.Error("store was called for a conflicting version")
+	}
+	doc, err = docs.GetDocumentByID(doc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Versions) != 1 || !doc.Versions[0].IsLatest {
+		t.Errorf("versions = %+v, want the original 1.0.0 as the only latest", doc.Versions)
+	}
+}
+
+func TestResolveExpiry(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		cfg       config.Config
+		requested string
+		want      time.Time // zero = never
+		invalid   bool
+	}{
+		{name: "instance default", cfg: config.Config{LinkExpiration: 30 * 24 * time.Hour}, want: now.AddDate(0, 0, 30)},
+		{name: "instance default of never", cfg: config.Config{}, want: time.Time{}},
+		{name: "days", cfg: config.Config{LinkExpiration: time.Hour}, requested: "7d", want: now.AddDate(0, 0, 7)},
+		{name: "duration", cfg: config.Config{LinkExpiration: time.Hour}, requested: "36h", want: now.Add(36 * time.Hour)},
+		{name: "never when allowed", cfg: config.Config{AllowNeverExpire: true}, requested: "never", want: time.Time{}},
+		{name: "never when not allowed", cfg: config.Config{}, requested: "never", invalid: true},
+		{name: "0 means never", cfg: config.Config{}, requested: "0", invalid: true},
+		{name: "zero duration", cfg: config.Config{AllowNeverExpire: true}, requested: "0s", invalid: true},
+		{name: "zero days", cfg: config.Config{AllowNeverExpire: true}, requested: "0d", invalid: true},
+		{name: "negative duration", cfg: config.Config{}, requested: "-1h", invalid: true},
+		{name: "negative days", cfg: config.Config{}, requested: "-3d", invalid: true},
+		{name: "malformed", cfg: config.Config{}, requested: "soon", invalid: true},
+		{name: "at the maximum", cfg: config.Config{MaxLinkExpiration: 7 * 24 * time.Hour}, requested: "7d", want: now.AddDate(0, 0, 7)},
+		{name: "over the maximum", cfg: config.Config{MaxLinkExpiration: 7 * 24 * time.Hour}, requested: "8d", invalid: true},
+		{name: "never is not capped by the maximum", cfg: config.Config{AllowNeverExpire: true, MaxLinkExpiration: time.Hour}, requested: "never", want: time.Time{}},
+	}
+	for _, tt := range tests {
+		s := NewDocumentService(nil, &tt.cfg)
+		got, err := s.ResolveExpiry(tt.requested, now)
+		if tt.invalid {
+			if !errors.Is(err, ErrInvalidTTL) {
+				t.Errorf("%s: ResolveExpiry(%q) = %v, %v, want ErrInvalidTTL", tt.name, tt.requested, got, err)
+			}
+			continue
+		}
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("%s: ResolveExpiry(%q) = %v, %v, want %v", tt.name, tt.requested, got, err, tt.want)
+		}
+	}
+}
+
+func TestCheckLifetime(t *testing.T) {
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		max       time.Duration
+		expiresAt time.Time
+		invalid   bool
+	}{
+		{name: "unlimited", expiresAt: created.AddDate(10, 0, 0)},
+		{name: "unlimited, never", expiresAt: time.Time{}},
+		{name: "within the lifetime", max: 180 * 24 * time.Hour, expiresAt: created.AddDate(0, 0, 90)},
+		{name: "at the lifetime", max: 180 * 24 * time.Hour, expiresAt: created.AddDate(0, 0, 180)},
+		{name: "past the lifetime", max: 180 * 24 * time.Hour, expiresAt: created.AddDate(0, 0, 180).Add(time.Second), invalid: true},
+		{name: "never", max: 180 * 24 * time.Hour, expiresAt: time.Time{}, invalid: true},
+	}
+	for _, tt := range tests {
+		s := NewDocumentService(nil, &config.Config{MaxDocumentLifetime: tt.max})
+		if err := s.CheckLifetime(created, tt.expiresAt); errors.Is(err, ErrInvalidTTL) != tt.invalid {
+			t.Errorf("%s: CheckLifetime = %v, want invalid = %v", tt.name, err, tt.invalid)
+		}
+	}
+}
+
+// TestRenewDocumentVersions checks that a renewal moves the expiry of the
+// version records along with the document's.
+func TestRenewDocumentVersions(t *testing.T) {
+	docs, _ := newTestDocumentService(t)
+	doc, err := docs.CreateDocument("Pets", "", "openapi", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	noop := func(*models.Version) error { return nil }
+	for _, v := range []string{"1.0.0", "1.1.0"} {
+		if _, err := docs.AddVersion(doc.ID, v, noop); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Renewed to expire shortly: the versions must go with the document
+	if err := docs.RenewDocument(doc, time.Now().Add(50*time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	// Renewed again before that: the versions must outlive the first renewal
+	renewed := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := docs.RenewDocument(doc, renewed); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	doc, err = docs.GetDocumentByID(doc.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !doc.ExpiresAt.Equal(renewed) || len(doc.Versions) != 2 {
+		t.Fatalf("after renewal: expires %v with %d versions, want %v with 2", doc.ExpiresAt, len(doc.Versions), renewed)
+	}
+
+	if err := docs.RenewDocument(doc, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if versions, err := docs.getVersionsByDocumentID(doc.ID); err != nil || len(versions) != 0 {
+		t.Errorf("versions after expiry = %+v, %v, want none", versions, err)
+	}
+}
+
+func TestSetRedactionPolicyKinds(t *testing.T) {
+	docs, _ := newTestDocumentService(t)
+	policy := &models.RedactionPolicy{Extension: "x-internal"}
+	for _, tt := range []struct {
+		kind string
+		ok   bool
+	}{
+		{kind: "openapi", ok: true},
+		{kind: "asyncapi", ok: true},
+		{kind: "protobuf"},
+		{kind: "graphql"},
+	} {
+		doc, err := docs.CreateDocument("Pets", "", tt.kind, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := docs.SetRedactionPolicy(doc, policy); (err == nil) != tt.ok {
+			t.Errorf("%s: err = %v, want accepted = %v", tt.kind, err, tt.ok)
+		}
+		if err := docs.SetRedactionPolicy(doc, nil); err != nil {
+			t.Errorf("%s: restoring the default: %v", tt.kind, err)
+		}
+	}
+}
+
+// TestPolicyUpdatesKeepConcurrentChanges checks that setting a policy through
+// a copy of the document read before another change does not undo it.
+func TestPolicyUpdatesKeepConcurrentChanges(t *testing.T) {
+	docs, _ := newTestDocumentService(t)
+	retention := &models.RetentionPolicy{KeepLast: 3}
+	servers := &models.ServerPolicy{Mode: models.ServersStrip}
+	redaction := &models.RedactionPolicy{Extension: "x-private", Tags: []string{"admin"}}
+	tests := []struct {
+		name  string
+		set   func(doc *models.Document) error
+		check func(stored *models.Document) bool
+	}{
+		{
+			name:  "retention",
+			set:   func(doc *models.Document) error { return docs.SetRetentionPolicy(doc, retention) },
+			check: func(stored *models.Document) bool { return stored.Retention != nil && *stored.Retention == *retention },
+		},
+		{
+			name:  "servers",
+			set:   func(doc *models.Document) error { return docs.SetServerPolicy(doc, servers) },
+			check: func(stored *models.Document) bool { return stored.Servers != nil && *stored.Servers == *servers },
+		},
+		{
+			name: "redaction",
+			set:  func(doc *models.Document) error { return docs.SetRedactionPolicy(doc, redaction) },
+			check: func(stored *models.Document) bool {
+				return stored.Redaction != nil && reflect.DeepEqual(*stored.Redaction, *redaction)
+			},
+		},
+	}
+	for _, tt := range tests {
+		doc, err := docs.CreateDocument("Pets", "", "openapi", time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stale := *doc
+
+		renewed := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+		if err := docs.RenewDocument(doc, renewed); err != nil {
+			t.Fatal(err)
+		}
+		if err := tt.set(&stale); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !stale.ExpiresAt.Equal(renewed) {
+			t.Errorf("%s: the caller's copy expires at %v, want %v", tt.name, stale.ExpiresAt, renewed)
+		}
+
+		stored, err := docs.GetDocumentByID(doc.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !stored.ExpiresAt.Equal(renewed) {
+			t.Errorf("%s: expiry = %v, want the renewal to %v to stick", tt.name, stored.ExpiresAt, renewed)
+		}
+		if !tt.check(stored) {
+			t.Errorf("%s: policy not stored: %+v", tt.name, stored)
+		}
+	}
+}
+
+func TestRenewalAndShareSlugKeepConcurrentChanges(t *testing.T) {
+	docs, _ := newTestDocumentService(t)
+	doc, err := docs.CreateDocument("Pets", "", "openapi", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale := *doc
+
+	policy := &models.RetentionPolicy{KeepLast: 3}
+	if err := docs.SetRetentionPolicy(doc, policy); err != nil {
+		t.Fatal(err)
+	}
+	renewed := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	if err := docs.RenewDocument(&stale, renewed); err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.SetShareSlug(doc, "pets"); err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.SetShareSlug(&stale, "other"); err == nil {
+		t.Error("a second share slug was set through a stale copy")
+	}
+
+	stored, err := docs.GetDocumentByShareSlug("pets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Retention == nil || *stored.Retention != *policy {
+		t.Errorf("retention = %+v, want %+v", stored.Retention, policy)
+	}
+	if !stored.ExpiresAt.Equal(renewed) {
+		t.Errorf("expiry = %v, want %v", stored.ExpiresAt, renewed)
+	}
+	if stored.ShareSlug != "pets" {
+		t.Errorf("share slug = %q, want pets", stored.ShareSlug)
+	}
+}
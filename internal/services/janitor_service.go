@@ -0,0 +1,81 @@
+package services
+
+import (
+	"APIScope/internal/config"
+	"log"
+	"time"
+)
+
+// JanitorService reclaims data that outlived its document: version records
+// written before versions expired with their document, and stored files of
+// deleted or expired documents.
+type JanitorService struct {
+	docService     *DocumentService
+	storageService *StorageService
+	config         *config.Config
+}
+
+func NewJanitorService(docService *DocumentService, storageService *StorageService, cfg *config.Config) *JanitorService {
+	return &JanitorService{
+		docService:     docService,
+		storageService: storageService,
+		config:         cfg,
+	}
+}
+
+// Start runs a sweep immediately and then every JanitorInterval until stop is closed.
+func (s *JanitorService) Start(stop <-chan struct{}) {
+	if s.config.JanitorInterval <= 0 {
+		return
+	}
+	go func() {
+		s.Sweep()
+		ticker := time.NewTicker(s.config.JanitorInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.Sweep()
+			}
+		}
+	}()
+}
+
+// Sweep removes orphaned version records and storage directories.
+func (s *JanitorService) Sweep() {
+	ids, err := s.docService.ListVersionedDocumentIDs()
+	if err != nil {
+		log.Printf("janitor: cannot list version records: %v", err)
+	} else {
+		for _, id := range ids {
+			gone, err := s.docService.IsDocumentGone(id)
+			if err != nil || !gone {
+				continue
+			}
+			if err := s.docService.PurgeVersions(id); err != nil {
+				log.Printf("janitor: document %s: cannot purge version records: %v", id, err)
+				continue
+			}
+			log.Printf("janitor: removed orphaned version records of document %s", id)
+		}
+	}
+
+	ids, err = s.storageService.ListDocumentIDs()
+	if err != nil {
+		log.Printf("janitor: cannot list storage: %v", err)
+		return
+	}
+	for _, id := range ids {
+		gone, err := s.docService.IsDocumentGone(id)
+		if err != nil || !gone {
+			continue
+		}
+		if err := s.storageService.DeleteDocument(id); err != nil {
+			log.Printf("janitor: document %s: cannot delete stored files: %v", id, err)
+			continue
+		}
+		log.Printf("janitor: removed stored files of document %s", id)
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"APIScope/internal/config"
+	"APIScope/internal/models"
+	"APIScope/internal/openapi"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LintService runs the instance ruleset (LINT_RULESET, optionally refined by
+// LINT_RULESET_FILE) on uploaded versions.
+type LintService struct {
+	storageService *StorageService
+	ruleset        *openapi.Ruleset
+}
+
+func NewLintService(storageService *StorageService, cfg *config.Config) (*LintService, error) {
+	ruleset, ok := openapi.BuiltinRuleset(cfg.LintRuleset)
+	if !ok {
+		return nil, fmt.Errorf("unknown LINT_RULESET %q (recommended, strict or off)", cfg.LintRuleset)
+	}
+	if cfg.LintRulesetFile != "" {
+		var err error
+		ruleset, err = LoadRuleset(cfg.LintRulesetFile, ruleset)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &LintService{storageService: storageService, ruleset: ruleset}, nil
+}
+
+// rulesetFile is the YAML layout of a ruleset file:
+//
+//	extends: recommended   # optional, defaults to the LINT_RULESET ruleset
+//	rules:
+//	  path-kebab-case: error
+//	  operation-tags: off
+type rulesetFile struct {
+	Extends string            `yaml:"extends"`
+	Rules   map[string]string `yaml:"rules"`
+}
+
+// LoadRuleset reads a ruleset file whose rules override base (or the built-in
+// ruleset it extends).
+func LoadRuleset(path string, base *openapi.Ruleset) (*openapi.Ruleset, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("lint ruleset: %w", err)
+	}
+	var file rulesetFile
+	if err := yaml.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("lint ruleset %s: %w", path, err)
+	}
+	if file.Extends != "" {
+		var ok bool
+		if base, ok = openapi.BuiltinRuleset(file.Extends); !ok {
+			return nil, fmt.Errorf("lint ruleset %s: unknown ruleset %q to extend", path, file.Extends)
+		}
+	}
+	ruleset := &openapi.Ruleset{Name: path, Rules: map[string]openapi.LintSeverity{}}
+	for id, severity := range base.Rules {
+		ruleset.Rules[id] = severity
+	}
+	var errs []error
+	for id, value := range file.Rules {
+		if _, ok := openapi.LintRuleByID(id); !ok {
+			errs = append(errs, fmt.Errorf("unknown rule %q", id))
+			continue
+		}
+		severity, err := openapi.ParseLintSeverity(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %s: %w", id, err))
+			continue
+		}
+		ruleset.Rules[id] = severity
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, fmt.Errorf("lint ruleset %s: %w", path, err)
+	}
+	return ruleset, nil
+}
+
+// Lint runs the instance ruleset on a spec.
+func (s *LintService) Lint(spec *openapi.Spec) *openapi.LintReport {
+	return openapi.Lint(spec, s.ruleset)
+}
+
+// VersionReport returns the report stored when the version was uploaded. Versions
+// stored before linting existed are linted now, without storing the result.
+func (s *LintService) VersionReport(version *models.Version) (*openapi.LintReport, error) {
+	if version.LintKey != "" {
+		raw, err := s.storageService.GetFile(version.LintKey)
+		if err != nil {
+			return nil, err
+		}
+		var report openapi.LintReport
+		if err := json.Unmarshal(raw, &report); err != nil {
+			return nil, fmt.Errorf("version %s: corrupt lint report: %w", version.Version, err)
+		}
+		return &report, nil
+	}
+	spec, err := s.storageService.GetVersionSpec(version)
+	if err != nil {
+		return nil, err
+	}
+	return s.Lint(spec), nil
+}
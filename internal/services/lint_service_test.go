@@ -0,0 +1,104 @@
+package services
+
+import (
+	"APIScope/internal/config"
+	"APIScope/internal/openapi"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadRuleset(t *testing.T) {
+	recommended, _ := openapi.BuiltinRuleset("recommended")
+	tests := []struct {
+		name string
+		file string
+		base *openapi.Ruleset
+		want map[string]openapi.LintSeverity // severities to check, "" = not enabled
+		err  string
+	}{
+		{
+			name: "overrides the base",
+			file: "rules:\n  path-kebab-case: error\n  operation-tags: off\n",
+			base: recommended,
+			want: map[string]openapi.LintSeverity{
+				"path-kebab-case":       openapi.LintError,
+				"operation-tags":        openapi.LintOff,
+				"operation-operationId": openapi.LintWarn,
+			},
+		},
+		{
+			name: "extends another built-in ruleset",
+			file: "extends: off\nrules:\n  operation-operationId-unique: warning\n",
+			base: recommended,
+			want: map[string]openapi.LintSeverity{
+				"operation-operationId-unique": openapi.LintWarn,
+				"operation-operationId":        "",
+			},
+		},
+		{
+			name: "unknown rule",
+			file: "rules:\n  no-such-rule: error\n",
+			base: recommended,
+			err:  `unknown rule "no-such-rule"`,
+		},
+		{
+			name: "unknown severity",
+			file: "rules:\n  path-kebab-case: fatal\n",
+			base: recommended,
+			err:  "rule path-kebab-case: unknown severity",
+		},
+		{
+			name: "unknown ruleset to extend",
+			file: "extends: lenient\n",
+			base: recommended,
+			err:  `unknown ruleset "lenient"`,
+		},
+		{
+			name: "not YAML",
+			file: "rules: [\n",
+			base: recommended,
+			err:  "lint ruleset",
+		},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "ruleset.yaml")
+		if err := os.WriteFile(path, []byte(tt.file), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		rs, err := LoadRuleset(path, tt.base)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if rs.Name != path {
+			t.Errorf("%s: name = %q, want the file path", tt.name, rs.Name)
+		}
+		for id, want := range tt.want {
+			if got := rs.Rules[id]; got != want {
+				t.Errorf("%s: %s = %q, want %q", tt.name, id, got, want)
+			}
+		}
+	}
+	if recommended.Rules["path-kebab-case"] != openapi.LintWarn {
+		t.Error("loading a ruleset changed its base")
+	}
+}
+
+func TestNewLintService(t *testing.T) {
+	if _, err := NewLintService(nil, &config.Config{LintRuleset: "recommended"}); err != nil {
+		t.Errorf("recommended: %v", err)
+	}
+	if _, err := NewLintService(nil, &config.Config{LintRuleset: "lenient"}); err == nil {
+		t.Error("an unknown LINT_RULESET was accepted")
+	}
+	if _, err := NewLintService(nil, &config.Config{LintRuleset: "recommended", LintRulesetFile: filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Error("a missing LINT_RULESET_FILE was accepted")
+	}
+}
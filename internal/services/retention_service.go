@@ -0,0 +1,126 @@
+package services
+
+import (
+	"APIScope/internal/config"
+	"APIScope/internal/models"
+	"log"
+	"sort"
+	"time"
+)
+
+// RetentionService prunes old versions according to the instance policy
+// (MAX_VERSIONS, RETENTION_MAX_AGE_DAYS) or a document's own override.
+type RetentionService struct {
+	docService     *DocumentService
+	storageService *StorageService
+	config         *config.Config
+}
+
+func NewRetentionService(docService *DocumentService, storageService *StorageService, cfg *config.Config) *RetentionService {
+	return &RetentionService{
+		docService:     docService,
+		storageService: storageService,
+		config:         cfg,
+	}
+}
+
+// EffectivePolicy returns the policy that applies to a document.
+func (s *RetentionService) EffectivePolicy(doc *models.Document) models.RetentionPolicy {
+	if doc.Retention != nil {
+		return *doc.Retention
+	}
+	return models.RetentionPolicy{
+		KeepLast:   s.config.MaxVersions,
+		MaxAgeDays: s.config.RetentionMaxAgeDays,
+	}
+}
+
+// Start runs the pruner immediately and then every RetentionInterval until stop is closed.
+func (s *RetentionService) Start(stop <-chan struct{}) {
+	if s.config.RetentionInterval <= 0 {
+		return
+	}
+	go func() {
+		s.PruneAll()
+		ticker := time.NewTicker(s.config.RetentionInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.PruneAll()
+			}
+		}
+	}()
+}
+
+// PruneAll applies retention to every active document.
+func (s *RetentionService) PruneAll() {
+	ids, err := s.docService.ListActiveDocumentIDs()
+	if err != nil {
+		log.Printf("retention: cannot list documents: %v", err)
+		return
+	}
+	total := 0
+	for _, id := range ids {
+		doc, err := s.docService.GetDocumentByID(id)
+		if err != nil {
+			continue
+		}
+		removed, err := s.PruneDocument(doc)
+		if err != nil {
+			log.Printf("retention: document %s: %v", id, err)
+		}
+		total += len(removed)
+	}
+	if total > 0 {
+		log.Printf("retention: pruned %d versions across %d documents", total, len(ids))
+	}
+}
+
+// PruneDocument deletes the versions of doc that fall outside its policy and
+// returns the ones that were removed.
+func (s *RetentionService) PruneDocument(doc *models.Document) ([]models.Version, error) {
+	candidates := PruneCandidates(doc.Versions, s.EffectivePolicy(doc), time.Now())
+	var removed []models.Version
+	for _, v := range candidates {
+		if err := s.docService.DeleteVersion(doc.ID, v.Version); err != nil {
+			return removed, err
+		}
+		if err := s.storageService.DeleteVersionFile(&v); err != nil {
+			log.Printf("retention: document %s version %s: record removed but file delete failed: %v", doc.ID, v.Version, err)
+		}
+		log.Printf("retention: removed document %s version %s (created %s)", doc.ID, v.Version, v.CreatedAt.Format(time.RFC3339))
+		removed = append(removed, v)
+	}
+	return removed, nil
+}
+
+// PruneCandidates lists the versions a policy would remove: those beyond the
+// newest KeepLast and those older than MaxAgeDays, skipping protected versions.
+func PruneCandidates(versions []models.Version, policy models.RetentionPolicy, now time.Time) []models.Version {
+	sorted := make([]models.Version, len(versions))
+	copy(sorted, versions)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+
+	var cutoff time.Time
+	if policy.MaxAgeDays > 0 {
+		cutoff = now.AddDate(0, 0, -policy.MaxAgeDays)
+	}
+
+	var out []models.Version
+	for i, v := range sorted {
+		if v.Protected() {
+			continue
+		}
+		tooMany := policy.KeepLast > 0 && i >= policy.KeepLast
+		tooOld := !cutoff.IsZero() && v.CreatedAt.Before(cutoff)
+		if tooMany || tooOld {
+			out = append(out, v)
+		}
+	}
+	return out
+}
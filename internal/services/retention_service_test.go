@@ -0,0 +1,190 @@
+package services
+
+import (
+	"APIScope/internal/config"
+	"APIScope/internal/models"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPruneCandidates(t *testing.T) {
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	// v1 is 50 days old, v5 10 days and the latest
+	versions := func() []models.Version {
+		var out []models.Version
+		for i := 1; i <= 5; i++ {
+			out = append(out, models.Version{
+				Version:   fmt.Sprintf("v%d", i),
+				CreatedAt: now.Add(-time.Duration(6-i) * 10 * day),
+				IsLatest:  i == 5,
+			})
+		}
+		return out
+	}
+	tests := []struct {
+		name     string
+		policy   models.RetentionPolicy
+		versions func(vs []models.Version) []models.Version
+		want     []string
+	}{
+		{
+			name:   "no limits",
+			policy: models.RetentionPolicy{},
+		},
+		{
+			name:   "max versions",
+			policy: models.RetentionPolicy{KeepLast: 2},
+			want:   []string{"v3", "v2", "v1"},
+		},
+		{
+			name:   "max versions above the count",
+			policy: models.RetentionPolicy{KeepLast: 10},
+		},
+		{
+			name:   "max age",
+			policy: models.RetentionPolicy{MaxAgeDays: 25},
+			want:   []string{"v3", "v2", "v1"},
+		},
+		{
+			name:   "max versions and max age together",
+			policy: models.RetentionPolicy{KeepLast: 4, MaxAgeDays: 35},
+			want:   []string{"v2", "v1"},
+		},
+		{
+			name:   "the latest version is kept however old",
+			policy: models.RetentionPolicy{KeepLast: 1, MaxAgeDays: 1},
+			versions: func(vs []models.Version) []models.Version {
+				for i := range vs {
+					vs[i].CreatedAt = vs[i].CreatedAt.Add(-365 * day)
+				}
+				return vs
+			},
+			want: []string{"v4", "v3", "v2", "v1"},
+		},
+		{
+			name:   "a latest version older than the rest still counts first",
+			policy: models.RetentionPolicy{KeepLast: 1},
+			versions: func(vs []models.Version) []models.Version {
+				vs[4].CreatedAt = now.Add(-100 * day)
+				return vs
+			},
+			want: []string{"v3", "v2", "v1"},
+		},
+		{
+			name:   "pinned and tagged versions are kept but still count",
+			policy: models.RetentionPolicy{KeepLast: 2},
+			versions: func(vs []models.Version) []models.Version {
+				vs[0].Pinned = true
+				vs[2].Tags = []string{"stable"}
+				return vs
+			},
+			want: []string{"v2"},
+		},
+		{
+			name:   "unsorted input",
+			policy: models.RetentionPolicy{KeepLast: 3},
+			versions: func(vs []models.Version) []models.Version {
+				return []models.Version{vs[2], vs[0], vs[4], vs[1], vs[3]}
+			},
+			want: []string{"v2", "v1"},
+		},
+	}
+	for _, tt := range tests {
+		vs := versions()
+		if tt.versions != nil {
+			vs = tt.versions(vs)
+		}
+		var got []string
+		for _, v := range PruneCandidates(vs, tt.policy, now) {
+			got = append(got, v.Version)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: pruned %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEffectivePolicy(t *testing.T) {
+	s := NewRetentionService(nil, nil, &config.Config{MaxVersions: 50, RetentionMaxAgeDays: 90})
+	tests := []struct {
+		name      string
+		retention *models.RetentionPolicy
+		want      models.RetentionPolicy
+	}{
+		{
+			name: "global default",
+			want: models.RetentionPolicy{KeepLast: 50, MaxAgeDays: 90},
+		},
+		{
+			name:      "document override",
+			retention: &models.RetentionPolicy{KeepLast: 3},
+			want:      models.RetentionPolicy{KeepLast: 3},
+		},
+		{
+			name:      "an empty override keeps everything",
+			retention: &models.RetentionPolicy{},
+			want:      models.RetentionPolicy{},
+		},
+	}
+	for _, tt := range tests {
+		if got := s.EffectivePolicy(&models.Document{Retention: tt.retention}); got != tt.want {
+			t.Errorf("%s: %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPruneDocument(t *testing.T) {
+	docs, storage := newTestDocumentService(t)
+	docs.config.MaxVersions = 2
+	retention := NewRetentionService(docs, storage, docs.config)
+	for _, name := range []string{"default", "override"} {
+		doc, err := docs.CreateDocument(name, "", "openapi", time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 4; i++ {
+			if _, err := docs.AddVersion(doc.ID, "", func(v *models.Version) error {
+				key, err := storage.SaveFile(doc.ID, v.Version, []byte("openapi: 3.0.0\n"), "yaml")
+				v.ObjectKey = key
+				return err
+			}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if name == "override" {
+			if err := docs.SetRetentionPolicy(doc, &models.RetentionPolicy{KeepLast: 3}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		doc, err = docs.GetDocumentByID(doc.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		removed, err := retention.PruneDocument(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]int{"default": 2, "override": 1}[name]
+		if len(removed) != want {
+			t.Errorf("%s: removed %d versions, want %d", name, len(removed), want)
+		}
+		for _, v := range removed {
+			if v.IsLatest {
+				t.Errorf("%s: removed the latest version %s", name, v.Version)
+			}
+			if _, err := storage.GetVersionFile(&v); err == nil {
+				t.Errorf("%s: the file of %s is still stored", name, v.Version)
+			}
+		}
+		doc, err = docs.GetDocumentByID(doc.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(doc.Versions) != 4-want {
+			t.Errorf("%s: %d versions left, want %d", name, len(doc.Versions), 4-want)
+		}
+	}
+}
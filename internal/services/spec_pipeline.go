@@ -0,0 +1,168 @@
+package services
+
+import (
+	"APIScope/internal/config"
+	"APIScope/internal/models"
+	"APIScope/internal/openapi"
+	"APIScope/internal/spectype"
+	"APIScope/internal/utils"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// SpecPipeline transforms stored specs before they leave the server, so the
+// content, downloads and viewer of a document follow its server policy, and
+// share links hide its internal parts.
+type SpecPipeline struct {
+	config *config.Config
+}
+
+// Audience is who a spec is prepared for.
+type Audience int
+
+const (
+	// AudienceOwner reached the document through its ID and sees the full spec.
+	AudienceOwner Audience = iota
+	// AudiencePublic reached the document through a share link; internal parts are redacted.
+	AudiencePublic
+)
+
+func NewSpecPipeline(cfg *config.Config) (*SpecPipeline, error) {
+	for _, p := range cfg.RedactPaths {
+		if _, err := openapi.CompilePathPattern(p); err != nil {
+			return nil, fmt.Errorf("REDACT_PATHS: %w", err)
+		}
+	}
+	return &SpecPipeline{config: cfg}, nil
+}
+
+// EffectiveServerPolicy returns the document's override, else the instance
+// policy: STRIP_OPENAPI_SERVERS, then AUTO_ADJUST_SERVER_ORIGIN.
+func (p *SpecPipeline) EffectiveServerPolicy(doc *models.Document) models.ServerPolicy {
+	if doc.Servers != nil {
+		return *doc.Servers
+	}
+	switch {
+	case p.config.StripServers:
+		return models.ServerPolicy{Mode: models.ServersStrip}
+	case p.config.AutoAdjustServerOrigin:
+		return models.ServerPolicy{Mode: models.ServersRewrite}
+	}
+	return models.ServerPolicy{Mode: models.ServersKeep}
+}
+
+// EffectiveRedaction returns the document's redaction rules, else the instance
+// ones (REDACT_EXTENSION, REDACT_TAGS, REDACT_PATHS). Kinds that are not YAML
+// or JSON trees are never redacted, so they get empty rules.
+func (p *SpecPipeline) EffectiveRedaction(doc *models.Document) models.RedactionPolicy {
+	if !spectype.Of(doc.Type).Tree() {
+		return models.RedactionPolicy{}
+	}
+	if doc.Redaction != nil {
+		return *doc.Redaction
+	}
+	return models.RedactionPolicy{
+		Extension: p.config.RedactExtension,
+		Tags:      p.config.RedactTags,
+		Paths:     p.config.RedactPaths,
+	}
+}
+
+// Prepare applies the pipeline to a spec of doc. origin is the URL APIScope
+// was reached at, used when the rewrite policy names no URL. Overlays are
+// applied first, in order, so redaction and the server policy still hold.
+// Specs that are not YAML or JSON trees, like .proto files, pass unchanged.
+func (p *SpecPipeline) Prepare(doc *models.Document, content []byte, origin string, audience Audience, overlays ...*openapi.Overlay) ([]byte, error) {
+	if !spectype.Of(doc.Type).Tree() {
+		return content, nil
+	}
+	transforms, err := p.transforms(doc, origin, audience)
+	if err != nil {
+		return nil, err
+	}
+	var applied []openapi.NodeTransform
+	for _, o := range overlays {
+		applied = append(applied, o.Transform())
+	}
+	return openapi.Transform(content, append(applied, transforms...)...)
+}
+
+// Redact removes the internal parts of a spec of doc and leaves its servers
+// alone, for consumers that need the original servers of what is public.
+func (p *SpecPipeline) Redact(doc *models.Document, content []byte) ([]byte, error) {
+	if !spectype.Of(doc.Type).Tree() {
+		return content, nil
+	}
+	redact, err := p.redaction(doc)
+	if err != nil {
+		return nil, err
+	}
+	return openapi.Transform(content, redact)
+}
+
+// PrepareArchive applies the pipeline to every YAML and JSON file of a source
+// archive.
+func (p *SpecPipeline) PrepareArchive(doc *models.Document, archive []byte, origin string, audience Audience) ([]byte, error) {
+	if !spectype.Of(doc.Type).Tree() {
+		return archive, nil
+	}
+	transforms, err := p.transforms(doc, origin, audience)
+	if err != nil {
+		return nil, err
+	}
+	if len(transforms) == 0 {
+		return archive, nil
+	}
+	files, err := utils.ReadZip(archive, p.config.MaxFileSize)
+	if err != nil {
+		return nil, err
+	}
+	for name, content := range files {
+		switch strings.ToLower(path.Ext(name)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		if files[name], err = openapi.Transform(content, transforms...); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return utils.WriteZip(files)
+}
+
+func (p *SpecPipeline) transforms(doc *models.Document, origin string, audience Audience) ([]openapi.NodeTransform, error) {
+	var transforms []openapi.NodeTransform
+	if audience == AudiencePublic {
+		redact, err := p.redaction(doc)
+		if err != nil {
+			return nil, err
+		}
+		transforms = append(transforms, redact)
+	}
+	// Server policies are about HTTP origins; broker servers are left alone
+	if spectype.Of(doc.Type) != spectype.OpenAPI {
+		return transforms, nil
+	}
+	switch policy := p.EffectiveServerPolicy(doc); policy.Mode {
+	case models.ServersStrip:
+		transforms = append(transforms, openapi.StripServers())
+	case models.ServersRewrite:
+		target := policy.URL
+		if target == "" {
+			target = origin
+		}
+		u, err := url.Parse(target)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("server rewrite origin %q is not an absolute URL", target)
+		}
+		transforms = append(transforms, openapi.RewriteServers(u))
+	}
+	return transforms, nil
+}
+
+func (p *SpecPipeline) redaction(doc *models.Document) (openapi.NodeTransform, error) {
+	rules := p.EffectiveRedaction(doc)
+	return openapi.Redact(openapi.RedactionRules{Extension: rules.Extension, Tags: rules.Tags, Paths: rules.Paths})
+}
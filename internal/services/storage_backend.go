@@ -0,0 +1,47 @@
+package services
+
+import (
+	"APIScope/internal/config"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrObjectNotFound is returned by Storage implementations when a key does not exist.
+var ErrObjectNotFound = errors.New("object not found")
+
+// Storage is a backend-neutral object store for spec files.
+// Keys are slash separated and relative to the backend root, e.g. "<documentID>/v1.yaml".
+type Storage interface {
+	Put(key string, content []byte) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+	// List returns every key starting with prefix.
+	List(prefix string) ([]string, error)
+}
+
+// NewStorage builds the backend selected by STORAGE_BACKEND.
+func NewStorage(cfg *config.Config) (Storage, error) {
+	switch strings.ToLower(cfg.StorageBackend) {
+	case "", "filesystem", "fs", "local":
+		return NewFilesystemStorage(cfg.StoragePath), nil
+	case "s3", "minio":
+		return NewS3Storage(cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+}
+
+// cleanKey normalizes a key and rejects anything that could escape the backend root.
+func cleanKey(key string) (string, error) {
+	k := strings.Trim(strings.ReplaceAll(key, "\\", "/"), "/")
+	if k == "" {
+		return "", errors.New("empty storage key")
+	}
+	for _, part := range strings.Split(k, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", fmt.Errorf("invalid storage key %q", key)
+		}
+	}
+	return k, nil
+}
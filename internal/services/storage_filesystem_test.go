@@ -0,0 +1,203 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCleanKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		want    string
+		wantErr bool
+	}{
+		{key: "doc/v1.yaml", want: "doc/v1.yaml"},
+		{key: "/doc/v1.yaml/", want: "doc/v1.yaml"},
+		{key: `doc\v1.yaml`, want: "doc/v1.yaml"},
+		{key: "doc/.lint/v1.json", want: "doc/.lint/v1.json"},
+		{key: "doc/v1..yaml", want: "doc/v1..yaml"},
+		{key: "", wantErr: true},
+		{key: "/", wantErr: true},
+		{key: "..", wantErr: true},
+		{key: "../etc/passwd", wantErr: true},
+		{key: "doc/../../etc/passwd", wantErr: true},
+		{key: `doc\..\..\etc\passwd`, wantErr: true},
+		{key: "doc/./v1.yaml", wantErr: true},
+		{key: "doc//v1.yaml", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := cleanKey(tt.key)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("cleanKey(%q) = %q, want an error", tt.key, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("cleanKey(%q) = %q, %v; want %q", tt.key, got, err, tt.want)
+		}
+	}
+}
+
+func TestFilesystemStorage(t *testing.T) {
+	testStorage(t, NewFilesystemStorage(t.TempDir()), "doc")
+}
+
+func TestFilesystemStorageRejectsEscapingKeys(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "documents")
+	s := NewFilesystemStorage(root)
+	for _, key := range []string{"../outside.yaml", "doc/../../outside.yaml"} {
+		if err := s.Put(key, []byte("x")); err == nil {
+			t.Errorf("Put(%q) succeeded", key)
+		}
+		if _, err := s.Get(key); err == nil || errors.Is(err, ErrObjectNotFound) {
+			t.Errorf("Get(%q) = %v, want an invalid key error", key, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(root), "outside.yaml")); !os.IsNotExist(err) {
+		t.Fatalf("a file was written outside the storage root: %v", err)
+	}
+}
+
+func TestFilesystemStoragePutIsAtomic(t *testing.T) {
+	s := NewFilesystemStorage(t.TempDir())
+	old := bytes.Repeat([]byte("a"), 1<<20)
+	updated := bytes.Repeat([]byte("b"), 1<<20)
+	if err := s.Put("doc/v1.yaml", old); err != nil {
+		t.Fatal(err)
+	}
+
+	// Readers racing the writers must always see one of the complete contents
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	errs := make(chan error, 1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			got, err := s.Get("doc/v1.yaml")
+			if err == nil && !bytes.Equal(got, old) && !bytes.Equal(got, updated) {
+				err = errors.New("read a partially written object")
+			}
+			if err != nil {
+				select {
+				case errs <- err:
+				default:
+				}
+				return
+			}
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		content := old
+		if i%2 == 0 {
+			content = updated
+		}
+		if err := s.Put("doc/v1.yaml", content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	select {
+	case err := <-errs:
+		t.Fatal(err)
+	default:
+	}
+
+	entries, err := os.ReadDir(filepath.Join(s.root, "doc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".tmp-") {
+			t.Errorf("temp file %s left behind", e.Name())
+		}
+	}
+	info, err := os.Stat(filepath.Join(s.root, "doc", "v1.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0644 {
+		t.Errorf("mode = %o, want 644", perm)
+	}
+}
+
+func TestFilesystemStorageListSkipsTempFiles(t *testing.T) {
+	s := NewFilesystemStorage(t.TempDir())
+	if err := s.Put("doc/v1.yaml", []byte("openapi: 3.0.0")); err != nil {
+		t.Fatal(err)
+	}
+	// What an interrupted Put leaves behind
+	if err := os.WriteFile(filepath.Join(s.root, "doc", ".tmp-123"), []byte("open"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := s.List("doc/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "doc/v1.yaml" {
+		t.Errorf("List = %v, want [doc/v1.yaml]", keys)
+	}
+}
+
+// testStorage runs the behaviour every Storage backend must share against s,
+// using keys below prefix.
+func testStorage(t *testing.T, s Storage, prefix string) {
+	t.Helper()
+	keys := []string{prefix + "/v1.yaml", prefix + "/.lint/v1.json", prefix + "/.overlays/v1/public.yaml"}
+	for _, key := range keys {
+		if err := s.Put(key, []byte(key)); err != nil {
+			t.Fatalf("Put(%q): %v", key, err)
+		}
+	}
+	for _, key := range keys {
+		got, err := s.Get(key)
+		if err != nil || string(got) != key {
+			t.Errorf("Get(%q) = %q, %v", key, got, err)
+		}
+	}
+	if err := s.Put(keys[0], []byte("replaced")); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Get(keys[0]); string(got) != "replaced" {
+		t.Errorf("Get after overwrite = %q, want %q", got, "replaced")
+	}
+
+	listed, err := s.List(prefix + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(listed)
+	want := append([]string(nil), keys...)
+	sort.Strings(want)
+	if strings.Join(listed, ",") != strings.Join(want, ",") {
+		t.Errorf("List = %v, want %v", listed, want)
+	}
+
+	if _, err := s.Get(prefix + "/missing.yaml"); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Get(missing) = %v, want ErrObjectNotFound", err)
+	}
+	for _, key := range keys {
+		if err := s.Delete(key); err != nil {
+			t.Errorf("Delete(%q): %v", key, err)
+		}
+	}
+	if err := s.Delete(keys[0]); err != nil {
+		t.Errorf("Delete of a missing key: %v", err)
+	}
+	if listed, err := s.List(prefix + "/"); err != nil || len(listed) != 0 {
+		t.Errorf("List after Delete = %v, %v", listed, err)
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"APIScope/internal/config"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Storage stores objects in an S3-compatible bucket (AWS S3, MinIO, ...).
+type S3Storage struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+func NewS3Storage(cfg *config.Config) (*S3Storage, error) {
+	if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+		return nil, fmt.Errorf("S3_ENDPOINT and S3_BUCKET are required for the s3 storage backend")
+	}
+	client, err := minio.New(cfg.S3Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.S3AccessKey, cfg.S3SecretKey, ""),
+		Secure: cfg.S3UseSSL,
+		Region: cfg.S3Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+
+	ctx := context.Background()
+	exists, err := client.BucketExists(ctx, cfg.S3Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bucket %s: %w", cfg.S3Bucket, err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, cfg.S3Bucket, minio.MakeBucketOptions{Region: cfg.S3Region}); err != nil {
+			return nil, fmt.Errorf("failed to create bucket %s: %w", cfg.S3Bucket, err)
+		}
+	}
+
+	prefix := strings.Trim(cfg.S3Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &S3Storage{client: client, bucket: cfg.S3Bucket, prefix: prefix}, nil
+}
+
+func (s *S3Storage) objectName(key string) (string, error) {
+	k, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return s.prefix + k, nil
+}
+
+func (s *S3Storage) Put(key string, content []byte) error {
+	name, err := s.objectName(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(context.Background(), s.bucket, name, bytes.NewReader(content), int64(len(content)),
+		minio.PutObjectOptions{ContentType: "application/octet-stream"})
+	if err != nil {
+		return fmt.Errorf("failed to save object: %w", err)
+	}
+	return nil
+}
+
+func (s *S3Storage) Get(key string) ([]byte, error) {
+	name, err := s.objectName(key)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := s.client.GetObject(context.Background(), s.bucket, name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	defer obj.Close()
+	content, err := io.ReadAll(obj)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrObjectNotFound
+		}
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return content, nil
+}
+
+func (s *S3Storage) Delete(key string) error {
+	name, err := s.objectName(key)
+	if err != nil {
+		return err
+	}
+	// RemoveObject succeeds for missing keys, matching FilesystemStorage
+	return s.client.RemoveObject(context.Background(), s.bucket, name, minio.RemoveObjectOptions{})
+}
+
+func (s *S3Storage) List(prefix string) ([]string, error) {
+	var keys []string
+	opts := minio.ListObjectsOptions{Prefix: s.prefix + prefix, Recursive: true}
+	for obj := range s.client.ListObjects(context.Background(), s.bucket, opts) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", obj.Err)
+		}
+		keys = append(keys, strings.TrimPrefix(obj.Key, s.prefix))
+	}
+	return keys, nil
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"APIScope/internal/config"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// TestS3Storage runs against a real S3-compatible server, e.g.
+//
+//	docker run -p 9000:9000 minio/minio server /data
+//	S3_TEST_ENDPOINT=localhost:9000 S3_TEST_ACCESS_KEY_ID=minioadmin \
+//	S3_TEST_SECRET_ACCESS_KEY=minioadmin go test ./internal/services -run S3
+func TestS3Storage(t *testing.T) {
+	endpoint := os.Getenv("S3_TEST_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("S3_TEST_ENDPOINT not set")
+	}
+	bucket := os.Getenv("S3_TEST_BUCKET")
+	if bucket == "" {
+		bucket = "apiscope-test"
+	}
+	useSSL, _ := strconv.ParseBool(os.Getenv("S3_TEST_USE_SSL"))
+	s, err := NewS3Storage(&config.Config{
+		S3Endpoint:  endpoint,
+		S3Region:    os.Getenv("S3_TEST_REGION"),
+		S3Bucket:    bucket,
+		S3AccessKey: os.Getenv("S3_TEST_ACCESS_KEY_ID"),
+		S3SecretKey: os.Getenv("S3_TEST_SECRET_ACCESS_KEY"),
+		S3UseSSL:    useSSL,
+		S3Prefix:    "/test-" + strconv.FormatInt(time.Now().UnixNano(), 36) + "/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorage(t, s, "doc")
+
+	if err := s.Put("../outside.yaml", []byte("x")); err == nil {
+		t.Error("Put with an escaping key succeeded")
+	}
+}
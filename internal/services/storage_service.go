@@ -2,41 +2,127 @@ package services
 
 import (
 	"APIScope/internal/config"
+	"APIScope/internal/models"
+	"APIScope/internal/openapi"
+	"APIScope/internal/spectype"
+	"APIScope/internal/utils"
+	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
+	"strings"
 )
 
 type StorageService struct {
-	storagePath string
+	backend     Storage
+	maxFileSize int64
 }
 
-func NewStorageService(cfg *config.Config) *StorageService {
+func NewStorageService(cfg *config.Config) (*StorageService, error) {
+	backend, err := NewStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
 	return &StorageService{
-		storagePath: cfg.StoragePath,
+		backend:     backend,
+		maxFileSize: cfg.MaxFileSize,
+	}, nil
+}
+
+// SaveFile stores the spec for a version and returns its object key. The file
+// extension follows the format of the content.
+func (s *StorageService) SaveFile(documentID, version string, content []byte, format string) (string, error) {
+	key := documentID + "/" + version + openapi.FormatExtension(format)
+	if err := s.backend.Put(key, content); err != nil {
+		return "", err
 	}
+	return key, nil
 }
 
-func (s *StorageService) SaveFile(documentID, version string, content []byte) (string, error) {
-	docDir := filepath.Join(s.storagePath, documentID)
-	err := os.MkdirAll(docDir, 0755)
-	if err != nil {
-		return "", fmt.Errorf("failed to create directory: %w", err)
+// SaveConvertedFile stores the OpenAPI 3 conversion of a version and returns
+// its object key. Auxiliary files live under their own prefix (.oas3/, .src/,
+// .lint/, .overlays/) so no version string can collide with them.
+func (s *StorageService) SaveConvertedFile(documentID, version string, content []byte) (string, error) {
+	key := fmt.Sprintf("%s/.oas3/%s.yaml", documentID, version)
+	if err := s.backend.Put(key, content); err != nil {
+		return "", err
 	}
+	return key, nil
+}
 
-	filename := fmt.Sprintf("%s.yaml", version)
-	filePath := filepath.Join(docDir, filename)
+// SaveSourceArchive stores the original files of a multi-file upload and
+// returns its object key.
+func (s *StorageService) SaveSourceArchive(documentID, version string, archive []byte) (string, error) {
+	key := fmt.Sprintf("%s/.src/%s.zip", documentID, version)
+	if err := s.backend.Put(key, archive); err != nil {
+		return "", err
+	}
+	return key, nil
+}
 
-	err = os.WriteFile(filePath, content, 0644)
-	if err != nil {
-		return "", fmt.Errorf("failed to save file: %w", err)
+// SaveLintReport stores the lint report of a version and returns its object key.
+func (s *StorageService) SaveLintReport(documentID, version string, report []byte) (string, error) {
+	key := fmt.Sprintf("%s/.lint/%s.json", documentID, version)
+	if err := s.backend.Put(key, report); err != nil {
+		return "", err
 	}
+	return key, nil
+}
+
+// SaveOverlay stores a named overlay of a version and returns its object key.
+func (s *StorageService) SaveOverlay(documentID, version, name string, content []byte) (string, error) {
+	key := fmt.Sprintf("%s/.overlays/%s/%s%s", documentID, version, name, openapi.FormatExtension(openapi.DetectFormat(content)))
+	if err := s.backend.Put(key, content); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// CopyOverlays stores the overlays of a version again for another version of
+// the same document and returns the new name to key map.
+func (s *StorageService) CopyOverlays(documentID string, from *models.Version, version string) (map[string]string, error) {
+	if len(from.Overlays) == 0 {
+		return nil, nil
+	}
+	copied := make(map[string]string, len(from.Overlays))
+	for name, key := range from.Overlays {
+		content, err := s.GetFile(key)
+		if err != nil {
+			return copied, fmt.Errorf("overlay %s: %w", name, err)
+		}
+		if copied[name], err = s.SaveOverlay(documentID, version, name, content); err != nil {
+			return copied, fmt.Errorf("overlay %s: %w", name, err)
+		}
+	}
+	return copied, nil
+}
 
-	return filePath, nil
+// ErrOverlayNotFound is returned when a version has no overlay of the requested name.
+var ErrOverlayNotFound = errors.New("overlay not found")
+
+// GetVersionOverlays reads and parses the named overlays of a version, in the
+// order given.
+func (s *StorageService) GetVersionOverlays(version *models.Version, names []string) ([]*openapi.Overlay, error) {
+	var overlays []*openapi.Overlay
+	for _, name := range names {
+		key, ok := version.Overlays[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrOverlayNotFound, name)
+		}
+		content, err := s.GetFile(key)
+		if err != nil {
+			return nil, err
+		}
+		overlay, err := openapi.ParseOverlay(content)
+		if err != nil {
+			return nil, fmt.Errorf("overlay %s: %w", name, err)
+		}
+		overlays = append(overlays, overlay)
+	}
+	return overlays, nil
 }
 
-func (s *StorageService) GetFile(filePath string) ([]byte, error) {
-	content, err := os.ReadFile(filePath)
+func (s *StorageService) GetFile(key string) ([]byte, error) {
+	content, err := s.backend.Get(key)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
@@ -44,7 +130,152 @@ func (s *StorageService) GetFile(filePath string) ([]byte, error) {
 	return content, nil
 }
 
+func (s *StorageService) DeleteFile(key string) error {
+	return s.backend.Delete(key)
+}
+
+// GetVersionFile reads the stored spec of a version.
+func (s *StorageService) GetVersionFile(version *models.Version) ([]byte, error) {
+	key, err := VersionObjectKey(version)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetFile(key)
+}
+
+// VersionFormat returns the serialization of a version's stored spec, sniffing
+// the content of versions stored before the format was recorded.
+func VersionFormat(version *models.Version, content []byte) string {
+	if version.Format != "" {
+		return version.Format
+	}
+	return openapi.DetectFormat(content)
+}
+
+// GetVersionSpec reads and parses the stored spec of a version.
+func (s *StorageService) GetVersionSpec(version *models.Version) (*openapi.Spec, error) {
+	content, err := s.GetVersionFile(version)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := openapi.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("version %s: %w", version.Version, err)
+	}
+	return spec, nil
+}
+
+// ParseVersion reads and parses the stored document of a version as kind.
+// Kinds whose files stay separate are parsed from the source archive.
+func (s *StorageService) ParseVersion(kind spectype.Kind, version *models.Version) (spectype.Spec, error) {
+	if fs, ok := kind.(spectype.FileSet); ok && version.SourceKey != "" {
+		archive, err := s.GetFile(version.SourceKey)
+		if err != nil {
+			return nil, err
+		}
+		files, err := utils.ReadZip(archive, s.maxFileSize)
+		if err != nil {
+			return nil, fmt.Errorf("version %s: %w", version.Version, err)
+		}
+		spec, err := fs.ParseFiles(files, version.EntryPoint)
+		if err != nil {
+			return nil, fmt.Errorf("version %s: %w", version.Version, err)
+		}
+		return spec, nil
+	}
+	content, err := s.GetVersionFile(version)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := kind.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("version %s: %w", version.Version, err)
+	}
+	return spec, nil
+}
+
+// GetVersionOAS3 returns a version as OpenAPI 3: the stored conversion when
+// there is one, otherwise the original, converted on the fly if it is Swagger 2.
+func (s *StorageService) GetVersionOAS3(version *models.Version) ([]byte, error) {
+	if version.ConvertedKey != "" {
+		return s.GetFile(version.ConvertedKey)
+	}
+	content, err := s.GetVersionFile(version)
+	if err != nil {
+		return nil, err
+	}
+	spec, err := openapi.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("version %s: %w", version.Version, err)
+	}
+	if !spec.IsSwagger2() {
+		return content, nil
+	}
+	return openapi.ConvertToOAS3(spec).YAML()
+}
+
+// DeleteVersionFile removes the stored spec of a version and the files stored with it.
+func (s *StorageService) DeleteVersionFile(version *models.Version) error {
+	key, err := VersionObjectKey(version)
+	if err != nil {
+		return err
+	}
+	extras := []string{version.ConvertedKey, version.SourceKey, version.LintKey}
+	for _, overlay := range version.Overlays {
+		extras = append(extras, overlay)
+	}
+	for _, extra := range extras {
+		if extra == "" {
+			continue
+		}
+		if err := s.backend.Delete(extra); err != nil {
+			return err
+		}
+	}
+	return s.backend.Delete(key)
+}
+
 func (s *StorageService) DeleteDocument(documentID string) error {
-	docDir := filepath.Join(s.storagePath, documentID)
-	return os.RemoveAll(docDir)
+	keys, err := s.backend.List(documentID + "/")
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.backend.Delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListDocumentIDs returns the IDs of all documents that have stored objects.
+func (s *StorageService) ListDocumentIDs() ([]string, error) {
+	keys, err := s.backend.List("")
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var ids []string
+	for _, key := range keys {
+		id, _, ok := strings.Cut(key, "/")
+		if !ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// VersionObjectKey returns the storage key of a version. Versions written before
+// object keys existed only carry an absolute/relative FilePath laid out as
+// <storage>/<documentID>/<file>, which maps onto the same key.
+func VersionObjectKey(version *models.Version) (string, error) {
+	if version.ObjectKey != "" {
+		return version.ObjectKey, nil
+	}
+	if version.FilePath == "" {
+		return "", errors.New("version has no stored file")
+	}
+	return version.DocumentID + "/" + filepath.Base(version.FilePath), nil
 }
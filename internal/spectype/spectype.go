@@ -0,0 +1,336 @@
+// Package spectype tells apart the kinds of API description APIScope stores
+// and gives the upload, diff and changelog code one interface to validate,
+// parse and compare them, whatever the kind.
+package spectype
+
+import (
+	"errors"
+
+	"APIScope/internal/asyncapi"
+	"APIScope/internal/graphql"
+	"APIScope/internal/openapi"
+	"APIScope/internal/protobuf"
+)
+
+// Kind is one supported kind of API description.
+type Kind interface {
+	// Name identifies the kind in stored documents, e.g. openapi.
+	Name() string
+	// Label names the kind for people, e.g. OpenAPI.
+	Label() string
+	// Detect reports whether content declares itself as this kind.
+	Detect(content []byte) bool
+	// Validate returns every problem found in content, nil when it is valid.
+	Validate(content []byte) []openapi.ValidationError
+	// Parse reads a document of this kind.
+	Parse(content []byte) (Spec, error)
+	// Format returns the serialization content is stored and served as.
+	Format(content []byte) string
+	// Tree reports whether documents are YAML or JSON trees, which overlays,
+	// redaction, server policies and reformatting work on.
+	Tree() bool
+}
+
+// FileSet is implemented by kinds whose multi-file uploads stay separate
+// files, like .proto files importing each other, instead of being bundled
+// into one document. The entry file is stored as the document; the others
+// are read from the source archive.
+type FileSet interface {
+	Kind
+	// DetectFiles reports whether an uploaded file tree holds documents of this kind.
+	DetectFiles(files map[string][]byte) bool
+	// DefaultEntry picks the file a tree is presented by when the upload names none.
+	DefaultEntry(files map[string][]byte) (string, bool)
+	ValidateFiles(files map[string][]byte) []openapi.ValidationError
+	ParseFiles(files map[string][]byte, entry string) (Spec, error)
+}
+
+// Spec is a parsed document of some kind.
+type Spec interface {
+	Title() string
+	// Compare computes the semantic diff to a document of the same kind.
+	Compare(to Spec) (Diff, error)
+	// CheckCompatibility classifies the changes to a document of the same kind.
+	CheckCompatibility(to Spec) (*openapi.CompatibilityReport, error)
+}
+
+// Diff is the kind-specific result of Spec.Compare, rendered as JSON.
+type Diff interface {
+	Empty() bool
+}
+
+// Indexed is implemented by specs that are shown as a reference rendered on
+// the server instead of by a client-side viewer.
+type Indexed interface {
+	// Index lists what the spec declares; it is served as JSON and passed to
+	// the reference template.
+	Index() any
+}
+
+// ErrKindMismatch is returned when documents of different kinds are compared.
+var ErrKindMismatch = errors.New("documents are of different kinds")
+
+// OpenAPI is the kind of OpenAPI 3.x and Swagger 2.0 documents. Documents
+// stored before kinds were recorded are OpenAPI.
+var OpenAPI Kind = openAPIKind{}
+
+// AsyncAPI is the kind of AsyncAPI 2.x and 3.x documents.
+var AsyncAPI Kind = asyncAPIKind{}
+
+// Protobuf is the kind of Protocol Buffers service definitions (.proto files).
+var Protobuf Kind = protobufKind{}
+
+// GraphQL is the kind of GraphQL schema documents (SDL).
+var GraphQL Kind = graphQLKind{}
+
+// kinds is the detection order; OpenAPI comes last as the fallback. GraphQL
+// goes before Protobuf, as both can start with an enum and only GraphQL
+// checks that the rest parses.
+var kinds = []Kind{AsyncAPI, GraphQL, Protobuf, OpenAPI}
+
+// Detect returns the kind content declares. Content no kind recognises is
+// treated as OpenAPI, whose validation then explains what is missing.
+func Detect(content []byte) Kind {
+	for _, k := range kinds {
+		if k.Detect(content) {
+			return k
+		}
+	}
+	return OpenAPI
+}
+
+// DetectFiles returns the kind of an uploaded file tree whose files stay
+// separate, if it is one.
+func DetectFiles(files map[string][]byte) (FileSet, bool) {
+	for _, k := range kinds {
+		if fs, ok := k.(FileSet); ok && fs.DetectFiles(files) {
+			return fs, true
+		}
+	}
+	return nil, false
+}
+
+// Lookup returns the kind with the given name; "" is OpenAPI.
+func Lookup(name string) (Kind, bool) {
+	if name == "" {
+		return OpenAPI, true
+	}
+	for _, k := range kinds {
+		if k.Name() == name {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+// Of returns the kind of a stored document type, OpenAPI when it is unknown.
+func Of(name string) Kind {
+	if k, ok := Lookup(name); ok {
+		return k
+	}
+	return OpenAPI
+}
+
+type openAPIKind struct{}
+
+func (openAPIKind) Name() string  { return "openapi" }
+func (openAPIKind) Label() string { return "OpenAPI" }
+
+func (openAPIKind) Detect(content []byte) bool {
+	root, err := openapi.Parse(content)
+	return err == nil && root.Version() != ""
+}
+
+func (openAPIKind) Validate(content []byte) []openapi.ValidationError {
+	return openapi.Validate(content)
+}
+
+func (openAPIKind) Format(content []byte) string { return openapi.DetectFormat(content) }
+func (openAPIKind) Tree() bool                   { return true }
+
+func (openAPIKind) Parse(content []byte) (Spec, error) {
+	spec, err := openapi.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	return openAPISpec{spec}, nil
+}
+
+type openAPISpec struct{ *openapi.Spec }
+
+func (s openAPISpec) Title() string {
+	info, _ := s.Root["info"].(map[string]any)
+	title, _ := info["title"].(string)
+	return title
+}
+
+func (s openAPISpec) Compare(to Spec) (Diff, error) {
+	t, ok := to.(openAPISpec)
+	if !ok {
+		return nil, ErrKindMismatch
+	}
+	return openapi.Compare(s.Spec, t.Spec), nil
+}
+
+func (s openAPISpec) CheckCompatibility(to Spec) (*openapi.CompatibilityReport, error) {
+	t, ok := to.(openAPISpec)
+	if !ok {
+		return nil, ErrKindMismatch
+	}
+	return openapi.CheckCompatibility(s.Spec, t.Spec), nil
+}
+
+type asyncAPIKind struct{}
+
+func (asyncAPIKind) Name() string  { return "asyncapi" }
+func (asyncAPIKind) Label() string { return "AsyncAPI" }
+
+func (asyncAPIKind) Detect(content []byte) bool {
+	return asyncapi.Detect(content)
+}
+
+func (asyncAPIKind) Validate(content []byte) []openapi.ValidationError {
+	return asyncapi.Validate(content)
+}
+
+func (asyncAPIKind) Format(content []byte) string { return openapi.DetectFormat(content) }
+func (asyncAPIKind) Tree() bool                   { return true }
+
+func (asyncAPIKind) Parse(content []byte) (Spec, error) {
+	doc, err := asyncapi.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	return asyncAPISpec{doc}, nil
+}
+
+type asyncAPISpec struct{ *asyncapi.Document }
+
+func (s asyncAPISpec) Compare(to Spec) (Diff, error) {
+	t, ok := to.(asyncAPISpec)
+	if !ok {
+		return nil, ErrKindMismatch
+	}
+	return asyncapi.Compare(s.Document, t.Document), nil
+}
+
+func (s asyncAPISpec) CheckCompatibility(to Spec) (*openapi.CompatibilityReport, error) {
+	t, ok := to.(asyncAPISpec)
+	if !ok {
+		return nil, ErrKindMismatch
+	}
+	return asyncapi.CheckCompatibility(s.Document, t.Document), nil
+}
+
+type protobufKind struct{}
+
+func (protobufKind) Name() string                 { return "protobuf" }
+func (protobufKind) Label() string                { return "Protobuf" }
+func (protobufKind) Format(content []byte) string { return openapi.FormatProto }
+func (protobufKind) Tree() bool                   { return false }
+func (protobufKind) Detect(content []byte) bool   { return protobuf.Detect(content) }
+
+// A single .proto file has no path; its problems are reported by position only.
+func (protobufKind) Validate(content []byte) []openapi.ValidationError {
+	return protobuf.Validate(map[string][]byte{"": content})
+}
+
+func (protobufKind) Parse(content []byte) (Spec, error) {
+	schema, err := protobuf.Parse(map[string][]byte{"": content}, "")
+	if err != nil {
+		return nil, err
+	}
+	return protobufSpec{schema}, nil
+}
+
+func (protobufKind) DetectFiles(files map[string][]byte) bool {
+	return len(protoFiles(files)) > 0
+}
+
+func (protobufKind) DefaultEntry(files map[string][]byte) (string, bool) {
+	return protobuf.DefaultEntry(protoFiles(files))
+}
+
+func (protobufKind) ValidateFiles(files map[string][]byte) []openapi.ValidationError {
+	return protobuf.Validate(protoFiles(files))
+}
+
+func (protobufKind) ParseFiles(files map[string][]byte, entry string) (Spec, error) {
+	schema, err := protobuf.Parse(protoFiles(files), entry)
+	if err != nil {
+		return nil, err
+	}
+	return protobufSpec{schema}, nil
+}
+
+// protoFiles leaves out the files of a tree that are not .proto files, such
+// as a README next to them.
+func protoFiles(files map[string][]byte) map[string][]byte {
+	out := map[string][]byte{}
+	for name, content := range files {
+		if protobuf.IsProtoFile(name) {
+			out[name] = content
+		}
+	}
+	return out
+}
+
+type protobufSpec struct{ *protobuf.Schema }
+
+func (s protobufSpec) Index() any { return s.Schema.Index() }
+
+func (s protobufSpec) Compare(to Spec) (Diff, error) {
+	t, ok := to.(protobufSpec)
+	if !ok {
+		return nil, ErrKindMismatch
+	}
+	return protobuf.Compare(s.Schema, t.Schema), nil
+}
+
+func (s protobufSpec) CheckCompatibility(to Spec) (*openapi.CompatibilityReport, error) {
+	t, ok := to.(protobufSpec)
+	if !ok {
+		return nil, ErrKindMismatch
+	}
+	return protobuf.CheckCompatibility(s.Schema, t.Schema), nil
+}
+
+type graphQLKind struct{}
+
+func (graphQLKind) Name() string                 { return "graphql" }
+func (graphQLKind) Label() string                { return "GraphQL" }
+func (graphQLKind) Format(content []byte) string { return openapi.FormatGraphQL }
+func (graphQLKind) Tree() bool                   { return false }
+func (graphQLKind) Detect(content []byte) bool   { return graphql.Detect(content) }
+
+func (graphQLKind) Validate(content []byte) []openapi.ValidationError {
+	return graphql.Validate(content)
+}
+
+func (graphQLKind) Parse(content []byte) (Spec, error) {
+	schema, err := graphql.Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	return graphQLSpec{schema}, nil
+}
+
+type graphQLSpec struct{ *graphql.Schema }
+
+func (s graphQLSpec) Index() any { return s.Schema.Index() }
+
+func (s graphQLSpec) Compare(to Spec) (Diff, error) {
+	t, ok := to.(graphQLSpec)
+	if !ok {
+		return nil, ErrKindMismatch
+	}
+	return graphql.Compare(s.Schema, t.Schema), nil
+}
+
+func (s graphQLSpec) CheckCompatibility(to Spec) (*openapi.CompatibilityReport, error) {
+	t, ok := to.(graphQLSpec)
+	if !ok {
+		return nil, ErrKindMismatch
+	}
+	return graphql.CheckCompatibility(s.Schema, t.Schema), nil
+}
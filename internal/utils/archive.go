@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// IsZip reports whether content starts with a zip local file header.
+func IsZip(content []byte) bool {
+	return bytes.HasPrefix(content, []byte("PK\x03\x04"))
+}
+
+// CleanArchivePath normalizes a slash-separated path inside an uploaded tree
+// and rejects paths that would escape it.
+func CleanArchivePath(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	cleaned := path.Clean(name)
+	if path.IsAbs(name) || cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("invalid file path %q", name)
+	}
+	return cleaned, nil
+}
+
+// ReadZip extracts the regular files of a zip archive keyed by their path.
+// The total uncompressed size is capped at maxSize bytes.
+func ReadZip(content []byte, maxSize int64) (map[string][]byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive: %w", err)
+	}
+	files := map[string][]byte{}
+	var total int64
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() || strings.HasPrefix(path.Base(f.Name), ".") || strings.HasPrefix(f.Name, "__MACOSX/") {
+			continue
+		}
+		name, err := CleanArchivePath(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, maxSize-total+1))
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		total += int64(len(data))
+		if total > maxSize {
+			return nil, errors.New("archive content too large")
+		}
+		files[name] = data
+	}
+	if len(files) == 0 {
+		return nil, errors.New("archive is empty")
+	}
+	return files, nil
+}
+
+// WriteZip packs files into a zip archive, in path order.
+func WriteZip(files map[string][]byte) ([]byte, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range names {
+		f, err := w.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(files[name]); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
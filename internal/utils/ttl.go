@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTTL parses a document lifetime such as "30d", "12h", "90m" or "never".
+// A never-expiring TTL is reported with never=true and a zero duration.
+func ParseTTL(in string) (ttl time.Duration, never bool, err error) {
+	s := strings.ToLower(strings.TrimSpace(in))
+	switch s {
+	case "":
+		return 0, false, errors.New("empty ttl")
+	case "never", "0", "none":
+		return 0, true, nil
+	}
+	if strings.HasSuffix(s, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err != nil || days <= 0 {
+			return 0, false, errors.New("invalid ttl: " + in)
+		}
+		return time.Duration(days) * 24 * time.Hour, false, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, false, errors.New("invalid ttl: " + in)
+	}
+	return d, false, nil
+}
@@ -77,12 +77,12 @@ document.addEventListener('DOMContentLoaded', function() {
             }
 
             // Check file type
-            const validTypes = ['.yaml', '.yml', '.json'];
+            const validTypes = ['.yaml', '.yml', '.json', '.proto', '.graphql', '.graphqls', '.gql', '.zip'];
             const fileName = file.name.toLowerCase();
             const isValidType = validTypes.some(ext => fileName.endsWith(ext));
             if (!isValidType) {
                 e.preventDefault();
-                alert('Please upload a valid YAML or JSON file.');
+                alert('Please upload a YAML, JSON, .proto or GraphQL file, or a zip of several.');
                 return false;
             }
         } else if (uploadMethod === 'paste') {